@@ -0,0 +1,117 @@
+package ctags
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DefaultBin is the `--ctags-bin` default, looked up on PATH.
+const DefaultBin = "ctags"
+
+// Candidates lists where ctags is installed by the usual package managers on
+// `goos`, for when it isn't on PATH. Editors started from a shortcut often don't
+// see the PATH of a shell, and Scoop and Chocolatey shims are easy to miss.
+func Candidates(goos string, getenv func(string) string) []string {
+	if goos != "windows" {
+		return nil
+	}
+
+	dirOr := func(name, fallback string) string {
+		if dir := getenv(name); dir != "" {
+			return dir
+		}
+		return fallback
+	}
+	programData := dirOr("ProgramData", `C:\ProgramData`)
+
+	var candidates []string
+	candidates = append(candidates, filepath.Join(dirOr("ChocolateyInstall", filepath.Join(programData, "chocolatey")), "bin", "ctags.exe"))
+	scoop := getenv("SCOOP")
+	if userProfile := getenv("USERPROFILE"); scoop == "" && userProfile != "" {
+		scoop = filepath.Join(userProfile, "scoop")
+	}
+	if scoop != "" {
+		candidates = append(candidates, filepath.Join(scoop, "shims", "ctags.exe"))
+	}
+	candidates = append(candidates, filepath.Join(dirOr("SCOOP_GLOBAL", filepath.Join(programData, "scoop")), "shims", "ctags.exe"))
+	if localAppData := getenv("LOCALAPPDATA"); localAppData != "" {
+		candidates = append(candidates, filepath.Join(localAppData, "Microsoft", "WinGet", "Links", "ctags.exe"))
+	}
+	for _, programFiles := range []string{dirOr("ProgramFiles", `C:\Program Files`), getenv("ProgramFiles(x86)")} {
+		if programFiles == "" {
+			continue
+		}
+		candidates = append(candidates,
+			filepath.Join(programFiles, "Universal Ctags", "ctags.exe"),
+			filepath.Join(programFiles, "ctags", "ctags.exe"),
+		)
+	}
+	return append(candidates, filepath.Join(`C:\msys64`, "usr", "bin", "ctags.exe"))
+}
+
+// Locate returns the ctags binary to run. An explicit `--ctags-bin` is used as
+// given. The default is looked up on PATH and then among `candidates`; if none of
+// them exist it is returned unchanged, along with everything that was tried.
+func Locate(ctagsBin string, candidates []string) (string, []string) {
+	if ctagsBin != DefaultBin {
+		return ctagsBin, nil
+	}
+	if _, err := exec.LookPath(ctagsBin); err == nil {
+		return ctagsBin, nil
+	}
+
+	tried := []string{ctagsBin + " (on PATH)"}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+		tried = append(tried, candidate)
+	}
+	return ctagsBin, tried
+}
+
+// WSLHasCtags reports whether Universal Ctags is installed in the default WSL
+// distribution. It can't index Windows paths, but knowing it's there explains
+// why ctags works in a WSL shell and not here.
+func WSLHasCtags() bool {
+	if _, err := exec.LookPath("wsl.exe"); err != nil {
+		return false
+	}
+	output, err := exec.Command("wsl.exe", "-e", "ctags", "--version").Output()
+	return err == nil && strings.Contains(string(output), "Universal Ctags")
+}
+
+// InstallInstructions tells how to install Universal Ctags on this system.
+func InstallInstructions() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "You can install Universal Ctags with: brew install universal-ctags"
+	case "linux":
+		return "You can install Universal Ctags with:\n" +
+			"- Ubuntu/Debian: sudo apt-get install universal-ctags\n" +
+			"- Fedora: sudo dnf install ctags\n" +
+			"- Arch Linux: sudo pacman -S ctags"
+	case "windows":
+		return "You can install Universal Ctags with:\n" +
+			"- Chocolatey: choco install universal-ctags\n" +
+			"- Scoop: scoop install universal-ctags\n" +
+			"Or download from: https://github.com/universal-ctags/ctags-win32/releases"
+	default:
+		return "Please visit https://github.com/universal-ctags/ctags for installation instructions"
+	}
+}
+
+// Check fails unless `ctagsBin` is Universal Ctags with JSON output.
+func Check(ctagsBin string) error {
+	cmd := exec.Command(ctagsBin, "--version", "--output-format=json")
+	output, err := cmd.Output()
+	if err != nil || !strings.Contains(string(output), "Universal Ctags") {
+		return fmt.Errorf("%s command not found or incorrect version. Universal Ctags with JSON support is required.\n%s", ctagsBin, InstallInstructions())
+	}
+
+	return nil
+}
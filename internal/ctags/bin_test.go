@@ -0,0 +1,46 @@
+package ctags
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLocateCtags(t *testing.T) {
+	if candidates := Candidates("linux", os.Getenv); candidates != nil {
+		t.Fatalf("expected no install locations outside Windows, got %v", candidates)
+	}
+	env := map[string]string{"USERPROFILE": `C:\Users\dev`, "LOCALAPPDATA": `C:\Users\dev\AppData\Local`}
+	candidates := Candidates("windows", func(name string) string { return env[name] })
+	for _, want := range []string{
+		filepath.Join(`C:\ProgramData`, "chocolatey", "bin", "ctags.exe"),
+		filepath.Join(`C:\Users\dev`, "scoop", "shims", "ctags.exe"),
+		filepath.Join(`C:\Program Files`, "Universal Ctags", "ctags.exe"),
+	} {
+		if !slices.Contains(candidates, want) {
+			t.Fatalf("expected candidate %s in %v", want, candidates)
+		}
+	}
+
+	if bin, tried := Locate("/opt/ctags/bin/ctags", candidates); bin != "/opt/ctags/bin/ctags" || tried != nil {
+		t.Fatalf("expected an explicit --ctags-bin to be used as given, got %q %v", bin, tried)
+	}
+	if _, err := exec.LookPath(DefaultBin); err == nil {
+		t.Skip("ctags is on PATH")
+	}
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing", "ctags.exe")
+	installed := filepath.Join(dir, "ctags.exe")
+	if err := os.WriteFile(installed, nil, 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	if bin, _ := Locate(DefaultBin, []string{missing, installed}); bin != installed {
+		t.Fatalf("expected %s, got %s", installed, bin)
+	}
+	bin, tried := Locate(DefaultBin, []string{missing})
+	if bin != DefaultBin || !slices.Equal(tried, []string{"ctags (on PATH)", missing}) {
+		t.Fatalf("expected the tried candidates to be reported, got %q %v", bin, tried)
+	}
+}
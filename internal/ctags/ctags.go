@@ -0,0 +1,140 @@
+// Package ctags runs Universal Ctags and reads the tags it writes, as JSON lines
+// or as a tags file. Paths are left as ctags wrote them; resolving them is up to
+// the caller.
+package ctags
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Entry matches the JSON entry shape produced by Universal Ctags `--output-format=json`.
+type Entry struct {
+	Type      string   `json:"_type"`
+	Name      string   `json:"name"`
+	Path      string   `json:"path"`
+	Pattern   string   `json:"pattern"`
+	Kind      string   `json:"kind"`
+	Line      int      `json:"line"`
+	End       int      `json:"end,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	ScopeKind string   `json:"scopeKind,omitempty"`
+	TypeRef   string   `json:"typeref,omitempty"`
+	Signature string   `json:"signature,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Roles     string   `json:"roles,omitempty"`
+	Extras    string   `json:"extras,omitempty"`
+	Inherits  BaseList `json:"inherits,omitempty"`
+
+	// FromTagfile is set for entries read from a tagfile rather than produced by a scan.
+	FromTagfile bool `json:"-"`
+}
+
+// BaseList is the `inherits` field of a tag: the names of the classes or
+// interfaces it extends, separated by commas. ctags writes false when there are none.
+type BaseList string
+
+func (list *BaseList) UnmarshalJSON(data []byte) error {
+	var bases string
+	if json.Unmarshal(data, &bases) == nil {
+		*list = BaseList(bases)
+	}
+	return nil
+}
+
+// Names returns the base names of the list.
+func (list BaseList) Names() []string {
+	var names []string
+	for _, name := range strings.Split(string(list), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ArgOptions choose the tags `Args` asks ctags for.
+type ArgOptions struct {
+	ReferenceTags     bool   // Also tag references (--extras=+r).
+	QualifiedTags     bool   // Also tag scope-qualified names (--extras=+q).
+	EmbeddedLanguages bool   // Also tag code embedded in other languages (--extras=+g).
+	Languages         string // Only tag these languages, e.g. "Go,C".
+}
+
+// Args returns the arguments that make ctags write the tags `options` ask for as
+// JSON lines, followed by `extra`.
+func Args(options ArgOptions, extra ...string) []string {
+	args := []string{"--output-format=json", "--fields=+nrSlie"}
+	if options.ReferenceTags {
+		args = append(args, "--extras=+r")
+	}
+	if options.QualifiedTags {
+		args = append(args, "--extras=+q", "--fields=+E")
+	}
+	if options.EmbeddedLanguages {
+		args = append(args, "--extras=+g", "--map-HTML=+.vue", "--map-HTML=+.svelte")
+	}
+	if options.Languages != "" {
+		args = append(args, "--languages="+options.Languages)
+	}
+	return append(args, extra...)
+}
+
+// IsReference reports whether `entry` records a use of a name rather than its
+// definition. Ctags marks definitions with the "def" role; tags without a roles
+// field (older ctags, or `--fields=-r`) are treated as definitions.
+func IsReference(entry Entry) bool {
+	if entry.Roles == "" {
+		return false
+	}
+	for _, role := range strings.Split(entry.Roles, ",") {
+		if role == "def" {
+			return false
+		}
+	}
+	return true
+}
+
+// SplitReferences separates definition tags from reference tags.
+func SplitReferences(entries []Entry) (definitions, references []Entry) {
+	definitions = make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if IsReference(entry) {
+			references = append(references, entry)
+		} else {
+			definitions = append(definitions, entry)
+		}
+	}
+	return definitions, references
+}
+
+// stripPatternBOM removes a UTF-8 BOM that ctags copied into the search pattern
+// of a tag on the first line of a file.
+func stripPatternBOM(pattern string) string {
+	if rest, ok := strings.CutPrefix(pattern, "/^\ufeff"); ok {
+		return "/^" + rest
+	}
+	return pattern
+}
+
+// cutScopeKind splits a scope written with its kind, as ctags does with
+// `--fields=+Z` ("class:Foo"), into kind and scope. A "::" separator isn't a kind.
+func cutScopeKind(scope string) (kind, rest string, ok bool) {
+	kind, rest, ok = strings.Cut(scope, ":")
+	if !ok || kind == "" || strings.HasPrefix(rest, ":") {
+		return "", scope, false
+	}
+	for _, c := range kind {
+		if !isKindChar(c) {
+			return "", scope, false
+		}
+	}
+	return kind, rest, true
+}
+
+func isKindChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '_' || c == '$'
+}
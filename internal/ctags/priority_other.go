@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package ctags
+
+import "os/exec"
+
+// startCommand starts `cmd`. Lowering its priority isn't supported on this platform.
+func startCommand(cmd *exec.Cmd, lowPriority bool) error {
+	return cmd.Start()
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package ctags
+
+import (
+	"log/slog"
+	"os/exec"
+	"syscall"
+)
+
+// lowestNiceness is the niceness of `nice -n 19`, the lowest scheduling priority.
+const lowestNiceness = 19
+
+// startCommand starts `cmd`, at the lowest scheduling priority if `lowPriority`
+// is set. Failing to lower the priority isn't worth failing the scan over.
+func startCommand(cmd *exec.Cmd, lowPriority bool) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if lowPriority {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, cmd.Process.Pid, lowestNiceness); err != nil {
+			slog.Debug("failed to lower ctags priority", "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package ctags
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// idlePriorityClass is IDLE_PRIORITY_CLASS from the Windows process creation flags.
+const idlePriorityClass = 0x00000040
+
+// startCommand starts `cmd`, in the idle priority class if `lowPriority` is set.
+func startCommand(cmd *exec.Cmd, lowPriority bool) error {
+	if lowPriority {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.CreationFlags |= idlePriorityClass
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,81 @@
+package ctags
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// RunOptions are the conditions `Run` runs ctags under.
+type RunOptions struct {
+	// Sandbox runs ctags under the limits of `sandboxCommand` and kills it when
+	// it runs longer than `sandboxTimeout` or writes more than `sandboxOutputBytes`.
+	Sandbox bool
+	// LowPriority runs ctags at the lowest scheduling priority.
+	LowPriority bool
+}
+
+// Run runs `cmd`, a ctags command writing JSON lines (see `Args`), and returns
+// its tags, with their paths as ctags wrote them.
+func Run(cmd *exec.Cmd, options RunOptions) ([]Entry, error) {
+	if options.Sandbox {
+		sandboxCommand(cmd)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stdout from ctags command: %v", err)
+	}
+
+	if err := startCommand(cmd, options.LowPriority); err != nil {
+		return nil, fmt.Errorf("failed to start ctags command: %v", err)
+	}
+
+	var output io.Reader = stdout
+	var timedOut atomic.Bool
+	if options.Sandbox {
+		output = &limitedReader{reader: stdout, remaining: sandboxOutputBytes}
+		timer := time.AfterFunc(sandboxTimeout, func() {
+			timedOut.Store(true)
+			cmd.Process.Kill()
+		})
+		defer timer.Stop()
+	}
+
+	scanner := bufio.NewScanner(output)
+	var entries []Entry
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal([]byte(scanner.Text()), &entry); err != nil {
+			log.Printf("Failed to parse ctags JSON entry: %v", err)
+			continue
+		}
+		entry.Pattern = stripPatternBOM(entry.Pattern)
+		if kind, scope, ok := cutScopeKind(entry.Scope); ok && entry.ScopeKind == "" {
+			entry.ScopeKind, entry.Scope = kind, scope
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, errOutputLimit) {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}
+		return nil, fmt.Errorf("error reading ctags output: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if timedOut.Load() {
+			return nil, fmt.Errorf("ctags command killed after %v", sandboxTimeout)
+		}
+		return nil, fmt.Errorf("ctags command failed: %v", err)
+	}
+
+	return entries, nil
+}
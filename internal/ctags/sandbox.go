@@ -0,0 +1,39 @@
+package ctags
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Limits applied to each ctags process with `--sandbox`. They are far above what
+// a chunk of `SandboxChunkSize` ordinary files needs, and only stop a parser that
+// hangs or blows up on a malformed file.
+const (
+	SandboxChunkSize   = 200
+	sandboxCPUSeconds  = 120
+	sandboxMemoryBytes = 4 << 30
+	sandboxOutputBytes = 512 << 20
+	sandboxTimeout     = 5 * time.Minute
+)
+
+var errOutputLimit = errors.New("ctags output exceeds the sandbox limit")
+
+// limitedReader reads from `reader` until `remaining` bytes have been read, and
+// then fails with `errOutputLimit`, unlike `io.LimitReader`, which reports EOF.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, errOutputLimit
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
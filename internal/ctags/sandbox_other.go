@@ -0,0 +1,9 @@
+//go:build !unix
+
+package ctags
+
+import "os/exec"
+
+// sandboxCommand leaves `cmd` as it is: per-process CPU and memory limits aren't
+// supported on this platform, so only the timeout and output limit apply.
+func sandboxCommand(cmd *exec.Cmd) {}
@@ -0,0 +1,59 @@
+package ctags
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLimitedReader(t *testing.T) {
+	if _, err := io.ReadAll(&limitedReader{reader: strings.NewReader("0123456789"), remaining: 5}); err != errOutputLimit {
+		t.Fatalf("expected the output limit error, got %v", err)
+	}
+}
+
+// runFakeCtags runs a shell script in place of ctags under the sandbox. The
+// script writes one tag, named by the shell expression `name`.
+func runFakeCtags(t *testing.T, name string) []Entry {
+	t.Helper()
+	script := "#!/bin/sh\n" + `echo "{\"_type\": \"tag\", \"name\": \"` + name + `\", \"path\": \"a.go\", \"line\": 1, \"kind\": \"func\"}"` + "\n"
+	bin := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	entries, err := Run(exec.Command(bin), RunOptions{Sandbox: true})
+	if err != nil {
+		t.Fatalf("run fake ctags: %v", err)
+	}
+	return entries
+}
+
+func TestSandboxedCtags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+
+	entries := runFakeCtags(t, "cpu$(ulimit -t)")
+	if len(entries) != 1 || entries[0].Name != "cpu"+strconv.Itoa(sandboxCPUSeconds) || entries[0].Path != "a.go" {
+		t.Fatalf("expected ctags to run with the CPU limit, got %+v", entries)
+	}
+}
+
+func TestSandboxedCtagsWithoutNewPrivileges(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("no_new_privs is Linux only")
+	}
+	if _, err := exec.LookPath("setpriv"); err != nil {
+		t.Skip("setpriv isn't installed")
+	}
+
+	entries := runFakeCtags(t, `nnp$(awk '/^NoNewPrivs:/ { print $2 }' /proc/self/status)`)
+	if len(entries) != 1 || entries[0].Name != "nnp1" {
+		t.Fatalf("expected ctags to run with no_new_privs, got %+v", entries)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build unix
+
+package ctags
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+)
+
+// setprivPath finds util-linux's setpriv, which the sandbox uses on Linux to run
+// ctags with no_new_privs; it is empty where there is none.
+var setprivPath = sync.OnceValue(func() string {
+	path, err := exec.LookPath("setpriv")
+	if err != nil {
+		return ""
+	}
+	return path
+})
+
+// sandboxCommand makes `cmd` run under the CPU time and memory limits of the
+// sandbox, and without core dumps. The limits are set by a shell wrapping the
+// command, since Go can't set them for a child process alone. A limit the system
+// doesn't support (macOS ignores memory limits, for one) is skipped.
+// Where setpriv is installed, the command also runs with no_new_privs, so neither
+// it nor a program it starts can gain privileges through setuid binaries or file
+// capabilities. It can still read and write whatever the user can.
+func sandboxCommand(cmd *exec.Cmd) {
+	if cmd.Err != nil {
+		// Leave a command that can't be found to fail as it is.
+		return
+	}
+	script := fmt.Sprintf(`ulimit -t %d 2>/dev/null; ulimit -v %d 2>/dev/null; ulimit -c 0 2>/dev/null; exec "$0" "$@"`,
+		sandboxCPUSeconds, sandboxMemoryBytes>>10)
+	command := []string{cmd.Path}
+	if setpriv := setprivPath(); setpriv != "" {
+		command = []string{setpriv, "--no-new-privs", cmd.Path}
+	}
+	cmd.Args = append(append([]string{"/bin/sh", "-c", script}, command...), cmd.Args[1:]...)
+	cmd.Path = "/bin/sh"
+}
@@ -1,9 +1,7 @@
-package main
+package ctags
 
 import (
 	"bufio"
-	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -56,8 +54,8 @@ func (kindMap *tagfileKindMap) isKindName(kind string) bool {
 	return kindMap.kindNames[kind]
 }
 
-// findTagsFile checks for a tags file in a few conventional locations under `root`.
-func findTagsFile(root string) (string, bool) {
+// FindTagfile checks for a tags file in a few conventional locations under `root`.
+func FindTagfile(root string) (string, bool) {
 	tagsLocations := []string{
 		"tags",
 		".tags",
@@ -74,8 +72,9 @@ func findTagsFile(root string) (string, bool) {
 	return "", false
 }
 
-// parseTagfile reads a tags file and returns entries in the same shape as `processTagsOutput`.
-func parseTagfile(tagsPath string) ([]TagEntry, error) {
+// ParseTagfile reads a tags file and returns entries in the same shape as `Run`,
+// with their paths as written in the file.
+func ParseTagfile(tagsPath string) ([]Entry, error) {
 	file, err := os.Open(tagsPath)
 	if err != nil {
 		return nil, err
@@ -83,7 +82,7 @@ func parseTagfile(tagsPath string) ([]TagEntry, error) {
 	defer file.Close()
 
 	kindMap := newTagfileKindMap()
-	entries := make([]TagEntry, 0, 1024)
+	entries := make([]Entry, 0, 1024)
 
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
@@ -97,7 +96,7 @@ func parseTagfile(tagsPath string) ([]TagEntry, error) {
 			continue
 		}
 
-		entry, ok := parseTagfileEntry(line, tagsPath, kindMap)
+		entry, ok := parseTagfileEntry(line, kindMap)
 		if ok {
 			entries = append(entries, entry)
 		}
@@ -142,19 +141,19 @@ func parseTagfileKindDescription(line string, kindMap *tagfileKindMap) {
 	kindMap.add(language, letter, kind)
 }
 
-// parseTagfileEntry parses a single tags file line into a TagEntry.
-// It skips invalid entries and entries whose paths can't be normalized to file URIs.
-func parseTagfileEntry(line, tagsPath string, kindMap *tagfileKindMap) (TagEntry, bool) {
+// parseTagfileEntry parses a single tags file line into an Entry, skipping invalid lines.
+func parseTagfileEntry(line string, kindMap *tagfileKindMap) (Entry, bool) {
 	fields := strings.Split(line, "\t")
 	if len(fields) < 3 {
-		return TagEntry{}, false
+		return Entry{}, false
 	}
 
-	entry := TagEntry{
-		Type:    "tag",
-		Name:    fields[0],
-		Path:    fields[1],
-		Pattern: strings.TrimSuffix(fields[2], ";\""),
+	entry := Entry{
+		Type:        "tag",
+		Name:        fields[0],
+		Path:        fields[1],
+		Pattern:     stripPatternBOM(strings.TrimSuffix(fields[2], ";\"")),
+		FromTagfile: true,
 	}
 
 	kindField := ""
@@ -178,16 +177,31 @@ func parseTagfileEntry(line, tagsPath string, kindMap *tagfileKindMap) (TagEntry
 			if lineNum, err := strconv.Atoi(value); err == nil {
 				entry.Line = lineNum
 			}
+		case "end":
+			if end, err := strconv.Atoi(value); err == nil {
+				entry.End = end
+			}
 		case "language":
 			entry.Language = value
 		case "kind":
 			kindField = value
 		case "typeref":
 			entry.TypeRef = value
+		case "signature":
+			entry.Signature = value
 		case "scope":
 			entry.Scope = value
+			if kind, scope, ok := cutScopeKind(value); ok {
+				entry.ScopeKind, entry.Scope = kind, scope
+			}
 		case "scopeKind":
 			entry.ScopeKind = value
+		case "roles":
+			entry.Roles = value
+		case "extras":
+			entry.Extras = value
+		case "inherits":
+			entry.Inherits = BaseList(value)
 		default:
 			if entry.Scope == "" && entry.ScopeKind == "" && kindMap.isKindName(key) {
 				entry.ScopeKind = key
@@ -207,18 +221,11 @@ func parseTagfileEntry(line, tagsPath string, kindMap *tagfileKindMap) (TagEntry
 		entry.Kind = kindField
 	}
 
-	uri, err := tagfilePathToFileURI(tagsPath, entry.Path)
-	if err != nil {
-		log.Printf("Failed to normalize path for %s: %v", entry.Path, err)
-		return TagEntry{}, false
-	}
-	entry.Path = uri
-
 	return entry, true
 }
 
 // resolveTagfileKind maps a kind letter to its kind name using tagfile metadata.
-func resolveTagfileKind(kindField string, entry *TagEntry, kindMap *tagfileKindMap) string {
+func resolveTagfileKind(kindField string, entry *Entry, kindMap *tagfileKindMap) string {
 	if len(kindField) != 1 {
 		return kindField
 	}
@@ -228,17 +235,3 @@ func resolveTagfileKind(kindField string, entry *TagEntry, kindMap *tagfileKindM
 	}
 	return kindField
 }
-
-// tagfilePathToFileURI normalizes a tags-file path to an absolute file URI.
-// Relative paths are interpreted relative to the tagfile's directory.
-func tagfilePathToFileURI(tagsPath, raw string) (string, error) {
-	if raw == "" {
-		return "", fmt.Errorf("empty path")
-	}
-	baseDir := filepath.Dir(tagsPath)
-	normalized, err := normalizePath(baseDir, raw)
-	if err != nil {
-		return "", err
-	}
-	return pathToFileURI(normalized), nil
-}
@@ -0,0 +1,18 @@
+package ctags
+
+import "testing"
+
+func TestParseTagfileEntry(t *testing.T) {
+	entry, ok := parseTagfileEntry("first\ta.c\t/^\ufeffint first;$/;\"\tv", newTagfileKindMap())
+	if !ok || entry.Pattern != "/^int first;$/" || entry.Path != "a.c" {
+		t.Fatalf("expected the BOM to be stripped from the pattern, got %+v", entry)
+	}
+
+	entry, ok = parseTagfileEntry("run\ta.py\t/^        def run(self): pass$/;\"\tm\tscope:class:Outer.Inner", newTagfileKindMap())
+	if !ok || entry.Scope != "Outer.Inner" || entry.ScopeKind != "class" {
+		t.Fatalf("expected the scope kind to be split off, got %+v", entry)
+	}
+	if _, scope, ok := cutScopeKind("ns::Widget"); ok || scope != "ns::Widget" {
+		t.Fatalf("expected a C++ scope to be left alone")
+	}
+}
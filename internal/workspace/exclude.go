@@ -0,0 +1,52 @@
+package workspace
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultExclude lists build output and dependency caches that would otherwise
+// dominate the index. Committed ones are excluded too, since they are usually
+// generated all the same.
+var DefaultExclude = []string{
+	"dist",
+	"build",
+	"target",
+	".venv",
+	"__pycache__",
+	".next",
+	"coverage",
+	"*.min.js",
+}
+
+// IsExcluded reports whether a file or directory `name` matches one of the
+// `exclude` glob patterns, which apply to a single path element, like gitignore
+// patterns without a slash.
+func IsExcluded(name string, exclude []string) bool {
+	for _, pattern := range exclude {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ExcludeFiles drops the files, given relative to the workspace root, that are
+// excluded or lie in an excluded directory.
+func ExcludeFiles(files []string, exclude []string) []string {
+	if len(exclude) == 0 {
+		return files
+	}
+	kept := make([]string, 0, len(files))
+files:
+	for _, file := range files {
+		for _, element := range strings.Split(filepath.ToSlash(file), "/") {
+			if IsExcluded(element, exclude) {
+				continue files
+			}
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
@@ -0,0 +1,38 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDefaultExcludes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"src", "build", "web/node/dist", "pkg/__pycache__"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", name, err)
+		}
+	}
+	writeFile(t, dir, "src/main.go", "package main\n")
+	writeFile(t, dir, "src/app.min.js", "var a;\n")
+	writeFile(t, dir, "build/out.go", "package out\n")
+	writeFile(t, dir, "web/node/dist/bundle.js", "var b;\n")
+	writeFile(t, dir, "pkg/__pycache__/mod.py", "x = 1\n")
+
+	files, _, err := ListFiles(dir, 0, DefaultExclude)
+	if err != nil {
+		t.Fatalf("list files: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "main.go" {
+		t.Fatalf("expected only src/main.go, got %v", files)
+	}
+
+	listed := []string{"src/main.go", "build/out.go", "lib/build.go", "web/dist/app.js", "web/app.min.js"}
+	if got := ExcludeFiles(listed, DefaultExclude); !slices.Equal(got, []string{"src/main.go", "lib/build.go"}) {
+		t.Fatalf("unexpected files after excludes %v", got)
+	}
+	if got := ExcludeFiles(listed, nil); len(got) != len(listed) {
+		t.Fatalf("expected no excludes, got %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package workspace lists the files of a workspace worth scanning with ctags.
+package workspace
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxFileSize is the size above which the directory walk leaves files out
+// of the scan; such files are almost always generated or data.
+const DefaultMaxFileSize = 10 << 20
+
+// binarySniffSize is how much of a file is checked for NUL bytes, like git does.
+const binarySniffSize = 8000
+
+// SkippedFiles counts the files a directory walk left out of the scan.
+type SkippedFiles struct {
+	Binary    int `json:"binary"`
+	Oversized int `json:"oversized"`
+}
+
+// ListFiles returns file paths using git, jj, or a directory walk, leaving
+// out paths matched by `exclude` (see `IsExcluded`).
+// These paths are not normalized and may be relative or absolute.
+// The directory walk, which sees untracked artifacts too, skips binary files and
+// files larger than `maxFileSize` (unless it is 0) and counts them.
+func ListFiles(rootDir string, maxFileSize int64, exclude []string) ([]string, SkippedFiles, error) {
+	var skipped SkippedFiles
+	if isGitRepo(rootDir) {
+		output, err := exec.Command("git", "-C", rootDir, "ls-files").Output()
+		if err != nil {
+			return nil, skipped, err
+		}
+		files := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return ExcludeFiles(files, exclude), skipped, nil
+	}
+
+	if isJjRepo(rootDir) {
+		output, err := exec.Command("jj", "file", "list", "--repository", rootDir).Output()
+		if err != nil {
+			return nil, skipped, err
+		}
+		files := strings.Split(strings.TrimSpace(string(output)), "\n")
+		return ExcludeFiles(files, exclude), skipped, nil
+	}
+
+	files, skipped := WalkFiles(rootDir, rootDir, maxFileSize, exclude)
+	return files, skipped, nil
+}
+
+// vcsDirs are the metadata directories of version control systems, never indexed.
+var vcsDirs = map[string]bool{".git": true, ".jj": true, ".hg": true, ".svn": true}
+
+// WalkFiles lists the files at or below `dir`, leaving out excluded files
+// and directories, version control metadata, binary files and files larger than
+// `maxFileSize` (unless it is 0), and counting the last two. `dir` itself is left
+// out like that too, and so is anything below an excluded or version control
+// directory between `rootDir` and `dir`. A relative `dir` is taken relative to
+// `rootDir`, and so are the paths returned for it.
+func WalkFiles(rootDir, dir string, maxFileSize int64, exclude []string) ([]string, SkippedFiles) {
+	var skipped SkippedFiles
+	var files []string
+	workspaceRoot := absoluteIn(rootDir, ".")
+	walkRoot := absoluteIn(rootDir, dir)
+	if rel, err := filepath.Rel(workspaceRoot, walkRoot); err == nil && rel != "." {
+		elements := strings.Split(filepath.ToSlash(rel), "/")
+		for _, element := range elements[:len(elements)-1] {
+			if IsExcluded(element, exclude) || vcsDirs[element] {
+				return nil, skipped
+			}
+		}
+	}
+	filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if path != workspaceRoot && (IsExcluded(d.Name(), exclude) || d.IsDir() && vcsDirs[d.Name()]) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Sockets and pipes would block ctags (and the sniff below) forever.
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && maxFileSize > 0 && info.Size() > maxFileSize {
+			skipped.Oversized++
+			return nil
+		}
+		if isBinaryFile(path) {
+			skipped.Binary++
+			return nil
+		}
+		if !filepath.IsAbs(dir) {
+			path, _ = filepath.Rel(rootDir, path)
+		}
+		files = append(files, path)
+		return nil
+	})
+	return files, skipped
+}
+
+// isBinaryFile reports whether the start of the file at `path` contains a NUL byte.
+// UTF-16 text is full of them, so files with a UTF-16 byte-order mark are text.
+func isBinaryFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffSize)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	if bytes.HasPrefix(head, []byte{0xff, 0xfe}) || bytes.HasPrefix(head, []byte{0xfe, 0xff}) {
+		return false
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+func isGitRepo(path string) bool {
+	cmd := exec.Command("git", "-C", path, "rev-parse", "--is-inside-work-tree")
+	return cmd.Run() == nil
+}
+
+func isJjRepo(path string) bool {
+	cmd := exec.Command("jj", "repo", "info", "--repository", path)
+	return cmd.Run() == nil
+}
+
+// absoluteIn resolves `path` against `dir` unless it is absolute already.
+func absoluteIn(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
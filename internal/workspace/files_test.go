@@ -0,0 +1,71 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWalkSkipsBinaryAndOversizedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "main.go", "package main\n")
+	writeFile(t, dir, "utf16.txt", "\xff\xfeh\x00i\x00")
+	writeFile(t, dir, "image.png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	writeFile(t, dir, "bundle.js", strings.Repeat("var a = 1;\n", 100))
+
+	files, skipped, err := ListFiles(dir, 512, nil)
+	if err != nil {
+		t.Fatalf("list files: %v", err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"main.go", "utf16.txt"}) {
+		t.Fatalf("unexpected files %v", names)
+	}
+	if skipped != (SkippedFiles{Binary: 1, Oversized: 1}) {
+		t.Fatalf("unexpected skipped counts %+v", skipped)
+	}
+
+	if files, _, _ := ListFiles(dir, 0, nil); len(files) != 3 {
+		t.Fatalf("expected no size limit with 0, got %v", files)
+	}
+}
+
+// writeFile writes `content` to `name` under `dir`.
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestWalkFilesChecksTheWalkRoot(t *testing.T) {
+	rootDir := filepath.Join(t.TempDir(), "build")
+	for _, dir := range []string{".git/hooks", "build", "src"} {
+		if err := os.MkdirAll(filepath.Join(rootDir, dir), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", dir, err)
+		}
+	}
+	writeFile(t, rootDir, ".git/config.go", "package git\n")
+	writeFile(t, rootDir, ".git/hooks/hook.go", "package hooks\n")
+	writeFile(t, rootDir, "build/out.go", "package build\n")
+	writeFile(t, rootDir, "src/a.go", "package src\n")
+
+	for _, dir := range []string{".git", ".git/config.go", ".git/hooks/hook.go", "build", "build/out.go"} {
+		if files, _ := WalkFiles(rootDir, dir, 0, DefaultExclude); len(files) != 0 {
+			t.Errorf("expected nothing from %s, got %v", dir, files)
+		}
+	}
+	if files, _ := WalkFiles(rootDir, "src/a.go", 0, DefaultExclude); !slices.Equal(files, []string{filepath.Join("src", "a.go")}) {
+		t.Errorf("expected src/a.go, got %v", files)
+	}
+	// The workspace root itself is walked even when its name is excluded.
+	if files, _ := WalkFiles(rootDir, rootDir, 0, DefaultExclude); !slices.Equal(files, []string{filepath.Join(rootDir, "src", "a.go")}) {
+		t.Errorf("expected only src/a.go in the workspace, got %v", files)
+	}
+}
@@ -0,0 +1,190 @@
+// Package lsp is the ctags-lsp language server. Besides `Main`, which runs the
+// command, it lets other Go programs build a ctags index and query it, or serve
+// the language server protocol over any connection, without running the binary.
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+)
+
+// Options configure a server made by `New`. They mirror the command-line
+// options of the same names; see the README. The zero value of a field keeps
+// the option's default, so a zero `Options` configures the server like running
+// ctags-lsp with no flags. Unlike the command, `New` doesn't read CTAGS_LSP_*
+// environment variables.
+type Options struct {
+	CtagsBin          string   // --ctags-bin; looked up like the command does.
+	Tagfile           string   // --tagfile
+	Languages         string   // --languages, e.g. "Go,Python".
+	CtagsArgs         []string // --ctags-args, one argument per element.
+	ReferenceTags     bool     // --reference-tags
+	QualifiedTags     bool     // --qualified-tags
+	EmbeddedLanguages bool     // --embedded-languages
+
+	// Exclude is --exclude, one pattern per element. Nil keeps the default
+	// patterns; an empty, non-nil slice excludes nothing.
+	Exclude []string
+	// MaxFileSize is --max-file-size in bytes. Negative disables the limit.
+	MaxFileSize int64
+	// WorkspaceSymbolLimit is --workspace-symbol-limit. Negative disables it.
+	WorkspaceSymbolLimit int
+	Workers              int    // --workers; zero runs one ctags per CPU.
+	Encoding             string // --encoding, e.g. "latin1".
+
+	RequireTrust bool     // --require-trust; see `Index`.
+	TrustedDirs  []string // --trusted-dirs
+	Sandbox      bool     // --sandbox
+	PathMapping  string   // --path-mapping, "none" or "wsl".
+
+	// Version is reported to clients in the `initialize` result.
+	Version string
+}
+
+// New returns a server configured by `options`. It fails if an option is
+// invalid or no Universal Ctags is found.
+func New(options Options) (*Server, error) {
+	config := &Config{}
+	newFlagSet(config, "ctags-lsp", io.Discard)
+	if options.CtagsBin != "" {
+		config.ctagsBin = options.CtagsBin
+	}
+	config.tagfilePath = options.Tagfile
+	config.languages = options.Languages
+	config.referenceTags = options.ReferenceTags
+	config.qualifiedTags = options.QualifiedTags
+	config.embeddedLanguages = options.EmbeddedLanguages
+	if options.MaxFileSize != 0 {
+		config.maxFileSize = max(options.MaxFileSize, 0)
+	}
+	if options.WorkspaceSymbolLimit != 0 {
+		config.workspaceSymbolLimit = max(options.WorkspaceSymbolLimit, 0)
+	}
+	config.workers = options.Workers
+	if options.Encoding != "" {
+		config.encoding = options.Encoding
+	}
+	config.requireTrust = options.RequireTrust
+	config.sandbox = options.Sandbox
+	if options.PathMapping != "" {
+		config.pathMapping = options.PathMapping
+	}
+	config.version = options.Version
+
+	if _, err := lookupEncoding(config.encoding); err != nil {
+		return nil, err
+	}
+	if err := validatePathMapping(config.pathMapping); err != nil {
+		return nil, err
+	}
+	config.ctagsBin, _ = ctags.Locate(config.ctagsBin, ctags.Candidates(runtime.GOOS, os.Getenv))
+	if err := ctags.Check(config.ctagsBin); err != nil {
+		return nil, err
+	}
+	server := newServer(config, io.Discard)
+	// Lists are set whole, since their elements may contain the separators
+	// the flags split on.
+	server.options.ctagArgs = options.CtagsArgs
+	server.trustedDirs = options.TrustedDirs
+	if options.Exclude != nil {
+		server.options.exclude = options.Exclude
+	}
+	return server, nil
+}
+
+// Serve speaks the language server protocol over `conn` until the client sends
+// `exit` or `conn` reaches EOF. A server serves one client, once.
+func (server *Server) Serve(conn io.ReadWriter) error {
+	server.outputMutex.Lock()
+	server.output = conn
+	server.outputMutex.Unlock()
+	if err := serve(conn, server); err != nil {
+		return err
+	}
+	server.pending.Wait()
+	return nil
+}
+
+// Index builds the index of the workspace at `root` like a client's `initialize`
+// does: from a tagfile, if there is one, or else by running ctags. It replaces
+// what was indexed before, and a later `initialize` for the same root keeps it.
+// With `RequireTrust`, `root` must be in `TrustedDirs`, since there is no user
+// to ask.
+func (server *Server) Index(root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	if server.requireTrust && !isTrustedDir(root, server.trustedDirs) {
+		return fmt.Errorf("workspace %s is not in --trusted-dirs", root)
+	}
+	server.rootURI = pathToFileURI(root)
+	server.untrusted.Store(false)
+	server.indexedRoot = ""
+	if err := server.rescanWorkspace(); err != nil {
+		return err
+	}
+	server.indexedRoot = server.rootURI
+	return nil
+}
+
+// TagFilter sees a tag before it is indexed. It returns the tag to index, which
+// it may have changed, or false to leave the tag out.
+type TagFilter func(entry TagEntry) (TagEntry, bool)
+
+// AddTagFilter makes the server pass every tag it indexes from now on through
+// `filter`, after the filters added before it. Add filters before calling
+// `Index` or `Serve`.
+func (server *Server) AddTagFilter(filter TagFilter) {
+	server.tagFilters = append(server.tagFilters, func(entries []TagEntry) []TagEntry {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry, ok := filter(entry); ok {
+				kept = append(kept, entry)
+			}
+		}
+		return kept
+	})
+}
+
+// Symbols returns the tags whose name matches `query` like a `workspace/symbol`
+// request: exact matches first, then case-insensitive, prefix and substring
+// matches, at most `WorkspaceSymbolLimit` of them.
+func (server *Server) Symbols(query string) []TagEntry {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	var candidates []symbolCandidate
+	for _, entry := range server.tagEntries {
+		if tier := matchSymbolQuery(entry.Name, query); tier != symbolMatchNone {
+			candidates = append(candidates, symbolCandidate{entry: entry, kind: GetLSPSymbolKind(entry.Kind), tier: tier})
+		}
+	}
+	candidates = rankSymbolCandidates(candidates, server.getOptions().workspaceSymbolLimit)
+
+	entries := make([]TagEntry, len(candidates))
+	for i, candidate := range candidates {
+		entries[i] = candidate.entry
+	}
+	return entries
+}
+
+// Definitions returns the tags that define `name`, which may be qualified like
+// "pkg.Name" when the index has qualified tags.
+func (server *Server) Definitions(name string) []TagEntry {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	var entries []TagEntry
+	for _, entry := range server.tagEntries {
+		if entry.Name == name {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
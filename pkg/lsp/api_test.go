@@ -0,0 +1,93 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLibraryAPI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+
+	script := `#!/bin/sh
+case "$*" in *--version*) echo "Universal Ctags 6.1.0, JSON output"; exit 0 ;; esac
+case "$*" in *--print-language*) exit 0 ;; esac
+files="$*"
+case "$*" in *"-L -"*) files="$(cat)" ;; esac
+for f in $files; do
+	case "$f" in
+	*shapes.go)
+		echo '{"_type": "tag", "name": "Shape", "path": "shapes.go", "line": 3, "kind": "struct"}'
+		echo '{"_type": "tag", "name": "shapeArea", "path": "shapes.go", "line": 5, "kind": "func"}' ;;
+	esac
+done
+`
+	ctagsBin := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	root := t.TempDir()
+	writeTestFile(t, root, "shapes.go", "package shapes\n\ntype Shape struct{}\n\nfunc shapeArea() int { return 0 }\n")
+	if err := os.Mkdir(filepath.Join(root, "vendor"), 0o755); err != nil {
+		t.Fatalf("mkdir vendor: %v", err)
+	}
+	writeTestFile(t, root, filepath.Join("vendor", "shapes.go"), "package shapes\n\ntype Shape struct{}\n")
+
+	if _, err := New(Options{CtagsBin: filepath.Join(root, "missing")}); err == nil {
+		t.Fatal("expected New to fail without ctags")
+	}
+	server, err := New(Options{CtagsBin: ctagsBin, Exclude: []string{"vendor"}, Version: "1.2.3"})
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	if err := server.Index(root); err != nil {
+		t.Fatalf("index: %v", err)
+	}
+
+	symbols := server.Symbols("shape")
+	if len(symbols) != 2 || symbols[0].Name != "Shape" || symbols[1].Name != "shapeArea" {
+		t.Fatalf("expected Shape and shapeArea, got %+v", symbols)
+	}
+	definitions := server.Definitions("shapeArea")
+	if len(definitions) != 1 || definitions[0].Line != 5 || definitions[0].Path != pathToFileURI(filepath.Join(root, "shapes.go")) {
+		t.Fatalf("expected the definition of shapeArea, got %+v", definitions)
+	}
+	// Indexing again, and initializing for the same root, replace the index.
+	if err := server.Index(root); err != nil {
+		t.Fatalf("index again: %v", err)
+	}
+
+	var input bytes.Buffer
+	for i, method := range []string{"initialize", "shutdown", "exit"} {
+		body := fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": %q, "params": {"rootUri": %q}}`, i, method, pathToFileURI(root))
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	var output bytes.Buffer
+	if err := server.Serve(struct {
+		io.Reader
+		io.Writer
+	}{&input, &output}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	var initialize InitializeResult
+	for _, frame := range readFrames(t, &output) {
+		if string(frame.ID) == "0" {
+			if err := json.Unmarshal(frame.Result, &initialize); err != nil {
+				t.Fatalf("decode initialize result: %v", err)
+			}
+		}
+	}
+	if !initialize.Capabilities.DefinitionProvider || initialize.Info.Version != "1.2.3" {
+		t.Fatalf("expected an initialize result, got %q", output.String())
+	}
+	if definitions := server.Definitions("Shape"); len(definitions) != 1 {
+		t.Fatalf("expected one definition of Shape, got %+v", definitions)
+	}
+}
@@ -0,0 +1,107 @@
+package lsp
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// syntheticLanguage describes how to write one language in a generated workspace.
+type syntheticLanguage struct {
+	extension string
+	header    func(file int) string
+	// block returns one unit of definitions; `n` makes every name in the workspace unique.
+	block func(n int) string
+}
+
+var syntheticLanguages = map[string]syntheticLanguage{
+	"go": {
+		extension: ".go",
+		header:    func(file int) string { return fmt.Sprintf("package p%d\n\n", file) },
+		block: func(n int) string {
+			return fmt.Sprintf("type Type%[1]d struct {\n\tfield%[1]d int\n}\n\n"+
+				"func (t *Type%[1]d) Method%[1]d() int {\n\treturn t.field%[1]d\n}\n\n"+
+				"func Func%[1]d(a int) int {\n\treturn a + %[1]d\n}\n\n", n)
+		},
+	},
+	"python": {
+		extension: ".py",
+		header:    func(int) string { return "" },
+		block: func(n int) string {
+			return fmt.Sprintf("class Class%[1]d:\n    def method_%[1]d(self):\n        return %[1]d\n\n\n"+
+				"def func_%[1]d(a):\n    return a + %[1]d\n\n\n", n)
+		},
+	},
+	"c": {
+		extension: ".c",
+		header:    func(int) string { return "#include <stdio.h>\n\n" },
+		block: func(n int) string {
+			return fmt.Sprintf("struct struct_%[1]d {\n\tint field_%[1]d;\n};\n\n"+
+				"int func_%[1]d(int a)\n{\n\treturn a + %[1]d;\n}\n\n", n)
+		},
+	},
+	"javascript": {
+		extension: ".js",
+		header:    func(int) string { return "" },
+		block: func(n int) string {
+			return fmt.Sprintf("class Class%[1]d {\n  method%[1]d() {\n    return %[1]d;\n  }\n}\n\n"+
+				"function func%[1]d(a) {\n  return a + %[1]d;\n}\n\n", n)
+		},
+	},
+	"ruby": {
+		extension: ".rb",
+		header:    func(int) string { return "" },
+		block: func(n int) string {
+			return fmt.Sprintf("class Class%[1]d\n  def method_%[1]d\n    %[1]d\n  end\nend\n\n"+
+				"def func_%[1]d(a)\n  a + %[1]d\nend\n\n", n)
+		},
+	},
+}
+
+// syntheticWorkspace configures `generateSyntheticWorkspace`.
+type syntheticWorkspace struct {
+	files     int
+	lines     int
+	languages []string
+	seed      int64
+}
+
+// generateSyntheticWorkspace writes `spec.files` source files of roughly `spec.lines`
+// lines each into `dir`, spread over nested directories. Each file's language is
+// picked at random from `spec.languages`; the same seed always produces the same tree.
+func generateSyntheticWorkspace(dir string, spec syntheticWorkspace) error {
+	if len(spec.languages) == 0 {
+		return fmt.Errorf("no languages to generate")
+	}
+	for _, language := range spec.languages {
+		if _, ok := syntheticLanguages[language]; !ok {
+			return fmt.Errorf("unsupported benchmark language %q", language)
+		}
+	}
+
+	random := rand.New(rand.NewSource(spec.seed))
+	n := 0
+	for file := range spec.files {
+		language := syntheticLanguages[spec.languages[random.Intn(len(spec.languages))]]
+
+		var content strings.Builder
+		content.WriteString(language.header(file))
+		for lines := 0; lines < spec.lines; n++ {
+			block := language.block(n)
+			content.WriteString(block)
+			lines += strings.Count(block, "\n")
+		}
+
+		subdir := filepath.Join(dir, fmt.Sprintf("dir%d", file%16), fmt.Sprintf("sub%d", file%7))
+		if err := os.MkdirAll(subdir, 0o755); err != nil {
+			return err
+		}
+		path := filepath.Join(subdir, fmt.Sprintf("file%d%s", file, language.extension))
+		if err := os.WriteFile(path, []byte(content.String()), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
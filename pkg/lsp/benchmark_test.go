@@ -0,0 +1,54 @@
+package lsp
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateSyntheticWorkspace(t *testing.T) {
+	generate := func() map[string]string {
+		dir := t.TempDir()
+		spec := syntheticWorkspace{files: 20, lines: 50, languages: []string{"go", "python"}, seed: 7}
+		if err := generateSyntheticWorkspace(dir, spec); err != nil {
+			t.Fatalf("generateSyntheticWorkspace: %v", err)
+		}
+
+		files := make(map[string]string)
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, path)
+			files[rel] = string(content)
+			return nil
+		})
+		return files
+	}
+
+	first := generate()
+	if len(first) != 20 {
+		t.Fatalf("expected 20 files, got %d", len(first))
+	}
+	for name := range first {
+		if ext := filepath.Ext(name); ext != ".go" && ext != ".py" {
+			t.Fatalf("unexpected file %s", name)
+		}
+	}
+
+	second := generate()
+	for name, content := range first {
+		if second[name] != content {
+			t.Fatalf("expected %s to be identical across runs with the same seed", name)
+		}
+	}
+
+	if err := generateSyntheticWorkspace(t.TempDir(), syntheticWorkspace{files: 1, lines: 1, languages: []string{"cobol"}}); err == nil {
+		t.Fatal("expected an error for an unsupported language")
+	}
+}
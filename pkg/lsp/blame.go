@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blameTimeout bounds a `git blame` run for a hover.
+const blameTimeout = 2 * time.Second
+
+// maxBlameCacheFiles is how many files' blame is kept before the cache starts over.
+const maxBlameCacheFiles = 64
+
+// blameCommit is what a hover shows about the commit that last changed a line.
+type blameCommit struct {
+	hash    string
+	author  string
+	time    time.Time
+	summary string
+}
+
+// blameCache keeps the blame of whole files by URI, for as long as the file on
+// disk keeps its modification time and size. Files git can't blame are cached
+// without lines.
+type blameCache struct {
+	mutex sync.Mutex
+	files map[string]*fileBlame
+}
+
+type fileBlame struct {
+	stamp fileStamp
+	lines map[int]*blameCommit
+}
+
+// blameSummary describes the commit that last changed `line` of the file `uri`,
+// e.g. "Last changed 2024-05-01 by Ada Lovelace: Fix parser (1a2b3c4)". It
+// returns "" if git can't tell.
+func (server *Server) blameSummary(uri string, line int) string {
+	if !server.mayRunCommands() || line <= 0 {
+		return ""
+	}
+	path := fileURIToPath(uri)
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+
+	cache := &server.blame
+	cache.mutex.Lock()
+	blame, ok := cache.files[uriKey(uri)]
+	cache.mutex.Unlock()
+	if !ok || blame.stamp != stamp {
+		blame = &fileBlame{stamp: stamp, lines: runGitBlame(path)}
+		cache.mutex.Lock()
+		if cache.files == nil || len(cache.files) >= maxBlameCacheFiles {
+			cache.files = make(map[string]*fileBlame)
+		}
+		cache.files[uriKey(uri)] = blame
+		cache.mutex.Unlock()
+	}
+
+	commit := blame.lines[line]
+	if commit == nil {
+		return ""
+	}
+	if strings.Trim(commit.hash, "0") == "" {
+		return "Not committed yet"
+	}
+	return fmt.Sprintf("Last changed %s by %s: %s (%s)", commit.time.Format(time.DateOnly), commit.author, commit.summary, commit.hash[:min(7, len(commit.hash))])
+}
+
+// runGitBlame blames the file at `path` with the repository it is in. It returns
+// nil if git fails, e.g. for files outside a repository.
+func runGitBlame(path string) map[int]*blameCommit {
+	ctx, cancel := context.WithTimeout(context.Background(), blameTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "-C", filepath.Dir(path), "blame", "--porcelain", "--", filepath.Base(path))
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return parseBlamePorcelain(output)
+}
+
+// parseBlamePorcelain maps line numbers to commits from the output of
+// `git blame --porcelain`, which describes each commit only the first time it
+// shows up.
+func parseBlamePorcelain(output []byte) map[int]*blameCommit {
+	lines := make(map[int]*blameCommit)
+	commits := make(map[string]*blameCommit)
+	var current *blameCommit
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			// The content of the line ends its entry.
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		if isCommitHash(key) {
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+			current = commits[key]
+			if current == nil {
+				current = &blameCommit{hash: key}
+				commits[key] = current
+			}
+			if final, err := strconv.Atoi(fields[1]); err == nil {
+				lines[final] = current
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "author":
+			current.author = value
+		case "author-time":
+			if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.time = time.Unix(seconds, 0)
+			}
+		case "summary":
+			current.summary = value
+		}
+	}
+	return lines
+}
+
+// isCommitHash reports whether `s` is a full SHA-1 or SHA-256 commit hash.
+func isCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestHoverBlame(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "view.go", "package view\n\nfunc Render() {}\n")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Ada Lovelace", "GIT_AUTHOR_EMAIL=ada@example.com", "GIT_AUTHOR_DATE=2024-05-01T12:00:00Z",
+			"GIT_COMMITTER_NAME=Ada Lovelace", "GIT_COMMITTER_EMAIL=ada@example.com", "GIT_COMMITTER_DATE=2024-05-01T12:00:00Z",
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "-q")
+	git("add", "view.go")
+	git("commit", "-q", "-m", "Add the view")
+
+	server := newTestServer(t, []TagEntry{{Name: "Render", Path: uri, Line: 3, Kind: "func", Language: "Go"}})
+	server.options.hoverBlame = true
+	hover := func(line int) string {
+		frames := callHandler(t, server, "textDocument/hover", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: 6},
+		})
+		var result Hover
+		if err := json.Unmarshal(frames[0].Result, &result); err != nil {
+			t.Fatalf("decode hover %s: %v", frames[0].Result, err)
+		}
+		return result.Contents.Value
+	}
+
+	if value := hover(2); !strings.Contains(value, "Last changed 2024-05-01 by Ada Lovelace: Add the view (") {
+		t.Fatalf("expected blame in hover, got %q", value)
+	}
+
+	writeTestFile(t, dir, "view.go", "package view\n\nfunc Render(width int) {}\n")
+	server.cache.content = make(map[string][]string)
+	if value := hover(2); !strings.Contains(value, "Not committed yet") {
+		t.Fatalf("expected the changed line to be uncommitted, got %q", value)
+	}
+}
@@ -0,0 +1,263 @@
+package lsp
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// browsePageSize is how many matches `browse` lists at a time.
+	browsePageSize = 20
+	// browseContextBefore and browseContextAfter are the lines around a
+	// definition that a preview shows.
+	browseContextBefore = 2
+	browseContextAfter  = 10
+)
+
+const browseHelp = `Type a name to search the index, typos included, or:
+  <n>              Preview match n
+  :more            List the next matches
+  :kind <kinds>    Only list these comma-separated kinds, e.g. "function,method"
+  :lang <langs>    Only list these comma-separated languages, e.g. "Go,C"
+  :path <text>     Only list matches whose path contains the text
+  :filters         Show the filters; a filter command without a value clears it
+  :help            Show this help
+  :quit            Leave (or Ctrl-D)
+`
+
+// indexBrowser is the state of a `ctags-lsp browse` session.
+type indexBrowser struct {
+	server    *Server
+	rootDir   string
+	out       io.Writer
+	kinds     []string
+	languages []string
+	path      string
+	matches   []TagEntry
+	shown     int
+}
+
+// runBrowseCommand implements `ctags-lsp browse [options] [<dir>]`: it indexes
+// `dir`, `--root` by default, and lets the user search and preview
+// the index, for machines without an LSP-capable editor. On a terminal that
+// is a full-screen view (see `interact`); otherwise it is a prompt reading lines.
+func runBrowseCommand(process Process, args []string, stdin io.Reader, stdout, stderr io.Writer, checkCtags func(string) error) int {
+	config, err := parseFlags(args, stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	config.version = process.Version
+	if len(config.args) > 1 {
+		fmt.Fprintf(stderr, "Error: expected at most one directory\n")
+		return 2
+	}
+	root := config.root
+	if len(config.args) == 1 {
+		root = config.args[0]
+	}
+	if err := process.setLogger(config, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := checkCtags(config.ctagsBin); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	server := newServer(config, io.Discard)
+	if err := server.Index(root); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	browser := &indexBrowser{server: server, rootDir: fileURIToPath(server.rootURI), out: stdout}
+	if terminal, err := openTerminal(stdin, stdout); err == nil {
+		entry, picked := browser.interact(stdin, terminal.size, terminal.resized)
+		terminal.restore()
+		if picked {
+			fmt.Fprintln(stdout, browser.location(entry))
+		}
+		return 0
+	}
+	fmt.Fprintf(stdout, "Indexed %d symbols in %s. Type :help for help.\n", server.indexSize(), browser.rootDir)
+
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			return 0
+		}
+		if !browser.handle(strings.TrimSpace(scanner.Text())) {
+			return 0
+		}
+	}
+}
+
+// handle runs one line of input. It returns false when the user leaves.
+func (browser *indexBrowser) handle(line string) bool {
+	command, value, _ := strings.Cut(line, " ")
+	value = strings.TrimSpace(value)
+	switch {
+	case line == "":
+	case command == ":quit" || command == ":q":
+		return false
+	case command == ":help":
+		fmt.Fprint(browser.out, browseHelp)
+	case command == ":more":
+		browser.list()
+	case command == ":kind":
+		browser.kinds = splitList(value)
+		browser.showFilters()
+	case command == ":lang":
+		browser.languages = splitList(value)
+		browser.showFilters()
+	case command == ":path":
+		browser.path = value
+		browser.showFilters()
+	case command == ":filters":
+		browser.showFilters()
+	case strings.HasPrefix(line, ":"):
+		fmt.Fprintf(browser.out, "Unknown command %s. Type :help for help.\n", command)
+	default:
+		if n, err := strconv.Atoi(line); err == nil {
+			browser.preview(n)
+			return true
+		}
+		browser.search(line)
+	}
+	return true
+}
+
+// search lists the matches of `query` that pass the filters, best first, like a
+// typo-tolerant `workspace/symbol` request.
+func (browser *indexBrowser) search(query string) {
+	browser.matches = browser.find(query, 0)
+	browser.shown = 0
+	if len(browser.matches) == 0 {
+		fmt.Fprintln(browser.out, "No matches.")
+		return
+	}
+	browser.list()
+}
+
+// find returns the best `limit` matches of `query` that pass the filters, or
+// all of them if `limit` isn't positive.
+func (browser *indexBrowser) find(query string, limit int) []TagEntry {
+	browser.server.mutex.Lock()
+	var candidates []symbolCandidate
+	for _, entry := range browser.server.tagEntries {
+		if !browser.accepts(entry) {
+			continue
+		}
+		tier, score := matchSymbolQuery(entry.Name, query), 0
+		if tier == symbolMatchNone {
+			typoScore, ok := matchSymbolTypo(entry.Name, query)
+			if !ok {
+				continue
+			}
+			tier, score = symbolMatchTypo, typoScore
+		}
+		candidates = append(candidates, symbolCandidate{entry: entry, kind: GetLSPSymbolKind(entry.Kind), tier: tier, score: score})
+	}
+	browser.server.mutex.Unlock()
+
+	candidates = rankSymbolCandidates(candidates, limit)
+	matches := make([]TagEntry, len(candidates))
+	for i, candidate := range candidates {
+		matches[i] = candidate.entry
+	}
+	return matches
+}
+
+// list prints the next page of matches.
+func (browser *indexBrowser) list() {
+	if browser.shown >= len(browser.matches) {
+		fmt.Fprintln(browser.out, "No more matches.")
+		return
+	}
+	end := min(browser.shown+browsePageSize, len(browser.matches))
+	width := len(strconv.Itoa(end))
+	for i := browser.shown; i < end; i++ {
+		entry := browser.matches[i]
+		fmt.Fprintf(browser.out, "%*d  %s  %s  %s\n", width, i+1, qualifiedEntryName(entry), entry.Kind, browser.location(entry))
+	}
+	browser.shown = end
+	if remaining := len(browser.matches) - end; remaining > 0 {
+		fmt.Fprintf(browser.out, "%d more; type :more to list them.\n", remaining)
+	}
+}
+
+// preview prints the definition of match `n` with the lines around it.
+func (browser *indexBrowser) preview(n int) {
+	if n < 1 || n > len(browser.matches) {
+		fmt.Fprintf(browser.out, "No match %d.\n", n)
+		return
+	}
+	entry := browser.matches[n-1]
+	fmt.Fprintf(browser.out, "%s  %s  %s\n", qualifiedEntryName(entry), entry.Kind, browser.location(entry))
+	if entry.Signature != "" {
+		fmt.Fprintf(browser.out, "  %s%s\n", entry.Name, entry.Signature)
+	}
+
+	content, err := browser.server.cache.GetOrLoadFileContent(entry.Path)
+	if err != nil {
+		fmt.Fprintf(browser.out, "Can't read %s: %v\n", browser.location(entry), err)
+		return
+	}
+	line := entry.Line
+	if line <= 0 || line > len(content) {
+		return
+	}
+	first, last := max(line-browseContextBefore, 1), min(line+browseContextAfter, len(content))
+	width := len(strconv.Itoa(last))
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(browser.out, "%s %*d  %s\n", marker, width, i, content[i-1])
+	}
+}
+
+func (browser *indexBrowser) accepts(entry TagEntry) bool {
+	if len(browser.kinds) > 0 && !slices.ContainsFunc(browser.kinds, func(kind string) bool { return strings.EqualFold(kind, entry.Kind) }) {
+		return false
+	}
+	if len(browser.languages) > 0 && !slices.ContainsFunc(browser.languages, func(language string) bool { return strings.EqualFold(language, entry.Language) }) {
+		return false
+	}
+	return browser.path == "" || strings.Contains(browser.relativePath(entry.Path), browser.path)
+}
+
+func (browser *indexBrowser) showFilters() {
+	fmt.Fprintf(browser.out, "kind: %s  lang: %s  path: %s\n",
+		cmp.Or(strings.Join(browser.kinds, ","), "any"),
+		cmp.Or(strings.Join(browser.languages, ","), "any"),
+		cmp.Or(browser.path, "any"))
+}
+
+func (browser *indexBrowser) location(entry TagEntry) string {
+	return fmt.Sprintf("%s:%d", browser.relativePath(entry.Path), entry.Line)
+}
+
+// relativePath returns the path of the file `uri` under the root, or its full path
+// if it's outside.
+func (browser *indexBrowser) relativePath(uri string) string {
+	path := fileURIToPath(uri)
+	if relative, err := filepath.Rel(browser.rootDir, path); err == nil && !strings.HasPrefix(relative, "..") {
+		return filepath.ToSlash(relative)
+	}
+	return path
+}
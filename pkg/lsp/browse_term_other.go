@@ -0,0 +1,22 @@
+//go:build !unix
+
+package lsp
+
+import (
+	"io"
+	"os"
+)
+
+// rawTerminal is the terminal the full-screen browser runs on. It needs stty,
+// so on this platform `browse` always reads lines instead.
+type rawTerminal struct {
+	resized chan os.Signal
+}
+
+func openTerminal(stdin io.Reader, stdout io.Writer) (*rawTerminal, error) {
+	return nil, errNotTerminal
+}
+
+func (terminal *rawTerminal) size() (rows, cols int) { return 24, 80 }
+
+func (terminal *rawTerminal) restore() {}
@@ -0,0 +1,81 @@
+//go:build unix
+
+package lsp
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// rawTerminal is the terminal the full-screen browser runs on, switched to raw
+// mode, so that keys arrive as they are pressed and aren't echoed. The modes are
+// set with stty, which every Unix system has, rather than terminal ioctls, which
+// differ between them.
+type rawTerminal struct {
+	tty     *os.File
+	saved   string // The modes to restore, as `stty -g` prints them.
+	resized chan os.Signal
+}
+
+// openTerminal switches the terminal to raw mode if both `stdin` and `stdout` are
+// one, and fails with `errNotTerminal` otherwise.
+func openTerminal(stdin io.Reader, stdout io.Writer) (*rawTerminal, error) {
+	in, inOK := stdin.(*os.File)
+	out, outOK := stdout.(*os.File)
+	if !inOK || !outOK || !isCharDevice(in) || !isCharDevice(out) {
+		return nil, errNotTerminal
+	}
+	// stty fails on a character device that isn't a terminal, like /dev/null.
+	saved, err := stty(in, "-g")
+	if err != nil {
+		return nil, errNotTerminal
+	}
+	if _, err := stty(in, "raw", "-echo"); err != nil {
+		return nil, errNotTerminal
+	}
+	terminal := &rawTerminal{tty: in, saved: saved, resized: make(chan os.Signal, 1)}
+	signal.Notify(terminal.resized, syscall.SIGWINCH)
+	return terminal, nil
+}
+
+// size returns the rows and columns of the terminal, or 24 by 80 if stty can't tell.
+func (terminal *rawTerminal) size() (rows, cols int) {
+	output, err := stty(terminal.tty, "size")
+	if err != nil {
+		return 24, 80
+	}
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 24, 80
+	}
+	rows, rowsErr := strconv.Atoi(fields[0])
+	cols, colsErr := strconv.Atoi(fields[1])
+	if rowsErr != nil || colsErr != nil || rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// restore puts the terminal back in the modes it had before `openTerminal`.
+func (terminal *rawTerminal) restore() {
+	signal.Stop(terminal.resized)
+	stty(terminal.tty, terminal.saved)
+}
+
+func isCharDevice(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stty runs stty on `tty` and returns what it printed.
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
@@ -0,0 +1,115 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrowseCommand(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "view.go", "package view\n\n// Render draws the view.\nfunc Render() {}\n\nfunc renderAll() {}\n")
+	tags := strings.Join([]string{
+		"Render\tview.go\t/^func Render() {}$/;\"\tkind:func\tline:4\tlanguage:Go\tsignature:()",
+		"renderAll\tview.go\t/^func renderAll() {}$/;\"\tkind:func\tline:6\tlanguage:Go\tsignature:()",
+		"view\tview.go\t/^package view$/;\"\tkind:package\tline:1\tlanguage:Go",
+	}, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(root, "tags"), []byte(tags), 0o644); err != nil {
+		t.Fatalf("write tags: %v", err)
+	}
+
+	input := strings.NewReader("rendr\n1\n:kind package\nrender\n:kind\n:nope\n:quit\n")
+	var stdout, stderr bytes.Buffer
+	if code := run(Process{}, []string{"ctags-lsp", "browse", root}, input, &stdout, &stderr, func(string) error { return nil }); code != 0 {
+		t.Fatalf("browse exited with %d: %s", code, stderr.String())
+	}
+	output := stdout.String()
+	for _, want := range []string{
+		"Indexed 3 symbols",
+		"1  Render  func  view.go:4\n",
+		"2  renderAll  func  view.go:6\n",
+		"  3  // Render draws the view.\n> 4  func Render() {}\n",
+		"kind: package  lang: any  path: any\n> No matches.\n",
+		"Unknown command :nope.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in the output:\n%s", want, output)
+		}
+	}
+}
+
+func TestBrowseInteractive(t *testing.T) {
+	root := t.TempDir()
+	uri := writeTestFile(t, root, "view.go", "package view\n\n// Render draws the view.\nfunc Render() {}\n\nfunc renderAll() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Render", Path: uri, Line: 4, Kind: "func", Language: "Go"},
+		{Name: "renderAll", Path: uri, Line: 6, Kind: "func", Language: "Go"},
+		{Name: "view", Path: uri, Line: 1, Kind: "package", Language: "Go"},
+	})
+	server.rootURI = pathToFileURI(root)
+
+	var screen bytes.Buffer
+	browser := &indexBrowser{server: server, rootDir: root, out: &screen}
+	size := func() (int, int) { return 8, 100 }
+
+	// A typo fixed with Backspace, then Down to the second match.
+	keys := strings.NewReader("rendr\x7fer\x1b[B\r")
+	entry, picked := browser.interact(keys, size, nil)
+	if !picked || entry.Name != "renderAll" {
+		t.Fatalf("expected renderAll to be picked, got %+v (%t)", entry, picked)
+	}
+	output := screen.String()
+	last := output[strings.LastIndex(output, "\x1b[H"):]
+	for _, want := range []string{
+		"> render ",
+		"2/2",
+		"Render  func  view.go:4",
+		"\x1b[7mrenderAll  func  view.go:6",
+		"│ view.go:6",
+		"│ > 6  func renderAll() {}",
+	} {
+		if !strings.Contains(last, want) {
+			t.Errorf("expected %q on the last screen:\n%q", want, last)
+		}
+	}
+	if !strings.HasPrefix(output, "\x1b[?1049h") || !strings.HasSuffix(output, "\x1b[?1049l") {
+		t.Errorf("expected the alternate screen to be entered and left, got %q", output)
+	}
+
+	// Filters alone list everything that passes them; Escape leaves.
+	screen.Reset()
+	if _, picked := browser.interact(strings.NewReader("kind:package\x1b"), size, nil); picked {
+		t.Fatal("expected Escape to leave without picking")
+	}
+	output = screen.String()
+	if last := output[strings.LastIndex(output, "\x1b[H"):]; !strings.Contains(last, "1/1  kind: package") || !strings.Contains(last, "view  package  view.go:1") {
+		t.Errorf("expected the package to be listed, got:\n%q", last)
+	}
+}
+
+func TestReadBrowseKey(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("aé\x1b[A\x1bOB\x1b[5~\x1b[6~\x1b[1;5C\x15\x03"))
+	want := []browseKey{
+		{kind: browseKeyRune, r: 'a'},
+		{kind: browseKeyRune, r: 'é'},
+		{kind: browseKeyUp},
+		{kind: browseKeyDown},
+		{kind: browseKeyPageUp},
+		{kind: browseKeyPageDown},
+		{kind: browseKeyNone},
+		{kind: browseKeyClear},
+		{kind: browseKeyQuit},
+	}
+	for i, expected := range want {
+		key, err := readBrowseKey(reader)
+		if err != nil || key != expected {
+			t.Fatalf("key %d: expected %+v, got %+v (%v)", i, expected, key, err)
+		}
+	}
+	if got := fitWidth("\tab\x1bc", 6); got != "    ab" {
+		t.Fatalf("expected tabs expanded and the line cut, got %q", got)
+	}
+}
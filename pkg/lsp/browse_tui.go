@@ -0,0 +1,363 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// browseMatchLimit is how many matches the full-screen browser ranks for a query.
+// More than fit on a screen, and few enough to rank on every key.
+const browseMatchLimit = 1000
+
+// browseTabWidth is how many columns a tab takes in the preview.
+const browseTabWidth = 4
+
+var errNotTerminal = errors.New("not a terminal")
+
+// Keys the full-screen browser acts on, besides the runes it adds to the query.
+const (
+	browseKeyNone = iota
+	browseKeyRune
+	browseKeyEnter
+	browseKeyBackspace
+	browseKeyClear
+	browseKeyUp
+	browseKeyDown
+	browseKeyPageUp
+	browseKeyPageDown
+	browseKeyQuit
+)
+
+type browseKey struct {
+	kind int
+	r    rune // The rune typed, for `browseKeyRune`.
+}
+
+// readBrowseKey reads one key from a terminal in raw mode. Escape sequences the
+// browser has no use for are read whole and reported as `browseKeyNone`.
+func readBrowseKey(reader *bufio.Reader) (browseKey, error) {
+	r, _, err := reader.ReadRune()
+	if err != nil {
+		return browseKey{}, err
+	}
+	switch r {
+	case '\r', '\n':
+		return browseKey{kind: browseKeyEnter}, nil
+	case 0x7f, 0x08:
+		return browseKey{kind: browseKeyBackspace}, nil
+	case 0x15: // Ctrl-U
+		return browseKey{kind: browseKeyClear}, nil
+	case 0x10: // Ctrl-P
+		return browseKey{kind: browseKeyUp}, nil
+	case 0x0e: // Ctrl-N
+		return browseKey{kind: browseKeyDown}, nil
+	case 0x03, 0x04: // Ctrl-C, Ctrl-D
+		return browseKey{kind: browseKeyQuit}, nil
+	case 0x1b:
+		// A lone Escape leaves; the keys that send sequences send them at once.
+		if reader.Buffered() == 0 {
+			return browseKey{kind: browseKeyQuit}, nil
+		}
+		return readEscapeSequence(reader)
+	}
+	if unicode.IsPrint(r) {
+		return browseKey{kind: browseKeyRune, r: r}, nil
+	}
+	return browseKey{}, nil
+}
+
+// readEscapeSequence reads the rest of a CSI or SS3 sequence, e.g. "[A" for Up
+// or "[5~" for Page Up.
+func readEscapeSequence(reader *bufio.Reader) (browseKey, error) {
+	introducer, err := reader.ReadByte()
+	if err != nil {
+		return browseKey{}, err
+	}
+	if introducer != '[' && introducer != 'O' {
+		return browseKey{}, nil
+	}
+	var parameters []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return browseKey{}, err
+		}
+		if b < 0x40 || b > 0x7e {
+			parameters = append(parameters, b)
+			continue
+		}
+		switch {
+		case b == 'A':
+			return browseKey{kind: browseKeyUp}, nil
+		case b == 'B':
+			return browseKey{kind: browseKeyDown}, nil
+		case b == '~' && string(parameters) == "5":
+			return browseKey{kind: browseKeyPageUp}, nil
+		case b == '~' && string(parameters) == "6":
+			return browseKey{kind: browseKeyPageDown}, nil
+		}
+		return browseKey{}, nil
+	}
+}
+
+// browseView is the state of the full-screen browser: the query typed so far,
+// its matches and the one selected, and the screen they are drawn on.
+type browseView struct {
+	browser  *indexBrowser
+	query    []rune
+	matches  []TagEntry
+	selected int
+	offset   int // The first match on screen.
+	rows     int
+	cols     int
+}
+
+// interact runs the full-screen browser on a terminal in raw mode, reading keys
+// from `keys` and drawing on `browser.out`: the matches of the query, updated as
+// it is typed, are listed on the left, with the definition of the selected one
+// on the right. The query narrows the matches down like the prompt's filters
+// with "kind:", "lang:" and "path:" words, e.g. "kind:func,method lang:go draw".
+// It returns the match picked with Enter, or false if the user left.
+func (browser *indexBrowser) interact(keys io.Reader, size func() (rows, cols int), resized <-chan os.Signal) (TagEntry, bool) {
+	view := &browseView{browser: browser}
+	view.rows, view.cols = size()
+
+	fmt.Fprint(browser.out, "\x1b[?1049h") // Switch to the alternate screen.
+	defer fmt.Fprint(browser.out, "\x1b[?1049l")
+
+	pressed, done := make(chan browseKey), make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(pressed)
+		reader := bufio.NewReader(keys)
+		for {
+			key, err := readBrowseKey(reader)
+			if err != nil {
+				return
+			}
+			select {
+			case pressed <- key:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	view.update()
+	for {
+		view.draw()
+		select {
+		case <-resized:
+			view.rows, view.cols = size()
+		case key, ok := <-pressed:
+			if !ok {
+				return TagEntry{}, false
+			}
+			switch key.kind {
+			case browseKeyQuit:
+				return TagEntry{}, false
+			case browseKeyEnter:
+				if len(view.matches) > 0 {
+					return view.matches[view.selected], true
+				}
+			default:
+				view.handle(key)
+			}
+		}
+	}
+}
+
+// handle applies a key that edits the query or moves the selection.
+func (view *browseView) handle(key browseKey) {
+	switch key.kind {
+	case browseKeyRune:
+		view.query = append(view.query, key.r)
+		view.update()
+	case browseKeyBackspace:
+		if len(view.query) > 0 {
+			view.query = view.query[:len(view.query)-1]
+			view.update()
+		}
+	case browseKeyClear:
+		view.query = nil
+		view.update()
+	case browseKeyUp:
+		view.selected = max(view.selected-1, 0)
+	case browseKeyDown:
+		view.selected = min(view.selected+1, max(len(view.matches)-1, 0))
+	case browseKeyPageUp:
+		view.selected = max(view.selected-view.listHeight(), 0)
+	case browseKeyPageDown:
+		view.selected = min(view.selected+view.listHeight(), max(len(view.matches)-1, 0))
+	}
+}
+
+// update finds the matches of the query and selects the best one. A query of
+// only filters lists everything that passes them; an empty one lists nothing.
+func (view *browseView) update() {
+	browser := view.browser
+	browser.kinds, browser.languages, browser.path = nil, nil, ""
+	var words []string
+	for _, word := range strings.Fields(string(view.query)) {
+		switch filter, value, _ := strings.Cut(word, ":"); filter {
+		case "kind":
+			browser.kinds = splitList(value)
+		case "lang":
+			browser.languages = splitList(value)
+		case "path":
+			browser.path = value
+		default:
+			words = append(words, word)
+		}
+	}
+	view.selected, view.offset = 0, 0
+	if len(words) == 0 && len(browser.kinds) == 0 && len(browser.languages) == 0 && browser.path == "" {
+		view.matches = nil
+		return
+	}
+	view.matches = browser.find(strings.Join(words, " "), browseMatchLimit)
+}
+
+// listHeight is how many matches fit on the screen, below the prompt and status lines.
+func (view *browseView) listHeight() int {
+	return max(view.rows-2, 1)
+}
+
+// draw redraws the whole screen and leaves the cursor at the end of the query.
+func (view *browseView) draw() {
+	height := view.listHeight()
+	if view.selected < view.offset {
+		view.offset = view.selected
+	} else if view.selected >= view.offset+height {
+		view.offset = view.selected - height + 1
+	}
+
+	listWidth := max(view.cols*2/5, min(view.cols, 20))
+	previewWidth := view.cols - listWidth - 3
+	var preview []string
+	if len(view.matches) > 0 && previewWidth > 0 {
+		preview = view.preview(view.matches[view.selected], height)
+	}
+
+	var screen bytes.Buffer
+	screen.WriteString("\x1b[H")
+	writeScreenLine(&screen, fitWidth("> "+string(view.query), view.cols), "")
+	writeScreenLine(&screen, fitWidth(view.status(), view.cols), "\x1b[2m")
+	for row := range height {
+		i := view.offset + row
+		var item string
+		if i < len(view.matches) {
+			entry := view.matches[i]
+			item = fmt.Sprintf("%s  %s  %s", qualifiedEntryName(entry), entry.Kind, view.browser.location(entry))
+		}
+		item = fitWidth(item, listWidth)
+		if i == view.selected && i < len(view.matches) {
+			item = "\x1b[7m" + item + "\x1b[0m"
+		}
+		if previewWidth > 0 {
+			var line string
+			if row < len(preview) {
+				line = preview[row]
+			}
+			item += " │ " + fitWidth(line, previewWidth)
+		}
+		screen.WriteString(item)
+		screen.WriteString("\x1b[K")
+		if row < height-1 {
+			screen.WriteString("\r\n")
+		}
+	}
+	fmt.Fprintf(&screen, "\x1b[1;%dH", min(len(view.query)+3, view.cols))
+	view.browser.out.Write(screen.Bytes())
+}
+
+// status sums up the matches and filters, or says how to use the browser.
+func (view *browseView) status() string {
+	browser := view.browser
+	if len(view.query) == 0 {
+		return `Type a name to search, with "kind:", "lang:" and "path:" to filter. Up and Down select, Enter picks, Esc leaves.`
+	}
+	status := fmt.Sprintf("%d matches", len(view.matches))
+	if len(view.matches) > 0 {
+		status = fmt.Sprintf("%d/%d", view.selected+1, len(view.matches))
+	}
+	if len(browser.kinds) > 0 {
+		status += "  kind: " + strings.Join(browser.kinds, ",")
+	}
+	if len(browser.languages) > 0 {
+		status += "  lang: " + strings.Join(browser.languages, ",")
+	}
+	if browser.path != "" {
+		status += "  path: " + browser.path
+	}
+	return status
+}
+
+// preview returns `height` lines showing the definition of `entry`: its location
+// and signature, then the file from a few lines above the definition.
+func (view *browseView) preview(entry TagEntry, height int) []string {
+	lines := []string{view.browser.location(entry)}
+	if entry.Signature != "" {
+		lines = append(lines, entry.Name+entry.Signature)
+	}
+	content, err := view.browser.server.cache.GetOrLoadFileContent(entry.Path)
+	if err != nil {
+		return append(lines, fmt.Sprintf("Can't read the file: %v", err))
+	}
+	if entry.Line <= 0 || entry.Line > len(content) {
+		return lines
+	}
+	first := max(entry.Line-browseContextBefore, 1)
+	last := min(first+height-len(lines)-1, len(content))
+	width := len(strconv.Itoa(last))
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == entry.Line {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %*d  %s", marker, width, i, content[i-1]))
+	}
+	return lines
+}
+
+// writeScreenLine writes one line of the screen, in `style` if there is one,
+// clearing what is left of the line from the last draw.
+func writeScreenLine(screen *bytes.Buffer, line, style string) {
+	if style != "" {
+		line = style + line + "\x1b[0m"
+	}
+	screen.WriteString(line)
+	screen.WriteString("\x1b[K\r\n")
+}
+
+// fitWidth pads or cuts `text` to exactly `width` columns, expanding tabs and
+// dropping control characters, which would move the cursor. Every other rune is
+// taken to be one column wide.
+func fitWidth(text string, width int) string {
+	var line []rune
+	for _, r := range text {
+		if len(line) >= width {
+			break
+		}
+		switch {
+		case r == '\t':
+			for range browseTabWidth - len(line)%browseTabWidth {
+				line = append(line, ' ')
+			}
+		case unicode.IsControl(r):
+		default:
+			line = append(line, r)
+		}
+	}
+	if len(line) > width {
+		line = line[:width]
+	}
+	return string(line) + strings.Repeat(" ", width-len(line))
+}
@@ -0,0 +1,364 @@
+package lsp
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CallHierarchyItem matches LSP 3.17 `CallHierarchyItem`. Data names the tag it
+// was made from, for the follow-up requests.
+type CallHierarchyItem struct {
+	Name           string   `json:"name"`
+	Kind           int      `json:"kind"`
+	Detail         string   `json:"detail,omitempty"`
+	URI            string   `json:"uri"`
+	Range          Range    `json:"range"`
+	SelectionRange Range    `json:"selectionRange"`
+	Data           *tagData `json:"data,omitempty"`
+}
+
+// tagData identifies a tag across requests by its name and location.
+type tagData struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+type CallHierarchyCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+// handlePrepareCallHierarchy returns an item for each callable definition of the
+// symbol at the cursor (see `isCallableEntry`).
+func handlePrepareCallHierarchy(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	var items []CallHierarchyItem
+	for _, entry := range server.findDefinitionEntries(normalizedURI, params.Position, symbol) {
+		if !isCallableEntry(entry) || isQualifiedTag(entry) {
+			continue
+		}
+		if item, ok := server.hierarchyItem(entry); ok {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, items)
+}
+
+// handleIncomingCalls finds the calls of the item's name in the indexed files of
+// its extension family: the name followed by "(", outside comments and string
+// literals. Calls are grouped by the callable they are in, or by file for calls
+// outside any. Like `ctags-lsp graph`, a name several callables define counts
+// only where the item is the one a caller's file would call: the only one of
+// that name in the file, or else the only one anywhere.
+func handleIncomingCalls(server *Server, req RPCRequest) {
+	var params CallHierarchyCallsParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Item.Data == nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+	ctx, done := server.trackRequest(req, "")
+	defer done()
+
+	server.mutex.Lock()
+	target, ok := server.findTag(*params.Item.Data)
+	if !ok {
+		server.mutex.Unlock()
+		server.sendResult(req.ID, nil)
+		return
+	}
+	family := server.documentFamily(target.Path)
+	var callables []TagEntry
+	var paths []string
+	languages := make(map[string]string)
+	for _, entry := range server.tagEntries {
+		if isQualifiedTag(entry) || !family.includes(entry) {
+			continue
+		}
+		if _, ok := languages[entry.Path]; !ok {
+			languages[entry.Path] = entry.Language
+			paths = append(paths, entry.Path)
+		}
+		if isCallableEntry(entry) {
+			callables = append(callables, entry)
+		}
+	}
+	server.mutex.Unlock()
+
+	byPath := make(map[string][]TagEntry)
+	var namesakes []TagEntry
+	for _, entry := range callables {
+		byPath[entry.Path] = append(byPath[entry.Path], entry)
+		if entry.Name == target.Name {
+			namesakes = append(namesakes, entry)
+		}
+	}
+
+	var calls []CallHierarchyIncomingCall
+	for i, path := range paths {
+		if deadlineExceeded(ctx, i) {
+			break
+		}
+		if callee, ok := pickCallee(namesakes, path); !ok || callee != target {
+			continue
+		}
+		lines, err := server.cache.GetOrLoadFileContent(path)
+		if err != nil {
+			continue
+		}
+		fileCallables := byPath[path]
+		slices.SortFunc(fileCallables, func(a, b TagEntry) int { return cmp.Compare(a.Line, b.Line) })
+		bodies := callableBodies(fileCallables, len(lines))
+
+		fromRanges := make(map[int][]Range)
+		forEachCodeIdentifier(lines, syntaxForFile(path, languages[path]), func(identifier []rune, rng Range) {
+			if string(identifier) != target.Name || !isCallSite(lines[rng.Start.Line], rng.End.Character) {
+				return
+			}
+			caller := -1
+			for j, body := range bodies {
+				if body.start <= rng.Start.Line && rng.Start.Line < body.end {
+					caller = j
+				}
+			}
+			// The definition itself is no call.
+			if caller >= 0 && fileCallables[caller].Line == rng.Start.Line+1 && fileCallables[caller].Name == target.Name {
+				return
+			}
+			fromRanges[caller] = append(fromRanges[caller], rng)
+		})
+
+		for _, caller := range slices.Sorted(maps.Keys(fromRanges)) {
+			var from CallHierarchyItem
+			if caller < 0 {
+				from = fileCallHierarchyItem(path, len(lines))
+			} else if from, ok = server.hierarchyItem(fileCallables[caller]); !ok {
+				continue
+			}
+			calls = append(calls, CallHierarchyIncomingCall{From: from, FromRanges: fromRanges[caller]})
+		}
+	}
+	if calls == nil {
+		calls = []CallHierarchyIncomingCall{}
+	}
+	server.sendResult(req.ID, calls)
+}
+
+// handleOutgoingCalls finds the calls in the body of the item: the names of
+// callables followed by "(", resolved like incoming calls.
+func handleOutgoingCalls(server *Server, req RPCRequest) {
+	var params CallHierarchyCallsParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Item.Data == nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	caller, ok := server.findTag(*params.Item.Data)
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	lines, err := server.cache.GetOrLoadFileContent(caller.Path)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	family := server.documentFamily(caller.Path)
+	callablesByName := make(map[string][]TagEntry)
+	var fileCallables []TagEntry
+	for _, entry := range server.tagEntries {
+		if !isCallableEntry(entry) || isQualifiedTag(entry) || !family.includes(entry) {
+			continue
+		}
+		callablesByName[entry.Name] = append(callablesByName[entry.Name], entry)
+		if entry.Path == caller.Path {
+			fileCallables = append(fileCallables, entry)
+		}
+	}
+	slices.SortFunc(fileCallables, func(a, b TagEntry) int { return cmp.Compare(a.Line, b.Line) })
+	index := slices.Index(fileCallables, caller)
+	if index < 0 {
+		server.sendResult(req.ID, []CallHierarchyOutgoingCall{})
+		return
+	}
+	body := callableBodies(fileCallables, len(lines))[index]
+
+	type callee struct {
+		entry      TagEntry
+		fromRanges []Range
+	}
+	var callees []*callee
+	byCallee := make(map[TagEntry]*callee)
+	forEachCodeIdentifier(lines[body.start:body.end], syntaxForFile(caller.Path, caller.Language), func(identifier []rune, rng Range) {
+		rng.Start.Line += body.start
+		rng.End.Line += body.start
+		if !isCallSite(lines[rng.Start.Line], rng.End.Character) {
+			return
+		}
+		// The definition itself is no call.
+		if rng.Start.Line == caller.Line-1 && string(identifier) == caller.Name {
+			return
+		}
+		entry, ok := pickCallee(callablesByName[string(identifier)], caller.Path)
+		if !ok {
+			return
+		}
+		if byCallee[entry] == nil {
+			byCallee[entry] = &callee{entry: entry}
+			callees = append(callees, byCallee[entry])
+		}
+		byCallee[entry].fromRanges = append(byCallee[entry].fromRanges, rng)
+	})
+
+	calls := []CallHierarchyOutgoingCall{}
+	for _, callee := range callees {
+		if to, ok := server.hierarchyItem(callee.entry); ok {
+			calls = append(calls, CallHierarchyOutgoingCall{To: to, FromRanges: callee.fromRanges})
+		}
+	}
+	server.sendResult(req.ID, calls)
+}
+
+// hierarchyItem describes `entry` for the call and type hierarchies, with its
+// body as the range.
+// The caller holds `server.mutex`.
+func (server *Server) hierarchyItem(entry TagEntry) (CallHierarchyItem, bool) {
+	lines, err := server.cache.GetOrLoadFileContent(entry.Path)
+	if err != nil {
+		return CallHierarchyItem{}, false
+	}
+	selection := findEntryRange(lines, entry)
+	data := tagDataOf(entry)
+	end := selection.End
+	if entry.End >= entry.Line && entry.End <= len(lines) {
+		end = Position{Line: entry.End - 1, Character: len([]rune(lines[entry.End-1]))}
+	}
+	return CallHierarchyItem{
+		Name:           entry.Name,
+		Kind:           GetLSPSymbolKind(entry.Kind),
+		Detail:         strings.TrimSpace(containerName(entry) + " " + entry.Signature),
+		URI:            entry.Path,
+		Range:          Range{Start: Position{Line: selection.Start.Line}, End: end},
+		SelectionRange: selection,
+		Data:           &data,
+	}, true
+}
+
+// fileCallHierarchyItem stands for the code of a file outside its callables.
+func fileCallHierarchyItem(path string, lineCount int) CallHierarchyItem {
+	rng := Range{End: Position{Line: max(lineCount-1, 0)}}
+	return CallHierarchyItem{
+		Name:           filepath.Base(fileURIToPath(path)),
+		Kind:           SymbolKindFile,
+		URI:            path,
+		Range:          rng,
+		SelectionRange: Range{},
+	}
+}
+
+func tagDataOf(entry TagEntry) tagData {
+	return tagData{Name: entry.Name, Path: entry.Path, Line: entry.Line}
+}
+
+// findTag returns the tag `data` names, if the index still has it.
+// The caller holds `server.mutex`.
+func (server *Server) findTag(data tagData) (TagEntry, bool) {
+	if i, ok := server.findTagIndex(data); ok {
+		return server.tagEntries[i], true
+	}
+	return TagEntry{}, false
+}
+
+// findTagIndex is `findTag` for the index of the tag in `server.tagEntries`.
+// The caller holds `server.mutex`.
+func (server *Server) findTagIndex(data tagData) (int, bool) {
+	for i, entry := range server.tagEntries {
+		if entry.Name == data.Name && entry.Line == data.Line && sameURI(entry.Path, data.Path) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// lineSpan is a range of line indexes, `end` excluded.
+type lineSpan struct {
+	start, end int
+}
+
+// callableBodies returns the lines of each of `callables`, the callables of one
+// file in line order: up to their `end` field if ctags recorded it, or else up
+// to the next callable or the end of the file.
+func callableBodies(callables []TagEntry, lineCount int) []lineSpan {
+	bodies := make([]lineSpan, len(callables))
+	for i, entry := range callables {
+		start := min(max(entry.Line-1, 0), lineCount)
+		end := lineCount
+		if entry.End >= entry.Line {
+			end = min(entry.End, lineCount)
+		} else if i+1 < len(callables) {
+			end = max(min(callables[i+1].Line-1, lineCount), start)
+		}
+		bodies[i] = lineSpan{start: start, end: end}
+	}
+	return bodies
+}
+
+// pickCallee returns the callable of `candidates`, which share a name, that a
+// call in the file `path` calls: the only one in the file, or else the only one.
+func pickCallee(candidates []TagEntry, path string) (TagEntry, bool) {
+	var local, other []TagEntry
+	for _, candidate := range candidates {
+		if candidate.Path == path {
+			local = append(local, candidate)
+		} else {
+			other = append(other, candidate)
+		}
+	}
+	switch {
+	case len(local) == 1:
+		return local[0], true
+	case len(local) == 0 && len(other) == 1:
+		return other[0], true
+	}
+	return TagEntry{}, false
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCallHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "app.go", "package app\n\nfunc helper() int { return 1 }\n\nfunc run() {\n\t// helper() in a comment\n\tx := helper() + helper()\n}\n\nvar y = helper()\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "helper", Path: uri, Line: 3, Kind: "func", Language: "Go"},
+		{Name: "run", Path: uri, Line: 5, End: 8, Kind: "func", Language: "Go"},
+		{Name: "y", Path: uri, Line: 10, Kind: "var", Language: "Go"},
+	})
+
+	frames := callHandler(t, server, "textDocument/prepareCallHierarchy", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 6, Character: 8},
+	})
+	var items []CallHierarchyItem
+	if err := json.Unmarshal(frames[0].Result, &items); err != nil {
+		t.Fatalf("decode prepare %s: %v", frames[0].Result, err)
+	}
+	if len(items) != 1 || items[0].Name != "helper" || items[0].SelectionRange.Start != (Position{Line: 2, Character: 5}) {
+		t.Fatalf("expected an item for helper, got %+v", items)
+	}
+
+	frames = callHandler(t, server, "callHierarchy/incomingCalls", CallHierarchyCallsParams{Item: items[0]})
+	var incoming []CallHierarchyIncomingCall
+	if err := json.Unmarshal(frames[0].Result, &incoming); err != nil {
+		t.Fatalf("decode incoming calls %s: %v", frames[0].Result, err)
+	}
+	if len(incoming) != 2 || incoming[0].From.Name != "app.go" || len(incoming[0].FromRanges) != 1 || incoming[0].FromRanges[0].Start.Line != 9 ||
+		incoming[1].From.Name != "run" || len(incoming[1].FromRanges) != 2 || incoming[1].From.Range.End.Line != 7 {
+		t.Fatalf("expected the calls at file level and in run, got %+v", incoming)
+	}
+
+	frames = callHandler(t, server, "callHierarchy/outgoingCalls", CallHierarchyCallsParams{Item: incoming[1].From})
+	var outgoing []CallHierarchyOutgoingCall
+	if err := json.Unmarshal(frames[0].Result, &outgoing); err != nil {
+		t.Fatalf("decode outgoing calls %s: %v", frames[0].Result, err)
+	}
+	want := []Range{
+		{Start: Position{Line: 6, Character: 6}, End: Position{Line: 6, Character: 12}},
+		{Start: Position{Line: 6, Character: 17}, End: Position{Line: 6, Character: 23}},
+	}
+	if len(outgoing) != 1 || outgoing[0].To.Name != "helper" || !slices.Equal(outgoing[0].FromRanges, want) {
+		t.Fatalf("expected the two calls of helper in run, got %+v", outgoing)
+	}
+}
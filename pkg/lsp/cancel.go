@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// errRequestCancelled is the cause of contexts cancelled by `$/cancelRequest`
+// or by a newer request superseding an older one.
+var errRequestCancelled = errors.New("request cancelled")
+
+// inflightRequest is a tracked request that can still be cancelled.
+type inflightRequest struct {
+	method string
+	uri    string
+	cancel context.CancelCauseFunc
+}
+
+type CancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// requestKey identifies a request by its JSON-RPC id, whether number or string.
+func requestKey(id json.RawMessage) string {
+	return strings.TrimSpace(string(id))
+}
+
+// trackRequest returns the deadline-bounded context of `req` and registers it
+// for cancellation. A tracked request on `uri` cancels older requests of the same
+// method on that document, whose results the client no longer wants: each
+// keystroke triggers a completion that makes the previous one obsolete. The
+// returned function must be called once the request is answered.
+func (server *Server) trackRequest(req RPCRequest, uri string) (context.Context, context.CancelFunc) {
+	parent, cancelCause := context.WithCancelCause(context.Background())
+	ctx, cancel := server.boundedContext(parent)
+	if req.ID == nil {
+		return ctx, func() { cancel(); cancelCause(nil) }
+	}
+
+	key := requestKey(*req.ID)
+	server.inflightMutex.Lock()
+	if server.inflight == nil {
+		server.inflight = make(map[string]*inflightRequest)
+	}
+	if uri != "" {
+		for _, other := range server.inflight {
+			if other.method == req.Method && sameURI(other.uri, uri) {
+				other.cancel(errRequestCancelled)
+			}
+		}
+	}
+	server.inflight[key] = &inflightRequest{method: req.Method, uri: uri, cancel: cancelCause}
+	server.inflightMutex.Unlock()
+
+	return ctx, func() {
+		server.inflightMutex.Lock()
+		delete(server.inflight, key)
+		server.inflightMutex.Unlock()
+		cancel()
+		cancelCause(nil)
+	}
+}
+
+// cancelDocumentRequests cancels the tracked requests on `uri`, e.g. because the
+// document changed underneath them.
+func (server *Server) cancelDocumentRequests(uri string) {
+	server.inflightMutex.Lock()
+	defer server.inflightMutex.Unlock()
+	for _, request := range server.inflight {
+		if request.uri != "" && sameURI(request.uri, uri) {
+			request.cancel(errRequestCancelled)
+		}
+	}
+}
+
+// handleCancelRequest cancels a tracked request by id. Untracked or finished
+// requests are ignored, as the protocol allows.
+func handleCancelRequest(server *Server, req RPCRequest) {
+	var params CancelParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	server.inflightMutex.Lock()
+	defer server.inflightMutex.Unlock()
+	if request, ok := server.inflight[requestKey(params.ID)]; ok {
+		request.cancel(errRequestCancelled)
+	}
+}
+
+// requestCancelled reports whether `ctx` was cancelled by the client or by a
+// newer request rather than by its deadline.
+func requestCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRequestCancelled)
+}
+
+// sendRequestCancelled answers a cancelled request with LSP's RequestCancelled error.
+func (server *Server) sendRequestCancelled(req RPCRequest) {
+	server.sendError(req.ID, -32800, "Request cancelled", req.Method)
+}
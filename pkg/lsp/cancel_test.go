@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestSupersededRequestsAreCancelled(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n")
+	server := newTestServer(t, nil)
+
+	request := func(id, method string) RPCRequest {
+		raw := json.RawMessage(id)
+		return RPCRequest{Jsonrpc: "2.0", ID: &raw, Method: method}
+	}
+
+	t.Run("newer completion on the same document", func(t *testing.T) {
+		older, doneOlder := server.trackRequest(request("1", "textDocument/completion"), uri)
+		defer doneOlder()
+		other, doneOther := server.trackRequest(request("2", "textDocument/completion"), pathToFileURI(filepath.Join(dir, "b.go")))
+		defer doneOther()
+		newer, doneNewer := server.trackRequest(request("3", "textDocument/completion"), uri)
+		defer doneNewer()
+
+		if !requestCancelled(older) {
+			t.Fatalf("expected older completion to be cancelled")
+		}
+		if requestCancelled(other) || requestCancelled(newer) {
+			t.Fatalf("expected completions on other documents and the newest one to keep running")
+		}
+	})
+
+	t.Run("document change", func(t *testing.T) {
+		ctx, done := server.trackRequest(request("4", "textDocument/completion"), uri)
+		defer done()
+		callHandler(t, server, "textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: "package a\n\nfunc b() {}\n"}},
+		})
+		if !requestCancelled(ctx) {
+			t.Fatalf("expected completion to be cancelled by didChange")
+		}
+	})
+
+	t.Run("cancelRequest", func(t *testing.T) {
+		ctx, done := server.trackRequest(request(`"five"`, "workspace/symbol"), "")
+		defer done()
+		callHandler(t, server, "$/cancelRequest", CancelParams{ID: json.RawMessage(`"five"`)})
+		if !requestCancelled(ctx) {
+			t.Fatalf("expected workspace symbol request to be cancelled")
+		}
+	})
+
+	t.Run("finished requests are forgotten", func(t *testing.T) {
+		_, done := server.trackRequest(request("6", "textDocument/completion"), uri)
+		done()
+		server.inflightMutex.Lock()
+		defer server.inflightMutex.Unlock()
+		if _, ok := server.inflight["6"]; ok {
+			t.Fatalf("expected finished request to be untracked")
+		}
+	})
+}
@@ -0,0 +1,258 @@
+package lsp
+
+import (
+	"slices"
+	"strings"
+)
+
+// ClientCapabilities is the subset of LSP 3.17 client capabilities the server shapes
+// its responses by. Anything not listed here is assumed unsupported.
+type ClientCapabilities struct {
+	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+}
+
+type WorkspaceClientCapabilities struct {
+	Configuration bool                     `json:"configuration"`
+	Symbol        SymbolClientCapabilities `json:"symbol"`
+}
+
+type TextDocumentClientCapabilities struct {
+	Completion     CompletionClientCapabilities     `json:"completion"`
+	Definition     DefinitionClientCapabilities     `json:"definition"`
+	DocumentSymbol DocumentSymbolClientCapabilities `json:"documentSymbol"`
+	Hover          HoverClientCapabilities          `json:"hover"`
+	Rename         RenameClientCapabilities         `json:"rename"`
+}
+
+type RenameClientCapabilities struct {
+	PrepareSupport bool `json:"prepareSupport"`
+}
+
+type CompletionClientCapabilities struct {
+	CompletionItem struct {
+		SnippetSupport      bool     `json:"snippetSupport"`
+		DocumentationFormat []string `json:"documentationFormat"`
+		LabelDetailsSupport bool     `json:"labelDetailsSupport"`
+	} `json:"completionItem"`
+	CompletionItemKind struct {
+		ValueSet []int `json:"valueSet"`
+	} `json:"completionItemKind"`
+}
+
+type DefinitionClientCapabilities struct {
+	LinkSupport bool `json:"linkSupport"`
+}
+
+type HoverClientCapabilities struct {
+	ContentFormat []string `json:"contentFormat"`
+}
+
+type SymbolClientCapabilities struct {
+	SymbolKind struct {
+		ValueSet []int `json:"valueSet"`
+	} `json:"symbolKind"`
+}
+
+type DocumentSymbolClientCapabilities struct {
+	SymbolClientCapabilities
+	HierarchicalDocumentSymbolSupport bool `json:"hierarchicalDocumentSymbolSupport"`
+}
+
+// DocumentSymbol is the hierarchical outline node sent to clients that support it.
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail,omitempty"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// LocationLink is returned from go-to-definition to clients with `linkSupport`.
+type LocationLink struct {
+	OriginSelectionRange *Range `json:"originSelectionRange,omitempty"`
+	TargetURI            string `json:"targetUri"`
+	TargetRange          Range  `json:"targetRange"`
+	TargetSelectionRange Range  `json:"targetSelectionRange"`
+}
+
+// Clients that send no value set support the kinds of the initial LSP release:
+// SymbolKind File..Array and CompletionItemKind Text..Reference.
+const (
+	defaultMaxSymbolKind     = SymbolKindArray
+	defaultMaxCompletionKind = CompletionItemKindReference
+)
+
+// symbolKindFallbacks maps newer symbol kinds to the closest kind of the initial release.
+var symbolKindFallbacks = map[int]int{
+	SymbolKindObject:        SymbolKindClass,
+	SymbolKindKey:           SymbolKindProperty,
+	SymbolKindNull:          SymbolKindConstant,
+	SymbolKindEnumMember:    SymbolKindConstant,
+	SymbolKindStruct:        SymbolKindClass,
+	SymbolKindEvent:         SymbolKindField,
+	SymbolKindOperator:      SymbolKindFunction,
+	SymbolKindTypeParameter: SymbolKindVariable,
+}
+
+// completionKindFallbacks maps newer completion kinds to the closest kind of the initial release.
+var completionKindFallbacks = map[int]int{
+	CompletionItemKindFolder:        CompletionItemKindFile,
+	CompletionItemKindEnumMember:    CompletionItemKindValue,
+	CompletionItemKindConstant:      CompletionItemKindValue,
+	CompletionItemKindStruct:        CompletionItemKindClass,
+	CompletionItemKindEvent:         CompletionItemKindField,
+	CompletionItemKindOperator:      CompletionItemKindFunction,
+	CompletionItemKindTypeParameter: CompletionItemKindVariable,
+}
+
+// supportedKind returns `kind` if the client's value set contains it, otherwise its
+// fallback, otherwise `last` (a kind every client supports).
+func supportedKind(kind int, valueSet []int, defaultMax int, fallbacks map[int]int, last int) int {
+	supported := func(k int) bool {
+		if len(valueSet) == 0 {
+			return k >= 1 && k <= defaultMax
+		}
+		return slices.Contains(valueSet, k)
+	}
+	if supported(kind) {
+		return kind
+	}
+	if fallback, ok := fallbacks[kind]; ok && supported(fallback) {
+		return fallback
+	}
+	return last
+}
+
+func (capabilities ClientCapabilities) documentSymbolKind(kind int) int {
+	return supportedKind(kind, capabilities.TextDocument.DocumentSymbol.SymbolKind.ValueSet,
+		defaultMaxSymbolKind, symbolKindFallbacks, SymbolKindVariable)
+}
+
+func (capabilities ClientCapabilities) workspaceSymbolKind(kind int) int {
+	return supportedKind(kind, capabilities.Workspace.Symbol.SymbolKind.ValueSet,
+		defaultMaxSymbolKind, symbolKindFallbacks, SymbolKindVariable)
+}
+
+func (capabilities ClientCapabilities) completionKind(kind int) int {
+	return supportedKind(kind, capabilities.TextDocument.Completion.CompletionItemKind.ValueSet,
+		defaultMaxCompletionKind, completionKindFallbacks, CompletionItemKindText)
+}
+
+func (capabilities ClientCapabilities) supportsMarkdownDocumentation() bool {
+	return slices.Contains(capabilities.TextDocument.Completion.CompletionItem.DocumentationFormat, "markdown")
+}
+
+// completionDocumentation renders the documentation of a completion item, see
+// `renderDocumentation`.
+func (capabilities ClientCapabilities) completionDocumentation(entry TagEntry, docstring string) *MarkupContent {
+	return renderDocumentation(entry, docstring, capabilities.supportsMarkdownDocumentation())
+}
+
+// hoverContent renders the documentation shown on hover, see `renderDocumentation`,
+// with the tag's kind, scope and type (see `hoverMetadata`) before the docstring.
+func (capabilities ClientCapabilities) hoverContent(entry TagEntry, docstring string) *MarkupContent {
+	markdown := slices.Contains(capabilities.TextDocument.Hover.ContentFormat, "markdown")
+	if metadata := hoverMetadata(entry, markdown); metadata != "" {
+		docstring = strings.TrimSuffix(metadata+"\n\n"+docstring, "\n\n")
+	}
+	return renderDocumentation(entry, docstring, markdown)
+}
+
+// renderDocumentation renders the tag's search pattern, as a fenced code block
+// in markdown, followed by its docstring if there is one.
+func renderDocumentation(entry TagEntry, docstring string, markdown bool) *MarkupContent {
+	if !markdown {
+		value := entry.Pattern
+		if docstring != "" {
+			value += "\n\n" + docstring
+		}
+		return &MarkupContent{Kind: "plaintext", Value: value}
+	}
+	line := strings.TrimSuffix(strings.TrimPrefix(entry.Pattern, "/^"), "$/")
+	value := "```" + strings.ToLower(entry.Language) + "\n" + strings.TrimSpace(line) + "\n```"
+	if docstring != "" {
+		value += "\n\n" + docstring
+	}
+	return &MarkupContent{Kind: "markdown", Value: value}
+}
+
+// renameProvider announces `textDocument/prepareRename` to clients that support it,
+// and rename alone to the others, as the specification asks.
+func (capabilities ClientCapabilities) renameProvider() any {
+	if capabilities.TextDocument.Rename.PrepareSupport {
+		return &RenameOptions{PrepareProvider: true}
+	}
+	return true
+}
+
+// completionSnippet returns a call snippet for callables, or "" when the client
+// doesn't support snippets or the entry isn't callable.
+func (capabilities ClientCapabilities) completionSnippet(name string, kind int) string {
+	if !capabilities.TextDocument.Completion.CompletionItem.SnippetSupport {
+		return ""
+	}
+	switch kind {
+	case CompletionItemKindFunction, CompletionItemKindMethod, CompletionItemKindConstructor:
+		return name + "($1)$0"
+	}
+	return ""
+}
+
+// buildDocumentSymbolTree nests flat symbols under the symbol named by their
+// container. Symbols whose container isn't in the list stay at the top level.
+// The input order is kept among siblings.
+func buildDocumentSymbolTree(symbols []SymbolInformation) []DocumentSymbol {
+	type node struct {
+		symbol   DocumentSymbol
+		children []*node
+	}
+
+	path := func(container, name string) string {
+		container = normalizeQualifiedName(container)
+		if container == "" {
+			return name
+		}
+		return container + "." + name
+	}
+
+	nodes := make([]*node, len(symbols))
+	byPath := make(map[string]*node, len(symbols))
+	for i, symbol := range symbols {
+		nodes[i] = &node{symbol: DocumentSymbol{
+			Name:           symbol.Name,
+			Kind:           symbol.Kind,
+			Range:          symbol.Location.Range,
+			SelectionRange: symbol.Location.Range,
+		}}
+		key := path(symbol.ContainerName, symbol.Name)
+		if _, ok := byPath[key]; !ok {
+			byPath[key] = nodes[i]
+		}
+	}
+
+	var roots []*node
+	for i, symbol := range symbols {
+		parent, ok := byPath[normalizeQualifiedName(symbol.ContainerName)]
+		if symbol.ContainerName == "" || !ok {
+			roots = append(roots, nodes[i])
+			continue
+		}
+		parent.children = append(parent.children, nodes[i])
+	}
+
+	var convert func([]*node) []DocumentSymbol
+	convert = func(list []*node) []DocumentSymbol {
+		result := make([]DocumentSymbol, 0, len(list))
+		for _, n := range list {
+			symbol := n.symbol
+			if len(n.children) > 0 {
+				symbol.Children = convert(n.children)
+			}
+			result = append(result, symbol)
+		}
+		return result
+	}
+	return convert(roots)
+}
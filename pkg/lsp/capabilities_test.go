@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResponsesFollowClientCapabilities(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shape.go", "type Shape struct {\n\tWidth int\n}\nvar s Shape\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "Shape", Path: uri, Line: 1, Kind: "structure"},
+		{Name: "Width", Path: uri, Line: 2, Kind: "field", Scope: "Shape", ScopeKind: "struct"},
+	})
+	if err := json.Unmarshal([]byte(`{
+		"textDocument": {
+			"definition": {"linkSupport": true},
+			"documentSymbol": {"hierarchicalDocumentSymbolSupport": true}
+		}
+	}`), &server.clientCapabilities); err != nil {
+		t.Fatalf("unmarshal capabilities: %v", err)
+	}
+
+	frames := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	var outline []DocumentSymbol
+	if err := json.Unmarshal(frames[0].Result, &outline); err != nil {
+		t.Fatalf("unmarshal outline: %v", err)
+	}
+	if len(outline) != 1 || len(outline[0].Children) != 1 || outline[0].Children[0].Name != "Width" {
+		t.Fatalf("expected Width nested under Shape, got %+v", outline)
+	}
+	if outline[0].Kind != SymbolKindClass {
+		t.Fatalf("expected struct to fall back to class without a kind value set, got %d", outline[0].Kind)
+	}
+
+	frames = callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 3, Character: 7},
+	})
+	var links []LocationLink
+	if err := json.Unmarshal(frames[0].Result, &links); err != nil {
+		t.Fatalf("unmarshal links: %v", err)
+	}
+	wantOrigin := Range{Start: Position{Line: 3, Character: 6}, End: Position{Line: 3, Character: 11}}
+	if len(links) != 1 || links[0].OriginSelectionRange == nil || *links[0].OriginSelectionRange != wantOrigin {
+		t.Fatalf("expected one link originating at %+v, got %+v", wantOrigin, links)
+	}
+}
@@ -0,0 +1,654 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+// Config holds values parsed from command-line flags.
+type Config struct {
+	showVersion            bool
+	selftest               bool
+	benchmark              bool
+	benchmarkFiles         int
+	benchmarkLines         int
+	benchmarkLanguages     string
+	ctagsBin               string
+	tagfilePath            string
+	root                   string
+	requireTrust           bool
+	trustedDirs            string
+	sandbox                bool
+	tagFilter              string
+	upstream               string
+	session                bool
+	pathMapping            string
+	languages              string
+	ctagArgs               string
+	workspaceSymbolLimit   int
+	maxResponseSize        int
+	requestTimeout         time.Duration
+	metricsAddr            string
+	debugAddr              string
+	queryAddr              string
+	logFormat              string
+	logLevel               string
+	logFile                string
+	rpcLogPath             string
+	rpcLogRedact           bool
+	telemetry              string
+	trimTrailingWhitespace bool
+	referenceTags          bool
+	qualifiedTags          bool
+	embeddedLanguages      bool
+	encoding               string
+	includePaths           string
+	compileCommands        string
+	goModuleDeps           bool
+	sitePackages           bool
+	venv                   string
+	rubyGems               bool
+	jvmSources             bool
+	extensionFamilies      string
+	maxFileSize            int64
+	maxWorkspaceFiles      int
+	exclude                string
+	workers                int
+	lowPriority            bool
+	typingPause            time.Duration
+	fuzzySymbolSearch      bool
+	fileSymbols            bool
+	followTypedefs         bool
+	hoverBlame             bool
+	unusedSymbols          bool
+	unknownSymbols         bool
+	inlayHints             bool
+	codeLens               bool
+	documentSymbolExclude  string
+	documentSymbolOrder    string
+	disabledProviders      []string
+	args                   []string
+	version                string // Not a flag; see `Process.Version`.
+}
+
+// Process is the state of the program running the command that the command
+// configures but doesn't own: the version the program was built as, and the
+// process-wide logger. `Main` leaves the globals behind it to the program.
+type Process struct {
+	// Version is reported to clients, by `--version` and in the tagfiles `watch`
+	// writes.
+	Version string
+	// SetLogger makes the logger asked for by `--log-level`, `--log-format` and
+	// `--log-file` the process-wide one, typically with `slog.SetDefault`. When it
+	// is nil, the logger of the process is left alone.
+	SetLogger func(logger *slog.Logger)
+}
+
+// setLogger opens the logger the flags in `config` ask for and hands it to
+// `process.SetLogger`.
+func (process Process) setLogger(config *Config, stderr io.Writer) error {
+	logger, err := openLogger(config, stderr)
+	if err != nil {
+		return err
+	}
+	if process.SetLogger != nil {
+		process.SetLogger(logger)
+	}
+	return nil
+}
+
+// Main runs the ctags-lsp command with the command-line `args`, including the
+// program name, and returns its exit code.
+func Main(process Process, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	return run(process, args, stdin, stdout, stderr, ctags.Check)
+}
+
+func run(process Process, args []string, stdin io.Reader, stdout, stderr io.Writer, checkCtags func(string) error) int {
+	if len(args) > 1 && args[1] == "replay" {
+		return runReplayCommand(process, append([]string{args[0] + " replay"}, args[2:]...), stdout, stderr, checkCtags)
+	}
+	if len(args) > 1 && args[1] == "browse" {
+		return runBrowseCommand(process, append([]string{args[0] + " browse"}, args[2:]...), stdin, stdout, stderr, checkCtags)
+	}
+	if len(args) > 1 && args[1] == "watch" {
+		return runWatchCommand(process, append([]string{args[0] + " watch"}, args[2:]...), stdout, stderr, checkCtags)
+	}
+	if len(args) > 1 && args[1] == "graph" {
+		return runGraphCommand(process, append([]string{args[0] + " graph"}, args[2:]...), stdout, stderr, checkCtags)
+	}
+
+	config, err := parseFlags(args, stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	config.version = process.Version
+
+	if config.showVersion {
+		fmt.Fprintf(stdout, "CTags Language Server %s\n", process.Version)
+		return 0
+	}
+
+	if err := process.setLogger(config, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if err := validateTelemetryTarget(config.telemetry); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if _, err := lookupEncoding(config.encoding); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if config.tagFilter != "" {
+		if err := validateTagFilter(config.tagFilter); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	if _, err := parseUpstreams(config.upstream); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if err := validatePathMapping(config.pathMapping); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	ctagsBin, tried := ctags.Locate(config.ctagsBin, ctags.Candidates(runtime.GOOS, os.Getenv))
+	config.ctagsBin = ctagsBin
+	if err := checkCtags(config.ctagsBin); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		if len(tried) > 0 {
+			fmt.Fprintf(stderr, "Looked for ctags at:\n  %s\n", strings.Join(tried, "\n  "))
+		}
+		if runtime.GOOS == "windows" && ctags.WSLHasCtags() {
+			fmt.Fprintln(stderr, "Universal Ctags is installed in WSL, but ctags-lsp needs a Windows build of ctags; install one, run the editor inside WSL, or run ctags-lsp in WSL with --path-mapping wsl.")
+		}
+		return 1
+	}
+
+	server := newServer(config, stdout)
+
+	if config.rpcLogPath != "" {
+		rpcLog, err := openRPCLog(config.rpcLogPath, config.rpcLogRedact)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		server.rpcLog = rpcLog
+	}
+
+	if config.metricsAddr != "" {
+		if err := startMetricsServer(config.metricsAddr, server); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if config.debugAddr != "" {
+		if err := startDebugServer(config.debugAddr, server); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if config.queryAddr != "" {
+		if err := startQueryServer(config.queryAddr, server); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	if config.selftest {
+		failures, err := runSelftest(server, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		if failures > 0 {
+			return 1
+		}
+		return 0
+	}
+
+	if config.benchmark {
+		if err := runBenchmark(server, config, stderr); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if err := serve(stdin, server); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if config.telemetry != "" {
+		server.pending.Wait()
+		if err := sendTelemetry(config.telemetry, server.collectTelemetry(), stderr); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+		}
+	}
+
+	return 0
+}
+
+func newServer(config *Config, output io.Writer) *Server {
+	enc, _ := lookupEncoding(config.encoding)
+	server := &Server{
+		cache: FileCache{
+			content:  make(map[string][]string),
+			encoding: enc,
+		},
+		ctagsBin:          config.ctagsBin,
+		tagfilePath:       config.tagfilePath,
+		requireTrust:      config.requireTrust,
+		trustedDirs:       splitList(config.trustedDirs),
+		sandbox:           config.sandbox,
+		version:           config.version,
+		wslPaths:          config.pathMapping == pathMappingWSL,
+		output:            output,
+		upstreams:         make(map[string]*upstreamServer),
+		documentLanguages: make(map[string]string),
+		options: serverOptions{
+			languages:              config.languages,
+			ctagArgs:               strings.Fields(config.ctagArgs),
+			workspaceSymbolLimit:   config.workspaceSymbolLimit,
+			maxResponseSize:        config.maxResponseSize,
+			requestTimeout:         config.requestTimeout,
+			trimTrailingWhitespace: config.trimTrailingWhitespace,
+			referenceTags:          config.referenceTags,
+			qualifiedTags:          config.qualifiedTags,
+			embeddedLanguages:      config.embeddedLanguages,
+			encoding:               config.encoding,
+			includePaths:           splitList(config.includePaths),
+			compileCommands:        config.compileCommands,
+			goModuleDeps:           config.goModuleDeps,
+			sitePackages:           config.sitePackages,
+			venv:                   config.venv,
+			rubyGems:               config.rubyGems,
+			jvmSources:             config.jvmSources,
+			extensionFamilies:      parseExtensionFamilies(config.extensionFamilies),
+			maxFileSize:            config.maxFileSize,
+			maxWorkspaceFiles:      config.maxWorkspaceFiles,
+			exclude:                splitList(config.exclude),
+			workers:                config.workers,
+			lowPriority:            config.lowPriority,
+			typingPause:            config.typingPause,
+			fuzzySymbolSearch:      config.fuzzySymbolSearch,
+			fileSymbols:            config.fileSymbols,
+			followTypedefs:         config.followTypedefs,
+			hoverBlame:             config.hoverBlame,
+			unusedSymbols:          config.unusedSymbols,
+			unknownSymbols:         config.unknownSymbols,
+			inlayHints:             config.inlayHints,
+			codeLens:               config.codeLens,
+
+			documentSymbolExcludeKinds: splitList(config.documentSymbolExclude),
+			documentSymbolOrder:        config.documentSymbolOrder,
+		},
+	}
+	if config.tagFilter != "" {
+		server.tagFilters = append(server.tagFilters, server.execTagFilter(config.tagFilter))
+	}
+	server.upstreamCommands, _ = parseUpstreams(config.upstream)
+	if config.session {
+		server.session.dir = defaultSessionDir()
+	}
+	server.disableProviders(config.disabledProviders)
+	return server
+}
+
+// serve reads messages from `r` until EOF or an `exit` notification.
+// Messages are handled concurrently unless `server.sequential` is set.
+func serve(r io.Reader, server *Server) error {
+	// A client may exit, or go away, without a shutdown request first, which
+	// would leave the upstream servers running.
+	defer server.stopUpstreams()
+	reader := bufio.NewReader(r)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			server.sendError(nil, -32600, "Malformed request", err.Error())
+			continue
+		}
+		server.rpcLog.record(rpcDirectionIn, body)
+
+		req, err := parseMessage(body)
+		if err != nil {
+			server.sendError(nil, -32600, "Malformed request", err.Error())
+			continue
+		}
+		if isResponse(req) {
+			server.handleClientResponse(req)
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+		server.proxyNotification(req)
+
+		server.pending.Add(1)
+		go func() {
+			defer server.pending.Done()
+			handleRequest(server, req)
+		}()
+		if server.sequential {
+			server.pending.Wait()
+		}
+	}
+}
+
+func parseFlags(args []string, output io.Writer) (*Config, error) {
+	config := &Config{}
+	flagset := newFlagSet(config, args[0], output)
+	if err := applyEnvironment(flagset); err != nil {
+		return nil, err
+	}
+	if err := flagset.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	config.args = flagset.Args()
+
+	return config, nil
+}
+
+// newFlagSet defines the flags of `program`, which set the fields of `config`.
+// Defining them sets the defaults, so a config nothing else has parsed into is
+// the default configuration.
+func newFlagSet(config *Config, program string, output io.Writer) *flag.FlagSet {
+	flagset := flag.NewFlagSet(program, flag.ContinueOnError)
+	flagset.SetOutput(output)
+	flagset.Usage = func() {
+		flagUsage(output, program)
+	}
+	flagset.BoolVar(&config.showVersion, "version", false, "")
+	flagset.BoolVar(&config.selftest, "selftest", false, "")
+	flagset.BoolVar(&config.benchmark, "benchmark", false, "")
+	flagset.IntVar(&config.benchmarkFiles, "benchmark-files", 0, "")
+	flagset.IntVar(&config.benchmarkLines, "benchmark-lines", 200, "")
+	flagset.StringVar(&config.benchmarkLanguages, "benchmark-languages", "go,python,c,javascript,ruby", "")
+	flagset.StringVar(&config.ctagsBin, "ctags-bin", ctags.DefaultBin, "")
+	flagset.StringVar(&config.tagfilePath, "tagfile", "", "")
+	flagset.StringVar(&config.root, "root", ".", "")
+	flagset.BoolVar(&config.requireTrust, "require-trust", false, "")
+	flagset.StringVar(&config.trustedDirs, "trusted-dirs", "", "")
+	flagset.BoolVar(&config.sandbox, "sandbox", false, "")
+	flagset.StringVar(&config.tagFilter, "tag-filter", "", "")
+	flagset.StringVar(&config.upstream, "upstream", "", "")
+	flagset.BoolVar(&config.session, "session", false, "")
+	flagset.StringVar(&config.pathMapping, "path-mapping", pathMappingNone, "")
+	flagset.StringVar(&config.languages, "languages", "", "")
+	flagset.StringVar(&config.ctagArgs, "ctags-args", "", "")
+	flagset.IntVar(&config.workspaceSymbolLimit, "workspace-symbol-limit", defaultWorkspaceSymbolLimit, "")
+	flagset.IntVar(&config.maxResponseSize, "max-response-size", defaultMaxResponseSize, "")
+	flagset.DurationVar(&config.requestTimeout, "request-timeout", defaultRequestTimeout, "")
+	flagset.StringVar(&config.metricsAddr, "metrics-addr", "", "")
+	flagset.StringVar(&config.debugAddr, "debug-addr", "", "")
+	flagset.StringVar(&config.queryAddr, "query-addr", "", "")
+	flagset.StringVar(&config.logFormat, "log-format", "text", "")
+	flagset.StringVar(&config.logLevel, "log-level", "info", "")
+	flagset.StringVar(&config.logFile, "log-file", "", "")
+	flagset.StringVar(&config.rpcLogPath, "rpc-log", "", "")
+	flagset.BoolVar(&config.rpcLogRedact, "rpc-log-redact", false, "")
+	flagset.StringVar(&config.telemetry, "telemetry", "", "")
+	flagset.BoolVar(&config.trimTrailingWhitespace, "trim-trailing-whitespace", false, "")
+	flagset.BoolVar(&config.referenceTags, "reference-tags", false, "")
+	flagset.BoolVar(&config.qualifiedTags, "qualified-tags", false, "")
+	flagset.BoolVar(&config.embeddedLanguages, "embedded-languages", false, "")
+	flagset.StringVar(&config.encoding, "encoding", defaultEncoding, "")
+	flagset.StringVar(&config.includePaths, "include-paths", "", "")
+	flagset.StringVar(&config.compileCommands, "compile-commands", "", "")
+	flagset.BoolVar(&config.goModuleDeps, "go-module-deps", false, "")
+	flagset.BoolVar(&config.sitePackages, "site-packages", false, "")
+	flagset.StringVar(&config.venv, "venv", "", "")
+	flagset.BoolVar(&config.rubyGems, "ruby-gems", false, "")
+	flagset.BoolVar(&config.jvmSources, "jvm-sources", false, "")
+	flagset.StringVar(&config.extensionFamilies, "extension-families", "", "")
+	flagset.Int64Var(&config.maxFileSize, "max-file-size", workspace.DefaultMaxFileSize, "")
+	flagset.IntVar(&config.maxWorkspaceFiles, "max-workspace-files", defaultMaxWorkspaceFiles, "")
+	flagset.StringVar(&config.exclude, "exclude", strings.Join(workspace.DefaultExclude, ","), "")
+	flagset.IntVar(&config.workers, "workers", 0, "")
+	flagset.BoolVar(&config.lowPriority, "low-priority", false, "")
+	flagset.DurationVar(&config.typingPause, "typing-pause", 0, "")
+	flagset.BoolVar(&config.fuzzySymbolSearch, "fuzzy-symbol-search", false, "")
+	flagset.BoolVar(&config.fileSymbols, "file-symbols", false, "")
+	flagset.BoolVar(&config.followTypedefs, "follow-typedefs", false, "")
+	flagset.BoolVar(&config.hoverBlame, "hover-blame", false, "")
+	flagset.BoolVar(&config.unusedSymbols, "unused-symbols", false, "")
+	flagset.BoolVar(&config.unknownSymbols, "unknown-symbols", false, "")
+	flagset.BoolVar(&config.inlayHints, "inlay-hints", false, "")
+	flagset.BoolVar(&config.codeLens, "code-lens", false, "")
+	flagset.StringVar(&config.documentSymbolExclude, "document-symbol-exclude-kinds", "", "")
+	flagset.StringVar(&config.documentSymbolOrder, "document-symbol-order", documentSymbolOrderPosition, "")
+	for _, provider := range providers {
+		flagset.BoolFunc("disable-"+provider, "", func(value string) error {
+			disable, err := strconv.ParseBool(value)
+			if err != nil {
+				return err
+			}
+			if disable {
+				config.disabledProviders = append(config.disabledProviders, provider)
+			}
+			return nil
+		})
+	}
+	return flagset
+}
+
+// envPrefix prefixes the environment variables that set options, e.g.
+// CTAGS_LSP_CTAGS_BIN for `--ctags-bin`.
+const envPrefix = "CTAGS_LSP_"
+
+// envName returns the environment variable for the flag `name`.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvironment sets flags from their environment variables. It runs before
+// the command line is parsed, so flags take precedence.
+func applyEnvironment(flagset *flag.FlagSet) error {
+	var err error
+	flagset.VisitAll(func(f *flag.Flag) {
+		// Environment variables configure the server, not one-off commands.
+		if err != nil || f.Name == "version" || f.Name == "selftest" || strings.HasPrefix(f.Name, "benchmark") {
+			return
+		}
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := flagset.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %v", value, envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+func flagUsage(w io.Writer, program string) {
+	fmt.Fprintf(w, `CTags Language Server
+Provides LSP functionality based on ctags.
+
+Usage:
+  %[1]s [options]
+  %[1]s replay [options] <rpc-log>
+  %[1]s graph [options] <dot|json> [<dir>]
+  %[1]s browse [options] [<dir>]
+  %[1]s watch [options]
+
+Options (each can also be set with a CTAGS_LSP_* environment variable, e.g.
+CTAGS_LSP_CTAGS_BIN for --ctags-bin; flags take precedence):
+  --help               Show this help message
+  --version            Show version information
+  --selftest           Run a scripted LSP session against a built-in workspace and report whether it passes
+  --benchmark          Index the current directory once and report how long it took
+  --benchmark-files <n>
+                       Benchmark a generated workspace of n files instead (default: 0, uses the current directory)
+  --benchmark-lines <n>
+                       Approximate lines per generated file (default: 200)
+  --benchmark-languages <list>
+                       Languages mixed into the generated workspace (default: "go,python,c,javascript,ruby")
+  --ctags-bin <name>   Use custom ctags binary name (default: "ctags", on Windows also looked for in the
+                       Chocolatey, Scoop, WinGet, Program Files and MSYS2 install locations)
+  --tagfile <path>     Use custom tagfile (default: tries "tags", ".tags" and ".git/tags")
+  --root <dir>         Directory the watch, graph and browse commands index (default: ".")
+  --require-trust      Ask before running git, jj or ctags in a workspace outside --trusted-dirs
+  --trusted-dirs <dirs>
+                       Comma-separated directories whose workspaces are trusted with --require-trust
+  --sandbox            Run ctags with CPU time, memory, output and run time limits, and on Linux
+                       without gaining privileges; it can still read and write what the user can
+  --tag-filter <command>
+                       Pipe tags through a program as JSON lines before indexing them
+  --upstream <value>   Ask other language servers first, as languageId=command pairs separated by
+                       semicolons, e.g. "go=gopls;python=pylsp"
+  --session            Remember settings, the tagfile, prompt answers and recent jumps per workspace
+  --path-mapping <value>
+                       Translate paths between the editor, ctags and tagfiles: "none" or "wsl" for
+                       C:\... and /mnt/c/... (default: "none")
+  --languages <value>  Pass through language filter list to ctags
+  --ctags-args <value> Pass through ctags arg
+  --workspace-symbol-limit <n>
+                       Maximum number of workspace symbols returned per query (default: 500, 0 disables)
+  --fuzzy-symbol-search
+                       Also match workspace symbols within a few typos of the query
+  --file-symbols       Also return indexed files whose name matches a workspace symbol query
+  --follow-typedefs    Also return the underlying type when a definition is a typedef or alias
+  --hover-blame        Show who last changed a definition's line, when and why in hover, from git blame
+  --unused-symbols     Report functions, types, constants and variables that are never used as hints
+  --unknown-symbols    Report identifiers in open documents that nothing defines (C, C++, Go, Java,
+                       JavaScript, TypeScript, Python, Ruby and Lua)
+  --inlay-hints        Show the types of variables and the return types of functions inline
+  --code-lens          Show reference counts above functions, types, constants and variables
+  --request-timeout <duration>
+                       Soft deadline for completion, symbol and reference requests (default: 3s,
+                       0 disables)
+  --max-response-size <bytes>
+                       Drop results from larger responses (default: 4194304, 0 disables)
+  --metrics-addr <addr> Serve Prometheus metrics on /metrics and expvar on /debug/vars (e.g. "localhost:9090")
+  --debug-addr <addr>  Serve net/http/pprof handlers and the index state on a loopback address
+                       (e.g. "localhost:6060")
+  --query-addr <addr>  Serve the index as JSON on /symbols and /definition on a loopback address
+                       (e.g. "localhost:7070")
+  --log-format <value> Log format: "text" or "json" (default: "text")
+  --log-level <value>  Minimum log level: "debug", "info", "warn" or "error" (default: "info")
+  --log-file <path>    Append logs to a file instead of writing them to stderr
+  --rpc-log <path>     Append every inbound and outbound JSON-RPC message to a file
+  --rpc-log-redact     Replace document text in the --rpc-log file with "[redacted]"
+  --telemetry <target> Opt in to an anonymous usage report on exit: "stderr" or an http(s) URL to POST it to
+  --trim-trailing-whitespace
+                       Remove trailing whitespace on save (via willSaveWaitUntil)
+  --reference-tags     Also index reference tags (ctags --extras=+r), kept apart from definitions
+  --qualified-tags     Also index scope-qualified names (ctags --extras=+q), e.g. "Outer.method"
+  --embedded-languages Also index code embedded in other languages (ctags --extras=+g), e.g. script
+                       blocks in HTML, Vue and Svelte files
+  --encoding <value>   Encoding of source files that aren't UTF-8: "utf-8", "latin1", "windows-1252",
+                       "shift_jis", "utf-16le" or "utf-16be" (default: "utf-8"); UTF-16 BOMs are always detected
+  --include-paths <dirs>
+                       Comma-separated directories searched for #include/import targets, relative to the workspace root
+  --compile-commands <path>
+                       compile_commands.json that decides which C/C++ files are indexed and adds include paths
+                       (default: looked for in the workspace root and "build")
+  --go-module-deps     Also index the downloaded dependencies of a Go module (via "go list -m all")
+  --site-packages      Also index the Python sources installed in the virtualenv (up to 200 MB)
+  --venv <path>        Virtualenv for --site-packages (default: $VIRTUAL_ENV, then ".venv" or "venv" in the root)
+  --ruby-gems          Also index the gems of the bundle (via "bundle list --paths"), or all installed gems
+  --jvm-sources        Also index the *-sources.jar files in the Maven and Gradle caches of a JVM project
+  --extension-families <value>
+                       Extra groups of extensions that share symbols, e.g. ".vert,.frag;.pyx,.pxd"
+  --max-file-size <bytes>
+                       Skip larger files when walking a workspace that isn't a git or jj repository
+                       (default: 10485760, 0 disables); binary files are always skipped
+  --max-workspace-files <n>
+                       Ask before scanning a workspace with more files (default: 100000, 0 disables)
+  --exclude <patterns> Comma-separated file and directory names (globs) left out of the workspace scan
+                       (default: "dist,build,target,.venv,__pycache__,.next,coverage,*.min.js", "" disables)
+  --workers <n>        Number of ctags processes run in parallel during a scan (default: 0, one per CPU)
+  --low-priority       Run ctags at the lowest CPU priority (nice 19, or the idle class on Windows)
+  --typing-pause <duration>
+                       Pause scans until no document has changed for this long, e.g. "1s" (default: 0, disabled)
+  --document-symbol-exclude-kinds <kinds>
+                       Comma-separated ctags kinds to hide from outlines (e.g. "local,variable")
+  --document-symbol-order <value>
+                       Outline order: "position" or "kind" (default: "position")
+  --disable-completion, --disable-definition, --disable-type-definition, --disable-references,
+  --disable-rename, --disable-call-hierarchy, --disable-type-hierarchy, --disable-semantic-tokens,
+  --disable-document-highlight, --disable-code-lens, --disable-folding-range, --disable-inlay-hint,
+  --disable-hover, --disable-workspace-symbol, --disable-document-symbol, --disable-moniker,
+  --disable-document-link, --disable-diagnostics
+                       Leave out a feature, e.g. when another language server already provides it
+`, program)
+}
+
+// runBenchmark initializes the server on the current directory, or on a generated
+// workspace when `--benchmark-files` is set, and reports how long indexing took.
+func runBenchmark(server *Server, config *Config, report io.Writer) error {
+	root, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("get current working directory: %w", err)
+	}
+
+	if config.benchmarkFiles > 0 {
+		root, err = os.MkdirTemp("", "ctags-lsp-benchmark-")
+		if err != nil {
+			return fmt.Errorf("create benchmark workspace: %w", err)
+		}
+		defer os.RemoveAll(root)
+
+		err = generateSyntheticWorkspace(root, syntheticWorkspace{
+			files:     config.benchmarkFiles,
+			lines:     config.benchmarkLines,
+			languages: splitList(config.benchmarkLanguages),
+			seed:      1,
+		})
+		if err != nil {
+			return fmt.Errorf("generate benchmark workspace: %w", err)
+		}
+	}
+
+	mockID := json.RawMessage(`1`)
+	mockParams := InitializeParams{RootURI: pathToFileURI(root)}
+	mockParamsBytes, err := json.Marshal(mockParams)
+	if err != nil {
+		return fmt.Errorf("marshal initialize params: %w", err)
+	}
+
+	mockReq := RPCRequest{
+		Jsonrpc: "2.0",
+		ID:      &mockID,
+		Method:  "initialize",
+		Params:  mockParamsBytes,
+	}
+
+	start := time.Now()
+	handleInitialize(server, mockReq)
+	fmt.Fprintf(report, "Indexed %d tags in %s\n", len(server.tagEntries), time.Since(start))
+	return nil
+}
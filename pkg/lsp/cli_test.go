@@ -1,4 +1,4 @@
-package main
+package lsp
 
 import (
 	"bufio"
@@ -35,7 +35,9 @@ func TestInitializeLSPRequest(t *testing.T) {
 		},
 		ctagsBin:    config.ctagsBin,
 		tagfilePath: config.tagfilePath,
-		languages:   config.languages,
+		options: serverOptions{
+			languages: config.languages,
+		},
 	}
 
 	resp := initializeServer(t, server, tempDir)
@@ -128,10 +130,12 @@ func parseLSPResponse(t *testing.T, raw string) rpcSuccessEnvelope {
 		t.Fatalf("missing Content-Length header in %q", parts[0])
 	}
 
+	// The response may be followed by notifications such as the indexing status.
 	body := parts[1]
-	if contentLength != len(body) {
+	if contentLength > len(body) {
 		t.Fatalf("expected Content-Length %d, got %d", contentLength, len(body))
 	}
+	body = body[:contentLength]
 
 	var resp rpcSuccessEnvelope
 	if err := json.Unmarshal([]byte(body), &resp); err != nil {
@@ -193,3 +197,35 @@ func parseFlagsForTest(t *testing.T, args []string) *Config {
 	}
 	return config
 }
+
+func TestEnvironmentConfiguration(t *testing.T) {
+	t.Setenv("CTAGS_LSP_CTAGS_BIN", "/usr/local/bin/ctags")
+	t.Setenv("CTAGS_LSP_LANGUAGES", "Go")
+	t.Setenv("CTAGS_LSP_LOG_LEVEL", "debug")
+	t.Setenv("CTAGS_LSP_QUALIFIED_TAGS", "true")
+	t.Setenv("CTAGS_LSP_VERSION", "true")
+
+	config := parseFlagsForTest(t, []string{"ctags-lsp", "--languages", "Python"})
+	if config.ctagsBin != "/usr/local/bin/ctags" || config.logLevel != "debug" || !config.qualifiedTags {
+		t.Fatalf("expected environment variables to set options, got %+v", config)
+	}
+	if config.languages != "Python" {
+		t.Fatalf("expected flags to take precedence, got languages %q", config.languages)
+	}
+	if config.showVersion {
+		t.Fatalf("expected CTAGS_LSP_VERSION to be ignored")
+	}
+
+	t.Setenv("CTAGS_LSP_WORKERS", "many")
+	if _, err := parseFlags([]string{"ctags-lsp"}, io.Discard); err == nil || !strings.Contains(err.Error(), "CTAGS_LSP_WORKERS") {
+		t.Fatalf("expected an error naming the variable, got %v", err)
+	}
+}
+
+func TestCtagsArgsFlag(t *testing.T) {
+	config := parseFlagsForTest(t, []string{"ctags-lsp", "--ctags-args", " --kinds-c=+p   --fields=+n "})
+	server := newServer(config, io.Discard)
+	if args := server.getOptions().ctagArgs; len(args) != 2 || args[0] != "--kinds-c=+p" || args[1] != "--fields=+n" {
+		t.Fatalf("expected the arguments split at runs of spaces, got %q", args)
+	}
+}
@@ -0,0 +1,120 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type CodeLensOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+type CodeLensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// CodeLens matches LSP 3.17 `CodeLens`. Lenses are sent without a command; Data
+// names the tag whose references `codeLens/resolve` counts.
+type CodeLens struct {
+	Range   Range    `json:"range"`
+	Command *Command `json:"command,omitempty"`
+	Data    *tagData `json:"data,omitempty"`
+}
+
+// Command matches LSP 3.17 `Command`. Reference count lenses have no command to
+// run, only a title to show.
+type Command struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+// handleCodeLens puts, with `--code-lens`, a lens on each definition in the
+// document of a kind the unused symbol check looks at (see `unusedSymbolKinds`).
+// Counting references may take a workspace text search, so it waits for
+// `codeLens/resolve`, which clients send for the lenses on screen.
+func handleCodeLens(server *Server, req RPCRequest) {
+	var params CodeLensParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	lenses := []CodeLens{}
+	if !server.getOptions().codeLens {
+		server.sendResult(req.ID, lenses)
+		return
+	}
+	lines, err := server.cache.GetOrLoadFileContent(normalizedURI)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	entries := entriesForURI(server.tagEntries, normalizedURI)
+	server.mutex.Unlock()
+
+	for _, entry := range entries {
+		if isQualifiedTag(entry) {
+			continue
+		}
+		if kind, ok := symbolKindByTagKind[entry.Kind]; !ok || !unusedSymbolKinds[kind] {
+			continue
+		}
+		data := tagDataOf(entry)
+		lenses = append(lenses, CodeLens{Range: findEntryRange(lines, entry), Data: &data})
+	}
+	server.sendResult(req.ID, lenses)
+}
+
+// handleCodeLensResolve counts the references of the lens's tag: its reference
+// tags in the extension family with `--reference-tags`, or else the uses of its
+// name the usage index found in the code of indexed files. Either way, names are
+// compared without regard to scope. A lens whose count the request deadline cut
+// short is sent back as it came.
+func handleCodeLensResolve(server *Server, req RPCRequest) {
+	var lens CodeLens
+	if err := json.Unmarshal(req.Params, &lens); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+	if lens.Data == nil {
+		server.sendResult(req.ID, lens)
+		return
+	}
+	ctx, done := server.trackRequest(req, "")
+	defer done()
+
+	count := 0
+	if server.getOptions().referenceTags {
+		server.mutex.Lock()
+		family := server.documentFamily(lens.Data.Path)
+		for _, entry := range server.referenceEntries {
+			if entry.Name == lens.Data.Name && family.includes(entry) {
+				count++
+			}
+		}
+		server.mutex.Unlock()
+	} else {
+		usage := server.usageIndex(ctx)
+		if usage == nil {
+			server.sendResult(req.ID, lens)
+			return
+		}
+		count = usage.uses[lens.Data.Name]
+	}
+
+	title := fmt.Sprintf("%d references", count)
+	if count == 1 {
+		title = "1 reference"
+	}
+	lens.Command = &Command{Title: title}
+	server.sendResult(req.ID, lens)
+}
@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCodeLens(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "main.go", "package main\n\nfunc helper() {}\n\nfunc main() {\n\thelper()\n\thelper() // helper\n}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "main", Path: uri, Line: 1, Kind: "package", Language: "Go"},
+		{Name: "helper", Path: uri, Line: 3, Kind: "func", Language: "Go"},
+		{Name: "main", Path: uri, Line: 5, Kind: "func", Language: "Go"},
+	})
+
+	params := CodeLensParams{TextDocument: TextDocumentIdentifier{URI: uri}}
+	frames := callHandler(t, server, "textDocument/codeLens", params)
+	if string(frames[0].Result) != "[]" {
+		t.Fatalf("expected no lenses without --code-lens, got %s", frames[0].Result)
+	}
+
+	server.options.codeLens = true
+	frames = callHandler(t, server, "textDocument/codeLens", params)
+	var lenses []CodeLens
+	if err := json.Unmarshal(frames[0].Result, &lenses); err != nil {
+		t.Fatalf("decode lenses %s: %v", frames[0].Result, err)
+	}
+	if len(lenses) != 2 || lenses[0].Data == nil || lenses[0].Data.Name != "helper" || lenses[0].Command != nil {
+		t.Fatalf("expected unresolved lenses for the functions, got %+v", lenses)
+	}
+	if lenses[0].Range.Start != (Position{Line: 2, Character: 5}) {
+		t.Fatalf("expected the lens on helper, got %+v", lenses[0].Range)
+	}
+
+	title := func(lens CodeLens) string {
+		frames := callHandler(t, server, "codeLens/resolve", lens)
+		var resolved CodeLens
+		if err := json.Unmarshal(frames[0].Result, &resolved); err != nil || resolved.Command == nil {
+			t.Fatalf("expected a resolved lens, got %s", frames[0].Result)
+		}
+		return resolved.Command.Title
+	}
+	if got := title(lenses[0]); got != "2 references" {
+		t.Fatalf("expected the uses of helper to be counted, got %q", got)
+	}
+
+	// Editing another file recounts only that file.
+	other := writeTestFile(t, dir, "other.go", "package main\n\nfunc other() { helper() }\n")
+	server.tagEntries = append(server.tagEntries, TagEntry{Name: "other", Path: other, Line: 3, Kind: "func", Language: "Go"})
+	server.invalidateUsageOf(other)
+	if got := title(lenses[0]); got != "3 references" {
+		t.Fatalf("expected the use in the new file to be counted, got %q", got)
+	}
+	callHandler(t, server, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: other, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "package main\n\nfunc other() {}\n"}},
+	})
+	if got := title(lenses[0]); got != "2 references" {
+		t.Fatalf("expected the edit to be counted, got %q", got)
+	}
+	if _, ok := server.cache.content[uriKey(other)]; !ok || len(server.usageCounts.files) != 2 {
+		t.Fatalf("expected counts for both files, got %v", server.usageCounts.files)
+	}
+
+	server.options.referenceTags = true
+	server.referenceEntries = []TagEntry{{Name: "helper", Path: uri, Line: 6, Kind: "func", Language: "Go", Roles: "ref"}}
+	if got := title(lenses[0]); got != "1 reference" {
+		t.Fatalf("expected the reference tags of helper to be counted, got %q", got)
+	}
+}
@@ -0,0 +1,248 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// compileCommandsName is the file CMake, Meson, Bear and others write the
+// compilation database to.
+const compileCommandsName = "compile_commands.json"
+
+// compileCommandsDirs are searched in order for a compilation database, relative to
+// the workspace root, when `--compile-commands` isn't given.
+var compileCommandsDirs = []string{".", "build"}
+
+// cSourceExtensions are the files a compilation database decides about: sources
+// are indexed only if they are compiled, headers only if the compiler can see them.
+var (
+	cSourceExtensions = map[string]bool{".c": true, ".cc": true, ".cpp": true, ".cxx": true, ".c++": true, ".m": true, ".mm": true}
+	cHeaderExtensions = map[string]bool{".h": true, ".hh": true, ".hpp": true, ".hxx": true, ".h++": true, ".inl": true}
+)
+
+// compileCommand is one entry of a JSON compilation database.
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Command   string   `json:"command,omitempty"`
+	Arguments []string `json:"arguments,omitempty"`
+}
+
+// compilationDatabase is what the server takes from compile_commands.json: the
+// translation units and the include directories of their commands, all absolute.
+type compilationDatabase struct {
+	path        string
+	sources     map[string]bool
+	includeDirs []string
+}
+
+// findCompilationDatabase returns the path of the compilation database to use:
+// `configured` if set, otherwise the first compile_commands.json found in
+// `compileCommandsDirs`. It returns "" if there is none.
+func findCompilationDatabase(rootDir, configured string) string {
+	if configured != "" {
+		if !filepath.IsAbs(configured) {
+			configured = filepath.Join(rootDir, configured)
+		}
+		return filepath.Clean(configured)
+	}
+	for _, dir := range compileCommandsDirs {
+		path := filepath.Join(rootDir, dir, compileCommandsName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadCompilationDatabase reads the compilation database at `path`.
+func loadCompilationDatabase(path string) (*compilationDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var commands []compileCommand
+	if err := json.Unmarshal(data, &commands); err != nil {
+		return nil, fmt.Errorf("invalid compilation database %q: %v", path, err)
+	}
+
+	db := &compilationDatabase{path: path, sources: make(map[string]bool)}
+	seen := make(map[string]bool)
+	for _, command := range commands {
+		dir := command.Directory
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(filepath.Dir(path), dir)
+		}
+		db.sources[absoluteIn(dir, command.File)] = true
+
+		args := command.Arguments
+		if len(args) == 0 {
+			args = splitCommandLine(command.Command)
+		}
+		for _, include := range includeDirArgs(args) {
+			include = absoluteIn(dir, include)
+			if !seen[include] {
+				seen[include] = true
+				db.includeDirs = append(db.includeDirs, include)
+			}
+		}
+	}
+	return db, nil
+}
+
+// loadCompilationDatabase loads the workspace's compilation database, if it has
+// one, and remembers it for include resolution. It returns nil without one.
+func (server *Server) loadCompilationDatabase(rootDir string) *compilationDatabase {
+	var db *compilationDatabase
+	if path := findCompilationDatabase(rootDir, server.getOptions().compileCommands); path != "" {
+		loaded, err := loadCompilationDatabase(path)
+		if err != nil {
+			slog.Warn("ignoring compilation database", "error", err)
+		} else {
+			slog.Info("using compilation database", "path", path, "sources", len(loaded.sources), "includeDirs", len(loaded.includeDirs))
+			db = loaded
+		}
+	}
+
+	server.mutex.Lock()
+	server.compilationDB = db
+	server.mutex.Unlock()
+	return db
+}
+
+// includeDirArgs returns the directories named by -I, -isystem, -iquote and
+// -idirafter in compiler arguments, written joined to the flag or after it, and by
+// /I if the compiler is MSVC's.
+func includeDirArgs(args []string) []string {
+	flags := []string{"-isystem", "-iquote", "-idirafter", "-I"}
+	if len(args) > 0 {
+		// Windows paths may reach us on any platform, so split on both separators.
+		compiler := strings.ToLower(args[0][strings.LastIndexAny(args[0], `/\`)+1:])
+		compiler = strings.TrimSuffix(compiler, ".exe")
+		if compiler == "cl" || compiler == "clang-cl" {
+			flags = append(flags, "/I")
+		}
+	}
+
+	var dirs []string
+	for i := 0; i < len(args); i++ {
+		for _, flag := range flags {
+			value, ok := strings.CutPrefix(args[i], flag)
+			if !ok {
+				continue
+			}
+			if value == "" && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			if value != "" {
+				dirs = append(dirs, value)
+			}
+			break
+		}
+	}
+	return dirs
+}
+
+// splitCommandLine splits a shell command line into arguments, honoring single and
+// double quotes and backslash escapes, which is all compilation databases use.
+func splitCommandLine(command string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// absoluteIn returns `path` made absolute against `dir` and cleaned.
+func absoluteIn(dir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// filterFiles narrows the C and C++ files of a workspace listing to what the
+// database compiles: sources it has a command for, and headers in an include
+// directory or next to a compiled source. Other files are kept as they are, and
+// compiled sources missing from the listing, such as generated ones, are added.
+// Paths in `files` may be relative to `rootDir`.
+func (db *compilationDatabase) filterFiles(rootDir string, files []string) []string {
+	sourceDirs := make(map[string]bool)
+	for source := range db.sources {
+		sourceDirs[filepath.Dir(source)] = true
+	}
+
+	listed := make(map[string]bool)
+	var kept []string
+	for _, file := range files {
+		path := absoluteIn(rootDir, file)
+		listed[path] = true
+		ext := strings.ToLower(filepath.Ext(path))
+		switch {
+		case cSourceExtensions[ext] && !db.sources[path]:
+			continue
+		case cHeaderExtensions[ext] && !sourceDirs[filepath.Dir(path)] && !db.inIncludeDir(path):
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	var generated []string
+	for source := range db.sources {
+		if listed[source] {
+			continue
+		}
+		if info, err := os.Stat(source); err == nil && !info.IsDir() {
+			generated = append(generated, source)
+		}
+	}
+	slices.Sort(generated)
+	return append(kept, generated...)
+}
+
+// inIncludeDir reports whether `path` is below one of the include directories, for
+// headers included with a directory prefix such as `#include "net/socket.h"`.
+func (db *compilationDatabase) inIncludeDir(path string) bool {
+	for _, dir := range db.includeDirs {
+		if isWithinDir(dir, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompilationDatabase(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"src", "include/net", "tests", "build/gen"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for _, name := range []string{"src/main.c", "src/util.h", "include/net/socket.h", "tests/old.c", "tests/old.h", "build/gen/version.c", "tool.py"} {
+		writeTestFile(t, dir, name, "")
+	}
+	writeTestFile(t, dir, "build/compile_commands.json", `[
+  {"directory": "`+filepath.ToSlash(filepath.Join(dir, "build"))+`", "file": "../src/main.c",
+   "command": "cc -c -I ../include -isystem \"../sdk include\" -DNAME=\\\"x\\\" ../src/main.c"},
+  {"directory": "`+filepath.ToSlash(filepath.Join(dir, "build"))+`", "file": "gen/version.c",
+   "arguments": ["cc", "-I../include", "-c", "gen/version.c"]}
+]`)
+
+	path := findCompilationDatabase(dir, "")
+	if path != filepath.Join(dir, "build", compileCommandsName) {
+		t.Fatalf("unexpected compilation database path %q", path)
+	}
+	db, err := loadCompilationDatabase(path)
+	if err != nil {
+		t.Fatalf("load compilation database: %v", err)
+	}
+	wantDirs := []string{filepath.Join(dir, "include"), filepath.Join(dir, "sdk include")}
+	if !slices.Equal(db.includeDirs, wantDirs) {
+		t.Fatalf("expected include dirs %v, got %v", wantDirs, db.includeDirs)
+	}
+
+	files := db.filterFiles(dir, []string{"src/main.c", "src/util.h", "include/net/socket.h", "tests/old.c", "tests/old.h", "tool.py"})
+	want := []string{"src/main.c", "src/util.h", "include/net/socket.h", "tool.py", filepath.Join(dir, "build", "gen", "version.c")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected files %v, got %v", want, files)
+	}
+
+	server := newTestServer(t, nil)
+	server.rootURI = pathToFileURI(dir)
+	server.compilationDB = db
+	target, ok := server.resolveIncludeTarget(pathToFileURI(filepath.Join(dir, "src", "main.c")), "net/socket.h", true)
+	if !ok || target != pathToFileURI(filepath.Join(dir, "include", "net", "socket.h")) {
+		t.Fatalf("expected include to resolve through the database, got %q", target)
+	}
+}
@@ -0,0 +1,128 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxResolvedLocations caps the definition list added by `completionItem/resolve`.
+const maxResolvedLocations = 10
+
+// declarationKinds are ctags kinds that declare a name defined elsewhere.
+var declarationKinds = []string{"prototype", "externvar", "declaration"}
+
+// CompletionItemData is round-tripped through the client to `completionItem/resolve`.
+type CompletionItemData struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// completionDetail shows the signature of `entry` within its scope, e.g.
+// "Canvas::draw(int x)". Entries with neither show their kind instead.
+func completionDetail(label string, entry TagEntry) string {
+	detail := qualifiedEntryName(entry) + entry.Signature
+	if detail == label || detail == entry.Name {
+		return entry.Kind
+	}
+	return detail
+}
+
+// completionEntryScore ranks entries sharing a completion label: definitions beat
+// declarations, and entries in the current document's family beat the rest.
+func completionEntryScore(entry TagEntry, family documentFamily) int {
+	score := 0
+	if !slices.Contains(declarationKinds, entry.Kind) {
+		score += 2
+	}
+	if family.includes(entry) {
+		score++
+	}
+	return score
+}
+
+// handleCompletionResolve fills in the item's documentation: the source line of
+// its definition and every place the name is defined, so deduplicated items still
+// show where the name comes from.
+func handleCompletionResolve(server *Server, req RPCRequest) {
+	var item CompletionItem
+	if err := json.Unmarshal(req.Params, &item); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+	if item.Data == nil {
+		server.sendResult(req.ID, item)
+		return
+	}
+
+	name := item.Data.Name
+	server.mutex.Lock()
+	var locations []string
+	var documented *TagEntry
+	for i, entry := range server.tagEntries {
+		var match bool
+		if isQualifiedName(name) {
+			match = matchesQualifiedName(entry, name)
+		} else {
+			match = entry.Name == name && !isQualifiedTag(entry)
+		}
+		if !match {
+			continue
+		}
+		if documented == nil || (entry.Path == item.Data.Path && entry.Line == item.Data.Line) {
+			documented = &server.tagEntries[i]
+		}
+		location := fmt.Sprintf("%s:%d (%s)", fileURIToPath(entry.Path), entry.Line, entry.Kind)
+		if !slices.Contains(locations, location) {
+			locations = append(locations, location)
+		}
+	}
+	var entry TagEntry
+	if documented != nil {
+		entry = *documented
+	}
+	server.mutex.Unlock()
+
+	if documented != nil {
+		var docstring string
+		if lines, err := server.cache.GetOrLoadFileContent(entry.Path); err == nil {
+			docstring = extractDocstring(lines, entry)
+		}
+		item.Documentation = server.clientCapabilities.completionDocumentation(entry, docstring)
+	}
+
+	if len(locations) == 0 {
+		server.sendResult(req.ID, item)
+		return
+	}
+
+	slices.Sort(locations)
+	var text strings.Builder
+	if item.Documentation == nil {
+		item.Documentation = &MarkupContent{Kind: "plaintext"}
+	} else if item.Documentation.Value != "" {
+		text.WriteString(item.Documentation.Value)
+		text.WriteString("\n\n")
+	}
+	format := "  %s\n"
+	if item.Documentation.Kind == "markdown" {
+		format = "- `%s`\n"
+	}
+	if len(locations) == 1 {
+		fmt.Fprintf(&text, "Defined in %s\n", locations[0])
+	} else {
+		fmt.Fprintf(&text, "Defined in %d places:\n", len(locations))
+		for i, location := range locations {
+			if i == maxResolvedLocations {
+				fmt.Fprintf(&text, "  … and %d more\n", len(locations)-i)
+				break
+			}
+			fmt.Fprintf(&text, format, location)
+		}
+	}
+	item.Documentation.Value = strings.TrimSuffix(text.String(), "\n")
+
+	server.sendResult(req.ID, item)
+}
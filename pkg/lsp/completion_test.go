@@ -0,0 +1,44 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompletionPrefersDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "util.h", "int parse_config(const char *path);\n")
+	source := writeTestFile(t, dir, "util.c", "int parse_config(const char *path) { return 0; }\n")
+	main := writeTestFile(t, dir, "main.c", "parse_\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "parse_config", Path: header, Line: 1, Kind: "prototype", Pattern: "/^int parse_config(const char *path);$/"},
+		{Name: "parse_config", Path: source, Line: 1, Kind: "function", Signature: "(const char *path)", Pattern: "/^int parse_config(const char *path) { return 0; }$/"},
+	})
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: main, LanguageID: "c", Text: "parse_\n"},
+	})
+
+	frames := callHandler(t, server, "textDocument/completion", CompletionParams{
+		TextDocument: PositionParams{URI: main},
+		Position:     Position{Line: 0, Character: 6},
+	})
+	var list CompletionList
+	if err := json.Unmarshal(frames[0].Result, &list); err != nil {
+		t.Fatalf("unmarshal completion: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Detail != "parse_config(const char *path)" || list.Items[0].Documentation != nil {
+		t.Fatalf("expected a single item for the function definition, got %+v", list.Items)
+	}
+
+	frames = callHandler(t, server, "completionItem/resolve", list.Items[0])
+	var resolved CompletionItem
+	if err := json.Unmarshal(frames[0].Result, &resolved); err != nil {
+		t.Fatalf("unmarshal resolved item: %v", err)
+	}
+	if resolved.Documentation == nil || !strings.Contains(resolved.Documentation.Value, "{ return 0; }") ||
+		!strings.Contains(resolved.Documentation.Value, "Defined in 2 places") {
+		t.Fatalf("expected both locations in the resolved documentation, got %+v", resolved.Documentation)
+	}
+}
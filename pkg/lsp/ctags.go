@@ -0,0 +1,263 @@
+package lsp
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+func (server *Server) parseCtagsArgs(extra ...string) []string {
+	options := server.getOptions()
+	return ctags.Args(ctags.ArgOptions{
+		ReferenceTags:     options.referenceTags,
+		QualifiedTags:     options.qualifiedTags,
+		EmbeddedLanguages: options.embeddedLanguages,
+		Languages:         options.languages,
+	}, extra...)
+}
+
+// scanWorkspace populates `server.tagEntries` from either:
+// - an explicit `--tagfile`, then
+// - a discovered tags file (see `ctags.FindTagfile`), or
+// - a fresh ctags scan of the workspace, if it is trusted (see `checkWorkspaceTrust`).
+// With `ignoreTagfiles`, only the scan is tried.
+func (server *Server) scanWorkspace() error {
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		observeScan("workspace", duration)
+		server.mutex.Lock()
+		server.lastScanDuration = duration
+		server.lastScanAt = start
+		server.mutex.Unlock()
+	}()
+
+	if server.tagfilePath != "" && !server.ignoreTagfiles {
+		rootDir := fileURIToPath(server.rootURI)
+		tagsPath := server.localPath(server.tagfilePath)
+		if !filepath.IsAbs(tagsPath) {
+			tagsPath = filepath.Join(rootDir, tagsPath)
+		}
+		tagsPath = filepath.Clean(tagsPath)
+		if _, err := os.Stat(tagsPath); err != nil {
+			return fmt.Errorf("tagfile not found at %q: %v", tagsPath, err)
+		}
+		return server.loadTagfile(tagsPath)
+	}
+
+	rootDir := fileURIToPath(server.rootURI)
+	if tagsPath, found := ctags.FindTagfile(rootDir); found && !server.ignoreTagfiles {
+		return server.loadTagfile(tagsPath)
+	}
+	if !server.mayRunCommands() {
+		return nil
+	}
+
+	options := server.getOptions()
+	files, skipped, err := workspace.ListFiles(rootDir, options.maxFileSize, options.exclude)
+	if err != nil {
+		return err
+	}
+	server.mutex.Lock()
+	server.lastScanSkipped = skipped
+	server.mutex.Unlock()
+	if skipped.Binary > 0 || skipped.Oversized > 0 {
+		slog.Info("skipped files in workspace walk", "binary", skipped.Binary, "oversized", skipped.Oversized)
+	}
+	if db := server.loadCompilationDatabase(rootDir); db != nil {
+		files = db.filterFiles(rootDir, files)
+	}
+
+	choice := ""
+	if limit := options.maxWorkspaceFiles; limit > 0 && len(files) > limit {
+		choice = server.confirmLargeWorkspace(len(files))
+	}
+	server.setLargeWorkspaceChoice(choice)
+	if choice == largeWorkspaceOpenFiles || choice == largeWorkspaceSkip {
+		slog.Info("not scanning large workspace", "files", len(files), "choice", choice)
+		return nil
+	}
+	if options.goModuleDeps {
+		files = append(files, goModuleFiles(rootDir)...)
+	}
+	if options.sitePackages {
+		if venv := findVirtualenv(rootDir, options.venv, os.Getenv); venv != "" {
+			files = append(files, sitePackagesFiles(venv, options.maxFileSize)...)
+		}
+	}
+	if options.rubyGems {
+		files = append(files, rubyGemFiles(rootDir, options.maxFileSize)...)
+	}
+	if options.jvmSources {
+		files = append(files, jvmSourceFiles(rootDir, defaultSourcesCacheDir(), options.maxFileSize, os.Getenv)...)
+	}
+
+	scanArgs := []string{"-L", "-"}
+	if options.languages == "" && !options.embeddedLanguages {
+		if languages := server.detectLanguages(rootDir, files); languages != "" {
+			scanArgs = append([]string{"--languages=" + languages}, scanArgs...)
+		}
+	}
+
+	workers := options.workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	size := (len(files) + workers - 1) / workers
+	if options.typingPause > 0 {
+		// Smaller chunks give the workers a chance to pause between them.
+		size = min(size, typingPauseChunkSize)
+	}
+	if server.sandbox {
+		// The sandbox limits apply per process, and a failing process loses its chunk.
+		size = min(size, ctags.SandboxChunkSize)
+	}
+	chunks := make(chan []string)
+	go func() {
+		defer close(chunks)
+		for start := 0; start < len(files); start += size {
+			chunks <- files[start:min(start+size, len(files))]
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				server.waitWhileTyping(options.typingPause)
+				server.scanChunk(rootDir, scanArgs, chunk)
+			}
+		}()
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// rescanWorkspace rebuilds the index from scratch, e.g. after settings changed.
+func (server *Server) rescanWorkspace() error {
+	server.sendIndexingStatus(indexingStateScanning, nil)
+
+	server.mutex.Lock()
+	server.tagEntries = nil
+	server.referenceEntries = nil
+	server.failedFiles = nil
+	server.mutex.Unlock()
+	server.invalidateUsage()
+	if err := server.scanWorkspace(); err != nil {
+		server.sendIndexingStatus(indexingStateError, err)
+		return err
+	}
+	server.reindexNotebooks()
+
+	server.sendIndexingStatus(indexingStateReady, nil)
+	return nil
+}
+
+// scanSingleFileTag rescans a single file URI and drops any previous entries for that URI.
+func (server *Server) scanSingleFileTag(fileURI string) error {
+	if !server.mayRunCommands() {
+		return nil
+	}
+	start := time.Now()
+	defer func() { observeScan("file", time.Since(start)) }()
+
+	keep := func(entry TagEntry) bool { return !sameURI(entry.Path, fileURI) }
+	server.mutex.Lock()
+	server.tagEntries = filterEntries(server.tagEntries, keep)
+	server.referenceEntries = filterEntries(server.referenceEntries, keep)
+	server.mutex.Unlock()
+	server.invalidateUsageOf(fileURI)
+
+	filePath := fileURIToPath(fileURI)
+	rootDir := fileURIToPath(server.rootURI)
+	if rel, err := filepath.Rel(rootDir, filePath); server.wslPaths && err == nil && filepath.IsLocal(rel) {
+		// ctags may run on the other side of WSL, where absolute paths differ.
+		filePath = rel
+	}
+	tmp := []string{filePath}
+	cmd := exec.Command(server.ctagsBin, server.parseCtagsArgs(append(tmp, server.getOptions().ctagArgs...)...)...)
+	cmd.Dir = rootDir
+	if err := server.processTagsOutput(cmd); err != nil {
+		return err
+	}
+	server.clearFailedFile(fileURI)
+	return nil
+}
+
+func (server *Server) processTagsOutput(cmd *exec.Cmd) error {
+	entries, err := server.runCtags(cmd)
+	if err != nil {
+		return err
+	}
+
+	definitions, references := ctags.SplitReferences(server.filterTags(entries))
+
+	server.mutex.Lock()
+	server.tagEntries = append(server.tagEntries, definitions...)
+	server.referenceEntries = append(server.referenceEntries, references...)
+	server.mutex.Unlock()
+	server.invalidateUsageOf(entryPaths(definitions)...)
+
+	return nil
+}
+
+// entryPaths returns the distinct paths of `entries`.
+func entryPaths(entries []TagEntry) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, entry := range entries {
+		if !seen[entry.Path] {
+			seen[entry.Path] = true
+			paths = append(paths, entry.Path)
+		}
+	}
+	return paths
+}
+
+// runCtags runs `cmd` and returns its JSON tag entries with paths normalized to file URIs.
+// With `--sandbox`, ctags runs under the limits of the sandbox (see `ctags.RunOptions`).
+func (server *Server) runCtags(cmd *exec.Cmd) ([]TagEntry, error) {
+	entries, err := ctags.Run(cmd, ctags.RunOptions{
+		Sandbox:     server.sandbox,
+		LowPriority: server.getOptions().lowPriority,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rootDir := fileURIToPath(server.rootURI)
+	normalized := entries[:0]
+	for _, entry := range entries {
+		path, err := normalizePath(rootDir, server.localPath(entry.Path))
+		if err != nil {
+			log.Printf("Failed to normalize path for %s: %v", entry.Path, err)
+			continue
+		}
+		entry.Path = pathToFileURI(path)
+		normalized = append(normalized, entry)
+	}
+	return normalized, nil
+}
+
+// filterEntries returns a new slice holding the entries for which `keep` is true.
+func filterEntries(entries []TagEntry, keep func(TagEntry) bool) []TagEntry {
+	kept := make([]TagEntry, 0, len(entries))
+	for _, entry := range entries {
+		if keep(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
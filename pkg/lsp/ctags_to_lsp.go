@@ -0,0 +1,535 @@
+package lsp
+
+// Numeric values match LSP 3.17 `CompletionItemKind`.
+const (
+	CompletionItemKindText          = 1
+	CompletionItemKindMethod        = 2
+	CompletionItemKindFunction      = 3
+	CompletionItemKindConstructor   = 4
+	CompletionItemKindField         = 5
+	CompletionItemKindVariable      = 6
+	CompletionItemKindClass         = 7
+	CompletionItemKindInterface     = 8
+	CompletionItemKindModule        = 9
+	CompletionItemKindProperty      = 10
+	CompletionItemKindUnit          = 11
+	CompletionItemKindValue         = 12
+	CompletionItemKindEnum          = 13
+	CompletionItemKindKeyword       = 14
+	CompletionItemKindSnippet       = 15
+	CompletionItemKindColor         = 16
+	CompletionItemKindFile          = 17
+	CompletionItemKindReference     = 18
+	CompletionItemKindFolder        = 19
+	CompletionItemKindEnumMember    = 20
+	CompletionItemKindConstant      = 21
+	CompletionItemKindStruct        = 22
+	CompletionItemKindEvent         = 23
+	CompletionItemKindOperator      = 24
+	CompletionItemKindTypeParameter = 25
+)
+
+// Numeric values match LSP 3.17 `SymbolKind`.
+const (
+	SymbolKindFile          = 1
+	SymbolKindModule        = 2
+	SymbolKindNamespace     = 3
+	SymbolKindPackage       = 4
+	SymbolKindClass         = 5
+	SymbolKindMethod        = 6
+	SymbolKindProperty      = 7
+	SymbolKindField         = 8
+	SymbolKindConstructor   = 9
+	SymbolKindEnum          = 10
+	SymbolKindInterface     = 11
+	SymbolKindFunction      = 12
+	SymbolKindVariable      = 13
+	SymbolKindConstant      = 14
+	SymbolKindString        = 15
+	SymbolKindNumber        = 16
+	SymbolKindBoolean       = 17
+	SymbolKindArray         = 18
+	SymbolKindObject        = 19
+	SymbolKindKey           = 20
+	SymbolKindNull          = 21
+	SymbolKindEnumMember    = 22
+	SymbolKindStruct        = 23
+	SymbolKindEvent         = 24
+	SymbolKindOperator      = 25
+	SymbolKindTypeParameter = 26
+)
+
+// completionKindByTagKind maps ctags `kind` strings to LSP `CompletionItemKind`.
+var completionKindByTagKind = map[string]int{
+	"accessor":              CompletionItemKindProperty,
+	"alias":                 CompletionItemKindVariable,
+	"anchor":                CompletionItemKindReference,
+	"annotation":            CompletionItemKindInterface,
+	"anonMember":            CompletionItemKindField,
+	"arg":                   CompletionItemKindVariable,
+	"array":                 CompletionItemKindVariable,
+	"attribute":             CompletionItemKindProperty,
+	"augroup":               CompletionItemKindModule,
+	"bibitem":               CompletionItemKindReference,
+	"block":                 CompletionItemKindModule,
+	"boolean":               CompletionItemKindConstant,
+	"callback":              CompletionItemKindFunction,
+	"category":              CompletionItemKindEnum,
+	"ccflag":                CompletionItemKindConstant,
+	"cell":                  CompletionItemKindVariable,
+	"chapter":               CompletionItemKindKeyword,
+	"citation":              CompletionItemKindReference,
+	"class":                 CompletionItemKindClass,
+	"collection":            CompletionItemKindClass,
+	"command":               CompletionItemKindFunction,
+	"common":                CompletionItemKindModule,
+	"component":             CompletionItemKindStruct,
+	"config":                CompletionItemKindConstant,
+	"const":                 CompletionItemKindConstant,
+	"constant":              CompletionItemKindConstant,
+	"constructor":           CompletionItemKindConstructor,
+	"context":               CompletionItemKindVariable,
+	"counter":               CompletionItemKindVariable,
+	"covergroup":            CompletionItemKindClass,
+	"cursor":                CompletionItemKindVariable,
+	"data":                  CompletionItemKindVariable,
+	"dataset":               CompletionItemKindVariable,
+	"def":                   CompletionItemKindFunction,
+	"define":                CompletionItemKindConstant,
+	"delegate":              CompletionItemKindClass,
+	"division":              CompletionItemKindModule,
+	"domain":                CompletionItemKindTypeParameter,
+	"entity":                CompletionItemKindClass,
+	"entry":                 CompletionItemKindFunction,
+	"enum":                  CompletionItemKindEnum,
+	"enumConstant":          CompletionItemKindEnumMember,
+	"enumerator":            CompletionItemKindEnumMember,
+	"environment":           CompletionItemKindVariable,
+	"error":                 CompletionItemKindEnum,
+	"event":                 CompletionItemKindEvent,
+	"exception":             CompletionItemKindClass,
+	"externvar":             CompletionItemKindVariable,
+	"face":                  CompletionItemKindInterface,
+	"feature":               CompletionItemKindProperty,
+	"field":                 CompletionItemKindField,
+	"fn":                    CompletionItemKindFunction,
+	"footnote":              CompletionItemKindReference,
+	"format":                CompletionItemKindFunction,
+	"fun":                   CompletionItemKindFunction,
+	"func":                  CompletionItemKindFunction,
+	"function":              CompletionItemKindFunction,
+	"functionVar":           CompletionItemKindVariable,
+	"functor":               CompletionItemKindClass,
+	"generator":             CompletionItemKindFunction,
+	"generic":               CompletionItemKindTypeParameter,
+	"getter":                CompletionItemKindMethod,
+	"global":                CompletionItemKindVariable,
+	"globalVar":             CompletionItemKindVariable,
+	"group":                 CompletionItemKindEnum,
+	"guard":                 CompletionItemKindVariable,
+	"handler":               CompletionItemKindFunction,
+	"hashtag":               CompletionItemKindReference,
+	"header":                CompletionItemKindFile,
+	"heredoc":               CompletionItemKindText,
+	"icon":                  CompletionItemKindEnum,
+	"id":                    CompletionItemKindVariable,
+	"implementation":        CompletionItemKindClass,
+	"include":               CompletionItemKindFile,
+	"index":                 CompletionItemKindVariable,
+	"infoitem":              CompletionItemKindVariable,
+	"inline":                CompletionItemKindKeyword,
+	"inputSection":          CompletionItemKindKeyword,
+	"instance":              CompletionItemKindVariable,
+	"interface":             CompletionItemKindInterface,
+	"it":                    CompletionItemKindVariable,
+	"jurisdiction":          CompletionItemKindVariable,
+	"key":                   CompletionItemKindKeyword,
+	"keyInMiddle":           CompletionItemKindKeyword,
+	"keyword":               CompletionItemKindKeyword,
+	"kind":                  CompletionItemKindKeyword,
+	"l4subsection":          CompletionItemKindKeyword,
+	"l5subsection":          CompletionItemKindKeyword,
+	"label":                 CompletionItemKindKeyword,
+	"langdef":               CompletionItemKindKeyword,
+	"legal":                 CompletionItemKindKeyword,
+	"legislation":           CompletionItemKindKeyword,
+	"letter":                CompletionItemKindKeyword,
+	"library":               CompletionItemKindModule,
+	"list":                  CompletionItemKindVariable,
+	"local":                 CompletionItemKindVariable,
+	"localVariable":         CompletionItemKindVariable,
+	"locale":                CompletionItemKindVariable,
+	"localvar":              CompletionItemKindVariable,
+	"macro":                 CompletionItemKindVariable,
+	"macroParameter":        CompletionItemKindVariable,
+	"macrofile":             CompletionItemKindFile,
+	"macroparam":            CompletionItemKindVariable,
+	"makefile":              CompletionItemKindFile,
+	"map":                   CompletionItemKindVariable,
+	"member":                CompletionItemKindField,
+	"message":               CompletionItemKindStruct,
+	"method":                CompletionItemKindMethod,
+	"methodSpec":            CompletionItemKindMethod,
+	"minorMode":             CompletionItemKindKeyword,
+	"misc":                  CompletionItemKindVariable,
+	"modport":               CompletionItemKindInterface,
+	"module":                CompletionItemKindModule,
+	"name":                  CompletionItemKindVariable,
+	"namelist":              CompletionItemKindVariable,
+	"namespace":             CompletionItemKindModule,
+	"net":                   CompletionItemKindVariable,
+	"nettype":               CompletionItemKindTypeParameter,
+	"newFile":               CompletionItemKindFile,
+	"node":                  CompletionItemKindVariable,
+	"null":                  CompletionItemKindValue,
+	"number":                CompletionItemKindValue,
+	"object":                CompletionItemKindClass,
+	"oneof":                 CompletionItemKindEnum,
+	"operator":              CompletionItemKindOperator,
+	"option":                CompletionItemKindKeyword,
+	"output":                CompletionItemKindVariable,
+	"package":               CompletionItemKindModule,
+	"packageName":           CompletionItemKindModule,
+	"paragraph":             CompletionItemKindFunction,
+	"param":                 CompletionItemKindVariable,
+	"parameter":             CompletionItemKindVariable,
+	"paramEntity":           CompletionItemKindVariable,
+	"part":                  CompletionItemKindVariable,
+	"pattern":               CompletionItemKindKeyword,
+	"placeholder":           CompletionItemKindVariable,
+	"port":                  CompletionItemKindVariable,
+	"procedure":             CompletionItemKindFunction,
+	"process":               CompletionItemKindFunction,
+	"program":               CompletionItemKindModule,
+	"property":              CompletionItemKindProperty,
+	"prototype":             CompletionItemKindFunction,
+	"protocol":              CompletionItemKindClass,
+	"provider":              CompletionItemKindClass,
+	"publication":           CompletionItemKindVariable,
+	"qkey":                  CompletionItemKindVariable,
+	"receiver":              CompletionItemKindVariable,
+	"record":                CompletionItemKindStruct,
+	"reference":             CompletionItemKindReference,
+	"region":                CompletionItemKindVariable,
+	"register":              CompletionItemKindVariable,
+	"repoid":                CompletionItemKindVariable,
+	"report":                CompletionItemKindVariable,
+	"repositoryId":          CompletionItemKindVariable,
+	"repr":                  CompletionItemKindVariable,
+	"resource":              CompletionItemKindVariable,
+	"response":              CompletionItemKindFunction,
+	"role":                  CompletionItemKindClass,
+	"rpc":                   CompletionItemKindVariable,
+	"schema":                CompletionItemKindVariable,
+	"script":                CompletionItemKindFile,
+	"section":               CompletionItemKindKeyword,
+	"selector":              CompletionItemKindKeyword,
+	"sequence":              CompletionItemKindVariable,
+	"server":                CompletionItemKindClass,
+	"service":               CompletionItemKindClass,
+	"set":                   CompletionItemKindVariable,
+	"setter":                CompletionItemKindMethod,
+	"signal":                CompletionItemKindFunction,
+	"singletonMethod":       CompletionItemKindMethod,
+	"slot":                  CompletionItemKindVariable,
+	"software":              CompletionItemKindClass,
+	"source":                CompletionItemKindFile,
+	"sourcefile":            CompletionItemKindFile,
+	"standard":              CompletionItemKindVariable,
+	"string":                CompletionItemKindText,
+	"struct":                CompletionItemKindStruct,
+	"structure":             CompletionItemKindStruct,
+	"stylesheet":            CompletionItemKindVariable,
+	"subdir":                CompletionItemKindFolder,
+	"submethod":             CompletionItemKindMethod,
+	"submodule":             CompletionItemKindModule,
+	"subprogram":            CompletionItemKindFunction,
+	"subprogspec":           CompletionItemKindVariable,
+	"subroutine":            CompletionItemKindFunction,
+	"subroutineDeclaration": CompletionItemKindFunction,
+	"subsection":            CompletionItemKindVariable,
+	"subst":                 CompletionItemKindVariable,
+	"substdef":              CompletionItemKindVariable,
+	"subsubsection":         CompletionItemKindKeyword,
+	"subtype":               CompletionItemKindTypeParameter,
+	"synonym":               CompletionItemKindVariable,
+	"table":                 CompletionItemKindStruct,
+	"tag":                   CompletionItemKindVariable,
+	"target":                CompletionItemKindFunction,
+	"task":                  CompletionItemKindFunction,
+	"template":              CompletionItemKindVariable,
+	"test":                  CompletionItemKindVariable,
+	"theme":                 CompletionItemKindVariable,
+	"theorem":               CompletionItemKindVariable,
+	"thriftFile":            CompletionItemKindFile,
+	"throwsparam":           CompletionItemKindVariable,
+	"title":                 CompletionItemKindVariable,
+	"token":                 CompletionItemKindVariable,
+	"toplevelVariable":      CompletionItemKindVariable,
+	"tparam":                CompletionItemKindTypeParameter,
+	"trait":                 CompletionItemKindVariable,
+	"trigger":               CompletionItemKindFunction,
+	"type":                  CompletionItemKindStruct,
+	"typealias":             CompletionItemKindVariable,
+	"typedef":               CompletionItemKindTypeParameter,
+	"typespec":              CompletionItemKindTypeParameter,
+	"union":                 CompletionItemKindStruct,
+	"unit":                  CompletionItemKindUnit,
+	"unknown":               CompletionItemKindVariable,
+	"username":              CompletionItemKindVariable,
+	"using":                 CompletionItemKindModule,
+	"val":                   CompletionItemKindVariable,
+	"value":                 CompletionItemKindVariable,
+	"var":                   CompletionItemKindVariable,
+	"variable":              CompletionItemKindVariable,
+	"vector":                CompletionItemKindVariable,
+	"version":               CompletionItemKindVariable,
+	"video":                 CompletionItemKindFile,
+	"view":                  CompletionItemKindVariable,
+	"wrapper":               CompletionItemKindVariable,
+	"xdata":                 CompletionItemKindVariable,
+	"xinput":                CompletionItemKindVariable,
+	"xtask":                 CompletionItemKindVariable,
+}
+
+// symbolKindByTagKind maps ctags `kind` strings to LSP `SymbolKind`.
+var symbolKindByTagKind = map[string]int{
+	"accessor":              SymbolKindProperty,
+	"alias":                 SymbolKindVariable,
+	"anchor":                SymbolKindKey,
+	"annotation":            SymbolKindInterface,
+	"anonMember":            SymbolKindField,
+	"arg":                   SymbolKindVariable,
+	"array":                 SymbolKindArray,
+	"attribute":             SymbolKindProperty,
+	"augroup":               SymbolKindNamespace,
+	"bibitem":               SymbolKindKey,
+	"block":                 SymbolKindNamespace,
+	"boolean":               SymbolKindConstant,
+	"callback":              SymbolKindFunction,
+	"category":              SymbolKindEnum,
+	"ccflag":                SymbolKindConstant,
+	"cell":                  SymbolKindVariable,
+	"chapter":               SymbolKindString,
+	"citation":              SymbolKindKey,
+	"class":                 SymbolKindClass,
+	"collection":            SymbolKindClass,
+	"command":               SymbolKindFunction,
+	"common":                SymbolKindNamespace,
+	"component":             SymbolKindStruct,
+	"config":                SymbolKindConstant,
+	"const":                 SymbolKindConstant,
+	"constant":              SymbolKindConstant,
+	"constructor":           SymbolKindConstructor,
+	"context":               SymbolKindVariable,
+	"counter":               SymbolKindVariable,
+	"covergroup":            SymbolKindClass,
+	"cursor":                SymbolKindVariable,
+	"data":                  SymbolKindVariable,
+	"dataset":               SymbolKindVariable,
+	"def":                   SymbolKindFunction,
+	"define":                SymbolKindConstant,
+	"delegate":              SymbolKindClass,
+	"division":              SymbolKindNamespace,
+	"domain":                SymbolKindTypeParameter,
+	"entity":                SymbolKindClass,
+	"entry":                 SymbolKindFunction,
+	"enum":                  SymbolKindEnum,
+	"enumConstant":          SymbolKindEnumMember,
+	"enumerator":            SymbolKindEnumMember,
+	"environment":           SymbolKindVariable,
+	"error":                 SymbolKindEnum,
+	"event":                 SymbolKindEvent,
+	"exception":             SymbolKindClass,
+	"externvar":             SymbolKindVariable,
+	"face":                  SymbolKindInterface,
+	"feature":               SymbolKindProperty,
+	"field":                 SymbolKindField,
+	"fn":                    SymbolKindFunction,
+	"footnote":              SymbolKindKey,
+	"format":                SymbolKindFunction,
+	"fun":                   SymbolKindFunction,
+	"func":                  SymbolKindFunction,
+	"function":              SymbolKindFunction,
+	"functionVar":           SymbolKindVariable,
+	"functor":               SymbolKindClass,
+	"generator":             SymbolKindFunction,
+	"generic":               SymbolKindTypeParameter,
+	"getter":                SymbolKindMethod,
+	"global":                SymbolKindVariable,
+	"globalVar":             SymbolKindVariable,
+	"group":                 SymbolKindEnum,
+	"guard":                 SymbolKindVariable,
+	"handler":               SymbolKindFunction,
+	"hashtag":               SymbolKindKey,
+	"header":                SymbolKindFile,
+	"heredoc":               SymbolKindString,
+	"icon":                  SymbolKindEnum,
+	"id":                    SymbolKindVariable,
+	"implementation":        SymbolKindClass,
+	"include":               SymbolKindFile,
+	"index":                 SymbolKindVariable,
+	"infoitem":              SymbolKindVariable,
+	"instance":              SymbolKindVariable,
+	"interface":             SymbolKindInterface,
+	"it":                    SymbolKindVariable,
+	"jurisdiction":          SymbolKindVariable,
+	"key":                   SymbolKindKey,
+	"keyword":               SymbolKindKey,
+	"l4subsection":          SymbolKindString,
+	"l5subsection":          SymbolKindString,
+	"label":                 SymbolKindKey,
+	"library":               SymbolKindModule,
+	"list":                  SymbolKindVariable,
+	"local":                 SymbolKindVariable,
+	"localVariable":         SymbolKindVariable,
+	"locale":                SymbolKindVariable,
+	"localvar":              SymbolKindVariable,
+	"macro":                 SymbolKindVariable,
+	"macroParameter":        SymbolKindVariable,
+	"macrofile":             SymbolKindFile,
+	"macroparam":            SymbolKindVariable,
+	"makefile":              SymbolKindFile,
+	"map":                   SymbolKindVariable,
+	"member":                SymbolKindField,
+	"message":               SymbolKindStruct,
+	"method":                SymbolKindMethod,
+	"methodSpec":            SymbolKindMethod,
+	"misc":                  SymbolKindVariable,
+	"modport":               SymbolKindInterface,
+	"module":                SymbolKindModule,
+	"name":                  SymbolKindVariable,
+	"namelist":              SymbolKindVariable,
+	"namespace":             SymbolKindModule,
+	"net":                   SymbolKindVariable,
+	"nettype":               SymbolKindTypeParameter,
+	"newFile":               SymbolKindFile,
+	"node":                  SymbolKindVariable,
+	"null":                  SymbolKindNull,
+	"number":                SymbolKindNumber,
+	"object":                SymbolKindClass,
+	"oneof":                 SymbolKindEnum,
+	"operator":              SymbolKindOperator,
+	"option":                SymbolKindProperty,
+	"output":                SymbolKindVariable,
+	"package":               SymbolKindModule,
+	"packageName":           SymbolKindModule,
+	"paragraph":             SymbolKindFunction,
+	"param":                 SymbolKindVariable,
+	"parameter":             SymbolKindVariable,
+	"paramEntity":           SymbolKindVariable,
+	"part":                  SymbolKindVariable,
+	"pattern":               SymbolKindString,
+	"placeholder":           SymbolKindVariable,
+	"port":                  SymbolKindVariable,
+	"procedure":             SymbolKindFunction,
+	"process":               SymbolKindFunction,
+	"program":               SymbolKindModule,
+	"property":              SymbolKindProperty,
+	"prototype":             SymbolKindFunction,
+	"protocol":              SymbolKindClass,
+	"provider":              SymbolKindClass,
+	"publication":           SymbolKindVariable,
+	"qkey":                  SymbolKindVariable,
+	"receiver":              SymbolKindVariable,
+	"record":                SymbolKindStruct,
+	"region":                SymbolKindVariable,
+	"register":              SymbolKindVariable,
+	"repoid":                SymbolKindVariable,
+	"report":                SymbolKindVariable,
+	"repositoryId":          SymbolKindVariable,
+	"repr":                  SymbolKindVariable,
+	"resource":              SymbolKindVariable,
+	"response":              SymbolKindFunction,
+	"role":                  SymbolKindClass,
+	"rpc":                   SymbolKindVariable,
+	"schema":                SymbolKindVariable,
+	"script":                SymbolKindFile,
+	"section":               SymbolKindString,
+	"selector":              SymbolKindKey,
+	"sequence":              SymbolKindVariable,
+	"server":                SymbolKindClass,
+	"service":               SymbolKindClass,
+	"set":                   SymbolKindVariable,
+	"setter":                SymbolKindMethod,
+	"signal":                SymbolKindFunction,
+	"singletonMethod":       SymbolKindMethod,
+	"slot":                  SymbolKindVariable,
+	"software":              SymbolKindClass,
+	"source":                SymbolKindFile,
+	"sourcefile":            SymbolKindFile,
+	"standard":              SymbolKindVariable,
+	"string":                SymbolKindString,
+	"struct":                SymbolKindStruct,
+	"structure":             SymbolKindStruct,
+	"stylesheet":            SymbolKindVariable,
+	"subdir":                SymbolKindPackage,
+	"submethod":             SymbolKindMethod,
+	"submodule":             SymbolKindModule,
+	"subprogram":            SymbolKindFunction,
+	"subprogspec":           SymbolKindVariable,
+	"subroutine":            SymbolKindFunction,
+	"subroutineDeclaration": SymbolKindFunction,
+	"subsection":            SymbolKindString,
+	"subst":                 SymbolKindVariable,
+	"substdef":              SymbolKindVariable,
+	"subsubsection":         SymbolKindString,
+	"subtype":               SymbolKindTypeParameter,
+	"synonym":               SymbolKindVariable,
+	"table":                 SymbolKindStruct,
+	"tag":                   SymbolKindVariable,
+	"target":                SymbolKindFunction,
+	"task":                  SymbolKindFunction,
+	"template":              SymbolKindVariable,
+	"test":                  SymbolKindVariable,
+	"theme":                 SymbolKindVariable,
+	"theorem":               SymbolKindVariable,
+	"thriftFile":            SymbolKindFile,
+	"throwsparam":           SymbolKindVariable,
+	"title":                 SymbolKindVariable,
+	"token":                 SymbolKindVariable,
+	"toplevelVariable":      SymbolKindVariable,
+	"tparam":                SymbolKindTypeParameter,
+	"trait":                 SymbolKindVariable,
+	"trigger":               SymbolKindFunction,
+	"type":                  SymbolKindStruct,
+	"typealias":             SymbolKindVariable,
+	"typedef":               SymbolKindTypeParameter,
+	"typespec":              SymbolKindTypeParameter,
+	"union":                 SymbolKindStruct,
+	"unit":                  SymbolKindModule,
+	"unknown":               SymbolKindVariable,
+	"username":              SymbolKindVariable,
+	"using":                 SymbolKindNamespace,
+	"val":                   SymbolKindVariable,
+	"value":                 SymbolKindVariable,
+	"var":                   SymbolKindVariable,
+	"variable":              SymbolKindVariable,
+	"vector":                SymbolKindVariable,
+	"version":               SymbolKindVariable,
+	"video":                 SymbolKindFile,
+	"view":                  SymbolKindVariable,
+	"wrapper":               SymbolKindVariable,
+	"xdata":                 SymbolKindVariable,
+	"xinput":                SymbolKindVariable,
+	"xtask":                 SymbolKindVariable,
+}
+
+// GetLSPCompletionKind returns the LSP `CompletionItemKind` for a ctags kind.
+// Unknown kinds fall back to `CompletionItemKindText`.
+func GetLSPCompletionKind(ctagsKind string) int {
+	if kind, ok := completionKindByTagKind[ctagsKind]; ok {
+		return kind
+	}
+	return CompletionItemKindText
+}
+
+// GetLSPSymbolKind returns the LSP `SymbolKind` for a ctags kind.
+// Unknown kinds fall back to `SymbolKindVariable`, so that tags of parsers newer
+// than this table still show up in symbol lists.
+func GetLSPSymbolKind(ctagsKind string) int {
+	if kind, ok := symbolKindByTagKind[ctagsKind]; ok {
+		return kind
+	}
+	return SymbolKindVariable
+}
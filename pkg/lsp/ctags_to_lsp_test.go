@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"encoding/json"
+	"maps"
+	"testing"
+)
+
+func TestSymbolKindCoverage(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shapes.c", "struct shape {\n  int sides;\n};\nenum color { RED };\nall: build\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "shape", Path: uri, Line: 1, Kind: "struct"},
+		{Name: "sides", Path: uri, Line: 2, Kind: "member"},
+		{Name: "RED", Path: uri, Line: 4, Kind: "enumerator"},
+		{Name: "all", Path: uri, Line: 5, Kind: "someFutureKind"},
+	})
+	var kinds []int
+	for kind := SymbolKindFile; kind <= SymbolKindTypeParameter; kind++ {
+		kinds = append(kinds, kind)
+	}
+	valueSet, _ := json.Marshal(kinds)
+	capabilities := `{"textDocument": {"documentSymbol": {"symbolKind": {"valueSet": ` + string(valueSet) + `}}}}`
+	if err := json.Unmarshal([]byte(capabilities), &server.clientCapabilities); err != nil {
+		t.Fatalf("unmarshal capabilities: %v", err)
+	}
+
+	frames := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	var symbols []SymbolInformation
+	if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+		t.Fatalf("unmarshal symbols: %v", err)
+	}
+	got := make(map[string]int)
+	for _, symbol := range symbols {
+		got[symbol.Name] = symbol.Kind
+	}
+	want := map[string]int{
+		"shape": SymbolKindStruct,
+		"sides": SymbolKindField,
+		"RED":   SymbolKindEnumMember,
+		"all":   SymbolKindVariable,
+	}
+	if !maps.Equal(got, want) {
+		t.Fatalf("expected kinds %v, got %v", want, got)
+	}
+}
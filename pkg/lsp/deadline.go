@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = 3 * time.Second
+
+	// deadlineCheckInterval bounds how often tight loops poll their context.
+	deadlineCheckInterval = 256
+)
+
+// requestContext returns a context bounded by the configured soft deadline.
+// A non-positive request timeout disables the deadline.
+func (server *Server) requestContext() (context.Context, context.CancelFunc) {
+	return server.boundedContext(context.Background())
+}
+
+// boundedContext derives a context from `parent` bounded by the configured soft deadline.
+func (server *Server) boundedContext(parent context.Context) (context.Context, context.CancelFunc) {
+	timeout := server.getOptions().requestTimeout
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// deadlineExceeded reports whether `ctx` is done, polling it only every
+// `deadlineCheckInterval` iterations so hot loops stay cheap.
+func deadlineExceeded(ctx context.Context, iteration int) bool {
+	return iteration%deadlineCheckInterval == 0 && ctx.Err() != nil
+}
+
+// sendRequestFailed reports that a request ran past its deadline without producing results.
+func (server *Server) sendRequestFailed(req RPCRequest) {
+	server.sendError(req.ID, -32803, "Request deadline exceeded", req.Method)
+}
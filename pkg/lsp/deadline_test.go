@@ -0,0 +1,29 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRequestDeadlineExceeded(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n\nfunc Handler() {}\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "Handler", Path: uri, Line: 3, Kind: "func"},
+	})
+	server.options.requestTimeout = time.Nanosecond
+
+	frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: "Handler"})
+	if len(frames) != 1 || frames[0].Error == nil {
+		t.Fatalf("expected a RequestFailed error, got %+v", frames)
+	}
+	var rpcErr RPCError
+	if err := json.Unmarshal(*frames[0].Error, &rpcErr); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if rpcErr.Code != -32803 {
+		t.Fatalf("expected code -32803, got %d", rpcErr.Code)
+	}
+}
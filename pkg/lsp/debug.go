@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// startDebugServer mounts the net/http/pprof handlers on `addr`, along with
+// `/debug/index`, which reports the `IndexStats` of `server`.
+// Profiles expose process internals, so only loopback addresses are accepted;
+// an empty host binds to localhost.
+func startDebugServer(addr string, server *Server) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid debug address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("debug address must be a loopback address, got %q", host)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("debug listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/index", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(server.indexStats())
+	})
+
+	go http.Serve(listener, mux)
+	return nil
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
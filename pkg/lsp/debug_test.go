@@ -0,0 +1,32 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDebugServer(t *testing.T) {
+	server := newTestServer(t, []TagEntry{{Name: "Render", Path: "file:///src/a.go", Line: 1, Kind: "func", Language: "Go"}})
+
+	for _, addr := range []string{"0.0.0.0:0", ":0x", "example.com:6060", "[::]:0"} {
+		if err := startDebugServer(addr, server); err == nil {
+			t.Fatalf("expected %q to be rejected", addr)
+		}
+	}
+
+	addr := freeLoopbackAddr(t)
+	if err := startDebugServer(addr, server); err != nil {
+		t.Fatalf("start debug server: %v", err)
+	}
+	if body := httpGet(t, "http://"+addr+"/debug/pprof/"); !strings.Contains(body, "goroutine") {
+		t.Fatalf("expected the pprof index, got:\n%s", body)
+	}
+	var stats IndexStats
+	if err := json.Unmarshal([]byte(httpGet(t, "http://"+addr+"/debug/index")), &stats); err != nil {
+		t.Fatalf("decode /debug/index: %v", err)
+	}
+	if stats.Entries != 1 || stats.Files != 1 || stats.ByLanguage["Go"] != 1 {
+		t.Fatalf("unexpected index state %+v", stats)
+	}
+}
@@ -0,0 +1,216 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Numeric values match LSP 3.17 `DiagnosticSeverity`.
+const (
+	DiagnosticSeverityError       = 1
+	DiagnosticSeverityWarning     = 2
+	DiagnosticSeverityInformation = 3
+	DiagnosticSeverityHint        = 4
+)
+
+// Numeric values match LSP 3.17 `DiagnosticTag`.
+const DiagnosticTagUnnecessary = 1
+
+const diagnosticSource = "ctags-lsp"
+
+type DiagnosticOptions struct {
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+	Tags     []int  `json:"tags,omitempty"`
+}
+
+type DocumentDiagnosticParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FullDocumentDiagnosticReport struct {
+	Kind  string       `json:"kind"`
+	Items []Diagnostic `json:"items"`
+}
+
+type WorkspaceDiagnosticReport struct {
+	Items []WorkspaceFullDocumentDiagnosticReport `json:"items"`
+}
+
+type WorkspaceFullDocumentDiagnosticReport struct {
+	URI     string       `json:"uri"`
+	Version *int         `json:"version"`
+	Kind    string       `json:"kind"`
+	Items   []Diagnostic `json:"items"`
+}
+
+func handleDocumentDiagnostic(server *Server, req RPCRequest) {
+	var params DocumentDiagnosticParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	ctx, cancel := server.requestContext()
+	defer cancel()
+	usage := server.unusedSymbolUsage(ctx)
+
+	server.mutex.Lock()
+	entries := entriesForURI(server.tagEntries, normalizedURI)
+	server.mutex.Unlock()
+
+	items := server.diagnoseEntries(normalizedURI, entries, usage)
+	if server.getOptions().unknownSymbols {
+		if lines, err := server.cache.GetOrLoadFileContent(normalizedURI); err == nil {
+			items = append(items, server.unknownSymbolDiagnostics(normalizedURI, lines, entries)...)
+		}
+	}
+	server.sendResult(req.ID, FullDocumentDiagnosticReport{
+		Kind:  "full",
+		Items: items,
+	})
+}
+
+// handleWorkspaceDiagnostic reports every indexed file that has at least one issue.
+// Files are visited in path order and the scan stops at the request deadline.
+func handleWorkspaceDiagnostic(server *Server, req RPCRequest) {
+	ctx, cancel := server.requestContext()
+	defer cancel()
+	usage := server.unusedSymbolUsage(ctx)
+
+	server.mutex.Lock()
+	byURI := make(map[string][]TagEntry)
+	for _, entry := range server.tagEntries {
+		byURI[entry.Path] = append(byURI[entry.Path], entry)
+	}
+	server.mutex.Unlock()
+
+	uris := make([]string, 0, len(byURI))
+	for uri := range byURI {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+
+	report := WorkspaceDiagnosticReport{Items: []WorkspaceFullDocumentDiagnosticReport{}}
+	for _, uri := range uris {
+		if ctx.Err() != nil {
+			break
+		}
+		items := server.diagnoseEntries(uri, byURI[uri], usage)
+		if len(items) == 0 {
+			continue
+		}
+		report.Items = append(report.Items, WorkspaceFullDocumentDiagnosticReport{
+			URI:   uri,
+			Kind:  "full",
+			Items: items,
+		})
+	}
+
+	server.sendResult(req.ID, report)
+}
+
+func entriesForURI(entries []TagEntry, uri string) []TagEntry {
+	var matched []TagEntry
+	for _, entry := range entries {
+		if sameURI(entry.Path, uri) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// unusedSymbolUsage returns the usage index if unused symbols are reported, or nil.
+func (server *Server) unusedSymbolUsage(ctx context.Context) *usageIndex {
+	if !server.getOptions().unusedSymbols {
+		return nil
+	}
+	return server.usageIndex(ctx)
+}
+
+// diagnoseEntries checks the tag entries of one file against its current content:
+// the file must be readable, every symbol must still appear on its recorded line,
+// and a name must not be defined twice with the same kind, scope and signature, so
+// overloads are fine. With a usage index, symbols that are never used are hinted
+// at as well. Tags lag behind a document with unsaved edits until it is saved and
+// indexed again, so symbols that moved there aren't reported as stale.
+func (server *Server) diagnoseEntries(uri string, entries []TagEntry, usage *usageIndex) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	if len(entries) == 0 {
+		return diagnostics
+	}
+
+	content, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil {
+		return append(diagnostics, Diagnostic{
+			Severity: DiagnosticSeverityError,
+			Code:     "unreadable-file",
+			Source:   diagnosticSource,
+			Message:  fmt.Sprintf("Indexed file cannot be read: %v", err),
+		})
+	}
+
+	slices.SortStableFunc(entries, func(a, b TagEntry) int { return a.Line - b.Line })
+
+	type definitionKey struct{ name, kind, scope, signature string }
+	firstLine := make(map[definitionKey]int)
+	modified := server.cache.isModified(uri)
+
+	for _, entry := range entries {
+		if entry.Line <= 0 || isQualifiedTag(entry) {
+			// Pattern-only tagfile entries carry no line to verify, and qualified
+			// tags duplicate a plain tag whose name is what appears on the line.
+			continue
+		}
+		lineIdx := entry.Line - 1
+		if lineIdx >= len(content) || !strings.Contains(content[lineIdx], entry.Name) {
+			if modified {
+				continue
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    findSymbolRangeInFile(content, entry.Name, max(1, min(entry.Line, len(content)))),
+				Severity: DiagnosticSeverityWarning,
+				Code:     "stale-tag",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("%s %q is no longer on line %d; the index may be out of date", entry.Kind, entry.Name, entry.Line),
+			})
+			continue
+		}
+
+		key := definitionKey{entry.Name, entry.Kind, entry.Scope, entry.Signature}
+		if line, ok := firstLine[key]; ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Range:    findSymbolRangeInFile(content, entry.Name, entry.Line),
+				Severity: DiagnosticSeverityWarning,
+				Code:     "duplicate-definition",
+				Source:   diagnosticSource,
+				Message:  fmt.Sprintf("%s %q is already defined on line %d", entry.Kind, entry.Name, line),
+			})
+			continue
+		}
+		firstLine[key] = entry.Line
+
+		if diagnostic, ok := unusedSymbolDiagnostic(entry, content, usage); ok {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.cpp", "int count;\nint count;\n\nvoid moved(void) {}\nvoid f(int);\nvoid f(char *);\n")
+	missing := pathToFileURI(filepath.Join(dir, "missing.c"))
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "count", Path: uri, Line: 1, Kind: "variable"},
+		{Name: "count", Path: uri, Line: 2, Kind: "variable"},
+		{Name: "moved", Path: uri, Line: 3, Kind: "function"},
+		{Name: "f", Path: uri, Line: 5, Kind: "prototype", Signature: "(int)"},
+		{Name: "f", Path: uri, Line: 6, Kind: "prototype", Signature: "(char *)"},
+		{Name: "past", Path: uri, Line: 40, Kind: "function"},
+		{Name: "gone", Path: missing, Line: 1, Kind: "function"},
+	})
+
+	diagnose := func(uri string) []Diagnostic {
+		frames := callHandler(t, server, "textDocument/diagnostic", DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		var report FullDocumentDiagnosticReport
+		if err := json.Unmarshal(frames[0].Result, &report); err != nil {
+			t.Fatalf("unmarshal report: %v", err)
+		}
+		return report.Items
+	}
+
+	items := diagnose(uri)
+	codes := make([]string, 0, len(items))
+	for _, item := range items {
+		codes = append(codes, item.Code)
+	}
+	if strings.Join(codes, ",") != "duplicate-definition,stale-tag,stale-tag" {
+		t.Fatalf("unexpected diagnostics: %+v", items)
+	}
+	if end := items[2].Range.Start.Line; end < 0 {
+		t.Fatalf("expected a tag past the end of the file on a real line, got %d", end)
+	}
+
+	// Until the edits are saved, the tags are expected to lag behind.
+	callHandler(t, server, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "\nint count;\nint count;\n"}},
+	})
+	if items := diagnose(uri); len(items) != 0 {
+		t.Fatalf("expected no stale tags in a modified document, got %+v", items)
+	}
+
+	items = diagnose(missing)
+	if len(items) != 1 || items[0].Code != "unreadable-file" {
+		t.Fatalf("expected unreadable-file diagnostic, got %+v", items)
+	}
+}
@@ -0,0 +1,151 @@
+package lsp
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxDocstringLines caps how far a docstring is read, so a missing closing
+// delimiter doesn't pull a whole file into a tooltip.
+const maxDocstringLines = 50
+
+// docstringAfterDefinition lists languages that document a definition with a
+// string literal right below it rather than with a comment above it.
+var docstringAfterDefinition = map[string][]string{
+	"Python": {`"""`, "'''"},
+}
+
+// extractDocstring returns the documentation written next to the definition of
+// `entry` in `lines`: a docstring below it for languages that use them, otherwise
+// the block of comments directly above it. Comment markers are stripped.
+func extractDocstring(lines []string, entry TagEntry) string {
+	lineIdx := entry.Line - 1
+	if lineIdx < 0 || lineIdx >= len(lines) {
+		return ""
+	}
+
+	language := entry.Language
+	if language == "" {
+		language = syntaxExtensions[strings.ToLower(filepath.Ext(fileURIToPath(entry.Path)))]
+	}
+	if quotes, ok := docstringAfterDefinition[language]; ok {
+		if doc := docstringBelow(lines, lineIdx, quotes); doc != "" {
+			return doc
+		}
+	}
+
+	syntax := syntaxForFile(entry.Path, entry.Language)
+	if syntax == nil {
+		return ""
+	}
+	return commentsAbove(lines, lineIdx, syntax)
+}
+
+// docstringBelow reads a string literal opening on the first line after the
+// definition's header, which may span several lines itself.
+func docstringBelow(lines []string, lineIdx int, quotes []string) string {
+	start := lineIdx + 1
+	for start < len(lines) && !strings.HasSuffix(strings.TrimSpace(lines[start-1]), ":") {
+		start++
+		if start-lineIdx > maxDocstringLines {
+			return ""
+		}
+	}
+	if start >= len(lines) {
+		return ""
+	}
+
+	first := strings.TrimSpace(lines[start])
+	for _, quote := range quotes {
+		body, ok := strings.CutPrefix(first, quote)
+		if !ok {
+			continue
+		}
+		if text, closed := strings.CutSuffix(body, quote); closed && body != "" {
+			return strings.TrimSpace(text)
+		}
+		docLines := []string{body}
+		for i := start + 1; i < len(lines) && i-start < maxDocstringLines; i++ {
+			if text, closed := strings.CutSuffix(strings.TrimSpace(lines[i]), quote); closed {
+				return dedentDocstring(append(docLines, text))
+			}
+			docLines = append(docLines, lines[i])
+		}
+		return ""
+	}
+	return ""
+}
+
+// commentsAbove collects the comment block that ends on the line right above the
+// definition: consecutive line comments, or one block comment.
+func commentsAbove(lines []string, lineIdx int, syntax *languageSyntax) string {
+	end := lineIdx - 1
+	if end < 0 {
+		return ""
+	}
+	last := strings.TrimSpace(lines[end])
+
+	for _, pair := range syntax.blockComments {
+		if !strings.HasSuffix(last, pair.close) {
+			continue
+		}
+		for start := end; start >= 0 && end-start < maxDocstringLines; start-- {
+			line := strings.TrimSpace(lines[start])
+			if !strings.HasPrefix(line, pair.open) {
+				continue
+			}
+			block := strings.Join(lines[start:end+1], "\n")
+			block = strings.TrimSpace(block)
+			block = strings.TrimSuffix(strings.TrimPrefix(block, pair.open), pair.close)
+			var docLines []string
+			for _, line := range strings.Split(block, "\n") {
+				line = strings.TrimSpace(line)
+				// Javadoc-style blocks start every line with "*", and "/**" leaves one behind.
+				line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+				docLines = append(docLines, line)
+			}
+			return strings.TrimSpace(strings.Join(docLines, "\n"))
+		}
+		return ""
+	}
+
+	var docLines []string
+	for i := end; i >= 0 && end-i < maxDocstringLines; i-- {
+		line := strings.TrimSpace(lines[i])
+		marker := ""
+		for _, comment := range syntax.lineComments {
+			if strings.HasPrefix(line, comment) {
+				marker = comment
+				break
+			}
+		}
+		// A shebang isn't documentation, even where "#" starts a comment.
+		if marker == "" || strings.HasPrefix(line, "#!") {
+			break
+		}
+		text := strings.TrimLeft(strings.TrimPrefix(line, marker), marker[:1])
+		docLines = append([]string{strings.TrimPrefix(text, " ")}, docLines...)
+	}
+	return strings.TrimSpace(strings.Join(docLines, "\n"))
+}
+
+// dedentDocstring removes the indentation shared by all but the first line, which
+// follows the opening quotes.
+func dedentDocstring(docLines []string) string {
+	indent := -1
+	for _, line := range docLines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		width := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || width < indent {
+			indent = width
+		}
+	}
+	for i := 1; i < len(docLines); i++ {
+		if len(docLines[i]) >= indent && indent > 0 {
+			docLines[i] = docLines[i][indent:]
+		}
+	}
+	return strings.TrimSpace(strings.Join(docLines, "\n"))
+}
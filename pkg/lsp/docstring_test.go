@@ -0,0 +1,26 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestExtractDocstring(t *testing.T) {
+	for _, test := range []struct {
+		file, text string
+		line       int
+		want       string
+	}{
+		{"a.py", "def area(r):\n    \"\"\"Return the area.\n\n    Uses pi.\n    \"\"\"\n", 1, "Return the area.\n\nUses pi."},
+		{"a.py", "# Old style.\ndef f(\n    x,\n):\n    '''One line.'''\n", 2, "One line."},
+		{"a.py", "# Comment above.\ndef g():\n    pass\n", 2, "Comment above."},
+		{"a.c", "/**\n * Frees the list.\n * @param l the list\n */\nvoid list_free(list *l);\n", 5, "Frees the list.\n@param l the list"},
+		{"a.c", "int x; /* unrelated */\n\nint y;\n", 3, ""},
+		{"a.sh", "#!/bin/sh\n# Prints usage.\n## Exits 1.\nusage() {\n", 4, "Prints usage.\nExits 1."},
+		{"a.lua", "--- Adds two numbers.\nlocal function add(a, b)\n", 2, "Adds two numbers."},
+	} {
+		entry := TagEntry{Path: pathToFileURI("/tmp/" + test.file), Line: test.line}
+		if got := extractDocstring(splitLines(test.text), entry); got != test.want {
+			t.Errorf("%s line %d: got %q, want %q", test.file, test.line, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const defaultEncoding = "utf-8"
+
+// sourceEncodings maps the accepted `--encoding` names to decoders.
+// UTF-8 has no decoder: it is the format content is kept in.
+var sourceEncodings = map[string]encoding.Encoding{
+	"utf-8":        nil,
+	"latin1":       charmap.ISO8859_1,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"windows-1252": charmap.Windows1252,
+	"shift_jis":    japanese.ShiftJIS,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+}
+
+// lookupEncoding returns the decoder for an encoding name, or nil for UTF-8.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	enc, ok := sourceEncodings[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+	return enc, nil
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeSource converts file content to UTF-8 without a byte-order mark. A BOM
+// always wins; otherwise content that isn't valid UTF-8 text is decoded with
+// `fallback`. Content that fails to decode is returned unchanged.
+func decodeSource(data []byte, fallback encoding.Encoding) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		// Editors don't count the BOM as part of the first line.
+		return string(data[len(bomUTF8):])
+	case bytes.HasPrefix(data, bomUTF16LE):
+		fallback = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		fallback = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case fallback == nil:
+		return string(data)
+	case utf8.Valid(data) && bytes.IndexByte(data, 0) < 0:
+		// Valid UTF-8 is kept as is, unless NUL bytes give away BOM-less UTF-16.
+		return string(data)
+	}
+
+	decoded, err := fallback.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// setEncoding changes how files are decoded from now on. Files already in the
+// cache keep their content until they are reloaded.
+func (cache *FileCache) setEncoding(enc encoding.Encoding) {
+	cache.mutex.Lock()
+	cache.encoding = enc
+	cache.mutex.Unlock()
+}
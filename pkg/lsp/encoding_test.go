@@ -0,0 +1,56 @@
+package lsp
+
+import (
+	"testing"
+)
+
+func TestFileEncodings(t *testing.T) {
+	dir := t.TempDir()
+	latin1 := writeTestFile(t, dir, "latin1.c", "int gr\xf6\xdfe;\n")
+	utf16 := writeTestFile(t, dir, "utf16.c", "\xff\xfei\x00n\x00t\x00 \x00x\x00;\x00")
+	shiftJIS := writeTestFile(t, dir, "sjis.c", "// \x93\xfa\x96\x7b\nint y;\n")
+	utf8 := writeTestFile(t, dir, "utf8.c", "// größe\n")
+
+	enc, err := lookupEncoding("Latin1")
+	if err != nil {
+		t.Fatalf("lookup encoding: %v", err)
+	}
+	server := newTestServer(t, nil)
+	server.cache.setEncoding(enc)
+
+	for uri, want := range map[string]string{
+		latin1: "int größe;",
+		utf16:  "int x;",
+		utf8:   "// größe",
+	} {
+		lines, err := server.cache.GetOrLoadFileContent(uri)
+		if err != nil || lines[0] != want {
+			t.Fatalf("expected %q from %s, got %q (%v)", want, uri, lines, err)
+		}
+	}
+
+	enc, _ = lookupEncoding("shift_jis")
+	lines, err := readFileLines(shiftJIS, enc)
+	if err != nil || lines[0] != "// 日本" {
+		t.Fatalf("expected Shift-JIS to be decoded, got %q (%v)", lines, err)
+	}
+
+	if _, err := lookupEncoding("ebcdic"); err == nil {
+		t.Fatalf("expected unsupported encoding to be rejected")
+	}
+}
+
+func TestByteOrderMark(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "\xef\xbb\xbfint first;\n")
+	server := newTestServer(t, nil)
+
+	lines, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil || lines[0] != "int first;" {
+		t.Fatalf("expected the BOM to be stripped, got %q (%v)", lines, err)
+	}
+	symbolRange := findSymbolRangeInFile(lines, "first", 1)
+	if symbolRange.Start.Character != 4 || symbolRange.End.Character != 9 {
+		t.Fatalf("unexpected range on the first line: %+v", symbolRange)
+	}
+}
@@ -0,0 +1,114 @@
+package lsp
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// extensionFamilies group file extensions whose files see each other's symbols,
+// such as C sources and their headers. ".h" belongs to both C and C++.
+var extensionFamilies = [][]string{
+	{".c", ".h"},
+	{".cpp", ".cc", ".cxx", ".c++", ".hpp", ".hh", ".hxx", ".h++", ".h"},
+	{".m", ".mm", ".h"},
+	{".ts", ".tsx", ".mts", ".cts"},
+	{".js", ".jsx", ".mjs", ".cjs"},
+}
+
+// sameExtensionFamily reports whether files with extensions `a` and `b` belong
+// together, by the built-in families and the configured `extra` ones.
+func sameExtensionFamily(a, b string, extra [][]string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == b {
+		return true
+	}
+	for _, families := range [][][]string{extensionFamilies, extra} {
+		for _, family := range families {
+			if slices.Contains(family, a) && slices.Contains(family, b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseExtensionFamilies parses the `--extension-families` flag: families are
+// separated by ";" and their extensions by ",", e.g. ".vert,.frag;.pyx,.pxd".
+func parseExtensionFamilies(value string) [][]string {
+	var families [][]string
+	for _, family := range strings.Split(value, ";") {
+		if extensions := normalizeExtensions(splitList(family)); len(extensions) > 1 {
+			families = append(families, extensions)
+		}
+	}
+	return families
+}
+
+// normalizeExtensions lowercases extensions and adds the leading dot `filepath.Ext` returns.
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, 0, len(extensions))
+	for _, extension := range extensions {
+		extension = strings.ToLower(strings.TrimSpace(extension))
+		if extension == "" {
+			continue
+		}
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		normalized = append(normalized, extension)
+	}
+	return normalized
+}
+
+// documentFamily describes which tags belong with a document: those from files in
+// its extension family, and those in a language the document contains. The latter
+// covers embedded code, like a script block in an HTML file, whose tags ctags
+// reports in the embedded language.
+type documentFamily struct {
+	extension string
+	languages map[string]bool
+	extra     [][]string
+}
+
+// documentFamily returns the family of the document `uri`. Its languages are the
+// one its extension implies and those of its tags.
+// The caller holds `server.mutex`.
+func (server *Server) documentFamily(uri string) documentFamily {
+	family := documentFamily{
+		extension: filepath.Ext(fileURIToPath(uri)),
+		languages: make(map[string]bool),
+		extra:     server.getOptions().extensionFamilies,
+	}
+	if language := languageForFile(uri, ""); language != "" {
+		family.languages[language] = true
+	}
+	for _, entry := range server.tagEntries {
+		if entry.Language != "" && sameURI(entry.Path, uri) {
+			family.languages[entry.Language] = true
+		}
+	}
+	return family
+}
+
+// includes reports whether `entry` belongs with the document.
+func (family documentFamily) includes(entry TagEntry) bool {
+	if sameExtensionFamily(filepath.Ext(fileURIToPath(entry.Path)), family.extension, family.extra) {
+		return true
+	}
+	return entry.Language != "" && family.languages[entry.Language]
+}
+
+// preferDocumentFamily narrows `entries` to those in `family`, unless none are.
+func preferDocumentFamily(entries []TagEntry, family documentFamily) []TagEntry {
+	var kept []TagEntry
+	for _, entry := range entries {
+		if family.includes(entry) {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) == 0 {
+		return entries
+	}
+	return kept
+}
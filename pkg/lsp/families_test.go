@@ -0,0 +1,103 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedLanguages(t *testing.T) {
+	dir := t.TempDir()
+	page := writeTestFile(t, dir, "page.html", "<script>\nfunction initPage() { helper() }\n</script>\n")
+	app := writeTestFile(t, dir, "app.js", "init\n")
+	style := writeTestFile(t, dir, "style.css", ".initStyle {}\n")
+	helperJS := writeTestFile(t, dir, "helper.js", "function helper() {}\n")
+	helperPy := writeTestFile(t, dir, "helper.py", "def helper(): pass\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "initPage", Path: page, Line: 2, Kind: "function", Language: "JavaScript"},
+		{Name: "initApp", Path: app, Line: 1, Kind: "function", Language: "JavaScript"},
+		{Name: "initStyle", Path: style, Line: 1, Kind: "class", Language: "CSS"},
+		{Name: "helper", Path: helperJS, Line: 1, Kind: "function", Language: "JavaScript"},
+		{Name: "helper", Path: helperPy, Line: 1, Kind: "function", Language: "Python"},
+	})
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: app, LanguageID: "javascript", Text: "init\n"},
+	})
+
+	frames := callHandler(t, server, "textDocument/completion", CompletionParams{
+		TextDocument: PositionParams{URI: app},
+		Position:     Position{Line: 0, Character: 4},
+	})
+	var list CompletionList
+	if err := json.Unmarshal(frames[0].Result, &list); err != nil {
+		t.Fatalf("unmarshal completion: %v", err)
+	}
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	slices.Sort(labels)
+	if !slices.Equal(labels, []string{"initApp", "initPage"}) {
+		t.Fatalf("expected the script block's function but not the CSS class, got %v", labels)
+	}
+
+	// The script block of the HTML file prefers the JavaScript definition.
+	frames = callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: page},
+		Position:     Position{Line: 1, Character: 22},
+	})
+	var location Location
+	if err := json.Unmarshal(frames[0].Result, &location); err != nil || location.URI != helperJS {
+		t.Fatalf("expected only the JavaScript definition, got %s", frames[0].Result)
+	}
+}
+
+func TestExtensionFamilies(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "point.h", "struct point_t;\n")
+	shader := writeTestFile(t, dir, "light.frag", "vec3 light_dir;\n")
+	python := writeTestFile(t, dir, "point.py", "def point_new(): pass\n")
+	cSource := writeTestFile(t, dir, "point.c", "void point_new(void) {}\n")
+	main := writeTestFile(t, dir, "main.c", "point\npoint_new\n")
+	vertex := writeTestFile(t, dir, "main.vert", "light\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "point_t", Path: header, Line: 1, Kind: "structure"},
+		{Name: "light_dir", Path: shader, Line: 1, Kind: "variable"},
+		{Name: "point_new", Path: python, Line: 1, Kind: "function"},
+		{Name: "point_new", Path: cSource, Line: 1, Kind: "function"},
+	})
+	server.options.extensionFamilies = parseExtensionFamilies("vert,FRAG")
+
+	complete := func(uri string, text string) []CompletionItem {
+		callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocument{URI: uri, Text: text},
+		})
+		frames := callHandler(t, server, "textDocument/completion", CompletionParams{
+			TextDocument: PositionParams{URI: uri},
+			Position:     Position{Line: 0, Character: len(strings.Split(text, "\n")[0])},
+		})
+		var list CompletionList
+		if err := json.Unmarshal(frames[0].Result, &list); err != nil {
+			t.Fatalf("unmarshal completion: %v", err)
+		}
+		return list.Items
+	}
+	if items := complete(main, "point\npoint_new\n"); len(items) != 2 {
+		t.Fatalf("expected header and source symbols from a .c file, got %+v", items)
+	}
+	if items := complete(vertex, "light\n"); len(items) != 1 || items[0].Label != "light_dir" {
+		t.Fatalf("expected the configured family to share symbols, got %+v", items)
+	}
+
+	frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: main},
+		Position:     Position{Line: 1, Character: 2},
+	})
+	var location Location
+	if err := json.Unmarshal(frames[0].Result, &location); err != nil || location.URI != cSource {
+		t.Fatalf("expected the C definition only, got %s (%v)", frames[0].Result, err)
+	}
+}
@@ -0,0 +1,224 @@
+package lsp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+type FileOperationOptions struct {
+	DidCreate  *FileOperationRegistrationOptions `json:"didCreate,omitempty"`
+	WillRename *FileOperationRegistrationOptions `json:"willRename,omitempty"`
+	DidRename  *FileOperationRegistrationOptions `json:"didRename,omitempty"`
+	DidDelete  *FileOperationRegistrationOptions `json:"didDelete,omitempty"`
+}
+
+type FileOperationRegistrationOptions struct {
+	Filters []FileOperationFilter `json:"filters"`
+}
+
+type FileOperationFilter struct {
+	Scheme  string               `json:"scheme,omitempty"`
+	Pattern FileOperationPattern `json:"pattern"`
+}
+
+type FileOperationPattern struct {
+	Glob string `json:"glob"`
+}
+
+type FileRename struct {
+	OldURI string `json:"oldUri"`
+	NewURI string `json:"newUri"`
+}
+
+type RenameFilesParams struct {
+	Files []FileRename `json:"files"`
+}
+
+type FileCreate struct {
+	URI string `json:"uri"`
+}
+
+type CreateFilesParams struct {
+	Files []FileCreate `json:"files"`
+}
+
+type FileDelete struct {
+	URI string `json:"uri"`
+}
+
+type DeleteFilesParams struct {
+	Files []FileDelete `json:"files"`
+}
+
+// allFileOperations matches every file and folder with a file:// URI.
+var allFileOperations = &FileOperationRegistrationOptions{
+	Filters: []FileOperationFilter{{Scheme: "file", Pattern: FileOperationPattern{Glob: "**/*"}}},
+}
+
+// handleWillRenameFiles never contributes edits; the index is updated once
+// the rename actually happened (see `handleDidRenameFiles`).
+func handleWillRenameFiles(server *Server, req RPCRequest) {
+	server.sendResult(req.ID, nil)
+}
+
+// handleDidRenameFiles rewrites tag entry paths and cache keys for renamed files and folders.
+func handleDidRenameFiles(server *Server, req RPCRequest) {
+	var params RenameFilesParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	for _, file := range params.Files {
+		oldURI, err := normalizeFileURI(server.localURI(file.OldURI))
+		if err != nil {
+			continue
+		}
+		newURI, err := normalizeFileURI(server.localURI(file.NewURI))
+		if err != nil {
+			continue
+		}
+		server.renameIndexedPath(oldURI, newURI)
+	}
+}
+
+// handleDidCreateFiles indexes newly created files, or every file below a created
+// folder, with one ctags run. Files the workspace scan would leave out are left
+// out here too: excluded ones, binary and oversized ones, and version control
+// metadata.
+func handleDidCreateFiles(server *Server, req RPCRequest) {
+	var params CreateFilesParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	if !server.mayRunCommands() {
+		return
+	}
+
+	options := server.getOptions()
+	rootDir := fileURIToPath(server.rootURI)
+	var files []string
+	for _, file := range params.Files {
+		uri, err := normalizeFileURI(server.localURI(file.URI))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(rootDir, fileURIToPath(uri))
+		if err != nil || !filepath.IsLocal(rel) || len(workspace.ExcludeFiles([]string{rel}, options.exclude)) == 0 {
+			continue
+		}
+		created, _ := workspace.WalkFiles(rootDir, rel, options.maxFileSize, options.exclude)
+		files = append(files, created...)
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	created := make(map[string]bool, len(files))
+	for _, file := range files {
+		created[uriKey(pathToFileURI(filepath.Join(rootDir, file)))] = true
+	}
+	keep := func(entry TagEntry) bool { return !created[uriKey(entry.Path)] }
+	server.mutex.Lock()
+	server.tagEntries = filterEntries(server.tagEntries, keep)
+	server.referenceEntries = filterEntries(server.referenceEntries, keep)
+	server.mutex.Unlock()
+
+	slog.Debug("indexing created files", "files", len(files))
+	server.scanChunk(rootDir, append([]string{"-L", "-"}, options.ctagArgs...), files)
+}
+
+// handleDidDeleteFiles drops tag entries and cached content for deleted files and folders.
+func handleDidDeleteFiles(server *Server, req RPCRequest) {
+	var params DeleteFilesParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	for _, file := range params.Files {
+		uri, err := normalizeFileURI(server.localURI(file.URI))
+		if err != nil {
+			continue
+		}
+		server.removeIndexedPath(uri)
+	}
+}
+
+// isUnderURI reports whether `uri` equals `base` or is nested below it.
+func isUnderURI(uri, base string) bool {
+	if sameURI(uri, base) {
+		return true
+	}
+	_, ok := cutURIPrefix(uri, base+"/")
+	return ok
+}
+
+// removeIndexedPath drops entries and cache content for `uri` and anything nested under it.
+func (server *Server) removeIndexedPath(uri string) {
+	keep := func(entry TagEntry) bool { return !isUnderURI(entry.Path, uri) }
+	server.mutex.Lock()
+	server.tagEntries = filterEntries(server.tagEntries, keep)
+	server.referenceEntries = filterEntries(server.referenceEntries, keep)
+	server.mutex.Unlock()
+	server.invalidateUsageOf(uri)
+
+	server.cache.mutex.Lock()
+	for key := range server.cache.content {
+		if isUnderURI(key, uri) {
+			delete(server.cache.content, key)
+		}
+	}
+	server.cache.mutex.Unlock()
+}
+
+// renameIndexedPath moves every indexed URI equal to `oldURI`, or nested under it
+// when it names a folder, to the corresponding location under `newURI`.
+func (server *Server) renameIndexedPath(oldURI, newURI string) {
+	rename := func(uri string) (string, bool) {
+		if sameURI(uri, oldURI) {
+			return newURI, true
+		}
+		if rest, ok := cutURIPrefix(uri, oldURI+"/"); ok {
+			return newURI + "/" + rest, true
+		}
+		return uri, false
+	}
+
+	// The slices are replaced rather than changed in place, since readers that
+	// took them under the lock may still be going through them.
+	renamed := 0
+	renameEntries := func(entries []TagEntry) []TagEntry {
+		result := make([]TagEntry, len(entries))
+		for i, entry := range entries {
+			if uri, ok := rename(entry.Path); ok {
+				entry.Path = uri
+				renamed++
+			}
+			result[i] = entry
+		}
+		return result
+	}
+	server.mutex.Lock()
+	server.tagEntries = renameEntries(server.tagEntries)
+	server.referenceEntries = renameEntries(server.referenceEntries)
+	server.mutex.Unlock()
+	// Counts and tokens are kept by URI, so the renamed files are counted again.
+	server.invalidateUsageOf(oldURI, newURI)
+	server.semantic.forgetMatching(func(key string) bool {
+		_, ok := rename(key)
+		return ok
+	})
+
+	server.cache.mutex.Lock()
+	for uri, content := range server.cache.content {
+		if newKey, ok := rename(uri); ok {
+			delete(server.cache.content, uri)
+			server.cache.content[uriKey(newKey)] = content
+		}
+	}
+	server.cache.mutex.Unlock()
+
+	slog.Debug("renamed indexed path", "from", oldURI, "to", newURI, "entries", renamed)
+}
@@ -0,0 +1,121 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+func TestDidRenameFilesUpdatesIndex(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := pathToFileURI(filepath.Join(dir, "old.go"))
+	oldDir := pathToFileURI(filepath.Join(dir, "pkg"))
+	nested := oldDir + "/nested.go"
+	sibling := pathToFileURI(filepath.Join(dir, "pkgx", "other.go"))
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "A", Path: oldFile},
+		{Name: "B", Path: nested},
+		{Name: "C", Path: sibling},
+	})
+	server.cache.content[oldFile] = []string{"package a"}
+	before := server.tagEntries
+
+	newFile := pathToFileURI(filepath.Join(dir, "new.go"))
+	newDir := pathToFileURI(filepath.Join(dir, "lib"))
+	callHandler(t, server, "workspace/didRenameFiles", RenameFilesParams{Files: []FileRename{
+		{OldURI: oldFile, NewURI: newFile},
+		{OldURI: oldDir, NewURI: newDir},
+	}})
+
+	want := []string{newFile, newDir + "/nested.go", sibling}
+	for i, entry := range server.tagEntries {
+		if entry.Path != want[i] {
+			t.Fatalf("entry %s: expected %q, got %q", entry.Name, want[i], entry.Path)
+		}
+	}
+	if before[0].Path != oldFile {
+		t.Fatal("expected the entries to be replaced, not changed in place under concurrent readers")
+	}
+	if _, ok := server.cache.content[newFile]; !ok {
+		t.Fatal("expected cached content to move to the new URI")
+	}
+	if _, ok := server.cache.content[oldFile]; ok {
+		t.Fatal("expected cached content for the old URI to be gone")
+	}
+}
+
+func TestDidCreateFilesIndexesInOneRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+	server := newTestServer(t, nil)
+	server.options.exclude = workspace.DefaultExclude
+	dir := fileURIToPath(server.rootURI)
+	// The fake ctags logs each run and tags every file it is given.
+	runs := filepath.Join(t.TempDir(), "runs")
+	script := "#!/bin/sh\n" + `echo run >> "` + runs + `"
+for f in $(cat); do
+	echo "{\"_type\": \"tag\", \"name\": \"$(basename $f)\", \"path\": \"$f\", \"line\": 1, \"kind\": \"func\"}"
+done
+`
+	server.ctagsBin = filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(server.ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	for _, sub := range []string{"pkg/.git", "pkg/dist", "build"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", sub, err)
+		}
+	}
+	writeTestFile(t, dir, "pkg/a.go", "package pkg\n")
+	writeTestFile(t, dir, "pkg/b.go", "package pkg\n")
+	writeTestFile(t, dir, "pkg/image.png", "\x89PNG\r\n\x1a\n\x00")
+	writeTestFile(t, dir, "pkg/.git/HEAD", "ref: refs/heads/main\n")
+	writeTestFile(t, dir, "pkg/dist/out.go", "package dist\n")
+	single := writeTestFile(t, dir, "c.go", "package c\n")
+	excluded := writeTestFile(t, dir, "build/gen.go", "package gen\n")
+
+	callHandler(t, server, "workspace/didCreateFiles", CreateFilesParams{Files: []FileCreate{
+		{URI: pathToFileURI(filepath.Join(dir, "pkg"))},
+		{URI: single},
+		{URI: excluded},
+	}})
+
+	var names []string
+	for _, entry := range server.tagEntries {
+		names = append(names, entry.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"a.go", "b.go", "c.go"}) {
+		t.Fatalf("expected only the source files to be indexed, got %v", names)
+	}
+	if log, err := os.ReadFile(runs); err != nil || string(log) != "run\n" {
+		t.Fatalf("expected a single ctags run, got %q (%v)", log, err)
+	}
+}
+
+func TestDidDeleteFilesDropsEntries(t *testing.T) {
+	dir := t.TempDir()
+	deletedDir := pathToFileURI(filepath.Join(dir, "gone"))
+	kept := pathToFileURI(filepath.Join(dir, "gone.go"))
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "A", Path: deletedDir + "/a.go"},
+		{Name: "B", Path: kept},
+	})
+	server.cache.content[deletedDir+"/a.go"] = []string{"package a"}
+
+	callHandler(t, server, "workspace/didDeleteFiles", DeleteFilesParams{Files: []FileDelete{{URI: deletedDir}}})
+
+	if len(server.tagEntries) != 1 || server.tagEntries[0].Name != "B" {
+		t.Fatalf("expected only B to remain, got %+v", server.tagEntries)
+	}
+	if len(server.cache.content) != 0 {
+		t.Fatalf("expected cache to be empty, got %d entries", len(server.cache.content))
+	}
+}
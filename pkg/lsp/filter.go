@@ -0,0 +1,106 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os/exec"
+	"strings"
+)
+
+// maxFilteredTagSize is the longest line a `--tag-filter` program may print.
+const maxFilteredTagSize = 1 << 20
+
+// tagFilter passes a batch of tags about to be indexed through a filter, which
+// returns the tags to index instead.
+type tagFilter func(entries []TagEntry) []TagEntry
+
+// filterTags passes `entries` through the filters of the server, in the order
+// they were added.
+func (server *Server) filterTags(entries []TagEntry) []TagEntry {
+	for _, filter := range server.tagFilters {
+		entries = filter(entries)
+	}
+	return entries
+}
+
+// validateTagFilter checks that the program of a `--tag-filter` command exists.
+func validateTagFilter(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty tag filter")
+	}
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return fmt.Errorf("invalid tag filter: %w", err)
+	}
+	return nil
+}
+
+// execTagFilter returns a filter that pipes each batch of tags through the
+// program `command`, run in the workspace root: it reads tags as JSON lines, in
+// the format of `ctags --output-format=json`, and prints the tags to index the
+// same way. It may leave tags out, change them or add new ones. Paths are file
+// URIs on the way in; relative paths on the way out are taken to be under the
+// workspace root. If the program fails, the tags are indexed unfiltered.
+func (server *Server) execTagFilter(command string) tagFilter {
+	fields := strings.Fields(command)
+	return func(entries []TagEntry) []TagEntry {
+		if len(entries) == 0 {
+			return entries
+		}
+		filtered, err := server.runTagFilter(fields, entries)
+		if err != nil {
+			slog.Warn("tag filter failed; indexing tags unfiltered", "command", command, "error", err)
+			return entries
+		}
+		return filtered
+	}
+}
+
+func (server *Server) runTagFilter(fields []string, entries []TagEntry) ([]TagEntry, error) {
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, entry := range entries {
+		entry.Type = "tag"
+		if err := encoder.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	rootDir := fileURIToPath(server.rootURI)
+	var stderr bytes.Buffer
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Dir = rootDir
+	cmd.Stdin = &input
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var filtered []TagEntry
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, maxFilteredTagSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry TagEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %v", line, err)
+		}
+		if parsed, err := url.Parse(entry.Path); err != nil || len(parsed.Scheme) < 2 {
+			normalized, err := normalizePath(rootDir, server.localPath(entry.Path))
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag %q: %v", line, err)
+			}
+			entry.Path = pathToFileURI(normalized)
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered, scanner.Err()
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTagFilters(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the filter is a shell script")
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, dir, "parser.go", "package parser\n\nfunc yyParse() {}\n\nfunc Parse() {}\n")
+	tagsPath := filepath.Join(dir, "tags")
+	tagfile := "yyParse\tparser.go\t3;\"\tf\tline:3\n" +
+		"Parse\tparser.go\t5;\"\tf\tline:5\n" +
+		"helper\tparser.go\t5;\"\tf\tline:5\n"
+	if err := os.WriteFile(tagsPath, []byte(tagfile), 0o644); err != nil {
+		t.Fatalf("write tagfile: %v", err)
+	}
+
+	// The filter drops the generated yy* tags and points Parse at the grammar.
+	filter := writeTestFile(t, dir, "filter.sh", "#!/bin/sh\ngrep -v '\"name\":\"yy' | sed 's|\"path\":\"[^\"]*\"|\"path\":\"grammar.y\"|'\n")
+	if err := os.Chmod(fileURIToPath(filter), 0o755); err != nil {
+		t.Fatalf("chmod filter: %v", err)
+	}
+	if err := validateTagFilter("no-such-tag-filter --flag"); err == nil {
+		t.Fatal("expected a missing filter program to be rejected")
+	}
+
+	server := newTestServer(t, nil)
+	server.rootURI = pathToFileURI(dir)
+	server.tagFilters = append(server.tagFilters, server.execTagFilter(fileURIToPath(filter)))
+	server.AddTagFilter(func(entry TagEntry) (TagEntry, bool) {
+		entry.Signature = "()"
+		return entry, entry.Name != "helper"
+	})
+	if err := server.loadTagfile(tagsPath); err != nil {
+		t.Fatalf("loadTagfile: %v", err)
+	}
+	want := TagEntry{Type: "tag", Name: "Parse", Path: pathToFileURI(filepath.Join(dir, "grammar.y")), Pattern: "5", Kind: "f", Line: 5, Signature: "()", FromTagfile: true}
+	if len(server.tagEntries) != 1 || server.tagEntries[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, server.tagEntries)
+	}
+
+	// A failing filter leaves the tags alone.
+	server.tagFilters = []tagFilter{server.execTagFilter("false")}
+	entries := []TagEntry{{Name: "Parse", Path: pathToFileURI(filepath.Join(dir, "parser.go")), Line: 5}}
+	if filtered := server.filterTags(entries); len(filtered) != 1 || filtered[0] != entries[0] {
+		t.Fatalf("expected the tags unfiltered, got %+v", filtered)
+	}
+}
@@ -0,0 +1,66 @@
+package lsp
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FoldingRange struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// handleFoldingRange folds the tags of the document that span several lines,
+// from their line to their `end` field, for languages the editor can't fold by
+// itself. A closing bracket on the last line stays visible, as editors do for
+// their own folds.
+func handleFoldingRange(server *Server, req RPCRequest) {
+	var params FoldingRangeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	lines, err := server.cache.GetOrLoadFileContent(normalizedURI)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	entries := entriesForURI(server.tagEntries, normalizedURI)
+	server.mutex.Unlock()
+
+	ranges := []FoldingRange{}
+	seen := make(map[FoldingRange]bool)
+	for _, entry := range entries {
+		if entry.End <= entry.Line || entry.End > len(lines) {
+			continue
+		}
+		rng := FoldingRange{StartLine: entry.Line - 1, EndLine: entry.End - 1}
+		if last := strings.TrimSpace(lines[rng.EndLine]); last != "" && strings.ContainsRune("})]", rune(last[0])) {
+			rng.EndLine--
+		}
+		if rng.EndLine <= rng.StartLine || seen[rng] {
+			continue
+		}
+		seen[rng] = true
+		ranges = append(ranges, rng)
+	}
+	slices.SortFunc(ranges, func(a, b FoldingRange) int {
+		return cmp.Or(cmp.Compare(a.StartLine, b.StartLine), cmp.Compare(b.EndLine, a.EndLine))
+	})
+	server.sendResult(req.ID, ranges)
+}
@@ -0,0 +1,37 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestFoldingRange(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shapes.py", "class Shape:\n    def area(self):\n        return 0\n\n    def name(self):\n        return \"shape\"\n")
+	cURI := writeTestFile(t, dir, "main.c", "int main(void)\n{\n\treturn 0;\n}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Shape", Path: uri, Line: 1, End: 6, Kind: "class", Language: "Python"},
+		{Name: "name", Path: uri, Line: 5, End: 6, Kind: "member", Language: "Python"},
+		{Name: "area", Path: uri, Line: 2, End: 3, Kind: "member", Language: "Python"},
+		{Name: "Shape", Path: uri, Line: 1, Kind: "class", Language: "Python"},
+		{Name: "main", Path: cURI, Line: 1, End: 4, Kind: "function", Language: "C"},
+	})
+
+	folds := func(uri string) []FoldingRange {
+		frames := callHandler(t, server, "textDocument/foldingRange", FoldingRangeParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+		var ranges []FoldingRange
+		if err := json.Unmarshal(frames[0].Result, &ranges); err != nil {
+			t.Fatalf("decode folding ranges %s: %v", frames[0].Result, err)
+		}
+		return ranges
+	}
+	expected := []FoldingRange{{StartLine: 0, EndLine: 5}, {StartLine: 1, EndLine: 2}, {StartLine: 4, EndLine: 5}}
+	if ranges := folds(uri); !slices.Equal(ranges, expected) {
+		t.Fatalf("expected folds %+v, got %+v", expected, ranges)
+	}
+	expected = []FoldingRange{{StartLine: 0, EndLine: 2}}
+	if ranges := folds(cURI); !slices.Equal(ranges, expected) {
+		t.Fatalf("expected the closing brace to stay visible, got %+v", ranges)
+	}
+}
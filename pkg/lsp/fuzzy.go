@@ -0,0 +1,152 @@
+package lsp
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSuggestions caps the names offered when a definition isn't found.
+const maxSuggestions = 5
+
+// editDistance returns the Levenshtein distance between `a` and `b` in runes, or
+// a value above `limit` as soon as the distance is known to exceed it.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// prefixEditDistance returns the smallest edit distance between `query` and any
+// prefix of `name`, so that a misspelled start of a name still matches it, or a
+// value above `limit` as soon as it is known to exceed it.
+func prefixEditDistance(query, name string, limit int) int {
+	rq, rn := []rune(query), []rune(name)
+	previous := make([]int, len(rn)+1)
+	current := make([]int, len(rn)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(rq); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(rn); j++ {
+			cost := 1
+			if rq[i-1] == rn[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return slices.Min(previous)
+}
+
+// commonPrefixLength returns the number of leading runes `a` and `b` share.
+func commonPrefixLength(a, b string) int {
+	n := 0
+	for _, r := range a {
+		next, size := utf8.DecodeRuneInString(b)
+		if size == 0 || next != r {
+			break
+		}
+		b = b[size:]
+		n++
+	}
+	return n
+}
+
+// typoLimit is the edit distance still considered a typo of a name of `length`
+// runes: none for very short names, where everything is a few edits apart.
+func typoLimit(length int) int {
+	switch {
+	case length < 3:
+		return 0
+	case length < 6:
+		return 1
+	case length < 10:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// suggestNames returns the tag names closest to `name`, best first: the same name
+// in a different case, then names within a few typos, then names it is a prefix of.
+func suggestNames(entries []TagEntry, name string) []string {
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	lowerName := strings.ToLower(name)
+	limit := typoLimit(len([]rune(name)))
+	seen := make(map[string]bool)
+	var suggestions []suggestion
+	for _, entry := range entries {
+		if seen[entry.Name] || entry.Name == name || isQualifiedTag(entry) {
+			continue
+		}
+		seen[entry.Name] = true
+
+		lowerEntry := strings.ToLower(entry.Name)
+		distance := editDistance(lowerName, lowerEntry, limit)
+		if distance > limit {
+			if !strings.HasPrefix(lowerEntry, lowerName) || len(lowerName) < 3 {
+				continue
+			}
+			// Prefix matches rank after typos, the shorter the better.
+			distance = limit + 1 + len(lowerEntry) - len(lowerName)
+		}
+		suggestions = append(suggestions, suggestion{name: entry.Name, distance: distance})
+	}
+
+	slices.SortFunc(suggestions, func(a, b suggestion) int {
+		return cmp.Or(cmp.Compare(a.distance, b.distance), cmp.Compare(a.name, b.name))
+	})
+	names := make([]string, 0, min(len(suggestions), maxSuggestions))
+	for _, s := range suggestions[:min(len(suggestions), maxSuggestions)] {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// suggestDefinitions tells the user which names they may have meant when `name`
+// has no definition. The caller holds `server.mutex`.
+func (server *Server) suggestDefinitions(name string) {
+	suggestions := suggestNames(server.tagEntries, name)
+	if len(suggestions) == 0 {
+		return
+	}
+	server.showMessage(MessageTypeInfo, fmt.Sprintf("No definition found for %q. Did you mean %s?", name, strings.Join(suggestions, ", ")))
+}
@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSuggestNames(t *testing.T) {
+	entries := []TagEntry{
+		{Name: "ParseConfig"},
+		{Name: "parseConfigFile"},
+		{Name: "parse_config"},
+		{Name: "ParseConfig"},
+		{Name: "printConfig"},
+		{Name: "unrelated"},
+	}
+	got := suggestNames(entries, "parseconfig")
+	want := []string{"ParseConfig", "parse_config", "parseConfigFile"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := suggestNames(entries, "xy"); len(got) != 0 {
+		t.Fatalf("expected no suggestions for short names, got %v", got)
+	}
+
+	if d := editDistance("kitten", "sitting", 5); d != 3 {
+		t.Fatalf("expected distance 3, got %d", d)
+	}
+	if d := editDistance("kitten", "sitting", 1); d != 2 {
+		t.Fatalf("expected distance above the limit, got %d", d)
+	}
+}
+
+func TestDefinitionNotFoundSuggestsNames(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "main.go", "package main\n\nfunc main() { parseconfig() }\n")
+	server := newTestServer(t, []TagEntry{{Name: "ParseConfig", Path: uri, Line: 3, Kind: "func"}})
+
+	frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 2, Character: 16},
+	})
+	if len(frames) != 2 || string(frames[0].Result) != "null" || frames[1].Method != "window/showMessage" {
+		t.Fatalf("expected an empty result and a message, got %+v", frames)
+	}
+	var message ShowMessageParams
+	if err := json.Unmarshal(frames[1].Params, &message); err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+	if !strings.Contains(message.Message, "Did you mean ParseConfig?") {
+		t.Fatalf("unexpected message %q", message.Message)
+	}
+}
@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// rubyGemDirs returns the source directories of the gems a Ruby workspace uses:
+// those of its bundle when it has a Gemfile, otherwise every gem installed in
+// Gem.path. Gems vendored inside `rootDir` are already part of the workspace and
+// left out.
+func rubyGemDirs(rootDir string) ([]string, error) {
+	var dirs []string
+	if _, err := os.Stat(filepath.Join(rootDir, "Gemfile")); err == nil {
+		cmd := exec.Command("bundle", "list", "--paths")
+		cmd.Dir = rootDir
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, err
+		}
+		dirs = strings.Split(strings.TrimSpace(string(output)), "\n")
+	} else {
+		output, err := exec.Command("ruby", "-e", "puts Gem.path").Output()
+		if err != nil {
+			return nil, err
+		}
+		for _, gemPath := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			gems, _ := filepath.Glob(filepath.Join(gemPath, "gems", "*"))
+			dirs = append(dirs, gems...)
+		}
+	}
+
+	var kept []string
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" || isWithinDir(rootDir, dir) {
+			continue
+		}
+		kept = append(kept, dir)
+	}
+	return kept, nil
+}
+
+// gemSourceFiles returns the Ruby sources in the gem directories `dirs`, leaving
+// out tests and files larger than `maxFileSize` (unless it is 0).
+func gemSourceFiles(dirs []string, maxFileSize int64) []string {
+	var files []string
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != dir && (name == "test" || name == "spec" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(name) != ".rb" {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || (maxFileSize > 0 && info.Size() > maxFileSize) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+	}
+	return files
+}
+
+// rubyGemFiles returns the Ruby sources of the gems the workspace in `rootDir`
+// uses, for go-to-definition into gems.
+func rubyGemFiles(rootDir string, maxFileSize int64) []string {
+	dirs, err := rubyGemDirs(rootDir)
+	if err != nil {
+		slog.Warn("failed to list Ruby gems", "error", err)
+		return nil
+	}
+	files := gemSourceFiles(dirs, maxFileSize)
+	slog.Info("indexing Ruby gems", "gems", len(dirs), "files", len(files))
+	return files
+}
@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestRubyGemFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake bundle is a shell script")
+	}
+	root := t.TempDir()
+	gems := t.TempDir()
+	rack := filepath.Join(gems, "rack-3.0.0")
+	for _, dir := range []string{filepath.Join(rack, "lib", "rack"), filepath.Join(rack, "test"), filepath.Join(root, "vendor", "local")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	for _, name := range []string{"lib/rack.rb", "lib/rack/request.rb", "test/spec_request.rb", "rack.gemspec"} {
+		writeTestFile(t, rack, name, "module Rack; end\n")
+	}
+	writeTestFile(t, root, "Gemfile", "gem 'rack'\n")
+
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho " + rack + "\necho " + filepath.Join(root, "vendor", "local") + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "bundle"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake bundle: %v", err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	files := rubyGemFiles(root, 0)
+	want := []string{filepath.Join(rack, "lib", "rack", "request.rb"), filepath.Join(rack, "lib", "rack.rb")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+}
@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// goModuleDirs returns the directories of the modules the Go module in `rootDir`
+// depends on, as `go list` reports them. Modules that aren't downloaded have no
+// directory and are left out; nothing is downloaded to find them. Directories
+// inside `rootDir`, such as the main module and local replacements, are already
+// part of the workspace and left out too.
+func goModuleDirs(rootDir string) ([]string, error) {
+	cmd := exec.Command("go", "list", "-mod=readonly", "-m", "-e", "-f", "{{.Dir}}", "all")
+	cmd.Dir = rootDir
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, dir := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if dir == "" || isWithinDir(rootDir, dir) {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+// goModuleFiles returns the Go source files of the dependencies of the Go module
+// in `rootDir`, for go-to-definition into third-party code. Tests, testdata and
+// directories the go tool ignores are left out.
+func goModuleFiles(rootDir string) []string {
+	if _, err := os.Stat(filepath.Join(rootDir, "go.mod")); err != nil {
+		return nil
+	}
+	dirs, err := goModuleDirs(rootDir)
+	if err != nil {
+		slog.Warn("failed to list Go module dependencies", "error", err)
+		return nil
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != dir && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	slog.Info("indexing Go module dependencies", "modules", len(dirs), "files", len(files))
+	return files
+}
+
+// isWithinDir reports whether `path` is `dir` or below it.
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestGoModuleFiles(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	base := t.TempDir()
+	root := filepath.Join(base, "app")
+	dep := filepath.Join(base, "dep")
+	for _, dir := range []string{root, filepath.Join(dep, "testdata"), filepath.Join(dep, "inner")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeTestFile(t, root, "go.mod", "module example.com/app\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ../dep\n")
+	writeTestFile(t, root, "main.go", "package main\n")
+	writeTestFile(t, dep, "go.mod", "module example.com/dep\n\ngo 1.21\n")
+	for _, name := range []string{"dep.go", "dep_test.go", "testdata/fixture.go", "inner/inner.go", "README.md"} {
+		writeTestFile(t, dep, name, "package dep\n")
+	}
+
+	files := goModuleFiles(root)
+	slices.Sort(files)
+	want := []string{filepath.Join(dep, "dep.go"), filepath.Join(dep, "inner", "inner.go")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+
+	if files := goModuleFiles(filepath.Join(dep, "inner")); files != nil {
+		t.Fatalf("expected no files without go.mod, got %v", files)
+	}
+}
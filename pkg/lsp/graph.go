@@ -0,0 +1,304 @@
+package lsp
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding"
+)
+
+// Edge kinds of the symbol graph.
+const (
+	graphEdgeContains = "contains"
+	graphEdgeInherits = "inherits"
+	graphEdgeCalls    = "calls"
+)
+
+// symbolGraph is the graph `ctags-lsp graph` exports: the indexed symbols and
+// how they relate.
+type symbolGraph struct {
+	Nodes []graphNode `json:"nodes"`
+	Edges []graphEdge `json:"edges"`
+}
+
+type graphNode struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Language string `json:"language,omitempty"`
+}
+
+type graphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// runGraphCommand implements `ctags-lsp graph [options] <dot|json> [<dir>]`. It
+// indexes `dir`, `--root` by default, like the server would, and
+// writes the symbol graph to `stdout`.
+func runGraphCommand(process Process, args []string, stdout, stderr io.Writer, checkCtags func(string) error) int {
+	config, err := parseFlags(args, stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	config.version = process.Version
+	if len(config.args) < 1 || len(config.args) > 2 || (config.args[0] != "dot" && config.args[0] != "json") {
+		fmt.Fprintf(stderr, "Error: expected a format, dot or json, and at most one directory\n")
+		return 2
+	}
+	root := config.root
+	if len(config.args) == 2 {
+		root = config.args[1]
+	}
+	if err := process.setLogger(config, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := checkCtags(config.ctagsBin); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	server := newServer(config, io.Discard)
+	if err := server.Index(root); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	graph := server.symbolGraph()
+	if config.args[0] == "json" {
+		err = writeGraphJSON(stdout, graph)
+	} else {
+		err = writeGraphDOT(stdout, graph)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// symbolGraph builds the graph of the index. Symbols contain the symbols scoped
+// to them and inherit from their `inherits` bases. Callables call the callables
+// whose name appears before a "(" between their definition and the next callable
+// of the file. A name several callables define counts only if exactly one of them
+// is in the caller's file.
+func (server *Server) symbolGraph() symbolGraph {
+	server.mutex.Lock()
+	entries := slices.Clone(server.tagEntries)
+	server.mutex.Unlock()
+	entries = slices.DeleteFunc(entries, isQualifiedTag)
+	slices.SortFunc(entries, func(a, b TagEntry) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), cmp.Compare(a.Line, b.Line), strings.Compare(a.Name, b.Name))
+	})
+
+	rootDir := fileURIToPath(server.rootURI)
+	var graph symbolGraph
+	byName := make(map[string][]int)
+	for i, entry := range entries {
+		path := fileURIToPath(entry.Path)
+		if relative, err := filepath.Rel(rootDir, path); err == nil && !strings.HasPrefix(relative, "..") {
+			path = filepath.ToSlash(relative)
+		}
+		graph.Nodes = append(graph.Nodes, graphNode{
+			ID:       fmt.Sprintf("n%d", i),
+			Name:     entry.Name,
+			Kind:     entry.Kind,
+			Path:     path,
+			Line:     entry.Line,
+			Language: entry.Language,
+		})
+		byName[entry.Name] = append(byName[entry.Name], i)
+	}
+
+	// resolve finds the node named `name`, preferring one in `path` and then one
+	// that `accept`s, or -1.
+	resolve := func(name, path string, accept func(TagEntry) bool) int {
+		found := -1
+		for _, i := range byName[name] {
+			if !accept(entries[i]) {
+				continue
+			}
+			if entries[i].Path == path {
+				return i
+			}
+			if found < 0 {
+				found = i
+			}
+		}
+		return found
+	}
+	isContainer := func(entry TagEntry) bool { return !isCallableEntry(entry) }
+	seen := make(map[graphEdge]bool)
+	addEdge := func(from, to int, kind string) {
+		edge := graphEdge{From: graph.Nodes[from].ID, To: graph.Nodes[to].ID, Kind: kind}
+		if from != to && !seen[edge] {
+			seen[edge] = true
+			graph.Edges = append(graph.Edges, edge)
+		}
+	}
+
+	for i, entry := range entries {
+		if entry.Scope != "" {
+			if parent := resolve(unqualifiedName(entry.Scope), entry.Path, isContainer); parent >= 0 {
+				addEdge(parent, i, graphEdgeContains)
+			}
+		}
+		for _, base := range entry.Inherits.Names() {
+			if parent := resolve(unqualifiedName(base), entry.Path, isContainer); parent >= 0 {
+				addEdge(i, parent, graphEdgeInherits)
+			}
+		}
+	}
+
+	server.cache.mutex.RLock()
+	fallback := server.cache.encoding
+	server.cache.mutex.RUnlock()
+	for start := 0; start < len(entries); {
+		end := start
+		for end < len(entries) && entries[end].Path == entries[start].Path {
+			end++
+		}
+		addCallEdges(entries, start, end, fallback, byName, addEdge)
+		start = end
+	}
+	return graph
+}
+
+// addCallEdges adds the call edges of the callables in `entries[start:end]`,
+// which are the tags of one file in line order.
+func addCallEdges(entries []TagEntry, start, end int, fallback encoding.Encoding, byName map[string][]int, addEdge func(from, to int, kind string)) {
+	var callers []int
+	for i := start; i < end; i++ {
+		if isCallableEntry(entries[i]) && entries[i].Line > 0 {
+			callers = append(callers, i)
+		}
+	}
+	if len(callers) == 0 {
+		return
+	}
+	path := entries[start].Path
+	lines, err := readFileLines(path, fallback)
+	if err != nil {
+		return
+	}
+	syntax := syntaxForFile(path, entries[start].Language)
+
+	for n, caller := range callers {
+		first, last := entries[caller].Line-1, len(lines)
+		if n+1 < len(callers) {
+			last = entries[callers[n+1]].Line - 1
+		}
+		if first >= len(lines) || first >= last {
+			continue
+		}
+		body := lines[first:last]
+		forEachCodeIdentifier(body, syntax, func(identifier []rune, rng Range) {
+			if !isCallSite(body[rng.Start.Line], rng.End.Character) {
+				return
+			}
+			var local, other []int
+			for _, i := range byName[string(identifier)] {
+				if !isCallableEntry(entries[i]) {
+					continue
+				}
+				if entries[i].Path == path {
+					local = append(local, i)
+				} else {
+					other = append(other, i)
+				}
+			}
+			callee := -1
+			if len(local) == 1 {
+				callee = local[0]
+			} else if len(local) == 0 && len(other) == 1 {
+				callee = other[0]
+			}
+			if callee >= 0 {
+				addEdge(caller, callee, graphEdgeCalls)
+			}
+		})
+	}
+}
+
+// isCallSite reports whether the identifier ending at rune `end` of `line` is
+// followed by an opening parenthesis.
+func isCallSite(line string, end int) bool {
+	rest := []rune(line)[end:]
+	for _, c := range rest {
+		if !unicode.IsSpace(c) {
+			return c == '('
+		}
+	}
+	return false
+}
+
+// isCallableEntry reports whether `entry` is a function, method or constructor,
+// or has a signature, like the methods of Python classes, which ctags calls members.
+func isCallableEntry(entry TagEntry) bool {
+	if entry.Signature != "" {
+		return true
+	}
+	switch GetLSPSymbolKind(entry.Kind) {
+	case SymbolKindFunction, SymbolKindMethod, SymbolKindConstructor:
+		return true
+	}
+	return false
+}
+
+func writeGraphJSON(w io.Writer, graph symbolGraph) error {
+	if graph.Nodes == nil {
+		graph.Nodes = []graphNode{}
+	}
+	if graph.Edges == nil {
+		graph.Edges = []graphEdge{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
+
+// writeGraphDOT writes `graph` for Graphviz: symbols are labeled with their kind
+// and location, containment is drawn dashed and inheritance with hollow arrows.
+func writeGraphDOT(w io.Writer, graph symbolGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph symbols {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"monospace\"];\n")
+	for _, node := range graph.Nodes {
+		label := fmt.Sprintf("%s\n%s %s:%d", node.Name, node.Kind, node.Path, node.Line)
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", node.ID, dotQuote(label))
+	}
+	for _, edge := range graph.Edges {
+		style := ""
+		switch edge.Kind {
+		case graphEdgeContains:
+			style = " [style=dashed, arrowhead=none]"
+		case graphEdgeInherits:
+			style = " [arrowhead=empty]"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s%s;\n", edge.From, edge.To, style)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes `s` as a DOT string, with newlines as line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGraphCommand(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "shapes.py", `class Shape:
+    def area(self):
+        return 0
+
+class Square(Shape):
+    def area(self):
+        # side(self) in a comment is no call
+        return side(self) * side(self)
+
+def side(square):
+    return 2
+`)
+	tags := strings.Join([]string{
+		"Shape\tshapes.py\t/^class Shape:$/;\"\tkind:class\tline:1\tlanguage:Python",
+		"area\tshapes.py\t/^    def area(self):$/;\"\tkind:member\tline:2\tlanguage:Python\tscope:class:Shape\tsignature:(self)",
+		"Square\tshapes.py\t/^class Square(Shape):$/;\"\tkind:class\tline:5\tlanguage:Python\tinherits:Shape",
+		"area\tshapes.py\t/^    def area(self):$/;\"\tkind:member\tline:6\tlanguage:Python\tscope:class:Square\tsignature:(self)",
+		"side\tshapes.py\t/^def side(square):$/;\"\tkind:function\tline:10\tlanguage:Python\tsignature:(square)",
+	}, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(root, "tags"), []byte(tags), 0o644); err != nil {
+		t.Fatalf("write tags: %v", err)
+	}
+	noCtags := func(string) error { return nil }
+
+	var stdout, stderr bytes.Buffer
+	if code := run(Process{}, []string{"ctags-lsp", "graph", "json", root}, nil, &stdout, &stderr, noCtags); code != 0 {
+		t.Fatalf("graph json exited with %d: %s", code, stderr.String())
+	}
+	var graph symbolGraph
+	if err := json.Unmarshal(stdout.Bytes(), &graph); err != nil {
+		t.Fatalf("decode graph: %v", err)
+	}
+	names := make(map[string]string)
+	for _, node := range graph.Nodes {
+		names[node.ID] = fmt.Sprintf("%s@%s:%d", node.Name, node.Path, node.Line)
+	}
+	var edges []string
+	for _, edge := range graph.Edges {
+		edges = append(edges, names[edge.From]+" "+edge.Kind+" "+names[edge.To])
+	}
+	slices.Sort(edges)
+	want := []string{
+		"Shape@shapes.py:1 contains area@shapes.py:2",
+		"Square@shapes.py:5 contains area@shapes.py:6",
+		"Square@shapes.py:5 inherits Shape@shapes.py:1",
+		"area@shapes.py:6 calls side@shapes.py:10",
+	}
+	if !slices.Equal(edges, want) {
+		t.Fatalf("expected edges %q, got %q", want, edges)
+	}
+
+	stdout.Reset()
+	if code := run(Process{}, []string{"ctags-lsp", "graph", "dot", root}, nil, &stdout, &stderr, noCtags); code != 0 {
+		t.Fatalf("graph dot exited with %d: %s", code, stderr.String())
+	}
+	if dot := stdout.String(); !strings.HasPrefix(dot, "digraph symbols {") || !strings.Contains(dot, `[label="Square\nclass shapes.py:5"]`) || !strings.Contains(dot, "[arrowhead=empty]") {
+		t.Fatalf("unexpected DOT output:\n%s", dot)
+	}
+
+	if code := run(Process{}, []string{"ctags-lsp", "graph", "svg"}, nil, &stdout, &stderr, noCtags); code != 2 {
+		t.Fatalf("expected an unknown format to exit with 2, got %d", code)
+	}
+}
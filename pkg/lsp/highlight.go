@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"encoding/json"
+	"unicode"
+)
+
+// DocumentHighlightKind values, as defined by LSP 3.17.
+const (
+	DocumentHighlightKindText  = 1
+	DocumentHighlightKindRead  = 2
+	DocumentHighlightKindWrite = 3
+)
+
+type DocumentHighlight struct {
+	Range Range `json:"range"`
+	Kind  int   `json:"kind"`
+}
+
+// assignmentOperators are the operators that, after a name, assign to it. Longer
+// operators come first, so "<<=" isn't taken for a comparison.
+var assignmentOperators = []string{
+	"<<=", ">>=", "**=", "//=", "&&=", "||=", "??=", "&^=",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", ":=", ".=",
+	"=",
+}
+
+// handleDocumentHighlight returns the occurrences in the document of the
+// identifier at the cursor, outside comments and string literals. Unlike rename,
+// it doesn't need the index: any identifier is highlighted. Occurrences being
+// assigned to are writes, the others reads; see `isAssignmentTarget`.
+func handleDocumentHighlight(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	lines, err := server.cache.GetOrLoadFileContent(normalizedURI)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	occurrences := findCodeOccurrences(lines, unqualifiedName(symbol), syntaxForFile(normalizedURI, ""))
+	var highlights []DocumentHighlight
+	atCursor := false
+	for _, rng := range occurrences {
+		if rng.Start.Line == params.Position.Line && rng.Start.Character <= params.Position.Character && params.Position.Character <= rng.End.Character {
+			atCursor = true
+		}
+		kind := DocumentHighlightKindRead
+		if isAssignmentTarget([]rune(lines[rng.Start.Line]), rng.Start.Character, rng.End.Character) {
+			kind = DocumentHighlightKindWrite
+		}
+		highlights = append(highlights, DocumentHighlight{Range: rng, Kind: kind})
+	}
+	// The cursor is in a comment or string literal.
+	if !atCursor {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, highlights)
+}
+
+// isAssignmentTarget guesses whether the name at `line[start:end]` is assigned
+// to: followed by an assignment operator, such as "=", "+=" or ":=", but not
+// "==" or "=>", or right before or after "++" or "--". Assignments to several
+// names at once, like "a, b = f()", only count for the last one.
+func isAssignmentTarget(line []rune, start, end int) bool {
+	for _, operator := range []string{"++", "--"} {
+		if start >= 2 && hasRunePrefix(line[start-2:], operator) || hasRunePrefix(line[end:], operator) {
+			return true
+		}
+	}
+	i := end
+	for i < len(line) && unicode.IsSpace(line[i]) {
+		i++
+	}
+	for _, operator := range assignmentOperators {
+		if !hasRunePrefix(line[i:], operator) {
+			continue
+		}
+		if operator == "=" {
+			next := i + 1
+			return next >= len(line) || (line[next] != '=' && line[next] != '>' && line[next] != '~')
+		}
+		return true
+	}
+	return false
+}
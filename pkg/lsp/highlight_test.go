@@ -0,0 +1,41 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDocumentHighlight(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "count.js", "let count = 0;\nif (count == 1) count += 2; // count\ncount++;\nlog(\"count\", count);\n")
+	server := newTestServer(t, nil)
+
+	highlight := func(position Position) []DocumentHighlight {
+		frames := callHandler(t, server, "textDocument/documentHighlight", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     position,
+		})
+		var highlights []DocumentHighlight
+		if err := json.Unmarshal(frames[0].Result, &highlights); err != nil {
+			t.Fatalf("decode highlights %s: %v", frames[0].Result, err)
+		}
+		return highlights
+	}
+
+	highlights := highlight(Position{Line: 3, Character: 16})
+	expected := []DocumentHighlight{
+		{Range: Range{Start: Position{Line: 0, Character: 4}, End: Position{Line: 0, Character: 9}}, Kind: DocumentHighlightKindWrite},
+		{Range: Range{Start: Position{Line: 1, Character: 4}, End: Position{Line: 1, Character: 9}}, Kind: DocumentHighlightKindRead},
+		{Range: Range{Start: Position{Line: 1, Character: 16}, End: Position{Line: 1, Character: 21}}, Kind: DocumentHighlightKindWrite},
+		{Range: Range{Start: Position{Line: 2, Character: 0}, End: Position{Line: 2, Character: 5}}, Kind: DocumentHighlightKindWrite},
+		{Range: Range{Start: Position{Line: 3, Character: 13}, End: Position{Line: 3, Character: 18}}, Kind: DocumentHighlightKindRead},
+	}
+	if !slices.Equal(highlights, expected) {
+		t.Fatalf("expected highlights %+v, got %+v", expected, highlights)
+	}
+
+	if highlights := highlight(Position{Line: 1, Character: 33}); highlights != nil {
+		t.Fatalf("expected no highlights in a comment, got %+v", highlights)
+	}
+}
@@ -0,0 +1,149 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// handleHover shows the definition the symbol under the cursor jumps to, and how
+// many other definitions of the name the index holds.
+func handleHover(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	server.mutex.Lock()
+	hover, entry, ok := server.hoverAt(normalizedURI, params.Position)
+	server.mutex.Unlock()
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	// git runs without the lock, which other requests need meanwhile.
+	if server.getOptions().hoverBlame {
+		if summary := server.blameSummary(entry.Path, entry.Line); summary != "" {
+			hover.Contents.Value += "\n\n" + summary
+		}
+	}
+	server.sendResult(req.ID, hover)
+}
+
+// hoverAt builds the hover for the symbol at `position` and returns the
+// definition it shows, or false if there is none.
+// The caller holds `server.mutex`.
+func (server *Server) hoverAt(uri string, position Position) (Hover, TagEntry, bool) {
+	symbol, err := server.getCurrentWord(uri, position)
+	if err != nil {
+		return Hover{}, TagEntry{}, false
+	}
+	matches := server.findDefinitionEntries(uri, position, symbol)
+	if len(matches) == 0 {
+		return Hover{}, TagEntry{}, false
+	}
+
+	entry := matches[0]
+	var docstring string
+	if lines, err := server.cache.GetOrLoadFileContent(entry.Path); err == nil {
+		docstring = extractDocstring(lines, entry)
+	}
+	contents := server.clientCapabilities.hoverContent(entry, docstring)
+	if summary := server.definitionCountSummary(uri, entry.Name); summary != "" {
+		contents.Value += "\n\n" + summary
+	}
+
+	hover := Hover{Contents: *contents}
+	if wordRange, err := server.getCurrentWordRange(uri, position); err == nil {
+		hover.Range = &wordRange
+	}
+	return hover, entry, true
+}
+
+// hoverMetadata describes the kind, scope and type of `entry` in one line, e.g.
+// "*member* in class `Shape::Circle`, type `float`", or without markup.
+func hoverMetadata(entry TagEntry, markdown bool) string {
+	code := func(s string) string {
+		if markdown {
+			return "`" + s + "`"
+		}
+		return s
+	}
+
+	var parts []string
+	if entry.Kind != "" {
+		if markdown {
+			parts = append(parts, "*"+entry.Kind+"*")
+		} else {
+			parts = append(parts, entry.Kind)
+		}
+	}
+	if entry.Scope != "" {
+		parts = append(parts, "in")
+		if entry.ScopeKind != "" {
+			parts = append(parts, entry.ScopeKind)
+		}
+		parts = append(parts, code(containerName(entry)))
+	}
+	description := strings.Join(parts, " ")
+	if kind, name, ok := strings.Cut(entry.TypeRef, ":"); ok && name != "" {
+		if kind != "typename" {
+			name = kind + " " + name
+		}
+		description = strings.TrimPrefix(description+", type "+code(name), ", ")
+	}
+	return description
+}
+
+// definitionCountSummary describes how many places define `name`, e.g.
+// "3 definitions (2 in other languages)", counting tags outside the family of
+// `uri` (see `documentFamily`) as other languages. It returns "" for names
+// defined once.
+// The caller holds `server.mutex`.
+func (server *Server) definitionCountSummary(uri, name string) string {
+	type place struct {
+		path string
+		line int
+	}
+
+	family := server.documentFamily(uri)
+	seen := make(map[place]bool)
+	others := 0
+	for _, entry := range server.tagEntries {
+		if entry.Name != name || isQualifiedTag(entry) {
+			continue
+		}
+		key := place{path: uriKey(entry.Path), line: entry.Line}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !family.includes(entry) {
+			others++
+		}
+	}
+
+	switch {
+	case len(seen) < 2:
+		return ""
+	case others == 1:
+		return fmt.Sprintf("%d definitions (1 in another language)", len(seen))
+	case others > 1:
+		return fmt.Sprintf("%d definitions (%d in other languages)", len(seen), others)
+	default:
+		return fmt.Sprintf("%d definitions", len(seen))
+	}
+}
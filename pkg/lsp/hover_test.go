@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHoverCountsDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	goURI := writeTestFile(t, dir, "main.go", "package main\n\n// render draws the frame.\nfunc render() {}\n\nfunc main() { render() }\n")
+	pyURI := writeTestFile(t, dir, "render.py", "def render():\n    pass\n")
+	jsURI := writeTestFile(t, dir, "render.js", "function render() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "render", Path: goURI, Line: 4, Kind: "func", Language: "Go", Pattern: "/^func render() {}$/"},
+		{Name: "render", Path: pyURI, Line: 1, Kind: "function", Language: "Python", Pattern: "/^def render():$/"},
+		{Name: "render", Path: jsURI, Line: 1, Kind: "function", Language: "JavaScript", Pattern: "/^function render() {}$/"},
+	})
+	server.clientCapabilities.TextDocument.Hover.ContentFormat = []string{"markdown"}
+
+	frames := callHandler(t, server, "textDocument/hover", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: goURI},
+		Position:     Position{Line: 5, Character: 15},
+	})
+	var hover Hover
+	if err := json.Unmarshal(frames[0].Result, &hover); err != nil {
+		t.Fatalf("unmarshal hover: %v", err)
+	}
+	want := "```go\nfunc render() {}\n```\n\n*func*\n\nrender draws the frame.\n\n3 definitions (2 in other languages)"
+	if hover.Contents.Kind != "markdown" || hover.Contents.Value != want {
+		t.Fatalf("unexpected hover %+v", hover.Contents)
+	}
+	if hover.Range == nil || hover.Range.Start.Character != 14 {
+		t.Fatalf("expected the range of the hovered word, got %+v", hover.Range)
+	}
+
+	server.tagEntries = server.tagEntries[:1]
+	frames = callHandler(t, server, "textDocument/hover", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: goURI},
+		Position:     Position{Line: 5, Character: 15},
+	})
+	if err := json.Unmarshal(frames[0].Result, &hover); err != nil {
+		t.Fatalf("unmarshal hover: %v", err)
+	}
+	if strings.Contains(hover.Contents.Value, "definitions") {
+		t.Fatalf("expected no count for a single definition, got %q", hover.Contents.Value)
+	}
+}
+
+func TestHoverMetadata(t *testing.T) {
+	tests := []struct {
+		entry    TagEntry
+		markdown bool
+		want     string
+	}{
+		{TagEntry{Kind: "function"}, true, "*function*"},
+		{TagEntry{Kind: "member", Scope: "Shape.Circle", ScopeKind: "class", TypeRef: "typename:float", Language: "C++"}, true, "*member* in class `Shape::Circle`, type `float`"},
+		{TagEntry{Kind: "typedef", TypeRef: "struct:point"}, false, "typedef, type struct point"},
+		{TagEntry{TypeRef: "typename:int"}, true, "type `int`"},
+		{TagEntry{}, true, ""},
+	}
+	for _, test := range tests {
+		if got := hoverMetadata(test.entry, test.markdown); got != test.want {
+			t.Errorf("hoverMetadata(%+v, %v) = %q, want %q", test.entry, test.markdown, got, test.want)
+		}
+	}
+}
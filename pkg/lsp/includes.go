@@ -0,0 +1,226 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// includeStatement matches lines that name another file: C's #include, import,
+// require and friends. The target itself is the quoted (or <bracketed>) string.
+var includeStatement = regexp.MustCompile(`(^\s*#\s*(include|import)\b)|\b(import|from|require|require_relative|load|source|include|use)\b|@import\b`)
+
+// includeQuotes pairs the opening and closing delimiters of include targets.
+var includeQuotes = map[rune]rune{'"': '"', '\'': '\'', '`': '`', '<': '>'}
+
+// includeTarget is a file path quoted in an include statement.
+type includeTarget struct {
+	path string
+	// system targets are written in <angle brackets>, which C doesn't look up next
+	// to the including file.
+	system bool
+	// rng covers the path without its quotes.
+	rng Range
+}
+
+// includeTargets returns the quoted file paths on `line` if it is an include statement.
+func includeTargets(line string, lineIdx int) []includeTarget {
+	if !includeStatement.MatchString(line) {
+		return nil
+	}
+
+	var targets []includeTarget
+	runes := []rune(line)
+	for start := 0; start < len(runes); start++ {
+		closing, ok := includeQuotes[runes[start]]
+		if !ok {
+			continue
+		}
+		end := start + 1
+		for end < len(runes) && runes[end] != closing {
+			end++
+		}
+		if end == len(runes) {
+			break
+		}
+		if end > start+1 {
+			targets = append(targets, includeTarget{
+				path:   string(runes[start+1 : end]),
+				system: runes[start] == '<',
+				rng: Range{
+					Start: Position{Line: lineIdx, Character: start + 1},
+					End:   Position{Line: lineIdx, Character: end},
+				},
+			})
+		}
+		start = end
+	}
+	return targets
+}
+
+// includeTargetAt returns the include target around `character`, counting the
+// closing quote so that a cursor right after the path still hits it.
+func includeTargetAt(line string, lineIdx, character int) (includeTarget, bool) {
+	for _, target := range includeTargets(line, lineIdx) {
+		if character >= target.rng.Start.Character && character <= target.rng.End.Character {
+			return target, true
+		}
+	}
+	return includeTarget{}, false
+}
+
+// resolveIncludeTarget finds the file an include target names. Package names
+// imported by JavaScript and TypeScript are looked up in node_modules first (see
+// `resolveNodeModule`). Then it tries the directory of the including file (unless
+// `system` is set), the workspace root, the configured include paths and those of
+// the compilation database in order, each with and without the including file's
+// extension, and finally any indexed file whose path ends with the target. The
+// first match wins, so include paths decide between headers of the same name.
+// The caller holds `server.mutex`.
+func (server *Server) resolveIncludeTarget(fromURI, target string, system bool) (string, bool) {
+	fromPath := fileURIToPath(fromURI)
+	if slices.Contains(nodeExtensions, strings.ToLower(filepath.Ext(fromPath))) && isBareSpecifier(target) {
+		if path, ok := resolveNodeModule(fromPath, target); ok {
+			return pathToFileURI(path), true
+		}
+	}
+
+	target = filepath.FromSlash(target)
+	rootDir := fileURIToPath(server.rootURI)
+
+	var dirs []string
+	if !system {
+		dirs = append(dirs, filepath.Dir(fromPath))
+	}
+	dirs = append(dirs, rootDir)
+	for _, dir := range server.getOptions().includePaths {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rootDir, dir)
+		}
+		dirs = append(dirs, dir)
+	}
+	if server.compilationDB != nil {
+		dirs = append(dirs, server.compilationDB.includeDirs...)
+	}
+
+	names := []string{target}
+	if ext := filepath.Ext(fromPath); ext != "" && filepath.Ext(target) != ext {
+		names = append(names, target+ext)
+	}
+
+	for _, dir := range dirs {
+		for _, name := range names {
+			path := name
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, name)
+			}
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return pathToFileURI(path), true
+			}
+		}
+	}
+
+	suffix := "/" + strings.TrimPrefix(filepath.ToSlash(filepath.Clean(target)), "/")
+	for _, entry := range server.tagEntries {
+		if strings.HasSuffix(entry.Path, suffix) {
+			return entry.Path, true
+		}
+	}
+	return "", false
+}
+
+// includeDefinition returns the location of the file named by the include target
+// at `pos`, and the range of the target, if there is one.
+func (server *Server) includeDefinition(uri string, pos Position) (Location, Range, bool) {
+	lines, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil || pos.Line >= len(lines) {
+		return Location{}, Range{}, false
+	}
+	target, ok := includeTargetAt(lines[pos.Line], pos.Line, pos.Character)
+	if !ok {
+		return Location{}, Range{}, false
+	}
+	targetURI, ok := server.resolveIncludeTarget(uri, target.path, target.system)
+	if !ok {
+		return Location{}, Range{}, false
+	}
+	return Location{URI: targetURI}, target.rng, true
+}
+
+type DocumentLinkOptions struct {
+	ResolveProvider bool `json:"resolveProvider"`
+}
+
+type DocumentLinkParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentLink struct {
+	Range  Range             `json:"range"`
+	Target string            `json:"target,omitempty"`
+	Data   *DocumentLinkData `json:"data,omitempty"`
+}
+
+// DocumentLinkData is round-tripped through the client to `documentLink/resolve`.
+type DocumentLinkData struct {
+	URI    string `json:"uri"`
+	Path   string `json:"path"`
+	System bool   `json:"system,omitempty"`
+}
+
+// handleDocumentLink lists the include targets of a document. Finding the file
+// behind each one may take several lookups, so that is left to `documentLink/resolve`.
+func handleDocumentLink(server *Server, req RPCRequest) {
+	var params DocumentLinkParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	links := []DocumentLink{}
+	lines, err := server.cache.GetOrLoadFileContent(normalizedURI)
+	if err != nil {
+		server.sendResult(req.ID, links)
+		return
+	}
+	for lineIdx, line := range lines {
+		for _, target := range includeTargets(line, lineIdx) {
+			links = append(links, DocumentLink{
+				Range: target.rng,
+				Data:  &DocumentLinkData{URI: normalizedURI, Path: target.path, System: target.system},
+			})
+		}
+	}
+	server.sendResult(req.ID, links)
+}
+
+// handleDocumentLinkResolve fills in the target of a link. Links whose file can't
+// be found are returned unchanged, which clients show as not navigable.
+func handleDocumentLinkResolve(server *Server, req RPCRequest) {
+	var link DocumentLink
+	if err := json.Unmarshal(req.Params, &link); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+	if link.Data == nil {
+		server.sendResult(req.ID, link)
+		return
+	}
+
+	server.mutex.Lock()
+	target, ok := server.resolveIncludeTarget(link.Data.URI, link.Data.Path, link.Data.System)
+	server.mutex.Unlock()
+	if ok {
+		link.Target = target
+	}
+	server.sendResult(req.ID, link)
+}
@@ -0,0 +1,91 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefinitionOfIncludeTarget(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "util.h", "int util(void);\n")
+	if err := os.MkdirAll(filepath.Join(dir, "vendor"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	library := writeTestFile(t, dir, "vendor/lib.h", "int lib(void);\n")
+	module := writeTestFile(t, dir, "mod.js", "export const x = 1;\n")
+	source := writeTestFile(t, dir, "main.c", "#include \"util.h\"\n#include <lib.h>\n#include <missing.h>\n")
+	script := writeTestFile(t, dir, "main.js", "import { x } from './mod';\n")
+
+	server := newTestServer(t, nil)
+	server.rootURI = pathToFileURI(dir)
+	server.options.includePaths = []string{"vendor"}
+
+	for _, test := range []struct {
+		uri  string
+		pos  Position
+		want string
+	}{
+		{source, Position{Line: 0, Character: 12}, header},
+		{source, Position{Line: 1, Character: 11}, library},
+		{source, Position{Line: 2, Character: 11}, ""},
+		{script, Position{Line: 0, Character: 21}, module},
+	} {
+		frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: test.uri},
+			Position:     test.pos,
+		})
+		var location *Location
+		if err := json.Unmarshal(frames[0].Result, &location); err != nil {
+			t.Fatalf("unmarshal location: %v", err)
+		}
+		if test.want == "" {
+			if location != nil {
+				t.Fatalf("expected no definition at %+v, got %+v", test.pos, location)
+			}
+			continue
+		}
+		if location == nil || location.URI != test.want || location.Range.Start.Line != 0 {
+			t.Fatalf("expected %s at %+v, got %+v", test.want, test.pos, location)
+		}
+	}
+}
+
+func TestDocumentLinkResolve(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"src", "include"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	local := writeTestFile(t, dir, "src/config.h", "")
+	system := writeTestFile(t, dir, "include/config.h", "")
+	source := writeTestFile(t, dir, "src/main.c", "#include \"config.h\"\n#include <config.h>\nint main;\n")
+
+	server := newTestServer(t, nil)
+	server.rootURI = pathToFileURI(dir)
+	server.options.includePaths = []string{"include"}
+
+	frames := callHandler(t, server, "textDocument/documentLink", DocumentLinkParams{
+		TextDocument: TextDocumentIdentifier{URI: source},
+	})
+	var links []DocumentLink
+	if err := json.Unmarshal(frames[0].Result, &links); err != nil {
+		t.Fatalf("unmarshal links: %v", err)
+	}
+	if len(links) != 2 || links[0].Target != "" {
+		t.Fatalf("expected two unresolved links, got %+v", links)
+	}
+
+	for i, want := range []string{local, system} {
+		frames := callHandler(t, server, "documentLink/resolve", links[i])
+		var resolved DocumentLink
+		if err := json.Unmarshal(frames[0].Result, &resolved); err != nil {
+			t.Fatalf("unmarshal link: %v", err)
+		}
+		if resolved.Target != want {
+			t.Fatalf("expected link %d to resolve to %s, got %+v", i, want, resolved)
+		}
+	}
+}
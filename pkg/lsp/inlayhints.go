@@ -0,0 +1,135 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// InlayHintKind values, as defined by LSP 3.17.
+const (
+	InlayHintKindType      = 1
+	InlayHintKindParameter = 2
+)
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type InlayHint struct {
+	Position    Position `json:"position"`
+	Label       string   `json:"label"`
+	Kind        int      `json:"kind,omitempty"`
+	PaddingLeft bool     `json:"paddingLeft,omitempty"`
+}
+
+// inlayHintKinds are the symbol kinds of the tags whose `typeref` field is shown
+// as their type, besides callables, whose field is their return type.
+var inlayHintKinds = map[int]bool{
+	SymbolKindVariable: true,
+	SymbolKindConstant: true,
+	SymbolKindField:    true,
+	SymbolKindProperty: true,
+}
+
+// handleInlayHint shows, with `--inlay-hints`, the type ctags recorded for the
+// variables, constants and fields of the range after their name, and the return
+// type of functions after their parameter list. Hints are left out when the type
+// is already written on the line, as in C, so they only show what inference or a
+// declaration elsewhere hides.
+func handleInlayHint(server *Server, req RPCRequest) {
+	var params InlayHintParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	hints := []InlayHint{}
+	if !server.getOptions().inlayHints {
+		server.sendResult(req.ID, hints)
+		return
+	}
+	lines, err := server.cache.GetOrLoadFileContent(normalizedURI)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	entries := entriesForURI(server.tagEntries, normalizedURI)
+	server.mutex.Unlock()
+
+	for _, entry := range entries {
+		_, typeName, ok := strings.Cut(entry.TypeRef, ":")
+		typeName = strings.TrimSpace(typeName)
+		if !ok || typeName == "" || isQualifiedTag(entry) || typeAliasKinds[entry.Kind] {
+			continue
+		}
+		if entry.Line-1 < params.Range.Start.Line || entry.Line-1 > params.Range.End.Line || entry.Line > len(lines) {
+			continue
+		}
+		line := []rune(lines[entry.Line-1])
+		if containsWords(line, []rune(typeName)) {
+			continue
+		}
+		rng := findEntryRange(lines, entry)
+		if rng.Start.Line != entry.Line-1 {
+			continue
+		}
+
+		if isCallableEntry(entry) {
+			end, ok := closingParen(line, rng.End.Character)
+			if !ok {
+				continue
+			}
+			hints = append(hints, InlayHint{
+				Position:    Position{Line: rng.Start.Line, Character: end},
+				Label:       "-> " + typeName,
+				Kind:        InlayHintKindType,
+				PaddingLeft: true,
+			})
+		} else if inlayHintKinds[GetLSPSymbolKind(entry.Kind)] {
+			hints = append(hints, InlayHint{Position: rng.End, Label: ": " + typeName, Kind: InlayHintKindType})
+		}
+	}
+	server.sendResult(req.ID, hints)
+}
+
+// closingParen returns the position after the ")" closing the first "(" at or
+// after `from` in `line`, if both are on the line.
+func closingParen(line []rune, from int) (int, bool) {
+	depth := 0
+	for i := from; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// containsWords reports whether `text` appears in `line` as whole words.
+func containsWords(line, text []rune) bool {
+	for i := 0; i+len(text) <= len(line); i++ {
+		if string(line[i:i+len(text)]) != string(text) {
+			continue
+		}
+		before := i == 0 || !isIdentifierChar(line[i-1]) || !isIdentifierChar(text[0])
+		after := i+len(text) == len(line) || !isIdentifierChar(line[i+len(text)]) || !isIdentifierChar(text[len(text)-1])
+		if before && after {
+			return true
+		}
+	}
+	return false
+}
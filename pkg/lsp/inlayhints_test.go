@@ -0,0 +1,41 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestInlayHint(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "sum.ts", "let total = sum(items);\nfunction sum(xs) {\n  const n: number = 1;\n}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "total", Path: uri, Line: 1, Kind: "variable", Language: "TypeScript", TypeRef: "typename:number"},
+		{Name: "sum", Path: uri, Line: 2, End: 4, Kind: "function", Language: "TypeScript", Signature: "(xs)", TypeRef: "typename:number"},
+		{Name: "n", Path: uri, Line: 3, Kind: "constant", Language: "TypeScript", TypeRef: "typename:number"},
+	})
+
+	hints := func() []InlayHint {
+		frames := callHandler(t, server, "textDocument/inlayHint", InlayHintParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Range:        Range{End: Position{Line: 4}},
+		})
+		var hints []InlayHint
+		if err := json.Unmarshal(frames[0].Result, &hints); err != nil {
+			t.Fatalf("decode inlay hints %s: %v", frames[0].Result, err)
+		}
+		return hints
+	}
+	if got := hints(); len(got) != 0 {
+		t.Fatalf("expected no hints without --inlay-hints, got %+v", got)
+	}
+
+	server.options.inlayHints = true
+	expected := []InlayHint{
+		{Position: Position{Line: 0, Character: 9}, Label: ": number", Kind: InlayHintKindType},
+		{Position: Position{Line: 1, Character: 16}, Label: "-> number", Kind: InlayHintKindType, PaddingLeft: true},
+	}
+	if got := hints(); !slices.Equal(got, expected) {
+		t.Fatalf("expected hints %+v, got %+v", expected, got)
+	}
+}
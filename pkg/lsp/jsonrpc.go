@@ -0,0 +1,373 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"mime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RPCRequest is any inbound message. Responses to server-initiated requests
+// carry `Result` or `Error` and no `Method`.
+type RPCRequest struct {
+	Jsonrpc string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// RPCOutgoingRequest is a request sent from the server to the client.
+type RPCOutgoingRequest struct {
+	Jsonrpc string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+const clientRequestTimeout = 30 * time.Second
+
+type RPCSuccessResponse struct {
+	Jsonrpc string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  any              `json:"result"`
+}
+
+type RPCErrorResponse struct {
+	Jsonrpc string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *RPCError        `json:"error"`
+}
+
+type RPCNotification struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// readMessage parses a single JSON-RPC message framed by `Content-Length` headers.
+// It validates the request `id` shape (string or integer) when present.
+func readMessage(reader *bufio.Reader) (RPCRequest, error) {
+	body, err := readFrame(reader)
+	if err != nil {
+		return RPCRequest{}, err
+	}
+	return parseMessage(body)
+}
+
+// readFrame reads the headers and raw body of a single framed message.
+// Header lines may end in "\r\n" or a bare "\n", names are matched case-insensitively,
+// and unknown headers are skipped. A `Content-Type` charset other than UTF-8 is rejected
+// after the body is consumed, so the stream stays in sync for the next message.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+	sawHeader := false
+	var headerErr error
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading header: %w", err)
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+		if line == "" {
+			if !sawHeader {
+				// Tolerate stray blank lines between messages.
+				continue
+			}
+			break
+		}
+		sawHeader = true
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "content-length":
+			cl, err := strconv.Atoi(value)
+			if err != nil || cl < 0 {
+				return nil, fmt.Errorf("invalid Content-Length: %q", value)
+			}
+			contentLength = cl
+		case "content-type":
+			if err := validateContentType(value); err != nil {
+				headerErr = err
+			}
+		}
+	}
+
+	if contentLength < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	_, err := io.ReadFull(reader, body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading body: %w", err)
+	}
+	if headerErr != nil {
+		return nil, headerErr
+	}
+	return body, nil
+}
+
+// validateContentType accepts any media type as long as its charset, if given, is UTF-8.
+// "utf8" is accepted for backwards compatibility, as the LSP specification allows.
+func validateContentType(value string) error {
+	_, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return fmt.Errorf("invalid Content-Type %q: %v", value, err)
+	}
+	charset, ok := params["charset"]
+	if !ok {
+		return nil
+	}
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return nil
+	default:
+		return fmt.Errorf("unsupported Content-Type charset %q", charset)
+	}
+}
+
+// parseMessage decodes a frame body and validates the request `id` shape.
+func parseMessage(body []byte) (RPCRequest, error) {
+	var req RPCRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return RPCRequest{}, fmt.Errorf("invalid JSON-RPC request: %v", err)
+	}
+	if isInvalidID(req.ID) {
+		return RPCRequest{}, fmt.Errorf("id must be a string or integer")
+	}
+
+	return req, nil
+}
+
+func isInvalidID(id *json.RawMessage) bool {
+	if id == nil {
+		return false
+	}
+
+	var s string
+	if json.Unmarshal(*id, &s) == nil {
+		return false
+	}
+
+	var n int64
+	if json.Unmarshal(*id, &n) == nil {
+		return false
+	}
+
+	return true
+}
+
+func isNotification(req RPCRequest) bool {
+	return req.ID == nil
+}
+
+func isResponse(req RPCRequest) bool {
+	return req.ID != nil && req.Method == ""
+}
+
+// sendRequest sends a request to the client and blocks until its response arrives.
+// It must not be called from the read loop, which is what delivers the response.
+func (server *Server) sendRequest(method string, params any) (json.RawMessage, error) {
+	id := server.nextRequestID.Add(1)
+	key := strconv.FormatInt(id, 10)
+	responses := make(chan RPCRequest, 1)
+
+	server.clientRequestsMutex.Lock()
+	if server.clientRequests == nil {
+		server.clientRequests = make(map[string]chan RPCRequest)
+	}
+	server.clientRequests[key] = responses
+	server.clientRequestsMutex.Unlock()
+
+	defer func() {
+		server.clientRequestsMutex.Lock()
+		delete(server.clientRequests, key)
+		server.clientRequestsMutex.Unlock()
+	}()
+
+	server.sendResponse(RPCOutgoingRequest{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+
+	select {
+	case resp := <-responses:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s (%d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		return resp.Result, nil
+	case <-time.After(clientRequestTimeout):
+		return nil, fmt.Errorf("%s: no response from client after %s", method, clientRequestTimeout)
+	}
+}
+
+// handleClientResponse routes a response to the pending `sendRequest` call, if any.
+func (server *Server) handleClientResponse(resp RPCRequest) {
+	server.clientRequestsMutex.Lock()
+	responses, ok := server.clientRequests[string(*resp.ID)]
+	server.clientRequestsMutex.Unlock()
+	if !ok {
+		slog.Debug("dropping response to unknown request", requestIDAttr(resp))
+		return
+	}
+	select {
+	case responses <- resp:
+	default:
+	}
+}
+
+func (server *Server) sendResult(id *json.RawMessage, result any) {
+	response := RPCSuccessResponse{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Result:  result,
+	}
+	server.sendResponse(response)
+}
+
+func (server *Server) sendError(id *json.RawMessage, code int, message string, data any) {
+	slog.Warn("request failed", requestIDAttr(RPCRequest{ID: id}), "code", code, "error", message, "data", data)
+
+	response := RPCErrorResponse{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Error: &RPCError{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	}
+	server.sendResponse(response)
+}
+
+func (server *Server) sendNotification(method string, params any) {
+	notification := RPCNotification{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	server.sendResponse(notification)
+}
+
+// Numeric values match LSP 3.17 `MessageType`.
+const (
+	MessageTypeError   = 1
+	MessageTypeWarning = 2
+	MessageTypeInfo    = 3
+	MessageTypeLog     = 4
+)
+
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+func (server *Server) showMessage(messageType int, message string) {
+	server.sendNotification("window/showMessage", ShowMessageParams{Type: messageType, Message: message})
+}
+
+type LogMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
+
+func (server *Server) logMessage(messageType int, message string) {
+	server.sendNotification("window/logMessage", LogMessageParams{Type: messageType, Message: message})
+}
+
+// outputBufferSize is the size of the buffer in front of `server.output`.
+const outputBufferSize = 64 << 10
+
+// maxPooledMessage caps the messages whose encoder goes back into the pool, so one
+// huge response doesn't pin its buffer for the rest of the session.
+const maxPooledMessage = 1 << 20
+
+// messageEncoder encodes outgoing messages into a reusable buffer.
+type messageEncoder struct {
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+	header  []byte
+}
+
+// messageEncoders pools encoders, since completion storms send many responses in
+// quick succession.
+var messageEncoders = sync.Pool{New: func() any {
+	encoder := &messageEncoder{}
+	encoder.encoder = json.NewEncoder(&encoder.buffer)
+	return encoder
+}}
+
+// encode returns `message` as JSON. The result is only valid until the encoder
+// is used again.
+func (encoder *messageEncoder) encode(message any) ([]byte, error) {
+	encoder.buffer.Reset()
+	if err := encoder.encoder.Encode(message); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(encoder.buffer.Bytes(), []byte("\n")), nil
+}
+
+// frameHeader returns the header framing a body of `length` bytes.
+func (encoder *messageEncoder) frameHeader(length int) []byte {
+	encoder.header = append(encoder.header[:0], "Content-Length: "...)
+	encoder.header = strconv.AppendInt(encoder.header, int64(length), 10)
+	encoder.header = append(encoder.header, "\r\n\r\n"...)
+	return encoder.header
+}
+
+// sendResponse writes a JSON-RPC response to `server.output`. Writes go through
+// a buffer that is flushed by the last of the messages waiting to be written, so
+// a burst of responses takes few writes while a lone one goes out immediately.
+func (server *Server) sendResponse(resp any) {
+	encoder := messageEncoders.Get().(*messageEncoder)
+	defer func() {
+		if encoder.buffer.Cap() <= maxPooledMessage {
+			messageEncoders.Put(encoder)
+		}
+	}()
+	body, err := encoder.encode(resp)
+	if err != nil {
+		log.Printf("Error marshaling response: %v", err)
+		return
+	}
+	body = server.limitResponseSize(resp, body)
+	if server.foreignClientPaths.Load() {
+		body = toForeignURIs(body)
+	}
+
+	server.queuedWrites.Add(1)
+	server.outputMutex.Lock()
+	defer server.outputMutex.Unlock()
+	server.rpcLog.record(rpcDirectionOut, body)
+	if server.outputWriter == nil || server.outputTarget != server.output {
+		server.outputWriter = bufio.NewWriterSize(server.output, outputBufferSize)
+		server.outputTarget = server.output
+	}
+	server.outputWriter.Write(encoder.frameHeader(len(body)))
+	server.outputWriter.Write(body)
+	if server.queuedWrites.Add(-1) == 0 {
+		server.outputWriter.Flush()
+	}
+}
@@ -0,0 +1,132 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadFrameHeaders(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"shutdown"}`
+	length := "Content-Length: " + strconv.Itoa(len(body))
+
+	cases := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "crlf", raw: length + "\r\n\r\n" + body},
+		{name: "bare lf", raw: length + "\n\n" + body},
+		{name: "mixed endings", raw: length + "\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\n\r\n" + body},
+		{name: "lowercase names", raw: "content-length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body},
+		{name: "utf8 alias", raw: length + "\r\nContent-Type: application/vscode-jsonrpc; charset=utf8\r\n\r\n" + body},
+		{name: "unknown header", raw: "X-Trace: abc\r\n" + length + "\r\nnot a header\r\n\r\n" + body},
+		{name: "leading blank line", raw: "\r\n" + length + "\r\n\r\n" + body},
+		{name: "unsupported charset", raw: length + "\r\nContent-Type: application/json; charset=latin1\r\n\r\n" + body, wantErr: "unsupported Content-Type charset"},
+		{name: "missing length", raw: "Content-Type: application/json\r\n\r\n" + body, wantErr: "missing Content-Length"},
+		{name: "negative length", raw: "Content-Length: -1\r\n\r\n", wantErr: "invalid Content-Length"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readFrame(bufio.NewReader(strings.NewReader(tc.raw)))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read frame: %v", err)
+			}
+			if string(got) != body {
+				t.Fatalf("expected body %q, got %q", body, got)
+			}
+		})
+	}
+}
+
+func TestReadFrameKeepsStreamInSyncAfterCharsetError(t *testing.T) {
+	first := `{"jsonrpc":"2.0","id":1,"method":"a"}`
+	second := `{"jsonrpc":"2.0","id":2,"method":"b"}`
+	raw := "Content-Length: " + strconv.Itoa(len(first)) + "\r\nContent-Type: application/json; charset=utf-16\r\n\r\n" + first +
+		"Content-Length: " + strconv.Itoa(len(second)) + "\r\n\r\n" + second
+
+	reader := bufio.NewReader(strings.NewReader(raw))
+	if _, err := readFrame(reader); err == nil {
+		t.Fatal("expected charset error for the first message")
+	}
+	got, err := readFrame(reader)
+	if err != nil {
+		t.Fatalf("read second frame: %v", err)
+	}
+	if string(got) != second {
+		t.Fatalf("expected %q, got %q", second, got)
+	}
+}
+
+func TestSendResponseFramesConcurrentMessages(t *testing.T) {
+	server := newTestServer(t, nil)
+	var output bytes.Buffer
+	server.output = &output
+
+	const senders = 50
+	var wg sync.WaitGroup
+	for i := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := json.RawMessage(strconv.Itoa(i))
+			server.sendResult(&id, strings.Repeat("<x>", i))
+		}()
+	}
+	wg.Wait()
+
+	frames := readFrames(t, &output)
+	if len(frames) != senders {
+		t.Fatalf("expected %d frames, got %d", senders, len(frames))
+	}
+	seen := make(map[string]bool)
+	for _, frame := range frames {
+		id, err := strconv.Atoi(string(frame.ID))
+		if err != nil {
+			t.Fatalf("parse id %q: %v", frame.ID, err)
+		}
+		var result string
+		if err := json.Unmarshal(frame.Result, &result); err != nil {
+			t.Fatalf("decode result of %d: %v", id, err)
+		}
+		if result != strings.Repeat("<x>", id) {
+			t.Fatalf("expected the result of %d to repeat <x> %d times, got %q", id, id, result)
+		}
+		seen[string(frame.ID)] = true
+	}
+	if len(seen) != senders {
+		t.Fatalf("expected %d distinct ids, got %d", senders, len(seen))
+	}
+}
+
+func TestSendResponseFlushesToCurrentOutput(t *testing.T) {
+	server := newTestServer(t, nil)
+	var first, second bytes.Buffer
+	id := json.RawMessage("1")
+
+	server.output = &first
+	server.sendResult(&id, "first")
+	if frames := readFrames(t, &first); len(frames) != 1 || string(frames[0].Result) != `"first"` {
+		t.Fatalf("expected the first result to be flushed, got %q", first.String())
+	}
+
+	server.output = &second
+	server.sendResult(&id, "second")
+	if first.Len() != 0 {
+		t.Fatalf("expected nothing more on the old output, got %q", first.String())
+	}
+	if frames := readFrames(t, &second); len(frames) != 1 || string(frames[0].Result) != `"second"` {
+		t.Fatalf("expected the second result on the new output, got %q", second.String())
+	}
+}
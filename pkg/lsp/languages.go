@@ -0,0 +1,76 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// samplesPerExtension is how many files of each extension are shown to ctags when
+// detecting languages. A few rather than one, since ctags picks the parser of some
+// extensions (".h", ".m", ".pl") by content.
+const samplesPerExtension = 3
+
+// languageSamples picks up to `samplesPerExtension` files per extension. Files
+// without an extension are grouped by name, since ctags knows "Makefile" or
+// "Dockerfile" by name and others only by their shebang.
+func languageSamples(files []string) []string {
+	counts := make(map[string]int)
+	var samples []string
+	for _, file := range files {
+		key := strings.ToLower(filepath.Ext(file))
+		if key == "" {
+			key = filepath.Base(file)
+		}
+		if counts[key] < samplesPerExtension {
+			counts[key]++
+			samples = append(samples, file)
+		}
+	}
+	return samples
+}
+
+// detectLanguages asks ctags which languages a sample of `files` are written in,
+// and returns them as a `--languages` value. Scanning with that list spares ctags
+// from trying every parser on every file. It returns "" when detection fails, in
+// which case all languages are scanned.
+func (server *Server) detectLanguages(rootDir string, files []string) string {
+	samples := languageSamples(files)
+	if len(samples) == 0 {
+		return ""
+	}
+
+	cmd := exec.Command(server.ctagsBin, "--print-language", "-L", "-")
+	cmd.Dir = rootDir
+	cmd.Stdin = strings.NewReader(strings.Join(samples, "\n"))
+	output, err := cmd.Output()
+	if err != nil {
+		slog.Warn("failed to detect workspace languages", "error", err)
+		return ""
+	}
+
+	var languages []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Lines are "<path>: <language>"; the path itself may contain ": ".
+		idx := strings.LastIndex(line, ": ")
+		if idx < 0 {
+			continue
+		}
+		language := strings.TrimSpace(line[idx+2:])
+		if language == "" || language == "NONE" || slices.Contains(languages, language) {
+			continue
+		}
+		languages = append(languages, language)
+	}
+	if len(languages) == 0 {
+		return ""
+	}
+	slices.Sort(languages)
+	return strings.Join(languages, ",")
+}
@@ -0,0 +1,35 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestDetectLanguages(t *testing.T) {
+	samples := languageSamples([]string{"a.go", "b.go", "c.GO", "d.go", "x.py", "Makefile", "sub/Makefile", "build"})
+	if !slices.Equal(samples, []string{"a.go", "b.go", "c.GO", "x.py", "Makefile", "sub/Makefile", "build"}) {
+		t.Fatalf("unexpected samples %v", samples)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nwhile read f; do case $f in *.go) echo \"$f: Go\";; *.py) echo \"$f: Python\";; *) echo \"$f: NONE\";; esac; done\n"
+	ctags := filepath.Join(dir, "ctags")
+	if err := os.WriteFile(ctags, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	server := newTestServer(t, nil)
+	server.ctagsBin = ctags
+
+	if got := server.detectLanguages(dir, []string{"main.go", "tool.py", "README"}); got != "Go,Python" {
+		t.Fatalf("expected Go,Python, got %q", got)
+	}
+	if got := server.detectLanguages(dir, []string{"README"}); got != "" {
+		t.Fatalf("expected no languages for unknown files, got %q", got)
+	}
+}
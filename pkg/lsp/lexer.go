@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// delimiterPair marks where a comment or string literal opens and closes.
+type delimiterPair struct {
+	open, close string
+	// multiline pairs may span lines; others end at the end of the line regardless.
+	multiline bool
+	// raw literals don't treat a backslash as escaping the closing delimiter.
+	raw bool
+}
+
+// languageSyntax is the little a text search needs to know about a language to
+// tell code from comments and string literals. Longer delimiters come first, so
+// `"""` is tried before `"`.
+type languageSyntax struct {
+	lineComments   []string
+	blockComments  []delimiterPair
+	stringLiterals []delimiterPair
+}
+
+var (
+	cBlockComment = delimiterPair{open: "/*", close: "*/", multiline: true, raw: true}
+	doubleQuoted  = delimiterPair{open: `"`, close: `"`}
+	singleQuoted  = delimiterPair{open: "'", close: "'"}
+	backtickRaw   = delimiterPair{open: "`", close: "`", multiline: true, raw: true}
+	tripleDouble  = delimiterPair{open: `"""`, close: `"""`, multiline: true}
+	tripleSingle  = delimiterPair{open: "'''", close: "'''", multiline: true}
+)
+
+// languageSyntaxes is keyed by ctags language name.
+var languageSyntaxes = map[string]*languageSyntax{
+	"C":          {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"C++":        {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"C#":         {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"Java":       {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{tripleDouble, doubleQuoted, singleQuoted}},
+	"Kotlin":     {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{tripleDouble, doubleQuoted, singleQuoted}},
+	"Scala":      {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{tripleDouble, doubleQuoted}},
+	"Swift":      {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{tripleDouble, doubleQuoted}},
+	"Go":         {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted, backtickRaw}},
+	"Rust":       {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted}},
+	"JavaScript": {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted, {open: "`", close: "`", multiline: true}}},
+	"TypeScript": {lineComments: []string{"//"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted, {open: "`", close: "`", multiline: true}}},
+	"PHP":        {lineComments: []string{"//", "#"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"Python":     {lineComments: []string{"#"}, stringLiterals: []delimiterPair{tripleDouble, tripleSingle, doubleQuoted, singleQuoted}},
+	"Ruby":       {lineComments: []string{"#"}, blockComments: []delimiterPair{{open: "=begin", close: "=end", multiline: true, raw: true}}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"Perl":       {lineComments: []string{"#"}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"Sh":         {lineComments: []string{"#"}, stringLiterals: []delimiterPair{doubleQuoted, {open: "'", close: "'", multiline: true, raw: true}}},
+	"R":          {lineComments: []string{"#"}, stringLiterals: []delimiterPair{doubleQuoted, singleQuoted}},
+	"Lua":        {lineComments: []string{"--"}, blockComments: []delimiterPair{{open: "--[[", close: "]]", multiline: true, raw: true}}, stringLiterals: []delimiterPair{{open: "[[", close: "]]", multiline: true, raw: true}, doubleQuoted, singleQuoted}},
+	"SQL":        {lineComments: []string{"--"}, blockComments: []delimiterPair{cBlockComment}, stringLiterals: []delimiterPair{{open: "'", close: "'", raw: true}}},
+	"Haskell":    {lineComments: []string{"--"}, blockComments: []delimiterPair{{open: "{-", close: "-}", multiline: true, raw: true}}, stringLiterals: []delimiterPair{doubleQuoted}},
+	"Lisp":       {lineComments: []string{";"}, stringLiterals: []delimiterPair{doubleQuoted}},
+	"Vim":        {lineComments: []string{`"`}, stringLiterals: []delimiterPair{singleQuoted}},
+}
+
+// syntaxExtensions maps file extensions to the ctags language of `languageSyntaxes`,
+// for searching files that have no tags to take the language from.
+var syntaxExtensions = map[string]string{
+	".c": "C", ".h": "C",
+	".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hh": "C++", ".hpp": "C++", ".hxx": "C++",
+	".cs":   "C#",
+	".java": "Java",
+	".kt":   "Kotlin", ".kts": "Kotlin",
+	".scala": "Scala",
+	".swift": "Swift",
+	".go":    "Go",
+	".rs":    "Rust",
+	".js":    "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript",
+	".php": "PHP",
+	".py":  "Python", ".pyi": "Python",
+	".rb": "Ruby",
+	".pl": "Perl", ".pm": "Perl",
+	".sh": "Sh", ".bash": "Sh", ".zsh": "Sh",
+	".r":   "R",
+	".lua": "Lua",
+	".sql": "SQL",
+	".hs":  "Haskell",
+	".el":  "Lisp", ".lisp": "Lisp", ".scm": "Lisp",
+	".vim": "Vim",
+}
+
+// languageForFile returns the ctags language of a file, taken from its extension
+// when `language` is empty.
+func languageForFile(uri, language string) string {
+	if language != "" {
+		return language
+	}
+	return syntaxExtensions[strings.ToLower(filepath.Ext(uri))]
+}
+
+// syntaxForFile returns the syntax of a file by ctags language, falling back to its
+// extension. It returns nil for unknown languages, whose text is all treated as code.
+func syntaxForFile(uri, language string) *languageSyntax {
+	if syntax, ok := languageSyntaxes[language]; ok {
+		return syntax
+	}
+	return languageSyntaxes[languageForFile(uri, "")]
+}
+
+// findCodeOccurrences returns the range of every whole-word occurrence of `name`
+// in `lines`, skipping those inside comments and string literals. Characters are
+// counted in runes, like the rest of the server's positions.
+func findCodeOccurrences(lines []string, name string, syntax *languageSyntax) []Range {
+	target := []rune(name)
+	if len(target) == 0 {
+		return nil
+	}
+	var ranges []Range
+	forEachCodeIdentifier(lines, syntax, func(identifier []rune, rng Range) {
+		if slices.Equal(identifier, target) {
+			ranges = append(ranges, rng)
+		}
+	})
+	return ranges
+}
+
+// forEachCodeIdentifier calls `visit` with every identifier in `lines` that is
+// outside comments and string literals, and its range.
+func forEachCodeIdentifier(lines []string, syntax *languageSyntax, visit func(identifier []rune, rng Range)) {
+	if syntax == nil {
+		syntax = &languageSyntax{}
+	}
+
+	// inside is the comment or literal that is still open, or nil in code.
+	var inside *delimiterPair
+	for lineIdx, line := range lines {
+		runes := []rune(line)
+		i := 0
+		if inside != nil && !inside.multiline {
+			inside = nil
+		}
+	scan:
+		for i < len(runes) {
+			if inside != nil {
+				if !inside.raw && runes[i] == '\\' {
+					i += 2
+					continue
+				}
+				if hasRunePrefix(runes[i:], inside.close) {
+					i += len([]rune(inside.close))
+					inside = nil
+					continue
+				}
+				i++
+				continue
+			}
+
+			// Block comments first: Lua's "--[[" also starts with its line comment.
+			if pair := openingDelimiter(runes[i:], syntax.blockComments); pair != nil {
+				inside = pair
+				i += len([]rune(pair.open))
+				continue
+			}
+			for _, comment := range syntax.lineComments {
+				if hasRunePrefix(runes[i:], comment) {
+					break scan
+				}
+			}
+			if pair := openingDelimiter(runes[i:], syntax.stringLiterals); pair != nil {
+				inside = pair
+				i += len([]rune(pair.open))
+				continue
+			}
+
+			if !isIdentifierChar(runes[i]) {
+				i++
+				continue
+			}
+			start := i
+			for i < len(runes) && isIdentifierChar(runes[i]) {
+				i++
+			}
+			visit(runes[start:i], Range{
+				Start: Position{Line: lineIdx, Character: start},
+				End:   Position{Line: lineIdx, Character: i},
+			})
+		}
+	}
+}
+
+// openingDelimiter returns the pair whose opening delimiter starts `runes`, if any.
+func openingDelimiter(runes []rune, pairs []delimiterPair) *delimiterPair {
+	for i := range pairs {
+		if hasRunePrefix(runes, pairs[i].open) {
+			return &pairs[i]
+		}
+	}
+	return nil
+}
+
+func hasRunePrefix(runes []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i >= len(runes) || runes[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
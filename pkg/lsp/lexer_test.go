@@ -0,0 +1,29 @@
+package lsp
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindCodeOccurrencesSkipsCommentsAndStrings(t *testing.T) {
+	lines := splitLines(`total = 1  # total
+print("total", 'total')
+"""
+total
+"""
+subtotal = total + 1`)
+	ranges := findCodeOccurrences(lines, "total", syntaxForFile("file:///a.py", ""))
+	want := []Range{
+		{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 5}},
+		{Start: Position{Line: 5, Character: 11}, End: Position{Line: 5, Character: 16}},
+	}
+	if !slices.Equal(ranges, want) {
+		t.Fatalf("expected %+v, got %+v", want, ranges)
+	}
+
+	lines = splitLines("int n; /* n\n n */ char *s = \"\\\"n\"; // n\nreturn n;")
+	ranges = findCodeOccurrences(lines, "n", syntaxForFile("file:///a.x", "C"))
+	if len(ranges) != 2 || ranges[0].Start.Line != 0 || ranges[1].Start.Line != 2 {
+		t.Fatalf("expected only the code occurrences of n, got %+v", ranges)
+	}
+}
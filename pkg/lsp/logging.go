@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openLogger returns the logger the flags ask for: writing to the `--log-file`
+// if one is given, to `stderr` otherwise.
+func openLogger(config *Config, stderr io.Writer) (*slog.Logger, error) {
+	w := stderr
+	if config.logFile != "" {
+		file, err := os.OpenFile(config.logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		w = file
+	}
+	return newLogger(w, config.logFormat, config.logLevel)
+}
+
+// newLogger returns a logger writing records of `level` and above to `w`.
+// The "text" format looks like the standard `log` output; "json" emits one
+// structured record per line. Either way, repeated records are collapsed by a
+// `rateLimitHandler`. The program makes it the process-wide logger, which also
+// takes the output of `log.Printf`; see `Process.SetLogger`.
+func newLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: expected debug, info, warn or error", level)
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		handler = newTextLogHandler(w, logLevel)
+	case "json":
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: logLevel})
+	default:
+		return nil, fmt.Errorf("invalid log format %q: expected text or json", format)
+	}
+	return slog.New(newRateLimitHandler(handler, logRepeatWindow)), nil
+}
+
+// textLogHandler writes records like the standard `log` output, with the
+// attributes formatted by a `slog.TextHandler`:
+// "2006/01/02 15:04:05 INFO message key=value".
+type textLogHandler struct {
+	attrs  slog.Handler // Writes only the attributes, to `output.buffer`.
+	output *textLogOutput
+}
+
+// textLogOutput is shared by a `textLogHandler` and the handlers derived from it.
+type textLogOutput struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	buffer bytes.Buffer
+}
+
+func newTextLogHandler(w io.Writer, level slog.Level) *textLogHandler {
+	output := &textLogOutput{w: w}
+	attrs := slog.NewTextHandler(&output.buffer, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && (attr.Key == slog.TimeKey || attr.Key == slog.LevelKey || attr.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	return &textLogHandler{attrs: attrs, output: output}
+}
+
+func (handler *textLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.attrs.Enabled(ctx, level)
+}
+
+func (handler *textLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	output := handler.output
+	output.mutex.Lock()
+	defer output.mutex.Unlock()
+
+	output.buffer.Reset()
+	if err := handler.attrs.Handle(ctx, record); err != nil {
+		return err
+	}
+	line := record.Level.String() + " " + record.Message
+	if !record.Time.IsZero() {
+		line = record.Time.Format("2006/01/02 15:04:05") + " " + line
+	}
+	if attrs := bytes.TrimSpace(output.buffer.Bytes()); len(attrs) > 0 {
+		line += " " + string(attrs)
+	}
+	_, err := io.WriteString(output.w, line+"\n")
+	return err
+}
+
+func (handler *textLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textLogHandler{attrs: handler.attrs.WithAttrs(attrs), output: handler.output}
+}
+
+func (handler *textLogHandler) WithGroup(name string) slog.Handler {
+	return &textLogHandler{attrs: handler.attrs.WithGroup(name), output: handler.output}
+}
+
+// logRepeatWindow is how long a `rateLimitHandler` collapses repeats of a record.
+const logRepeatWindow = 5 * time.Second
+
+// logRepeatBurst is how many records with the same message are logged per window
+// before the rest are only counted.
+const logRepeatBurst = 5
+
+// rateLimitHandler collapses records with the same level, message, method and
+// error, such as a failure logged for each of thousands of tags in one request. Of the records in
+// a window starting with the first, `logRepeatBurst` are logged; the rest are
+// summed up in one record at the end of the window, with a `repeated` count and
+// the attributes of the last of them. Debug records are never collapsed, since
+// they are only logged when asked for.
+type rateLimitHandler struct {
+	base    slog.Handler
+	window  time.Duration
+	repeats *logRepeats // Shared with the handlers derived by WithAttrs and WithGroup.
+}
+
+type logRepeats struct {
+	mutex   sync.Mutex
+	windows map[logRepeatKey]*logRepeatState
+}
+
+type logRepeatKey struct {
+	level   slog.Level
+	message string
+	method  string // The "method" attribute, if any.
+	err     string // The "error" attribute, if any.
+}
+
+// repeatKeyOf keys `record` by what tells its repeats apart from other failures
+// logged with the same message: the request method and the error.
+func repeatKeyOf(record slog.Record) logRepeatKey {
+	key := logRepeatKey{level: record.Level, message: record.Message}
+	record.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case "method":
+			key.method = attr.Value.String()
+		case "error":
+			key.err = attr.Value.String()
+		}
+		return true
+	})
+	return key
+}
+
+type logRepeatState struct {
+	count      int
+	suppressed int
+	last       slog.Record
+	handler    slog.Handler // The handler `last` came through.
+}
+
+func newRateLimitHandler(base slog.Handler, window time.Duration) *rateLimitHandler {
+	return &rateLimitHandler{
+		base:    base,
+		window:  window,
+		repeats: &logRepeats{windows: make(map[logRepeatKey]*logRepeatState)},
+	}
+}
+
+func (handler *rateLimitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.base.Enabled(ctx, level)
+}
+
+func (handler *rateLimitHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level < slog.LevelInfo {
+		return handler.base.Handle(ctx, record)
+	}
+
+	key := repeatKeyOf(record)
+	repeats := handler.repeats
+	repeats.mutex.Lock()
+	state := repeats.windows[key]
+	if state == nil {
+		state = &logRepeatState{}
+		repeats.windows[key] = state
+		time.AfterFunc(handler.window, func() { repeats.flush(key) })
+	}
+	state.count++
+	if state.count > logRepeatBurst {
+		state.suppressed++
+		state.last = record.Clone()
+		state.handler = handler.base
+		repeats.mutex.Unlock()
+		return nil
+	}
+	repeats.mutex.Unlock()
+	return handler.base.Handle(ctx, record)
+}
+
+// flush ends the window of `key`, logging the summary of the records it held back.
+func (repeats *logRepeats) flush(key logRepeatKey) {
+	repeats.mutex.Lock()
+	state := repeats.windows[key]
+	delete(repeats.windows, key)
+	repeats.mutex.Unlock()
+	if state == nil || state.suppressed == 0 {
+		return
+	}
+	summary := state.last.Clone()
+	summary.Time = time.Now()
+	summary.AddAttrs(slog.Int("repeated", state.suppressed))
+	state.handler.Handle(context.Background(), summary)
+}
+
+func (handler *rateLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &rateLimitHandler{base: handler.base.WithAttrs(attrs), window: handler.window, repeats: handler.repeats}
+}
+
+func (handler *rateLimitHandler) WithGroup(name string) slog.Handler {
+	return &rateLimitHandler{base: handler.base.WithGroup(name), window: handler.window, repeats: handler.repeats}
+}
+
+// requestIDAttr renders a JSON-RPC id for log records; notifications have none.
+func requestIDAttr(req RPCRequest) slog.Attr {
+	if req.ID == nil {
+		return slog.String("requestID", "")
+	}
+	return slog.String("requestID", strings.Trim(string(*req.ID), `"`))
+}
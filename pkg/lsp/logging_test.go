@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedLogging(t *testing.T) {
+	var output bytes.Buffer
+	handler := newRateLimitHandler(slog.NewJSONHandler(&output, nil), time.Hour)
+	logger := slog.New(handler)
+
+	for i := range 1000 {
+		logger.Warn("failed to get file content", "path", fmt.Sprintf("/src/file%d.go", i))
+	}
+	logger.Warn("another failure")
+	logger.Debug("failed to get file content")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != logRepeatBurst+1 {
+		t.Fatalf("expected %d records before the window ends, got %d:\n%s", logRepeatBurst+1, len(lines), output.String())
+	}
+
+	output.Reset()
+	handler.repeats.flush(logRepeatKey{level: slog.LevelWarn, message: "failed to get file content"})
+	var summary struct {
+		Msg      string `json:"msg"`
+		Path     string `json:"path"`
+		Repeated int    `json:"repeated"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &summary); err != nil {
+		t.Fatalf("decode summary %q: %v", output.String(), err)
+	}
+	if summary.Msg != "failed to get file content" || summary.Repeated != 1000-logRepeatBurst || summary.Path != "/src/file999.go" {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	output.Reset()
+	handler.repeats.flush(logRepeatKey{level: slog.LevelWarn, message: "another failure"})
+	if output.Len() != 0 {
+		t.Fatalf("expected no summary for a record that wasn't repeated, got %q", output.String())
+	}
+	logger.Warn("failed to get file content", "path", "/src/again.go")
+	if !strings.Contains(output.String(), "/src/again.go") {
+		t.Fatalf("expected a new window to log again, got %q", output.String())
+	}
+}
+
+func TestRateLimitedLoggingKeysByMethodAndError(t *testing.T) {
+	var output bytes.Buffer
+	logger := slog.New(newRateLimitHandler(slog.NewJSONHandler(&output, nil), time.Hour))
+
+	for range 2 * logRepeatBurst {
+		logger.Warn("upstream server failed", "method", "textDocument/hover", "error", errors.New("timeout"))
+	}
+	logger.Warn("upstream server failed", "method", "textDocument/definition", "error", errors.New("timeout"))
+	logger.Warn("upstream server failed", "method", "textDocument/hover", "error", errors.New("exited"))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != logRepeatBurst+2 {
+		t.Fatalf("expected %d records, got %d:\n%s", logRepeatBurst+2, len(lines), output.String())
+	}
+	for _, want := range []string{`"method":"textDocument/definition"`, `"error":"exited"`} {
+		if !strings.Contains(lines[len(lines)-1]+lines[len(lines)-2], want) {
+			t.Fatalf("expected a record with %s to be logged, got:\n%s", want, output.String())
+		}
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		var output bytes.Buffer
+		logger, err := newLogger(&output, format, "warn")
+		if err != nil {
+			t.Fatalf("new %s logger: %v", format, err)
+		}
+		logger.Debug("debug record")
+		logger.Info("info record")
+		logger.Warn("warn record", "method", "textDocument/hover")
+		logger.Error("error record")
+
+		logged := output.String()
+		if strings.Contains(logged, "debug record") || strings.Contains(logged, "info record") {
+			t.Fatalf("expected %s records below warn to be dropped, got:\n%s", format, logged)
+		}
+		if !strings.Contains(logged, "warn record") || !strings.Contains(logged, "error record") {
+			t.Fatalf("expected %s warn and error records, got:\n%s", format, logged)
+		}
+		if format == "text" && !regexp.MustCompile(`(?m)^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d WARN warn record method=textDocument/hover$`).MatchString(logged) {
+			t.Fatalf("expected records like the log package writes, got:\n%s", logged)
+		}
+		if format == "json" && !strings.Contains(logged, `"level":"WARN"`) {
+			t.Fatalf("expected JSON records, got:\n%s", logged)
+		}
+	}
+
+	if _, err := newLogger(&bytes.Buffer{}, "text", "verbose"); err == nil {
+		t.Fatal("expected an unknown log level to be rejected")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Fatal("expected an unknown log format to be rejected")
+	}
+}
+
+func TestLogFileFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ctags-lsp.log")
+	config, err := parseFlags([]string{"ctags-lsp", "--log-file", path, "--log-format", "json"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	var stderr bytes.Buffer
+	var logger *slog.Logger
+	process := Process{SetLogger: func(l *slog.Logger) { logger = l }}
+	if err := process.setLogger(config, &stderr); err != nil || logger == nil {
+		t.Fatalf("set logger: %v", err)
+	}
+	logger.Warn("written to the log file")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if !strings.Contains(string(data), `"msg":"written to the log file"`) || stderr.Len() != 0 {
+		t.Fatalf("expected the record in the log file only, got file %q and stderr %q", data, stderr.String())
+	}
+
+	config.logFile = filepath.Join(t.TempDir(), "missing", "ctags-lsp.log")
+	if err := process.setLogger(config, &stderr); err == nil {
+		t.Fatal("expected a log file that can't be opened to be an error")
+	}
+}
@@ -0,0 +1,1511 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+	"github.com/netmute/ctags-lsp/internal/workspace"
+	"golang.org/x/text/encoding"
+)
+
+type InitializeParams struct {
+	RootURI          string             `json:"rootUri"`
+	RootPath         string             `json:"rootPath"`
+	WorkspaceFolders []WorkspaceFolder  `json:"workspaceFolders"`
+	Capabilities     ClientCapabilities `json:"capabilities"`
+	// InitializationOptions is null for clients that send none.
+	InitializationOptions *InitializationOptions `json:"initializationOptions"`
+}
+
+type WorkspaceFolder struct {
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	Info         ServerInfo         `json:"serverInfo"`
+}
+
+type ServerCapabilities struct {
+	TextDocumentSync          *TextDocumentSyncOptions     `json:"textDocumentSync,omitempty"`
+	NotebookDocumentSync      *NotebookDocumentSyncOptions `json:"notebookDocumentSync,omitempty"`
+	CompletionProvider        *CompletionOptions           `json:"completionProvider,omitempty"`
+	DefinitionProvider        bool                         `json:"definitionProvider,omitempty"`
+	ReferencesProvider        bool                         `json:"referencesProvider,omitempty"`
+	TypeDefinitionProvider    bool                         `json:"typeDefinitionProvider,omitempty"`
+	CallHierarchyProvider     bool                         `json:"callHierarchyProvider,omitempty"`
+	TypeHierarchyProvider     bool                         `json:"typeHierarchyProvider,omitempty"`
+	DocumentHighlightProvider bool                         `json:"documentHighlightProvider,omitempty"`
+	CodeLensProvider          *CodeLensOptions             `json:"codeLensProvider,omitempty"`
+	FoldingRangeProvider      bool                         `json:"foldingRangeProvider,omitempty"`
+	InlayHintProvider         bool                         `json:"inlayHintProvider,omitempty"`
+	SemanticTokensProvider    *SemanticTokensOptions       `json:"semanticTokensProvider,omitempty"`
+	RenameProvider            any                          `json:"renameProvider,omitempty"` // bool or *RenameOptions.
+	HoverProvider             bool                         `json:"hoverProvider,omitempty"`
+	WorkspaceSymbolProvider   bool                         `json:"workspaceSymbolProvider,omitempty"`
+	DocumentSymbolProvider    bool                         `json:"documentSymbolProvider,omitempty"`
+	MonikerProvider           bool                         `json:"monikerProvider,omitempty"`
+	DocumentLinkProvider      *DocumentLinkOptions         `json:"documentLinkProvider,omitempty"`
+	DiagnosticProvider        *DiagnosticOptions           `json:"diagnosticProvider,omitempty"`
+	Workspace                 *WorkspaceServerCapabilities `json:"workspace,omitempty"`
+}
+
+type WorkspaceServerCapabilities struct {
+	FileOperations *FileOperationOptions `json:"fileOperations,omitempty"`
+}
+
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type TextDocumentSyncOptions struct {
+	Change            int  `json:"change"`
+	OpenClose         bool `json:"openClose"`
+	Save              bool `json:"save"`
+	WillSave          bool `json:"willSave,omitempty"`
+	WillSaveWaitUntil bool `json:"willSaveWaitUntil,omitempty"`
+}
+
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+	ResolveProvider   bool     `json:"resolveProvider,omitempty"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query              string           `json:"query"`
+	PartialResultToken *json.RawMessage `json:"partialResultToken,omitempty"`
+}
+
+type ProgressParams struct {
+	Token *json.RawMessage `json:"token"`
+	Value any              `json:"value"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocument `json:"textDocument"`
+}
+
+type TextDocument struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Text         string                 `json:"text,omitempty"`
+}
+
+type CompletionParams struct {
+	TextDocument PositionParams `json:"textDocument"`
+	Position     Position       `json:"position"`
+}
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type PositionParams struct {
+	URI string `json:"uri"`
+}
+
+type CompletionItem struct {
+	Label            string              `json:"label"`
+	Kind             int                 `json:"kind,omitempty"`
+	Detail           string              `json:"detail,omitempty"`
+	Documentation    *MarkupContent      `json:"documentation,omitempty"`
+	InsertText       string              `json:"insertText,omitempty"`
+	InsertTextFormat int                 `json:"insertTextFormat,omitempty"`
+	LabelDetails     *LabelDetails       `json:"labelDetails,omitempty"`
+	SortText         string              `json:"sortText,omitempty"`
+	Data             *CompletionItemData `json:"data,omitempty"`
+}
+
+type LabelDetails struct {
+	Detail      string `json:"detail,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// TagEntry is a tag as ctags writes it, with its path normalized to an absolute
+// file:// URI once ingested.
+type TagEntry = ctags.Entry
+
+// BaseList is the `inherits` field of a tag.
+type BaseList = ctags.BaseList
+
+type Server struct {
+	tagEntries          []TagEntry
+	referenceEntries    []TagEntry
+	rootURI             string
+	indexedRoot         string // The root `Index` built the index for.
+	cache               FileCache
+	initialized         bool
+	ctagsBin            string
+	tagfilePath         string
+	tagfileInUse        string
+	requireTrust        bool
+	trustedDirs         []string
+	untrusted           atomic.Bool // See `checkWorkspaceTrust`.
+	sandbox             bool
+	lastScanDuration    time.Duration
+	lastScanAt          time.Time
+	lastScanSkipped     workspace.SkippedFiles
+	workspaceChoice     string // See `confirmLargeWorkspace`.
+	options             serverOptions
+	optionsMutex        sync.RWMutex
+	clientCapabilities  ClientCapabilities
+	rpcLog              *rpcLogger
+	output              io.Writer
+	outputMutex         sync.Mutex
+	outputWriter        *bufio.Writer // Buffers `output`, see `sendResponse`.
+	outputTarget        io.Writer     // The `output` that `outputWriter` writes to.
+	queuedWrites        atomic.Int32
+	mutex               sync.Mutex
+	pending             sync.WaitGroup
+	sequential          bool
+	nextRequestID       atomic.Int64
+	lastEdit            atomic.Int64 // Unix nanoseconds, see `noteEdit`.
+	clientRequests      map[string]chan RPCRequest
+	clientRequestsMutex sync.Mutex
+	notebooks           map[string]*notebookState
+	notebooksMutex      sync.Mutex
+	inflight            map[string]*inflightRequest
+	inflightMutex       sync.Mutex
+	// disabledProviders is fixed once capabilities are announced.
+	disabledProviders map[string]bool
+	usage             *usageIndex
+	usageCounts       usageCounts
+	usageGeneration   int
+	usageMutex        sync.Mutex
+	compilationDB     *compilationDatabase
+	// failedFiles maps files the workspace scan couldn't index to the error.
+	failedFiles map[string]string
+	// symbolCache narrows workspace symbol queries typed one key at a time.
+	symbolCache *symbolQueryCache
+	// types is the inheritance graph for type hierarchies; see `typeGraph`.
+	types *typeGraph
+	// semantic keeps the last semantic tokens of each document for deltas.
+	semantic semanticTokensCache
+	// indexedFiles lists the files of the index for file symbols.
+	indexedFiles *indexedFiles
+	// ignoreTagfiles makes `scanWorkspace` run ctags even if there is a tagfile,
+	// for `ctags-lsp watch`, which writes it.
+	ignoreTagfiles bool
+	// tagFilters see every tag before it is indexed; see `filterTags`.
+	tagFilters []tagFilter
+	// blame caches `git blame` of files for `--hover-blame`.
+	blame blameCache
+	// session is the state `--session` keeps across restarts; see session.go.
+	session sessionState
+	// upstreamCommands maps language IDs to the command of their upstream server;
+	// see proxy.go.
+	upstreamCommands   map[string][]string
+	upstreamInitialize json.RawMessage
+	upstreamsMutex     sync.Mutex
+	upstreams          map[string]*upstreamServer
+	documentLanguages  map[string]string
+	version            string // Reported to clients; see `Process.Version`.
+	// wslPaths is set when paths are translated between their Windows form
+	// (C:\dir) and the form WSL mounts them at (/mnt/c/dir), for setups where the
+	// editor runs on one side and ctags or the server on the other. Paths from the
+	// client, ctags and tagfiles are translated to the form of the OS the server
+	// runs on; see `foreignClientPaths` for the way back.
+	wslPaths bool
+	// foreignClientPaths is set when the client uses the other form of paths
+	// than the server, with `--path-mapping wsl`.
+	foreignClientPaths atomic.Bool
+}
+
+type FileCache struct {
+	mutex   sync.RWMutex
+	content map[string][]string
+	// modified holds the open documents with edits that weren't saved yet.
+	modified map[string]bool
+	// encoding decodes files that aren't UTF-8 when they are loaded from disk.
+	encoding encoding.Encoding
+}
+
+func handleRequest(server *Server, req RPCRequest) {
+	start := time.Now()
+	defer func() {
+		duration := time.Since(start)
+		observeRequest(req.Method, duration)
+		slog.Debug("handled message", "method", req.Method, requestIDAttr(req), "duration", duration)
+	}()
+	defer server.recoverPanic(req)
+
+	if !server.initialized && req.Method != "initialize" && req.Method != "shutdown" && req.Method != "exit" {
+		if isNotification(req) {
+			return
+		}
+		server.sendError(req.ID, -32002, "Server not initialized", "Received request before successful initialization")
+		return
+	}
+
+	if server.providerDisabled(req.Method) {
+		if !isNotification(req) {
+			server.sendError(req.ID, -32601, fmt.Sprintf("Method not found: %s", req.Method), "provider disabled")
+		}
+		return
+	}
+
+	if server.proxyRequest(req) {
+		return
+	}
+
+	switch req.Method {
+	case "initialize":
+		handleInitialize(server, req)
+	case "initialized":
+		handleInitialized(server, req)
+	case "shutdown":
+		handleShutdown(server, req)
+	case "workspace/didChangeConfiguration":
+		handleDidChangeConfiguration(server, req)
+	case "workspace/willRenameFiles":
+		handleWillRenameFiles(server, req)
+	case "workspace/didRenameFiles":
+		handleDidRenameFiles(server, req)
+	case "workspace/didCreateFiles":
+		handleDidCreateFiles(server, req)
+	case "workspace/didDeleteFiles":
+		handleDidDeleteFiles(server, req)
+	case "textDocument/didOpen":
+		handleDidOpen(server, req)
+	case "textDocument/didChange":
+		handleDidChange(server, req)
+	case "textDocument/didClose":
+		handleDidClose(server, req)
+	case "textDocument/didSave":
+		handleDidSave(server, req)
+	case "notebookDocument/didOpen":
+		handleDidOpenNotebook(server, req)
+	case "notebookDocument/didChange":
+		handleDidChangeNotebook(server, req)
+	case "notebookDocument/didSave":
+		handleDidSaveNotebook(server, req)
+	case "notebookDocument/didClose":
+		handleDidCloseNotebook(server, req)
+	case "textDocument/willSave":
+		handleWillSave(server, req)
+	case "textDocument/willSaveWaitUntil":
+		handleWillSaveWaitUntil(server, req)
+	case "textDocument/completion":
+		handleCompletion(server, req)
+	case "completionItem/resolve":
+		handleCompletionResolve(server, req)
+	case "textDocument/definition":
+		handleDefinition(server, req)
+	case "textDocument/typeDefinition":
+		handleTypeDefinition(server, req)
+	case "textDocument/references":
+		handleReferences(server, req)
+	case "textDocument/rename":
+		handleRename(server, req)
+	case "textDocument/prepareRename":
+		handlePrepareRename(server, req)
+	case "textDocument/prepareCallHierarchy":
+		handlePrepareCallHierarchy(server, req)
+	case "callHierarchy/incomingCalls":
+		handleIncomingCalls(server, req)
+	case "callHierarchy/outgoingCalls":
+		handleOutgoingCalls(server, req)
+	case "textDocument/prepareTypeHierarchy":
+		handlePrepareTypeHierarchy(server, req)
+	case "typeHierarchy/supertypes":
+		handleSupertypes(server, req)
+	case "typeHierarchy/subtypes":
+		handleSubtypes(server, req)
+	case "textDocument/semanticTokens/full":
+		handleSemanticTokensFull(server, req)
+	case "textDocument/semanticTokens/full/delta":
+		handleSemanticTokensDelta(server, req)
+	case "textDocument/semanticTokens/range":
+		handleSemanticTokensRange(server, req)
+	case "textDocument/documentHighlight":
+		handleDocumentHighlight(server, req)
+	case "textDocument/codeLens":
+		handleCodeLens(server, req)
+	case "codeLens/resolve":
+		handleCodeLensResolve(server, req)
+	case "textDocument/foldingRange":
+		handleFoldingRange(server, req)
+	case "textDocument/inlayHint":
+		handleInlayHint(server, req)
+	case "textDocument/hover":
+		handleHover(server, req)
+	case "workspace/symbol":
+		handleWorkspaceSymbol(server, req)
+	case "textDocument/documentSymbol":
+		handleDocumentSymbol(server, req)
+	case "textDocument/moniker":
+		handleMoniker(server, req)
+	case "textDocument/documentLink":
+		handleDocumentLink(server, req)
+	case "documentLink/resolve":
+		handleDocumentLinkResolve(server, req)
+	case "textDocument/diagnostic":
+		handleDocumentDiagnostic(server, req)
+	case "workspace/diagnostic":
+		handleWorkspaceDiagnostic(server, req)
+	case "ctagsLsp/stats":
+		handleStats(server, req)
+	case "ctagsLsp/tags":
+		handleTags(server, req)
+	case "ctagsLsp/retryFailedFiles":
+		handleRetryFailedFiles(server, req)
+	case "$/cancelRequest":
+		handleCancelRequest(server, req)
+	case "$/setTrace":
+	case "$/logTrace":
+	default:
+		if isNotification(req) {
+			return
+		}
+		message := fmt.Sprintf("Method not found: %s", req.Method)
+		server.sendError(req.ID, -32601, message, nil)
+	}
+}
+
+func handleInitialize(server *Server, req RPCRequest) {
+	var params InitializeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	server.clientCapabilities = params.Capabilities
+	server.upstreamInitialize = req.Params
+	if params.InitializationOptions != nil {
+		server.disableProviders(params.InitializationOptions.Disable)
+	}
+
+	rootURI, err := server.resolveRootURI(params)
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	server.rootURI = rootURI
+	server.foreignClientPaths.Store(server.clientUsesForeignPaths(params))
+	server.checkWorkspaceTrust()
+	server.restoreSession()
+
+	// A library user may have indexed the root with `Index` already.
+	if server.indexedRoot == "" || !sameURI(server.indexedRoot, rootURI) {
+		if err := server.scanWorkspace(); err != nil {
+			server.sendError(req.ID, -32603, "Internal error while scanning tags", err.Error())
+			return
+		}
+	}
+
+	result := InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync: &TextDocumentSyncOptions{
+				Change:            1, // LSP TextDocumentSyncKindFull.
+				OpenClose:         true,
+				Save:              true,
+				WillSave:          true,
+				WillSaveWaitUntil: true,
+			},
+			NotebookDocumentSync: notebookSyncOptions,
+			CompletionProvider: &CompletionOptions{
+				TriggerCharacters: []string{".", "\""},
+				ResolveProvider:   true,
+			},
+			WorkspaceSymbolProvider:   true,
+			DefinitionProvider:        true,
+			ReferencesProvider:        true,
+			TypeDefinitionProvider:    true,
+			CallHierarchyProvider:     true,
+			TypeHierarchyProvider:     true,
+			SemanticTokensProvider:    semanticTokensOptions(),
+			DocumentHighlightProvider: true,
+			CodeLensProvider:          &CodeLensOptions{ResolveProvider: true},
+			FoldingRangeProvider:      true,
+			InlayHintProvider:         true,
+			RenameProvider:            server.clientCapabilities.renameProvider(),
+			HoverProvider:             true,
+			DocumentSymbolProvider:    true,
+			MonikerProvider:           true,
+			DocumentLinkProvider:      &DocumentLinkOptions{ResolveProvider: true},
+			DiagnosticProvider: &DiagnosticOptions{
+				InterFileDependencies: false,
+				WorkspaceDiagnostics:  true,
+			},
+			Workspace: &WorkspaceServerCapabilities{
+				FileOperations: &FileOperationOptions{
+					DidCreate:  allFileOperations,
+					WillRename: allFileOperations,
+					DidRename:  allFileOperations,
+					DidDelete:  allFileOperations,
+				},
+			},
+		},
+		Info: ServerInfo{
+			Name:    "ctags-lsp",
+			Version: server.version,
+		},
+	}
+
+	server.removeDisabledProviders(&result.Capabilities)
+
+	server.sendResult(req.ID, result)
+	server.initialized = true
+
+	// The initial scan runs before the response, when notifications aren't allowed yet,
+	// so only its outcome is reported.
+	server.sendIndexingStatus(indexingStateReady, nil)
+	if !server.mayRunCommands() {
+		server.showMessage(MessageTypeWarning, untrustedWorkspace)
+	}
+}
+
+// recoverPanic keeps a panic in the handler of `req` from taking down the server:
+// it logs the panic with its stack and fails only that request. It must be
+// deferred directly by the handling goroutine.
+func (server *Server) recoverPanic(req RPCRequest) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	slog.Error("panic while handling message", "method", req.Method, requestIDAttr(req), "panic", recovered, "stack", string(debug.Stack()))
+	if !isNotification(req) {
+		server.sendError(req.ID, -32603, "Internal error", fmt.Sprint(recovered))
+	}
+}
+
+// resolveRootURI picks the workspace root from, in order of precedence, the first
+// workspace folder, `rootUri` and the deprecated `rootPath`.
+// Only the first workspace folder is indexed.
+func (server *Server) resolveRootURI(params InitializeParams) (string, error) {
+	if len(params.WorkspaceFolders) > 0 {
+		if len(params.WorkspaceFolders) > 1 {
+			slog.Warn("multiple workspace folders are not supported; indexing the first one", "folder", params.WorkspaceFolders[0].URI)
+		}
+		return normalizeFileURI(server.localURI(params.WorkspaceFolders[0].URI))
+	}
+	if params.RootURI != "" {
+		return normalizeFileURI(server.localURI(params.RootURI))
+	}
+	if params.RootPath != "" {
+		path, err := filepath.Abs(params.RootPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve rootPath %q: %w", params.RootPath, err)
+		}
+		return pathToFileURI(path), nil
+	}
+	return "", fmt.Errorf("no workspace root: initialize must set workspaceFolders, rootUri or rootPath")
+}
+
+func handleShutdown(server *Server, req RPCRequest) {
+	server.stopUpstreams()
+	server.saveSession()
+	server.sendResult(req.ID, nil)
+}
+
+func handleDidOpen(server *Server, req RPCRequest) {
+	var params DidOpenTextDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		return
+	}
+
+	content := splitLines(params.TextDocument.Text)
+
+	server.cache.mutex.Lock()
+	server.cache.content[uriKey(normalizedURI)] = content
+	server.cache.mutex.Unlock()
+	server.cache.setModified(normalizedURI, false)
+	server.semantic.noteVersion(normalizedURI, params.TextDocument.Version)
+	server.invalidateUsageOf(normalizedURI)
+
+	if isFileURI(normalizedURI) && server.indexesOpenFilesOnly() {
+		if err := server.scanSingleFileTag(normalizedURI); err != nil {
+			log.Printf("Error scanning opened file %s: %v", normalizedURI, err)
+		}
+	}
+}
+
+func handleDidChange(server *Server, req RPCRequest) {
+	var params DidChangeTextDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		return
+	}
+
+	if len(params.ContentChanges) > 0 {
+		content := splitLines(params.ContentChanges[0].Text)
+		server.cache.mutex.Lock()
+		server.cache.content[uriKey(normalizedURI)] = content
+		server.cache.mutex.Unlock()
+		server.cache.setModified(normalizedURI, true)
+		server.semantic.noteVersion(normalizedURI, params.TextDocument.Version)
+	}
+	// Results computed against the old content would be out of date.
+	server.cancelDocumentRequests(normalizedURI)
+	server.invalidateUsageOf(normalizedURI)
+	server.noteEdit()
+}
+
+func handleDidClose(server *Server, req RPCRequest) {
+	var params DidCloseTextDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		return
+	}
+
+	server.cache.mutex.Lock()
+	delete(server.cache.content, uriKey(normalizedURI))
+	server.cache.mutex.Unlock()
+	server.cache.setModified(normalizedURI, false)
+	server.semantic.forget(normalizedURI)
+	// The file on disk may differ from what the editor had.
+	server.invalidateUsageOf(normalizedURI)
+}
+
+func handleDidSave(server *Server, req RPCRequest) {
+	var params DidSaveTextDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		return
+	}
+	server.cache.setModified(normalizedURI, false)
+
+	if !isFileURI(normalizedURI) || server.indexingSkipped() {
+		return
+	}
+
+	if err := server.scanSingleFileTag(normalizedURI); err != nil {
+		log.Printf("Error rescanning file %s: %v", normalizedURI, err)
+	}
+}
+
+func handleCompletion(server *Server, req RPCRequest) {
+	var params CompletionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	ctx, done := server.trackRequest(req, normalizedURI)
+	defer done()
+
+	server.mutex.Lock()
+	family := server.documentFamily(normalizedURI)
+	server.mutex.Unlock()
+
+	server.cache.mutex.RLock()
+	lines, ok := server.cache.content[uriKey(normalizedURI)]
+	server.cache.mutex.RUnlock()
+
+	if !ok || params.Position.Line >= len(lines) {
+		if !isFileURI(normalizedURI) {
+			server.sendResult(req.ID, CompletionList{Items: []CompletionItem{}})
+			return
+		}
+		server.sendError(req.ID, -32603, "Internal error", "Line out of range")
+		return
+	}
+
+	lineContent := lines[params.Position.Line]
+	runes := []rune(lineContent)
+	isAfterDot := false
+	if params.Position.Character > 0 && params.Position.Character-1 < len(runes) {
+		prevChar := runes[params.Position.Character-1]
+		isAfterDot = prevChar == '.'
+	}
+
+	word, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		if isAfterDot {
+			word = ""
+		} else {
+			server.sendResult(req.ID, CompletionList{
+				IsIncomplete: false,
+				Items:        []CompletionItem{},
+			})
+			return
+		}
+	}
+
+	// With a qualifier before the cursor ("Outer.me"), qualified tags restrict
+	// candidates to the members of that scope.
+	qualifiedPrefix, _ := server.getQualifiedWord(normalizedURI, params.Position, true)
+	if !isQualifiedName(qualifiedPrefix) {
+		qualifiedPrefix = ""
+	}
+	qualifiedPrefix = normalizeQualifiedName(qualifiedPrefix)
+
+	var items []CompletionItem
+	var scores []int
+	seenItems := make(map[string]int)
+	incomplete := false
+
+	// addItem keeps one item per label, preferring the best-scoring entry.
+	addItem := func(label string, entry TagEntry) {
+		score := completionEntryScore(entry, family)
+		if i, ok := seenItems[label]; ok {
+			if score > scores[i] {
+				items[i] = server.completionItem(label, entry)
+				scores[i] = score
+			}
+			return
+		}
+		seenItems[label] = len(items)
+		items = append(items, server.completionItem(label, entry))
+		scores = append(scores, score)
+	}
+
+	for i, entry := range server.tagEntries {
+		if deadlineExceeded(ctx, i) {
+			incomplete = true
+			break
+		}
+		if isQualifiedTag(entry) {
+			if qualifiedPrefix == "" || !strings.HasPrefix(strings.ToLower(normalizeQualifiedName(entry.Name)), strings.ToLower(qualifiedPrefix)) {
+				continue
+			}
+			addItem(unqualifiedName(entry.Name), entry)
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(entry.Name), strings.ToLower(word)) {
+			kind := GetLSPCompletionKind(entry.Kind)
+
+			includeEntry := false
+
+			sameFamily := family.includes(entry)
+			if isAfterDot {
+				if (kind == CompletionItemKindMethod || kind == CompletionItemKindFunction) && sameFamily {
+					includeEntry = true
+				}
+			} else {
+				if kind == CompletionItemKindText {
+					includeEntry = true
+				} else if sameFamily {
+					includeEntry = true
+				}
+			}
+
+			if includeEntry {
+				addItem(entry.Name, entry)
+			}
+		}
+	}
+
+	if requestCancelled(ctx) {
+		server.sendRequestCancelled(req)
+		return
+	}
+	if incomplete && len(items) == 0 {
+		server.sendRequestFailed(req)
+		return
+	}
+
+	rankRecentCompletions(items, server.recentSymbolRanks())
+	result := CompletionList{
+		IsIncomplete: incomplete,
+		Items:        items,
+	}
+
+	server.sendResult(req.ID, result)
+}
+
+// completionItem renders `entry` for the client, honoring its supported kinds,
+// label details and snippet support. Documentation is left to `completionItem/resolve`.
+func (server *Server) completionItem(label string, entry TagEntry) CompletionItem {
+	capabilities := server.clientCapabilities
+	item := CompletionItem{
+		Label:  label,
+		Kind:   capabilities.completionKind(GetLSPCompletionKind(entry.Kind)),
+		Detail: completionDetail(label, entry),
+		Data:   &CompletionItemData{Name: entry.Name, Path: entry.Path, Line: entry.Line},
+	}
+	if snippet := capabilities.completionSnippet(label, GetLSPCompletionKind(entry.Kind)); snippet != "" {
+		item.InsertText = snippet
+		item.InsertTextFormat = 2 // LSP InsertTextFormat.Snippet.
+	}
+	if capabilities.TextDocument.Completion.CompletionItem.LabelDetailsSupport {
+		details := LabelDetails{Detail: entry.Signature}
+		if qualified := qualifiedEntryName(entry); qualified != label {
+			details.Description = qualified
+		}
+		if details != (LabelDetails{}) {
+			item.LabelDetails = &details
+		}
+	}
+	return item
+}
+
+func handleDefinition(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	if location, origin, ok := server.includeDefinition(normalizedURI, params.Position); ok {
+		server.sendDefinitionResult(req.ID, []Location{location}, origin)
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	matches := server.findDefinitionEntries(normalizedURI, params.Position, symbol)
+	if server.getOptions().followTypedefs {
+		matches = server.followTypeAliases(matches, normalizedURI)
+	}
+
+	locations := server.entryLocations(matches)
+	if len(locations) == 0 {
+		server.sendResult(req.ID, nil)
+		server.suggestDefinitions(unqualifiedName(symbol))
+		return
+	}
+
+	server.rememberJump(unqualifiedName(matches[0].Name))
+	origin, _ := server.getCurrentWordRange(normalizedURI, params.Position)
+	server.sendDefinitionResult(req.ID, locations, origin)
+}
+
+// entryLocations returns the locations of the names of `entries` in their files,
+// in order and without duplicates. Entries whose file can't be read are skipped.
+func (server *Server) entryLocations(entries []TagEntry) []Location {
+	return server.entryLocationsUntil(context.Background(), entries)
+}
+
+// entryLocationsUntil is `entryLocations` stopping early when `ctx` is done.
+func (server *Server) entryLocationsUntil(ctx context.Context, entries []TagEntry) []Location {
+	locations := []Location{}
+	seen := make(map[Location]bool)
+	for i, entry := range entries {
+		if deadlineExceeded(ctx, i) {
+			break
+		}
+		content, err := server.cache.GetOrLoadFileContent(entry.Path)
+		if err != nil {
+			slog.Warn("failed to get file content", "path", entry.Path, "error", err)
+			continue
+		}
+		location := Location{URI: entry.Path, Range: findEntryRange(content, entry)}
+		// A qualified tag and its plain tag point at the same place.
+		if seen[location] {
+			continue
+		}
+		seen[location] = true
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// sendDefinitionResult sends `locations` as location links to clients with
+// `linkSupport`, with `origin` as the range the request was made from.
+func (server *Server) sendDefinitionResult(id *json.RawMessage, locations []Location, origin Range) {
+	if server.clientCapabilities.TextDocument.Definition.LinkSupport {
+		links := make([]LocationLink, 0, len(locations))
+		for _, location := range locations {
+			links = append(links, LocationLink{
+				OriginSelectionRange: &origin,
+				TargetURI:            location.URI,
+				TargetRange:          location.Range,
+				TargetSelectionRange: location.Range,
+			})
+		}
+		server.sendResult(id, links)
+		return
+	}
+
+	if len(locations) == 1 {
+		server.sendResult(id, locations[0])
+	} else {
+		server.sendResult(id, locations)
+	}
+}
+
+// findDefinitionEntries returns the tag entries defining `symbol`, the word at `pos`.
+// A qualified name at the cursor ("Outer.method") narrows the candidates to that
+// scope; if nothing matches it, all definitions of the bare name are returned.
+// The caller must hold `server.mutex`.
+func (server *Server) findDefinitionEntries(uri string, pos Position, symbol string) []TagEntry {
+	qualified, _ := server.getQualifiedWord(uri, pos, false)
+
+	var matches []TagEntry
+	if isQualifiedName(qualified) {
+		for _, entry := range server.tagEntries {
+			if matchesQualifiedName(entry, qualified) {
+				matches = append(matches, entry)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		for _, entry := range server.tagEntries {
+			if entry.Name == symbol && !isQualifiedTag(entry) {
+				matches = append(matches, entry)
+			}
+		}
+	}
+	return preferDocumentFamily(matches, server.documentFamily(uri))
+}
+
+func handleWorkspaceSymbol(server *Server, req RPCRequest) {
+	var params WorkspaceSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	ctx, done := server.trackRequest(req, "")
+	defer done()
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	// Typo matches don't contain the query, so they can't be narrowed down.
+	typoTolerant := server.getOptions().fuzzySymbolSearch
+	generation := server.indexGeneration()
+	narrowed, isNarrowed := server.symbolCache.narrow(params.Query, generation, len(server.tagEntries))
+	isNarrowed = isNarrowed && !typoTolerant
+
+	var candidates []symbolCandidate
+	var matches []int
+	complete := true
+	consider := func(n, i int) bool {
+		if deadlineExceeded(ctx, n) {
+			complete = false
+			return false
+		}
+		entry := server.tagEntries[i]
+		tier := matchSymbolQuery(entry.Name, params.Query)
+		score := 0
+		if tier == symbolMatchNone && typoTolerant {
+			if typoScore, ok := matchSymbolTypo(entry.Name, params.Query); ok {
+				tier, score = symbolMatchTypo, typoScore
+			}
+		}
+		if tier == symbolMatchNone {
+			return true
+		}
+
+		matches = append(matches, i)
+		candidates = append(candidates, symbolCandidate{entry: entry, kind: GetLSPSymbolKind(entry.Kind), tier: tier, score: score})
+		return true
+	}
+	if isNarrowed {
+		for n, i := range narrowed {
+			if !consider(n, i) {
+				break
+			}
+		}
+	} else {
+		for i := range server.tagEntries {
+			if !consider(i, i) {
+				break
+			}
+		}
+	}
+	if complete && !typoTolerant && params.Query != "" {
+		server.symbolCache = &symbolQueryCache{
+			query:      strings.ToLower(params.Query),
+			generation: generation,
+			size:       len(server.tagEntries),
+			matches:    matches,
+		}
+	}
+
+	if server.getOptions().fileSymbols && params.Query != "" {
+		candidates = append(candidates, server.fileSymbolCandidates(params.Query, generation)...)
+	}
+
+	// Rank before resolving ranges so only the returned entries have their files loaded.
+	candidates = rankSymbolCandidates(candidates, server.getOptions().workspaceSymbolLimit)
+
+	paths := make(map[string]bool)
+	for _, candidate := range candidates {
+		if candidate.entry.Scope != "" && !isQualifiedName(candidate.entry.Scope) {
+			paths[candidate.entry.Path] = true
+		}
+	}
+	scopes := newScopeIndex(server.tagEntries, paths)
+
+	symbols := make([]SymbolInformation, 0, len(candidates))
+	for i, candidate := range candidates {
+		if deadlineExceeded(ctx, i) {
+			break
+		}
+		entry := candidate.entry
+		if candidate.file {
+			symbols = append(symbols, SymbolInformation{
+				Name:          entry.Name,
+				Kind:          server.clientCapabilities.workspaceSymbolKind(SymbolKindFile),
+				Location:      Location{URI: entry.Path},
+				ContainerName: server.relativeDirectory(entry.Path),
+			})
+			continue
+		}
+		content, err := server.cache.GetOrLoadFileContent(entry.Path)
+		if err != nil {
+			slog.Warn("failed to get file content", "path", entry.Path, "error", err)
+			continue
+		}
+
+		symbolRange := findEntryRange(content, entry)
+		container := entry
+		container.Scope = scopes.fullScope(entry)
+
+		symbol := SymbolInformation{
+			Name: entry.Name,
+			Kind: server.clientCapabilities.workspaceSymbolKind(candidate.kind),
+			Location: Location{
+				URI:   entry.Path,
+				Range: symbolRange,
+			},
+			ContainerName: containerName(container),
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	if requestCancelled(ctx) {
+		server.sendRequestCancelled(req)
+		return
+	}
+	if ctx.Err() != nil && len(symbols) == 0 {
+		server.sendRequestFailed(req)
+		return
+	}
+
+	if params.PartialResultToken != nil {
+		// Stream pages through `$/progress`; the final response then carries no items.
+		for start := 0; start < len(symbols); start += workspaceSymbolPageSize {
+			end := min(start+workspaceSymbolPageSize, len(symbols))
+			server.sendNotification("$/progress", ProgressParams{
+				Token: params.PartialResultToken,
+				Value: symbols[start:end],
+			})
+		}
+		symbols = []SymbolInformation{}
+	}
+
+	server.sendResult(req.ID, symbols)
+}
+
+func handleDocumentSymbol(server *Server, req RPCRequest) {
+	var params DocumentSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	options := server.getOptions()
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	var symbols []SymbolInformation
+	var entries []TagEntry
+
+	for _, entry := range server.tagEntries {
+		if !sameURI(entry.Path, normalizedURI) {
+			continue
+		}
+		if isQualifiedTag(entry) || slices.Contains(options.documentSymbolExcludeKinds, entry.Kind) {
+			continue
+		}
+
+		kind := GetLSPSymbolKind(entry.Kind)
+
+		content, err := server.cache.GetOrLoadFileContent(entry.Path)
+		if err != nil {
+			slog.Warn("failed to get file content", "path", entry.Path, "error", err)
+			continue
+		}
+
+		symbolRange := findEntryRange(content, entry)
+
+		symbol := SymbolInformation{
+			Name:          entry.Name,
+			Kind:          server.clientCapabilities.documentSymbolKind(kind),
+			Location:      Location{URI: entry.Path, Range: symbolRange},
+			ContainerName: containerName(entry),
+		}
+
+		symbols = append(symbols, symbol)
+		entries = append(entries, entry)
+	}
+
+	hierarchical := server.clientCapabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport
+	if hierarchical && isOutlineDocument(entries) {
+		content, _ := server.cache.GetOrLoadFileContent(normalizedURI)
+		server.sendResult(req.ID, buildOutline(content, entries, symbols))
+		return
+	}
+
+	sortDocumentSymbols(symbols, options.documentSymbolOrder)
+	if hierarchical {
+		server.sendResult(req.ID, buildDocumentSymbolTree(symbols))
+		return
+	}
+	server.sendResult(req.ID, symbols)
+}
+
+// normalizeDocumentURI normalizes file:// URIs and passes URIs of other schemes
+// (untitled:, output:, ...) through unchanged. Those documents exist only in memory:
+// they are served from the content cache and never have tag entries.
+func normalizeDocumentURI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URI %q: %w", uri, err)
+	}
+	if parsed.Scheme == "" {
+		return "", fmt.Errorf("missing URI scheme: %q", uri)
+	}
+	if parsed.Scheme != "file" {
+		return uri, nil
+	}
+	return normalizeFileURI(uri)
+}
+
+// isFileURI reports whether `uri` refers to a filesystem document.
+func isFileURI(uri string) bool {
+	return strings.HasPrefix(uri, "file:")
+}
+
+// normalizeFileURI expects external URIs.
+func normalizeFileURI(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		// Surface parsing failures so we never normalize malformed URIs.
+		return "", fmt.Errorf("failed to parse URI %q: %w", uri, err)
+	}
+	if parsed.Scheme != "file" {
+		// The server only supports file:// URIs for filesystem-backed documents.
+		return "", fmt.Errorf("expected file:// URI: %q", uri)
+	}
+	if parsed.Path == "" {
+		// Empty paths cannot be resolved to a filesystem location.
+		return "", fmt.Errorf("empty file URI")
+	}
+
+	path := urlToFilePath(parsed)
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		// Avoid emitting a bogus URI if the filesystem path cannot be resolved.
+		return "", fmt.Errorf("failed to resolve path %q: %w", path, err)
+	}
+
+	return pathToFileURI(absPath), nil
+}
+
+// fileURIToPath expects normalized URIs.
+func fileURIToPath(uri string) string {
+	parsed, _ := url.Parse(uri)
+	return urlToFilePath(parsed)
+}
+
+// urlToFilePath converts the path (and, for Windows UNC shares, the host) of a file URL
+// to a cleaned filesystem path.
+func urlToFilePath(parsed *url.URL) string {
+	path := parsed.Path
+	if runtime.GOOS == "windows" {
+		if parsed.Host != "" && parsed.Host != "localhost" {
+			// "file://server/share/dir" names the UNC path "\\server\share\dir".
+			return filepath.Clean(`\\` + parsed.Host + filepath.FromSlash(path))
+		}
+		if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+			path = path[1:] // Turns "/C:/dir" into "C:/dir".
+		}
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+// pathToFileURI expects an absolute, cleaned filesystem path.
+func pathToFileURI(path string) string {
+	if runtime.GOOS == "windows" {
+		path = stripVerbatimPrefix(path)
+		if rest, ok := strings.CutPrefix(path, `\\`); ok {
+			host, share, _ := strings.Cut(rest, `\`)
+			return (&url.URL{Scheme: "file", Host: host, Path: "/" + filepath.ToSlash(share)}).String()
+		}
+	}
+	slashPath := filepath.ToSlash(path)
+	if runtime.GOOS == "windows" {
+		slashPath = "/" + slashPath // Turns invalid "file://C:/" into valid "file:///C:/"
+	}
+	return (&url.URL{Scheme: "file", Path: slashPath}).String()
+}
+
+// stripVerbatimPrefix removes the Windows `\\?\` prefix used to exceed MAX_PATH,
+// so verbatim and regular spellings of a path produce the same URI. Go's os package
+// adds the prefix back on its own when opening long paths.
+func stripVerbatimPrefix(path string) string {
+	if rest, ok := strings.CutPrefix(path, `\\?\UNC\`); ok {
+		return `\\` + rest
+	}
+	if rest, ok := strings.CutPrefix(path, `\\?\`); ok {
+		return rest
+	}
+	return path
+}
+
+// normalizePath expects raw filesystem paths from ctags/tagfiles, not file:// URIs.
+// On Windows, mixed separators (git and ctags print "/") and verbatim paths are accepted.
+func normalizePath(baseDir, raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if runtime.GOOS == "windows" {
+		raw = stripVerbatimPrefix(raw)
+	}
+
+	clean := filepath.Clean(raw)
+	if !filepath.IsAbs(clean) {
+		clean = filepath.Clean(filepath.Join(baseDir, clean))
+	}
+	return clean, nil
+}
+
+func readFileLines(fileURI string, fallback encoding.Encoding) ([]string, error) {
+	if !isFileURI(fileURI) {
+		return nil, fmt.Errorf("no content for non-file document %q", fileURI)
+	}
+	filePath := fileURIToPath(fileURI)
+	contentBytes, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(decodeSource(contentBytes, fallback)), nil
+}
+
+// splitLines splits text at "\n", "\r\n" and "\r", the line endings LSP recognizes,
+// so that cached lines never carry a trailing "\r" into character offsets.
+func splitLines(text string) []string {
+	if !strings.Contains(text, "\r") {
+		return strings.Split(text, "\n")
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+}
+
+func (cache *FileCache) GetOrLoadFileContent(filePath string) ([]string, error) {
+	cache.mutex.RLock()
+	content, ok := cache.content[uriKey(filePath)]
+	fallback := cache.encoding
+	cache.mutex.RUnlock()
+	if ok {
+		metricCacheHits.Add(1)
+		return content, nil
+	}
+	metricCacheMisses.Add(1)
+	lines, err := readFileLines(filePath, fallback)
+	if err != nil {
+		return nil, err
+	}
+	cache.mutex.Lock()
+	cache.content[uriKey(filePath)] = lines
+	cache.mutex.Unlock()
+	return lines, nil
+}
+
+// setModified records whether the open document `uri` has unsaved edits.
+func (cache *FileCache) setModified(uri string, modified bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if !modified {
+		delete(cache.modified, uriKey(uri))
+		return
+	}
+	if cache.modified == nil {
+		cache.modified = make(map[string]bool)
+	}
+	cache.modified[uriKey(uri)] = true
+}
+
+// isModified reports whether the open document `uri` has unsaved edits, so its
+// content may differ from the file the tags were made from.
+func (cache *FileCache) isModified(uri string) bool {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+	return cache.modified[uriKey(uri)]
+}
+
+// peekFileContent returns the cached content of `filePath`, or reads it from disk
+// without caching it, for passes over every indexed file.
+func (cache *FileCache) peekFileContent(filePath string) ([]string, error) {
+	cache.mutex.RLock()
+	content, ok := cache.content[uriKey(filePath)]
+	fallback := cache.encoding
+	cache.mutex.RUnlock()
+	if ok {
+		return content, nil
+	}
+	return readFileLines(filePath, fallback)
+}
+
+// findEntryRange returns the range of `entry` in `lines`. When its recorded line no
+// longer contains the name, as with a stale tagfile, the line matching its search
+// pattern that is closest to the recorded one is used instead.
+func findEntryRange(lines []string, entry TagEntry) Range {
+	name := unqualifiedName(entry.Name)
+	lineIdx := entry.Line - 1
+	if lineIdx >= 0 && lineIdx < len(lines) && strings.Contains(lines[lineIdx], name) {
+		return findSymbolRangeInFile(lines, name, entry.Line)
+	}
+	if line, ok := findPatternLine(lines, entry.Pattern, lineIdx); ok {
+		return findSymbolRangeInFile(lines, name, line+1)
+	}
+	return findSymbolRangeInFile(lines, name, entry.Line)
+}
+
+// findPatternLine returns the index of the line matching the ex search pattern
+// `pattern` ("/^line$/") that is closest to `near`.
+func findPatternLine(lines []string, pattern string, near int) (int, bool) {
+	body, ok := strings.CutPrefix(pattern, "/")
+	if !ok {
+		return 0, false
+	}
+	body = strings.TrimSuffix(body, "/")
+	// ctags drops the "$" anchor when it truncates long lines.
+	anchoredStart := strings.HasPrefix(body, "^")
+	body = strings.TrimPrefix(body, "^")
+	anchoredEnd := strings.HasSuffix(body, "$") && !strings.HasSuffix(body, "\\$")
+	body = strings.TrimSuffix(body, "$")
+	body = strings.NewReplacer(`\/`, "/", `\\`, `\`, `\$`, "$", `\^`, "^").Replace(body)
+	if body == "" {
+		return 0, false
+	}
+
+	matches := func(line string) bool {
+		switch {
+		case anchoredStart && anchoredEnd:
+			return line == body
+		case anchoredStart:
+			return strings.HasPrefix(line, body)
+		case anchoredEnd:
+			return strings.HasSuffix(line, body)
+		}
+		return strings.Contains(line, body)
+	}
+
+	best := -1
+	for i, line := range lines {
+		if !matches(line) {
+			continue
+		}
+		if best < 0 || abs(i-near) < abs(best-near) {
+			best = i
+		}
+	}
+	return best, best >= 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// findSymbolRangeInFile returns a range for `symbolName` on `lineNumber` (1-based).
+func findSymbolRangeInFile(lines []string, symbolName string, lineNumber int) Range {
+	lineIdx := lineNumber - 1
+	if lineIdx < 0 || lineIdx >= len(lines) {
+		return Range{
+			Start: Position{Line: lineIdx, Character: 0},
+			End:   Position{Line: lineIdx, Character: 0},
+		}
+	}
+
+	lineContent := lines[lineIdx]
+	startChar := strings.Index(lineContent, symbolName)
+	if startChar == -1 {
+		return Range{
+			Start: Position{Line: lineIdx, Character: 0},
+			End:   Position{Line: lineIdx, Character: len([]rune(lineContent))},
+		}
+	}
+
+	endChar := startChar + len([]rune(symbolName))
+
+	return Range{
+		Start: Position{Line: lineIdx, Character: startChar},
+		End:   Position{Line: lineIdx, Character: endChar},
+	}
+}
+
+func (server *Server) getCurrentWord(filePath string, pos Position) (string, error) {
+	runes, start, end, err := server.currentWordBounds(filePath, pos)
+	if err != nil {
+		return "", err
+	}
+	return string(runes[start:end]), nil
+}
+
+// getCurrentWordRange returns the range of the identifier at `pos`.
+func (server *Server) getCurrentWordRange(filePath string, pos Position) (Range, error) {
+	_, start, end, err := server.currentWordBounds(filePath, pos)
+	if err != nil {
+		return Range{}, err
+	}
+	return Range{
+		Start: Position{Line: pos.Line, Character: start},
+		End:   Position{Line: pos.Line, Character: end},
+	}, nil
+}
+
+// currentWordBounds returns the runes of the line at `pos` and the bounds of the identifier around it.
+func (server *Server) currentWordBounds(filePath string, pos Position) ([]rune, int, int, error) {
+	lines, err := server.cache.GetOrLoadFileContent(filePath)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to load file content: %v", err)
+	}
+
+	if pos.Line >= len(lines) {
+		return nil, 0, 0, fmt.Errorf("line %d out of range", pos.Line)
+	}
+
+	line := lines[pos.Line]
+	runes := []rune(line)
+	if pos.Character > len(runes) {
+		return nil, 0, 0, fmt.Errorf("character %d out of range", pos.Character)
+	}
+
+	start := pos.Character
+	for start > 0 && isIdentifierChar(runes[start-1]) {
+		start--
+	}
+
+	end := pos.Character
+	for end < len(runes) && isIdentifierChar(runes[end]) {
+		end++
+	}
+
+	if start == end {
+		return nil, 0, 0, fmt.Errorf("no word found at position")
+	}
+
+	return runes, start, end, nil
+}
+
+func isIdentifierChar(c rune) bool {
+	return (c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c == '_' || c == '$'
+}
@@ -0,0 +1,278 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type rpcRawEnvelope struct {
+	Jsonrpc string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params"`
+	Result  json.RawMessage  `json:"result"`
+	Error   *json.RawMessage `json:"error"`
+}
+
+func newTestServer(t *testing.T, entries []TagEntry) *Server {
+	t.Helper()
+
+	return &Server{
+		cache: FileCache{
+			content: make(map[string][]string),
+		},
+		tagEntries:  entries,
+		initialized: true,
+		rootURI:     pathToFileURI(t.TempDir()),
+	}
+}
+
+// callHandler dispatches `method` with `params` and returns every frame the server wrote.
+func callHandler(t *testing.T, server *Server, method string, params any) []rpcRawEnvelope {
+	t.Helper()
+
+	paramsBytes, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal params: %v", err)
+	}
+	id := json.RawMessage("1")
+	var output bytes.Buffer
+	server.output = &output
+	handleRequest(server, RPCRequest{Jsonrpc: "2.0", ID: &id, Method: method, Params: paramsBytes})
+	return readFrames(t, &output)
+}
+
+// readFrames parses every frame written to `output`.
+func readFrames(t *testing.T, output io.Reader) []rpcRawEnvelope {
+	t.Helper()
+
+	var frames []rpcRawEnvelope
+	reader := bufio.NewReader(output)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if !strings.HasPrefix(line, "Content-Length:") {
+			continue
+		}
+		if _, err := reader.ReadString('\n'); err != nil {
+			t.Fatalf("read header terminator: %v", err)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+		if err != nil {
+			t.Fatalf("parse Content-Length: %v", err)
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		var frame rpcRawEnvelope
+		if err := json.Unmarshal(body, &frame); err != nil {
+			t.Fatalf("unmarshal frame: %v", err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return pathToFileURI(path)
+}
+
+func TestResolveRootURI(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	dirURI := pathToFileURI(dir)
+	otherURI := pathToFileURI(other)
+
+	cases := []struct {
+		name   string
+		params InitializeParams
+		want   string
+	}{
+		{name: "rootUri", params: InitializeParams{RootURI: dirURI}, want: dirURI},
+		{name: "rootPath", params: InitializeParams{RootPath: dir}, want: dirURI},
+		{name: "rootUri wins over rootPath", params: InitializeParams{RootURI: dirURI, RootPath: other}, want: dirURI},
+		{name: "workspace folder wins", params: InitializeParams{
+			RootURI:          otherURI,
+			WorkspaceFolders: []WorkspaceFolder{{URI: dirURI, Name: "dir"}},
+		}, want: dirURI},
+	}
+	server := newTestServer(t, nil)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := server.resolveRootURI(tc.params)
+			if err != nil {
+				t.Fatalf("resolveRootURI: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("no root", func(t *testing.T) {
+		server := newTestServer(t, nil)
+		server.initialized = false
+		frames := callHandler(t, server, "initialize", InitializeParams{})
+		if frames[0].Error == nil {
+			t.Fatalf("expected an error without a workspace root, got %s", frames[0].Result)
+		}
+		if server.initialized {
+			t.Fatal("expected server to stay uninitialized")
+		}
+	})
+}
+
+func TestNonFileURIs(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n\nfunc Render() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Render", Path: uri, Line: 3, Kind: "function"},
+	})
+
+	untitled := "untitled:Untitled-1"
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: untitled, LanguageID: "go", Text: "Render()\n"},
+	})
+
+	frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: untitled},
+		Position:     Position{Line: 0, Character: 2},
+	})
+	var location Location
+	if err := json.Unmarshal(frames[0].Result, &location); err != nil || location.URI != uri {
+		t.Fatalf("expected definition in %s, got %s", uri, frames[0].Result)
+	}
+
+	for _, method := range []string{"textDocument/documentSymbol", "textDocument/diagnostic"} {
+		frames := callHandler(t, server, method, DocumentSymbolParams{
+			TextDocument: TextDocumentIdentifier{URI: "output:extension-output-1"},
+		})
+		if frames[0].Error != nil {
+			t.Fatalf("%s: expected an empty result, got error %s", method, *frames[0].Error)
+		}
+	}
+
+	frames = callHandler(t, server, "textDocument/completion", CompletionParams{
+		TextDocument: PositionParams{URI: "output:extension-output-1"},
+		Position:     Position{Line: 0, Character: 0},
+	})
+	if frames[0].Error != nil {
+		t.Fatalf("expected an empty completion list, got error %s", *frames[0].Error)
+	}
+}
+
+func TestCRLFLines(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "int first;\r\nint total\r\nold\rmac\n")
+	server := newTestServer(t, []TagEntry{{Name: "total", Path: uri, Line: 2, Kind: "variable"}})
+
+	lines, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if want := []string{"int first;", "int total", "old", "mac", ""}; !slices.Equal(lines, want) {
+		t.Fatalf("expected %q, got %q", want, lines)
+	}
+
+	symbolRange := findSymbolRangeInFile(lines, "total", 2)
+	if symbolRange.Start.Character != 4 || symbolRange.End.Character != 9 {
+		t.Fatalf("unexpected range for symbol at end of line: %+v", symbolRange)
+	}
+
+	edits := trailingWhitespaceEdits(splitLines("a  \r\nb\r\n"))
+	if len(edits) != 1 || edits[0].Range.Start.Character != 1 || edits[0].Range.End.Character != 3 {
+		t.Fatalf("expected the spaces before CRLF to be trimmed, got %+v", edits)
+	}
+}
+
+func TestStaleLineFallsBackToPattern(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "// new header\n// more\nint helper(int x) {\n  return x;\n}\nchar *path = \"a/b\";\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "helper", Path: uri, Line: 1, Kind: "function", Pattern: "/^int helper(int x) {$/"},
+		{Name: "path", Path: uri, Kind: "variable", Pattern: `/^char *path = "a\/b";$/`},
+	})
+
+	for _, test := range []struct {
+		name string
+		want Range
+	}{
+		{"helper", Range{Start: Position{Line: 2, Character: 4}, End: Position{Line: 2, Character: 10}}},
+		{"path", Range{Start: Position{Line: 5, Character: 6}, End: Position{Line: 5, Character: 10}}},
+	} {
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: test.name})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		if len(symbols) != 1 || symbols[0].Location.Range != test.want {
+			t.Fatalf("expected %s at %+v, got %+v", test.name, test.want, symbols)
+		}
+	}
+}
+
+func TestPanicFailsOnlyItsRequest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+	server := newTestServer(t, nil)
+	server.sequential = true
+	var output bytes.Buffer
+	server.output = &output
+	dir := fileURIToPath(server.rootURI)
+	writeTestFile(t, dir, "a.go", "package a\n")
+	server.recordFailedFiles(dir, []string{"a.go"}, errors.New("exit status 1"))
+	server.ctagsBin = filepath.Join(t.TempDir(), "ctags")
+	script := "#!/bin/sh\n" + `echo '{"_type": "tag", "name": "a", "path": "a.go", "line": 1, "kind": "package"}'` + "\n"
+	if err := os.WriteFile(server.ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	server.AddTagFilter(func(entry TagEntry) (TagEntry, bool) {
+		var kinds map[string]int
+		kinds[entry.Kind]++
+		return entry, true
+	})
+
+	var input strings.Builder
+	for _, body := range []string{
+		`{"jsonrpc": "2.0", "id": 7, "method": "ctagsLsp/retryFailedFiles"}`,
+		`{"jsonrpc": "2.0", "id": 8, "method": "ctagsLsp/stats"}`,
+	} {
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	if err := serve(strings.NewReader(input.String()), server); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	frames := readFrames(t, &output)
+	if len(frames) != 2 {
+		t.Fatalf("expected two responses, got %+v", frames)
+	}
+	if string(frames[0].ID) != "7" || frames[0].Error == nil || !strings.Contains(string(*frames[0].Error), "-32603") {
+		t.Fatalf("expected an internal error for the panicking request, got %+v", frames[0])
+	}
+	// The server keeps answering afterwards.
+	if string(frames[1].ID) != "8" || frames[1].Error != nil || frames[1].Result == nil {
+		t.Fatalf("expected stats for the next request, got %+v", frames[1])
+	}
+}
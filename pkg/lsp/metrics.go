@@ -0,0 +1,234 @@
+package lsp
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are histogram upper bounds in seconds.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram is a fixed-bucket, cumulative histogram in the Prometheus sense.
+// It implements `expvar.Var`.
+type histogram struct {
+	mutex  sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+type histogramSnapshot struct {
+	Buckets map[string]uint64 `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   uint64            `json:"count"`
+}
+
+func (h *histogram) snapshot() histogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	buckets := make(map[string]uint64, len(latencyBuckets))
+	for i, bound := range latencyBuckets {
+		buckets[formatBound(bound)] = h.counts[i]
+	}
+	return histogramSnapshot{Buckets: buckets, Sum: h.sum, Count: h.count}
+}
+
+// quantile estimates the q-th quantile (0 < q <= 1) in seconds as the upper bound of
+// the bucket holding it. Observations above the largest bucket report that bound.
+func (h *histogram) quantile(q float64) float64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.count)))
+	for i, bound := range latencyBuckets {
+		if h.counts[i] >= rank {
+			return bound
+		}
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+func (h *histogram) String() string {
+	data, _ := json.Marshal(h.snapshot())
+	return string(data)
+}
+
+// histogramMap holds one histogram per label value (e.g. per LSP method).
+// It implements `expvar.Var`.
+type histogramMap struct {
+	mutex      sync.Mutex
+	histograms map[string]*histogram
+}
+
+func (m *histogramMap) get(label string) *histogram {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.histograms == nil {
+		m.histograms = make(map[string]*histogram)
+	}
+	h, ok := m.histograms[label]
+	if !ok {
+		h = newHistogram()
+		m.histograms[label] = h
+	}
+	return h
+}
+
+func (m *histogramMap) labels() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	labels := make([]string, 0, len(m.histograms))
+	for label := range m.histograms {
+		labels = append(labels, label)
+	}
+	slices.Sort(labels)
+	return labels
+}
+
+func (m *histogramMap) String() string {
+	snapshots := make(map[string]histogramSnapshot)
+	for _, label := range m.labels() {
+		snapshots[label] = m.get(label).snapshot()
+	}
+	data, _ := json.Marshal(snapshots)
+	return string(data)
+}
+
+// Metrics of all servers in the process. They are always collected; `--metrics-addr`
+// only controls exposure. They are published by the program, see `Metrics`.
+var (
+	metricRequests       = new(expvar.Map).Init()
+	metricRequestLatency = &histogramMap{}
+	metricScanDuration   = &histogramMap{}
+	metricCacheHits      = new(expvar.Int)
+	metricCacheMisses    = new(expvar.Int)
+)
+
+// Metrics returns the request, scan and file cache metrics of the servers in the
+// process by name, for the program to publish with `expvar.Publish`. `/debug/vars`
+// on `--metrics-addr` serves the published variables.
+func Metrics() map[string]expvar.Var {
+	return map[string]expvar.Var{
+		"requests_total":           metricRequests,
+		"request_duration_seconds": metricRequestLatency,
+		"scan_duration_seconds":    metricScanDuration,
+		"file_cache_hits_total":    metricCacheHits,
+		"file_cache_misses_total":  metricCacheMisses,
+	}
+}
+
+// observeRequest records a handled message for `method`.
+func observeRequest(method string, d time.Duration) {
+	metricRequests.Add(method, 1)
+	metricRequestLatency.get(method).observe(d)
+}
+
+// observeScan records a ctags or tagfile scan; `scope` is "workspace" or "file".
+func observeScan(scope string, d time.Duration) {
+	metricScanDuration.get(scope).observe(d)
+}
+
+// indexSize returns the number of indexed tag entries.
+func (server *Server) indexSize() int {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return len(server.tagEntries)
+}
+
+// startMetricsServer serves expvar JSON on /debug/vars and Prometheus text on /metrics.
+// The listener is opened synchronously so address errors surface at startup.
+func startMetricsServer(addr string, server *Server) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("metrics listener: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/vars", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		writeExpvars(w, server)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusMetrics(w, server)
+	})
+
+	go http.Serve(listener, mux)
+	return nil
+}
+
+// writeExpvars writes the published expvar variables like `expvar.Handler`, along
+// with the index size of `server`, which isn't published since each server has its own.
+func writeExpvars(w io.Writer, server *Server) {
+	fmt.Fprintf(w, "{\n%q: %d", "index_entries", server.indexSize())
+	expvar.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(w, ",\n%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// writePrometheusMetrics renders all metrics in the Prometheus text exposition format.
+func writePrometheusMetrics(w io.Writer, server *Server) {
+	fmt.Fprintln(w, "# TYPE ctags_lsp_requests_total counter")
+	metricRequests.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(w, "ctags_lsp_requests_total{method=%q} %s\n", kv.Key, kv.Value)
+	})
+
+	writePrometheusHistograms(w, "ctags_lsp_request_duration_seconds", "method", metricRequestLatency)
+	writePrometheusHistograms(w, "ctags_lsp_scan_duration_seconds", "scope", metricScanDuration)
+
+	fmt.Fprintln(w, "# TYPE ctags_lsp_index_entries gauge")
+	fmt.Fprintf(w, "ctags_lsp_index_entries %d\n", server.indexSize())
+
+	fmt.Fprintln(w, "# TYPE ctags_lsp_file_cache_hits_total counter")
+	fmt.Fprintf(w, "ctags_lsp_file_cache_hits_total %d\n", metricCacheHits.Value())
+	fmt.Fprintln(w, "# TYPE ctags_lsp_file_cache_misses_total counter")
+	fmt.Fprintf(w, "ctags_lsp_file_cache_misses_total %d\n", metricCacheMisses.Value())
+}
+
+func writePrometheusHistograms(w io.Writer, name, labelName string, histograms *histogramMap) {
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, label := range histograms.labels() {
+		h := histograms.get(label)
+		h.mutex.Lock()
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(w, "%s_bucket{%s=%q,le=%q} %d\n", name, labelName, label, formatBound(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{%s=%q,le=\"+Inf\"} %d\n", name, labelName, label, h.count)
+		fmt.Fprintf(w, "%s_sum{%s=%q} %g\n", name, labelName, label, h.sum)
+		fmt.Fprintf(w, "%s_count{%s=%q} %d\n", name, labelName, label, h.count)
+		h.mutex.Unlock()
+	}
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
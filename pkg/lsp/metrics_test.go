@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"encoding/json"
+	"expvar"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// freeLoopbackAddr returns a loopback address with a port nothing listens on.
+func freeLoopbackAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func httpGet(t *testing.T, url string) string {
+	t.Helper()
+
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", url, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s\n%s", url, response.Status, body)
+	}
+	return string(body)
+}
+
+func requestCount(method string) int64 {
+	if counter, ok := metricRequests.Get(method).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
+
+func TestMetricsChangeAfterRequest(t *testing.T) {
+	path := writeTestFile(t, t.TempDir(), "a.go", "package a\n\nfunc Render() {}\n")
+	server := newTestServer(t, []TagEntry{{Name: "Render", Path: pathToFileURI(path), Line: 3, Kind: "func"}})
+
+	const method = "workspace/symbol"
+	requests := requestCount(method)
+	latencies := metricRequestLatency.get(method).snapshot().Count
+	hits, misses := metricCacheHits.Value(), metricCacheMisses.Value()
+
+	callHandler(t, server, method, WorkspaceSymbolParams{Query: "Render"})
+	// The request loaded the file; this finds it cached.
+	if _, err := server.cache.GetOrLoadFileContent(pathToFileURI(path)); err != nil {
+		t.Fatalf("load %s: %v", path, err)
+	}
+
+	if got := requestCount(method); got != requests+1 {
+		t.Fatalf("expected the request counter to go from %d to %d, got %d", requests, requests+1, got)
+	}
+	if got := metricRequestLatency.get(method).snapshot().Count; got != latencies+1 {
+		t.Fatalf("expected one more latency observation than %d, got %d", latencies, got)
+	}
+	if metricCacheMisses.Value() != misses+1 || metricCacheHits.Value() != hits+1 {
+		t.Fatalf("expected one cache miss and one hit, got %d misses and %d hits",
+			metricCacheMisses.Value()-misses, metricCacheHits.Value()-hits)
+	}
+
+	addr := freeLoopbackAddr(t)
+	if err := startMetricsServer(addr, server); err != nil {
+		t.Fatalf("start metrics server: %v", err)
+	}
+	metrics := httpGet(t, "http://"+addr+"/metrics")
+	for _, want := range []string{
+		`ctags_lsp_requests_total{method="workspace/symbol"} ` + strconv.FormatInt(requests+1, 10),
+		`ctags_lsp_request_duration_seconds_count{method="workspace/symbol"} ` + strconv.FormatUint(latencies+1, 10),
+		"ctags_lsp_index_entries 1",
+		"ctags_lsp_file_cache_misses_total " + strconv.FormatInt(misses+1, 10),
+	} {
+		if !strings.Contains(metrics, want) {
+			t.Fatalf("expected %q in /metrics, got:\n%s", want, metrics)
+		}
+	}
+
+	// The program publishes the metrics; see main.
+	for name, metric := range Metrics() {
+		if expvar.Get(name) == nil {
+			expvar.Publish(name, metric)
+		}
+	}
+	var vars struct {
+		Requests     map[string]int64 `json:"requests_total"`
+		IndexEntries int              `json:"index_entries"`
+	}
+	if err := json.Unmarshal([]byte(httpGet(t, "http://"+addr+"/debug/vars")), &vars); err != nil {
+		t.Fatalf("decode /debug/vars: %v", err)
+	}
+	if vars.Requests[method] != requests+1 || vars.IndexEntries != 1 {
+		t.Fatalf("unexpected /debug/vars %+v", vars)
+	}
+}
@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// monikerScheme identifies monikers produced by this server.
+const monikerScheme = "ctags"
+
+type MonikerParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// Moniker matches LSP 3.17 `Moniker`.
+type Moniker struct {
+	Scheme     string `json:"scheme"`
+	Identifier string `json:"identifier"`
+	Unique     string `json:"unique"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+// handleMoniker returns one moniker per definition of the symbol at the cursor.
+// Identifiers have the form "<language>:<qualified name>", e.g. "Python:Circle.draw".
+func handleMoniker(server *Server, req RPCRequest) {
+	var params MonikerParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	matches := server.findDefinitionEntries(normalizedURI, params.Position, symbol)
+	server.mutex.Unlock()
+
+	monikers := []Moniker{}
+	seen := make(map[string]bool)
+	for _, entry := range matches {
+		identifier := monikerIdentifier(entry)
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+		monikers = append(monikers, Moniker{
+			Scheme:     monikerScheme,
+			Identifier: identifier,
+			Unique:     "project",
+			Kind:       "export",
+		})
+	}
+
+	server.sendResult(req.ID, monikers)
+}
+
+// monikerIdentifier builds the language-qualified path of a tag entry.
+// Entries without a language fall back to the file extension, and scope
+// separators are normalized to "." so qualified and scoped tags agree.
+func monikerIdentifier(entry TagEntry) string {
+	language := entry.Language
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(fileURIToPath(entry.Path)), ".")
+	}
+
+	name := entry.Name
+	if !isQualifiedTag(entry) && entry.Scope != "" {
+		name = entry.Scope + "." + entry.Name
+	}
+	return language + ":" + normalizeQualifiedName(name)
+}
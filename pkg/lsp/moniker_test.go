@@ -0,0 +1,28 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMoniker(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shapes.py", "class Circle:\n    def draw(self): pass\nCircle.draw(c)\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "draw", Path: uri, Line: 2, Kind: "method", Scope: "Circle", ScopeKind: "class", Language: "Python"},
+		{Name: "Circle.draw", Path: uri, Line: 2, Kind: "method", Scope: "Circle", ScopeKind: "class", Language: "Python", Extras: "qualified"},
+	})
+
+	frames := callHandler(t, server, "textDocument/moniker", MonikerParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 2, Character: 9},
+	})
+	var monikers []Moniker
+	if err := json.Unmarshal(frames[0].Result, &monikers); err != nil {
+		t.Fatalf("unmarshal monikers: %v", err)
+	}
+	if len(monikers) != 1 || monikers[0].Identifier != "Python:Circle.draw" || monikers[0].Scheme != monikerScheme {
+		t.Fatalf("expected a single Python:Circle.draw moniker, got %+v", monikers)
+	}
+}
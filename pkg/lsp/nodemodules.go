@@ -0,0 +1,176 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nodeExtensions are the files that resolve bare import specifiers, and the
+// extensions tried for paths written without one, in order.
+var nodeExtensions = []string{".js", ".mjs", ".cjs", ".jsx", ".ts", ".mts", ".cts", ".tsx"}
+
+// nodeConditions are the conditional export keys followed, in order of preference.
+var nodeConditions = []string{"import", "require", "node", "default"}
+
+// packageJSON is the part of a package.json that decides a package's entry point.
+type packageJSON struct {
+	Exports json.RawMessage `json:"exports"`
+	Module  string          `json:"module"`
+	Main    string          `json:"main"`
+}
+
+// isBareSpecifier reports whether an import specifier names a package rather than
+// a path, like "react" or "@scope/pkg/sub".
+func isBareSpecifier(specifier string) bool {
+	return specifier != "" && !strings.HasPrefix(specifier, ".") && !strings.HasPrefix(specifier, "/") &&
+		!filepath.IsAbs(specifier) && !strings.Contains(specifier, ":")
+}
+
+// resolveNodeModule finds the file a bare specifier imported from `fromPath`
+// refers to, looking for the package in the node_modules directories of
+// `fromPath` and its parents, like Node does.
+func resolveNodeModule(fromPath, specifier string) (string, bool) {
+	name, subpath := splitPackageSpecifier(specifier)
+	if name == "" {
+		return "", false
+	}
+
+	for dir := filepath.Dir(fromPath); ; dir = filepath.Dir(dir) {
+		pkgDir := filepath.Join(dir, "node_modules", filepath.FromSlash(name))
+		if info, err := os.Stat(pkgDir); err == nil && info.IsDir() {
+			return resolvePackageEntry(pkgDir, subpath)
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return "", false
+		}
+	}
+}
+
+// splitPackageSpecifier splits "@scope/pkg/sub/path" into "@scope/pkg" and
+// "./sub/path", the form subpaths take in package.json exports. A specifier
+// without a subpath gives ".".
+func splitPackageSpecifier(specifier string) (name, subpath string) {
+	parts := strings.SplitN(specifier, "/", 3)
+	count := 1
+	if strings.HasPrefix(specifier, "@") {
+		if len(parts) < 2 {
+			return "", ""
+		}
+		count = 2
+	}
+	name = strings.Join(parts[:min(count, len(parts))], "/")
+	if rest := strings.TrimPrefix(specifier, name); rest != "" {
+		return name, "." + rest
+	}
+	return name, "."
+}
+
+// resolvePackageEntry returns the file `subpath` of the package in `pkgDir` maps
+// to: through "exports" when the package has them, otherwise "module" or "main"
+// for the package itself and the plain path for anything below it.
+func resolvePackageEntry(pkgDir, subpath string) (string, bool) {
+	var pkg packageJSON
+	if data, err := os.ReadFile(filepath.Join(pkgDir, "package.json")); err == nil {
+		json.Unmarshal(data, &pkg)
+	}
+
+	if len(pkg.Exports) > 0 && string(pkg.Exports) != "null" {
+		if target, ok := exportTarget(pkg.Exports, subpath); ok {
+			return resolveNodeFile(filepath.Join(pkgDir, filepath.FromSlash(target)))
+		}
+		return "", false
+	}
+
+	if subpath == "." {
+		for _, entry := range []string{pkg.Module, pkg.Main, "index"} {
+			if entry == "" {
+				continue
+			}
+			if path, ok := resolveNodeFile(filepath.Join(pkgDir, filepath.FromSlash(entry))); ok {
+				return path, true
+			}
+		}
+		return "", false
+	}
+	return resolveNodeFile(filepath.Join(pkgDir, filepath.FromSlash(subpath)))
+}
+
+// exportTarget looks `subpath` up in a package.json "exports" value, which is a
+// target, a map of subpaths (possibly with one "*" wildcard) or a map of
+// conditions, and returns the path it maps to.
+func exportTarget(exports json.RawMessage, subpath string) (string, bool) {
+	var subpaths map[string]json.RawMessage
+	if json.Unmarshal(exports, &subpaths) == nil && hasSubpathKeys(subpaths) {
+		if target, ok := subpaths[subpath]; ok {
+			return conditionalTarget(target, "")
+		}
+		for pattern, target := range subpaths {
+			prefix, suffix, ok := strings.Cut(pattern, "*")
+			if ok && strings.HasPrefix(subpath, prefix) && strings.HasSuffix(subpath, suffix) && len(subpath) >= len(prefix)+len(suffix) {
+				return conditionalTarget(target, subpath[len(prefix):len(subpath)-len(suffix)])
+			}
+		}
+		return "", false
+	}
+	if subpath != "." {
+		return "", false
+	}
+	return conditionalTarget(exports, "")
+}
+
+func hasSubpathKeys(exports map[string]json.RawMessage) bool {
+	for key := range exports {
+		if strings.HasPrefix(key, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// conditionalTarget resolves an export target: a path, an array of fallbacks or
+// a map of conditions, with "*" in paths replaced by `match`.
+func conditionalTarget(target json.RawMessage, match string) (string, bool) {
+	var path string
+	if json.Unmarshal(target, &path) == nil {
+		return strings.ReplaceAll(path, "*", match), path != ""
+	}
+	var fallbacks []json.RawMessage
+	if json.Unmarshal(target, &fallbacks) == nil {
+		for _, fallback := range fallbacks {
+			if path, ok := conditionalTarget(fallback, match); ok {
+				return path, true
+			}
+		}
+		return "", false
+	}
+	var conditions map[string]json.RawMessage
+	if json.Unmarshal(target, &conditions) == nil {
+		for _, condition := range nodeConditions {
+			if next, ok := conditions[condition]; ok {
+				if path, ok := conditionalTarget(next, match); ok {
+					return path, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveNodeFile returns `path` if it is a file, else the first file found by
+// adding one of `nodeExtensions` to it or by treating it as a directory with an
+// index file.
+func resolveNodeFile(path string) (string, bool) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path, true
+	}
+	for _, base := range []string{path, filepath.Join(path, "index")} {
+		for _, ext := range nodeExtensions {
+			if info, err := os.Stat(base + ext); err == nil && !info.IsDir() {
+				return base + ext, true
+			}
+		}
+	}
+	return "", false
+}
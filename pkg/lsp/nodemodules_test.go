@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveNodeModule(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"src/app", "node_modules/plain/lib", "node_modules/@scope/pkg/dist/feature", "node_modules/bare"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeTestFile(t, dir, "node_modules/plain/package.json", `{"main": "lib/main"}`)
+	writeTestFile(t, dir, "node_modules/plain/lib/main.js", "")
+	writeTestFile(t, dir, "node_modules/plain/lib/util.js", "")
+	writeTestFile(t, dir, "node_modules/@scope/pkg/package.json", `{
+  "main": "ignored.js",
+  "exports": {
+    ".": {"types": "./dist/index.d.ts", "import": "./dist/index.mjs", "require": "./dist/index.cjs"},
+    "./feature/*": "./dist/feature/*.js"
+  }
+}`)
+	writeTestFile(t, dir, "node_modules/@scope/pkg/dist/index.mjs", "")
+	writeTestFile(t, dir, "node_modules/@scope/pkg/dist/feature/a.js", "")
+	writeTestFile(t, dir, "node_modules/bare/index.ts", "")
+	from := filepath.Join(dir, "src", "app", "main.ts")
+
+	for specifier, want := range map[string]string{
+		"plain":                "node_modules/plain/lib/main.js",
+		"plain/lib/util":       "node_modules/plain/lib/util.js",
+		"@scope/pkg":           "node_modules/@scope/pkg/dist/index.mjs",
+		"@scope/pkg/feature/a": "node_modules/@scope/pkg/dist/feature/a.js",
+		"bare":                 "node_modules/bare/index.ts",
+	} {
+		got, ok := resolveNodeModule(from, specifier)
+		if !ok || got != filepath.Join(dir, filepath.FromSlash(want)) {
+			t.Fatalf("expected %s to resolve to %s, got %q", specifier, want, got)
+		}
+	}
+	for _, specifier := range []string{"missing", "@scope/pkg/internal"} {
+		if got, ok := resolveNodeModule(from, specifier); ok {
+			t.Fatalf("expected %s not to resolve, got %q", specifier, got)
+		}
+	}
+
+	server := newTestServer(t, nil)
+	server.rootURI = pathToFileURI(dir)
+	target, ok := server.resolveIncludeTarget(pathToFileURI(from), "@scope/pkg", false)
+	if !ok || target != pathToFileURI(filepath.Join(dir, "node_modules", "@scope", "pkg", "dist", "index.mjs")) {
+		t.Fatalf("expected the include target to resolve through node_modules, got %q", target)
+	}
+}
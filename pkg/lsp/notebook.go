@@ -0,0 +1,352 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+)
+
+// Numeric values match LSP 3.17 `NotebookCellKind`.
+const (
+	NotebookCellKindMarkup = 1
+	NotebookCellKindCode   = 2
+)
+
+// cellExtensions maps notebook cell language ids to a file extension ctags recognizes.
+// Cells in other languages are kept in memory but not indexed.
+var cellExtensions = map[string]string{
+	"c":           ".c",
+	"cpp":         ".cpp",
+	"csharp":      ".cs",
+	"fsharp":      ".fs",
+	"go":          ".go",
+	"haskell":     ".hs",
+	"java":        ".java",
+	"javascript":  ".js",
+	"julia":       ".jl",
+	"lua":         ".lua",
+	"perl":        ".pl",
+	"powershell":  ".ps1",
+	"python":      ".py",
+	"r":           ".r",
+	"ruby":        ".rb",
+	"rust":        ".rs",
+	"scala":       ".scala",
+	"shellscript": ".sh",
+	"sql":         ".sql",
+	"typescript":  ".ts",
+}
+
+type NotebookDocumentSyncOptions struct {
+	NotebookSelector []NotebookSelector `json:"notebookSelector"`
+	Save             bool               `json:"save,omitempty"`
+}
+
+type NotebookSelector struct {
+	Notebook string               `json:"notebook"`
+	Cells    []NotebookCellFilter `json:"cells,omitempty"`
+}
+
+type NotebookCellFilter struct {
+	Language string `json:"language"`
+}
+
+type NotebookDocument struct {
+	URI          string         `json:"uri"`
+	NotebookType string         `json:"notebookType"`
+	Version      int            `json:"version"`
+	Cells        []NotebookCell `json:"cells"`
+}
+
+type NotebookCell struct {
+	Kind     int    `json:"kind"`
+	Document string `json:"document"`
+}
+
+type NotebookDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type DidOpenNotebookDocumentParams struct {
+	NotebookDocument  NotebookDocument `json:"notebookDocument"`
+	CellTextDocuments []TextDocument   `json:"cellTextDocuments"`
+}
+
+type DidChangeNotebookDocumentParams struct {
+	NotebookDocument NotebookDocumentIdentifier  `json:"notebookDocument"`
+	Change           NotebookDocumentChangeEvent `json:"change"`
+}
+
+type NotebookDocumentChangeEvent struct {
+	Cells *NotebookDocumentCellChanges `json:"cells,omitempty"`
+}
+
+type NotebookDocumentCellChanges struct {
+	Structure *struct {
+		Array struct {
+			Start       int            `json:"start"`
+			DeleteCount int            `json:"deleteCount"`
+			Cells       []NotebookCell `json:"cells,omitempty"`
+		} `json:"array"`
+		DidOpen  []TextDocument           `json:"didOpen,omitempty"`
+		DidClose []TextDocumentIdentifier `json:"didClose,omitempty"`
+	} `json:"structure,omitempty"`
+	Data        []NotebookCell `json:"data,omitempty"`
+	TextContent []struct {
+		Document TextDocumentIdentifier           `json:"document"`
+		Changes  []TextDocumentContentChangeEvent `json:"changes"`
+	} `json:"textContent,omitempty"`
+}
+
+type DidSaveNotebookDocumentParams struct {
+	NotebookDocument NotebookDocumentIdentifier `json:"notebookDocument"`
+}
+
+type DidCloseNotebookDocumentParams struct {
+	NotebookDocument  NotebookDocumentIdentifier `json:"notebookDocument"`
+	CellTextDocuments []TextDocumentIdentifier   `json:"cellTextDocuments"`
+}
+
+// notebookState tracks the cells of an open notebook, in notebook order.
+type notebookState struct {
+	cells     []NotebookCell
+	languages map[string]string // cell URI -> language id
+}
+
+var notebookSyncOptions = &NotebookDocumentSyncOptions{
+	NotebookSelector: []NotebookSelector{{Notebook: "*", Cells: []NotebookCellFilter{{Language: "*"}}}},
+	Save:             true,
+}
+
+// handleDidOpenNotebook caches every cell and indexes the code cells.
+// Cells are indexed individually, so tag entries point at the cell URIs.
+func handleDidOpenNotebook(server *Server, req RPCRequest) {
+	var params DidOpenNotebookDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	notebook := &notebookState{
+		cells:     params.NotebookDocument.Cells,
+		languages: make(map[string]string),
+	}
+	for _, cell := range params.CellTextDocuments {
+		notebook.languages[cell.URI] = cell.LanguageID
+		server.setDocumentContent(cell.URI, cell.Text)
+	}
+
+	server.notebooksMutex.Lock()
+	if server.notebooks == nil {
+		server.notebooks = make(map[string]*notebookState)
+	}
+	server.notebooks[params.NotebookDocument.URI] = notebook
+	server.notebooksMutex.Unlock()
+
+	server.indexNotebook(params.NotebookDocument.URI)
+}
+
+// handleDidChangeNotebook applies cell structure and content changes. Like text
+// documents, cells are reindexed on save rather than on every change, except for
+// newly added cells.
+func handleDidChangeNotebook(server *Server, req RPCRequest) {
+	var params DidChangeNotebookDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	changes := params.Change.Cells
+	if changes == nil {
+		return
+	}
+
+	server.notebooksMutex.Lock()
+	notebook, ok := server.notebooks[params.NotebookDocument.URI]
+	if !ok {
+		server.notebooksMutex.Unlock()
+		return
+	}
+
+	var opened []TextDocument
+	var closed []string
+	if structure := changes.Structure; structure != nil {
+		array := structure.Array
+		start := min(max(array.Start, 0), len(notebook.cells))
+		end := min(start+array.DeleteCount, len(notebook.cells))
+		cells := append([]NotebookCell{}, notebook.cells[:start]...)
+		cells = append(cells, array.Cells...)
+		notebook.cells = append(cells, notebook.cells[end:]...)
+
+		for _, cell := range structure.DidOpen {
+			notebook.languages[cell.URI] = cell.LanguageID
+			opened = append(opened, cell)
+		}
+		for _, cell := range structure.DidClose {
+			delete(notebook.languages, cell.URI)
+			closed = append(closed, cell.URI)
+		}
+	}
+	for _, data := range changes.Data {
+		for i := range notebook.cells {
+			if notebook.cells[i].Document == data.Document {
+				notebook.cells[i].Kind = data.Kind
+			}
+		}
+	}
+	server.notebooksMutex.Unlock()
+
+	for _, uri := range closed {
+		server.removeIndexedPath(uri)
+	}
+	for _, cell := range opened {
+		server.setDocumentContent(cell.URI, cell.Text)
+		server.indexNotebookCell(cell.URI, cell.LanguageID)
+	}
+	for _, content := range changes.TextContent {
+		if len(content.Changes) > 0 {
+			// Full document sync: the last change holds the complete cell text.
+			server.setDocumentContent(content.Document.URI, content.Changes[len(content.Changes)-1].Text)
+		}
+	}
+}
+
+func handleDidSaveNotebook(server *Server, req RPCRequest) {
+	var params DidSaveNotebookDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	server.indexNotebook(params.NotebookDocument.URI)
+}
+
+// handleDidCloseNotebook drops the cells' content and tag entries.
+func handleDidCloseNotebook(server *Server, req RPCRequest) {
+	var params DidCloseNotebookDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	server.notebooksMutex.Lock()
+	notebook, ok := server.notebooks[params.NotebookDocument.URI]
+	delete(server.notebooks, params.NotebookDocument.URI)
+	server.notebooksMutex.Unlock()
+
+	uris := make([]string, 0, len(params.CellTextDocuments))
+	for _, cell := range params.CellTextDocuments {
+		uris = append(uris, cell.URI)
+	}
+	if ok {
+		for _, cell := range notebook.cells {
+			uris = append(uris, cell.Document)
+		}
+	}
+	for _, uri := range uris {
+		server.removeIndexedPath(uri)
+	}
+}
+
+// setDocumentContent replaces the cached content of an open document.
+func (server *Server) setDocumentContent(uri, text string) {
+	server.cache.mutex.Lock()
+	server.cache.content[uriKey(uri)] = splitLines(text)
+	server.cache.mutex.Unlock()
+}
+
+// indexNotebook reindexes every code cell of an open notebook.
+func (server *Server) indexNotebook(notebookURI string) {
+	server.notebooksMutex.Lock()
+	notebook, ok := server.notebooks[notebookURI]
+	var cells []NotebookCell
+	var languages []string
+	if ok {
+		for _, cell := range notebook.cells {
+			if cell.Kind == NotebookCellKindCode {
+				cells = append(cells, cell)
+				languages = append(languages, notebook.languages[cell.Document])
+			}
+		}
+	}
+	server.notebooksMutex.Unlock()
+
+	for i, cell := range cells {
+		server.indexNotebookCell(cell.Document, languages[i])
+	}
+}
+
+// reindexNotebooks indexes the code cells of every open notebook, e.g. after the
+// workspace index was rebuilt.
+func (server *Server) reindexNotebooks() {
+	server.notebooksMutex.Lock()
+	uris := make([]string, 0, len(server.notebooks))
+	for uri := range server.notebooks {
+		uris = append(uris, uri)
+	}
+	server.notebooksMutex.Unlock()
+
+	for _, uri := range uris {
+		server.indexNotebook(uri)
+	}
+}
+
+// indexNotebookCell runs ctags over a cell's cached text through a temporary file
+// and stores the resulting entries under the cell URI.
+func (server *Server) indexNotebookCell(cellURI, languageID string) {
+	extension, ok := cellExtensions[languageID]
+	if !ok {
+		return
+	}
+
+	server.cache.mutex.RLock()
+	lines, ok := server.cache.content[uriKey(cellURI)]
+	server.cache.mutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	entries, err := server.tagText(strings.Join(lines, "\n"), extension)
+	if err != nil {
+		slog.Warn("failed to index notebook cell", "uri", cellURI, "error", err)
+		return
+	}
+	for i := range entries {
+		entries[i].Path = cellURI
+	}
+	definitions, references := ctags.SplitReferences(server.filterTags(entries))
+
+	keep := func(entry TagEntry) bool { return !sameURI(entry.Path, cellURI) }
+	server.mutex.Lock()
+	server.tagEntries = append(filterEntries(server.tagEntries, keep), definitions...)
+	server.referenceEntries = append(filterEntries(server.referenceEntries, keep), references...)
+	server.mutex.Unlock()
+	server.invalidateUsageOf(cellURI)
+}
+
+// tagText runs ctags over `text` as if it were a file with the given extension.
+func (server *Server) tagText(text, extension string) ([]TagEntry, error) {
+	if !server.mayRunCommands() {
+		return nil, errors.New("workspace is not trusted")
+	}
+	start := time.Now()
+	defer func() { observeScan("file", time.Since(start)) }()
+
+	file, err := os.CreateTemp("", "ctags-lsp-*"+extension)
+	if err != nil {
+		return nil, fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("write temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("write temporary file: %w", err)
+	}
+
+	cmd := exec.Command(server.ctagsBin, server.parseCtagsArgs(append([]string{file.Name()}, server.getOptions().ctagArgs...)...)...)
+	return server.runCtags(cmd)
+}
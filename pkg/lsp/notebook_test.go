@@ -0,0 +1,47 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNotebookCellsAreTrackedInMemory(t *testing.T) {
+	server := newTestServer(t, nil)
+	notebook := "file:///work/analysis.ipynb"
+	cell := "vscode-notebook-cell:/work/analysis.ipynb#W0"
+
+	callHandler(t, server, "notebookDocument/didOpen", DidOpenNotebookDocumentParams{
+		NotebookDocument: NotebookDocument{
+			URI:          notebook,
+			NotebookType: "jupyter-notebook",
+			Cells:        []NotebookCell{{Kind: NotebookCellKindMarkup, Document: cell}},
+		},
+		CellTextDocuments: []TextDocument{{URI: cell, LanguageID: "markdown", Text: "# Title"}},
+	})
+
+	params := json.RawMessage(`{
+		"notebookDocument": {"uri": "` + notebook + `", "version": 2},
+		"change": {"cells": {"textContent": [
+			{"document": {"uri": "` + cell + `"}, "changes": [{"text": "# Results"}]}
+		]}}
+	}`)
+	callHandler(t, server, "notebookDocument/didChange", params)
+
+	server.cache.mutex.RLock()
+	content := server.cache.content[cell]
+	server.cache.mutex.RUnlock()
+	if len(content) != 1 || content[0] != "# Results" {
+		t.Fatalf("expected updated cell content, got %q", content)
+	}
+
+	callHandler(t, server, "notebookDocument/didClose", DidCloseNotebookDocumentParams{
+		NotebookDocument:  NotebookDocumentIdentifier{URI: notebook},
+		CellTextDocuments: []TextDocumentIdentifier{{URI: cell}},
+	})
+	if _, ok := server.cache.content[cell]; ok {
+		t.Fatal("expected cell content to be dropped on close")
+	}
+	if _, ok := server.notebooks[notebook]; ok {
+		t.Fatal("expected notebook to be forgotten on close")
+	}
+}
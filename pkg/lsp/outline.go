@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"slices"
+	"strings"
+)
+
+// outlineLanguages are the documentation languages whose document symbols are
+// nested by heading level rather than by container name.
+var outlineLanguages = map[string]bool{"Markdown": true, "Asciidoc": true, "ReStructuredText": true}
+
+// headingLevels gives the depth of the heading kinds of `outlineLanguages`;
+// a heading nests under the closest preceding heading of a lower level.
+var headingLevels = map[string]int{
+	"title":         0,
+	"chapter":       1,
+	"section":       2,
+	"subsection":    3,
+	"subsubsection": 4,
+	"l4subsection":  5,
+	"l5subsection":  6,
+}
+
+// headingScopeSeparator separates the headings in the scope of a heading tag.
+const headingScopeSeparator = `""`
+
+// isOutlineDocument reports whether `entries`, all from one document, come from
+// one of the `outlineLanguages`.
+func isOutlineDocument(entries []TagEntry) bool {
+	return len(entries) > 0 && outlineLanguages[entries[0].Language]
+}
+
+// outlineNode is a symbol in the tree `buildOutline` builds.
+type outlineNode struct {
+	symbol   DocumentSymbol
+	level    int
+	children []*outlineNode
+}
+
+// buildOutline nests the `symbols` of a documentation file with the given `lines`
+// into a tree of headings. `entries` are the tags the symbols were made from, in
+// the same order. A heading goes under the enclosing heading its scope names, or
+// by level when the scope doesn't say, and its range spans its whole section up
+// to the next heading of the same or a lower level. Other tags, like anchors and
+// footnotes, go under the heading whose section they are in. The tree is in
+// document order.
+func buildOutline(lines []string, entries []TagEntry, symbols []SymbolInformation) []DocumentSymbol {
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return entries[a].Line - entries[b].Line })
+
+	var roots []*outlineNode
+	var open []*outlineNode // Headings whose section hasn't ended yet, outermost first.
+	closeSections := func(keep int, line int) {
+		for _, heading := range open[keep:] {
+			heading.symbol.Range.End = sectionEnd(lines, heading.symbol.Range, line-1)
+		}
+		open = open[:keep]
+	}
+	appendTo := func(parent, child *outlineNode) {
+		if parent == nil {
+			roots = append(roots, child)
+			return
+		}
+		parent.children = append(parent.children, child)
+	}
+
+	for _, i := range order {
+		entry, symbol := entries[i], symbols[i]
+		current := &outlineNode{symbol: DocumentSymbol{
+			Name:           symbol.Name,
+			Kind:           symbol.Kind,
+			Range:          symbol.Location.Range,
+			SelectionRange: symbol.Location.Range,
+		}}
+
+		level, isHeading := headingLevels[entry.Kind]
+		if !isHeading {
+			var parent *outlineNode
+			if len(open) > 0 {
+				parent = open[len(open)-1]
+			}
+			appendTo(parent, current)
+			continue
+		}
+
+		current.level = level
+		current.symbol.Range.Start.Character = 0
+		keep := enclosingHeading(open, entry.Scope)
+		if keep < 0 {
+			keep = len(open)
+			for keep > 0 && open[keep-1].level >= level {
+				keep--
+			}
+		}
+		closeSections(keep, symbol.Location.Range.Start.Line)
+
+		var parent *outlineNode
+		if keep > 0 {
+			parent = open[keep-1]
+		}
+		appendTo(parent, current)
+		open = append(open, current)
+	}
+	closeSections(0, len(lines))
+
+	var convert func([]*outlineNode) []DocumentSymbol
+	convert = func(list []*outlineNode) []DocumentSymbol {
+		result := make([]DocumentSymbol, 0, len(list))
+		for _, n := range list {
+			symbol := n.symbol
+			if len(n.children) > 0 {
+				symbol.Children = convert(n.children)
+			}
+			result = append(result, symbol)
+		}
+		return result
+	}
+	return convert(roots)
+}
+
+// enclosingHeading returns how many of the `open` headings to keep so that the
+// innermost one is the heading `scope` names, or -1 if the scope doesn't name one.
+func enclosingHeading(open []*outlineNode, scope string) int {
+	if scope == "" {
+		return -1
+	}
+	name := scope
+	if i := strings.LastIndex(scope, headingScopeSeparator); i >= 0 {
+		name = scope[i+len(headingScopeSeparator):]
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i].symbol.Name == name {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// sectionEnd returns the end of the section of the heading at `heading`, which
+// lasts until line `last` at the latest, leaving out trailing blank lines.
+func sectionEnd(lines []string, heading Range, last int) Position {
+	start := heading.Start.Line
+	last = min(last, len(lines)-1)
+	for last > start && strings.TrimSpace(lines[last]) == "" {
+		last--
+	}
+	if last <= start {
+		return heading.End
+	}
+	return Position{Line: last, Character: utf16Len(lines[last])}
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownOutline(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "guide.md", "# Guide\nintro\n\n## Install\nsteps\n### From source\nmake\n\n## Usage in v1.2\nrun\n# Reference\nlist\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Guide", Path: uri, Line: 1, Kind: "chapter", Language: "Markdown"},
+		{Name: "Install", Path: uri, Line: 4, Kind: "section", Language: "Markdown", Scope: "Guide", ScopeKind: "chapter"},
+		{Name: "From source", Path: uri, Line: 6, Kind: "subsection", Language: "Markdown", Scope: `Guide""Install`, ScopeKind: "section"},
+		{Name: "Usage in v1.2", Path: uri, Line: 9, Kind: "section", Language: "Markdown", Scope: "Guide", ScopeKind: "chapter"},
+		{Name: "Reference", Path: uri, Line: 11, Kind: "chapter", Language: "Markdown"},
+	})
+	server.clientCapabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport = true
+
+	frames := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	var symbols []DocumentSymbol
+	if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+		t.Fatalf("unmarshal symbols: %v", err)
+	}
+
+	var outline func(symbols []DocumentSymbol) string
+	outline = func(symbols []DocumentSymbol) string {
+		var parts []string
+		for _, symbol := range symbols {
+			part := fmt.Sprintf("%s %d-%d:%d", symbol.Name, symbol.Range.Start.Line, symbol.Range.End.Line, symbol.Range.End.Character)
+			if len(symbol.Children) > 0 {
+				part += " [" + outline(symbol.Children) + "]"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, ", ")
+	}
+	want := "Guide 0-9:3 [Install 3-6:4 [From source 5-6:4], Usage in v1.2 8-9:3], Reference 10-11:4"
+	if got := outline(symbols); got != want {
+		t.Fatalf("unexpected outline:\n got %s\nwant %s", got, want)
+	}
+	if symbols[0].Kind != SymbolKindString || symbols[0].SelectionRange.Start.Character != 2 {
+		t.Fatalf("expected a string symbol selecting the heading text, got %+v", symbols[0])
+	}
+}
@@ -0,0 +1,71 @@
+//go:build windows
+
+package lsp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWindowsVerbatimPaths(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{raw: `\\?\C:\very\long\path\file.go`, want: `C:\very\long\path\file.go`},
+		{raw: `\\?\UNC\server\share\file.go`, want: `\\server\share\file.go`},
+		{raw: `C:\plain\file.go`, want: `C:\plain\file.go`},
+	}
+	for _, tc := range cases {
+		got, err := normalizePath(`C:\base`, tc.raw)
+		if err != nil {
+			t.Fatalf("normalizePath(%q): %v", tc.raw, err)
+		}
+		if got != tc.want {
+			t.Fatalf("normalizePath(%q): expected %q, got %q", tc.raw, tc.want, got)
+		}
+	}
+
+	if pathToFileURI(`\\?\C:\dir\file.go`) != pathToFileURI(`C:\dir\file.go`) {
+		t.Fatal("expected verbatim and regular paths to produce the same URI")
+	}
+}
+
+func TestWindowsMixedSeparators(t *testing.T) {
+	got, err := normalizePath(`C:\repo`, "src/pkg\\file.go")
+	if err != nil {
+		t.Fatalf("normalizePath: %v", err)
+	}
+	if want := `C:\repo\src\pkg\file.go`; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestWindowsDriveLetterURIs(t *testing.T) {
+	uri := pathToFileURI(`C:\repo\file.go`)
+	if uri != "file:///C:/repo/file.go" {
+		t.Fatalf("unexpected URI %q", uri)
+	}
+	if got := fileURIToPath(uri); got != `C:\repo\file.go` {
+		t.Fatalf("expected drive letter path, got %q", got)
+	}
+
+	normalized, err := normalizeFileURI("file:///c%3A/repo/sub/../file.go")
+	if err != nil {
+		t.Fatalf("normalizeFileURI: %v", err)
+	}
+	if !strings.EqualFold(normalized, "file:///C:/repo/file.go") {
+		t.Fatalf("unexpected normalized URI %q", normalized)
+	}
+}
+
+func TestWindowsUNCPaths(t *testing.T) {
+	path := `\\server\share\dir\file.go`
+	uri := pathToFileURI(path)
+	if uri != "file://server/share/dir/file.go" {
+		t.Fatalf("unexpected URI %q", uri)
+	}
+	if got := fileURIToPath(uri); got != path {
+		t.Fatalf("expected %q, got %q", path, got)
+	}
+}
@@ -0,0 +1,33 @@
+package lsp
+
+import (
+	"runtime"
+	"strings"
+)
+
+// caseInsensitivePaths reports whether file paths should compare case-insensitively.
+// The default filesystems on Windows (NTFS) and macOS (APFS) ignore case, and clients
+// there freely vary drive letter and directory casing between requests.
+var caseInsensitivePaths = runtime.GOOS == "windows" || runtime.GOOS == "darwin"
+
+// uriKey returns the form of `uri` used as a map key and for comparisons.
+// URIs keep their original casing everywhere else, so responses echo the client's paths.
+func uriKey(uri string) string {
+	if caseInsensitivePaths && isFileURI(uri) {
+		return strings.ToLower(uri)
+	}
+	return uri
+}
+
+// sameURI reports whether two normalized URIs name the same document.
+func sameURI(a, b string) bool {
+	return a == b || uriKey(a) == uriKey(b)
+}
+
+// cutURIPrefix returns the rest of `uri` after `prefix`, comparing like `sameURI`.
+func cutURIPrefix(uri, prefix string) (string, bool) {
+	if len(uri) < len(prefix) || !sameURI(uri[:len(prefix)], prefix) {
+		return "", false
+	}
+	return uri[len(prefix):], true
+}
@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCaseInsensitivePaths(t *testing.T) {
+	previous := caseInsensitivePaths
+	caseInsensitivePaths = true
+	defer func() { caseInsensitivePaths = previous }()
+
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "Widget.go", "package w\n\nfunc Render() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Render", Path: uri, Line: 3, Kind: "func"},
+	})
+
+	// The client reports the same file with different casing; on a case-insensitive
+	// filesystem it's the same document.
+	clientURI := strings.Replace(uri, "Widget.go", "widget.GO", 1)
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: clientURI, Text: "package w\n\nfunc Render() {}\n"},
+	})
+	if _, err := server.cache.GetOrLoadFileContent(uri); err != nil {
+		t.Fatalf("expected the opened document to be found by the indexed URI: %v", err)
+	}
+
+	frames := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: clientURI},
+	})
+	var symbols []SymbolInformation
+	if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+		t.Fatalf("unmarshal symbols: %v", err)
+	}
+	if len(symbols) != 1 || symbols[0].Location.URI != uri {
+		t.Fatalf("expected Render from %s, got %+v", uri, symbols)
+	}
+}
@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// providers names the features that can be turned off with `--disable-<name>`,
+// e.g. to run ctags-lsp next to a language server that already provides them.
+var providers = []string{
+	"completion",
+	"definition",
+	"type-definition",
+	"references",
+	"rename",
+	"call-hierarchy",
+	"type-hierarchy",
+	"semantic-tokens",
+	"document-highlight",
+	"code-lens",
+	"folding-range",
+	"inlay-hint",
+	"hover",
+	"workspace-symbol",
+	"document-symbol",
+	"moniker",
+	"document-link",
+	"diagnostics",
+}
+
+// providerMethods maps each request method to the provider that answers it.
+var providerMethods = map[string]string{
+	"textDocument/completion":                "completion",
+	"completionItem/resolve":                 "completion",
+	"textDocument/definition":                "definition",
+	"textDocument/typeDefinition":            "type-definition",
+	"textDocument/references":                "references",
+	"textDocument/rename":                    "rename",
+	"textDocument/prepareRename":             "rename",
+	"textDocument/prepareCallHierarchy":      "call-hierarchy",
+	"callHierarchy/incomingCalls":            "call-hierarchy",
+	"callHierarchy/outgoingCalls":            "call-hierarchy",
+	"textDocument/prepareTypeHierarchy":      "type-hierarchy",
+	"typeHierarchy/supertypes":               "type-hierarchy",
+	"typeHierarchy/subtypes":                 "type-hierarchy",
+	"textDocument/semanticTokens/full":       "semantic-tokens",
+	"textDocument/semanticTokens/range":      "semantic-tokens",
+	"textDocument/semanticTokens/full/delta": "semantic-tokens",
+	"textDocument/documentHighlight":         "document-highlight",
+	"textDocument/codeLens":                  "code-lens",
+	"codeLens/resolve":                       "code-lens",
+	"textDocument/foldingRange":              "folding-range",
+	"textDocument/inlayHint":                 "inlay-hint",
+	"textDocument/hover":                     "hover",
+	"workspace/symbol":                       "workspace-symbol",
+	"textDocument/documentSymbol":            "document-symbol",
+	"textDocument/moniker":                   "moniker",
+	"textDocument/documentLink":              "document-link",
+	"documentLink/resolve":                   "document-link",
+	"textDocument/diagnostic":                "diagnostics",
+	"workspace/diagnostic":                   "diagnostics",
+}
+
+// InitializationOptions are read from the `initialize` request. Unlike settings,
+// they are known before the server announces its capabilities.
+type InitializationOptions struct {
+	// Disable lists providers to leave out, by the names of the `--disable-*` flags.
+	Disable []string `json:"disable,omitempty"`
+}
+
+// disableProviders turns off the named providers for the rest of the session.
+// Unknown names are logged and skipped.
+func (server *Server) disableProviders(names []string) {
+	for _, name := range names {
+		if !slices.Contains(providers, name) {
+			slog.Warn("ignoring unknown provider", "provider", name, "known", strings.Join(providers, ", "))
+			continue
+		}
+		if server.disabledProviders == nil {
+			server.disabledProviders = make(map[string]bool)
+		}
+		server.disabledProviders[name] = true
+	}
+}
+
+// providerDisabled reports whether `method` belongs to a disabled provider.
+func (server *Server) providerDisabled(method string) bool {
+	provider, ok := providerMethods[method]
+	return ok && server.disabledProviders[provider]
+}
+
+// removeDisabledProviders clears the capabilities of disabled providers, so the
+// client doesn't send their requests in the first place.
+func (server *Server) removeDisabledProviders(capabilities *ServerCapabilities) {
+	for provider := range server.disabledProviders {
+		switch provider {
+		case "completion":
+			capabilities.CompletionProvider = nil
+		case "definition":
+			capabilities.DefinitionProvider = false
+		case "type-definition":
+			capabilities.TypeDefinitionProvider = false
+		case "references":
+			capabilities.ReferencesProvider = false
+		case "rename":
+			capabilities.RenameProvider = nil
+		case "call-hierarchy":
+			capabilities.CallHierarchyProvider = false
+		case "type-hierarchy":
+			capabilities.TypeHierarchyProvider = false
+		case "semantic-tokens":
+			capabilities.SemanticTokensProvider = nil
+		case "document-highlight":
+			capabilities.DocumentHighlightProvider = false
+		case "code-lens":
+			capabilities.CodeLensProvider = nil
+		case "folding-range":
+			capabilities.FoldingRangeProvider = false
+		case "inlay-hint":
+			capabilities.InlayHintProvider = false
+		case "hover":
+			capabilities.HoverProvider = false
+		case "workspace-symbol":
+			capabilities.WorkspaceSymbolProvider = false
+		case "document-symbol":
+			capabilities.DocumentSymbolProvider = false
+		case "moniker":
+			capabilities.MonikerProvider = false
+		case "document-link":
+			capabilities.DocumentLinkProvider = nil
+		case "diagnostics":
+			capabilities.DiagnosticProvider = nil
+		}
+	}
+}
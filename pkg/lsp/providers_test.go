@@ -0,0 +1,39 @@
+package lsp
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDisabledProviders(t *testing.T) {
+	config := parseFlagsForTest(t, []string{"ctags-lsp", "--disable-completion", "--disable-diagnostics=true", "--disable-moniker=false"})
+	if !slices.Equal(config.disabledProviders, []string{"completion", "diagnostics"}) {
+		t.Fatalf("unexpected disabled providers %v", config.disabledProviders)
+	}
+
+	server := newServer(config, io.Discard)
+	server.initialized = true
+	server.disableProviders([]string{"document-link", "unknown"})
+
+	capabilities := ServerCapabilities{
+		CompletionProvider:   &CompletionOptions{},
+		DefinitionProvider:   true,
+		MonikerProvider:      true,
+		DocumentLinkProvider: &DocumentLinkOptions{},
+		DiagnosticProvider:   &DiagnosticOptions{},
+	}
+	server.removeDisabledProviders(&capabilities)
+	if capabilities.CompletionProvider != nil || capabilities.DiagnosticProvider != nil || capabilities.DocumentLinkProvider != nil {
+		t.Fatalf("expected disabled capabilities to be removed, got %+v", capabilities)
+	}
+	if !capabilities.DefinitionProvider || !capabilities.MonikerProvider {
+		t.Fatalf("expected enabled capabilities to be kept, got %+v", capabilities)
+	}
+
+	frames := callHandler(t, server, "textDocument/completion", CompletionParams{})
+	if len(frames) != 1 || frames[0].Error == nil || !strings.Contains(string(*frames[0].Error), "-32601") {
+		t.Fatalf("expected method not found for a disabled provider, got %+v", frames)
+	}
+}
@@ -0,0 +1,589 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// upstreamRequestTimeout is how long a request forwarded to an upstream server
+// may take before the tag index answers it instead.
+const upstreamRequestTimeout = 5 * time.Second
+
+// upstreamShutdownTimeout is how long an upstream server gets to exit after
+// `shutdown` before it is killed.
+const upstreamShutdownTimeout = 2 * time.Second
+
+// upstreamQueueSize is how many messages can wait to be written to an upstream
+// server. An upstream server that falls this far behind is given up on.
+const upstreamQueueSize = 1024
+
+// proxiedCapabilities maps the requests forwarded to upstream servers to the
+// server capability they need.
+var proxiedCapabilities = map[string]string{
+	"textDocument/completion":        "completionProvider",
+	"textDocument/definition":        "definitionProvider",
+	"textDocument/typeDefinition":    "typeDefinitionProvider",
+	"textDocument/references":        "referencesProvider",
+	"textDocument/rename":            "renameProvider",
+	"textDocument/prepareRename":     "renameProvider",
+	"textDocument/documentHighlight": "documentHighlightProvider",
+	"textDocument/foldingRange":      "foldingRangeProvider",
+	"textDocument/hover":             "hoverProvider",
+	"textDocument/documentSymbol":    "documentSymbolProvider",
+	"textDocument/moniker":           "monikerProvider",
+	"textDocument/documentLink":      "documentLinkProvider",
+}
+
+// upstreamNotifications are the notifications of upstream servers passed on to
+// the client.
+var upstreamNotifications = map[string]bool{
+	"textDocument/publishDiagnostics": true,
+	"window/showMessage":              true,
+	"window/logMessage":               true,
+}
+
+// parseUpstreams parses a `--upstream` value: "languageId=command" pairs separated
+// by semicolons, such as "go=gopls;python=pylsp --check-parent-process".
+func parseUpstreams(value string) (map[string][]string, error) {
+	upstreams := make(map[string][]string)
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		languageID, command, ok := strings.Cut(pair, "=")
+		languageID = strings.TrimSpace(languageID)
+		fields := strings.Fields(command)
+		if !ok || languageID == "" || len(fields) == 0 {
+			return nil, fmt.Errorf("invalid upstream %q: expected languageId=command", pair)
+		}
+		if _, err := exec.LookPath(fields[0]); err != nil {
+			return nil, fmt.Errorf("invalid upstream for %s: %w", languageID, err)
+		}
+		upstreams[languageID] = fields
+	}
+	return upstreams, nil
+}
+
+// upstreamServer is a language server that requests about documents of one
+// language are forwarded to first. It is started when the first such document
+// opens, and is sent what the client sent to ctags-lsp: the `initialize` params
+// and the text document notifications of its documents.
+type upstreamServer struct {
+	languageID string
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	writeMutex sync.Mutex
+	queue      chan []byte // Messages waiting to be written, in order.
+	queueMutex sync.Mutex  // Guards sending to `queue` against closing it.
+	queueOpen  bool
+
+	ready        chan struct{} // Closed when the handshake finished or failed.
+	failed       chan struct{} // Closed by `fail`.
+	failOnce     sync.Once
+	stopOnce     sync.Once
+	capabilities map[string]json.RawMessage
+
+	nextID       int64
+	pendingMutex sync.Mutex
+	pending      map[string]chan RPCRequest
+}
+
+// proxyNotification passes text document notifications on to the upstream
+// server of their document, starting it on `didOpen`. It runs in the read loop,
+// so that upstream servers see the notifications in order.
+func (server *Server) proxyNotification(req RPCRequest) {
+	if len(server.upstreamCommands) == 0 || !isNotification(req) || !strings.HasPrefix(req.Method, "textDocument/") {
+		return
+	}
+	var params struct {
+		TextDocument TextDocument `json:"textDocument"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+	uri := params.TextDocument.URI
+
+	server.upstreamsMutex.Lock()
+	if req.Method == "textDocument/didOpen" {
+		server.documentLanguages[uriKey(uri)] = params.TextDocument.LanguageID
+	}
+	upstream := server.upstreamForLocked(uri)
+	if req.Method == "textDocument/didClose" {
+		delete(server.documentLanguages, uriKey(uri))
+	}
+	server.upstreamsMutex.Unlock()
+
+	if upstream != nil {
+		upstream.send(RPCNotification{Jsonrpc: "2.0", Method: req.Method, Params: req.Params})
+	}
+}
+
+// proxyRequest forwards `req` to the upstream server of its document, and answers
+// it with the upstream result. It returns false, leaving `req` to the tag index,
+// if there is no upstream server, it lacks the capability, fails or times out,
+// or its result is empty.
+func (server *Server) proxyRequest(req RPCRequest) bool {
+	if len(server.upstreamCommands) == 0 || isNotification(req) {
+		return false
+	}
+	if req.Method == "completionItem/resolve" {
+		return server.proxyCompletionResolve(req)
+	}
+	capability, ok := proxiedCapabilities[req.Method]
+	if !ok {
+		return false
+	}
+	var params struct {
+		TextDocument TextDocumentIdentifier `json:"textDocument"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return false
+	}
+
+	server.upstreamsMutex.Lock()
+	upstream := server.upstreamForLocked(params.TextDocument.URI)
+	server.upstreamsMutex.Unlock()
+	if upstream == nil || !upstream.provides(capability) {
+		return false
+	}
+	result, err := upstream.request(req.Method, req.Params)
+	if err != nil {
+		slog.Warn("upstream server failed; answering from tags", "language", upstream.languageID, "method", req.Method, "error", err)
+		return false
+	}
+	if isEmptyResult(result) {
+		return false
+	}
+	if req.Method == "textDocument/completion" {
+		result = tagCompletionItems(result, upstream.languageID)
+	}
+	server.sendResult(req.ID, result)
+	return true
+}
+
+// upstreamCompletionData replaces the `data` of completion items from upstream
+// servers, so that `completionItem/resolve` can send them back where they came
+// from.
+type upstreamCompletionData struct {
+	Upstream string          `json:"upstream"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// tagCompletionItems marks the items of the completion `result` of the upstream
+// server for `languageID`.
+func tagCompletionItems(result json.RawMessage, languageID string) json.RawMessage {
+	var list map[string]json.RawMessage
+	itemsJSON := result
+	if bytes.HasPrefix(bytes.TrimSpace(result), []byte("{")) {
+		if json.Unmarshal(result, &list) != nil {
+			return result
+		}
+		itemsJSON = list["items"]
+	}
+	var items []map[string]json.RawMessage
+	if json.Unmarshal(itemsJSON, &items) != nil {
+		return result
+	}
+	for _, item := range items {
+		data, err := json.Marshal(upstreamCompletionData{Upstream: languageID, Data: item["data"]})
+		if err != nil {
+			return result
+		}
+		item["data"] = data
+	}
+	tagged, err := json.Marshal(items)
+	if err != nil {
+		return result
+	}
+	if list != nil {
+		list["items"] = tagged
+		if tagged, err = json.Marshal(list); err != nil {
+			return result
+		}
+	}
+	return tagged
+}
+
+// proxyCompletionResolve sends completion items that came from an upstream
+// server back there to be resolved.
+func (server *Server) proxyCompletionResolve(req RPCRequest) bool {
+	var item map[string]json.RawMessage
+	var data upstreamCompletionData
+	if json.Unmarshal(req.Params, &item) != nil || json.Unmarshal(item["data"], &data) != nil || data.Upstream == "" {
+		return false
+	}
+	if data.Data == nil {
+		delete(item, "data")
+	} else {
+		item["data"] = data.Data
+	}
+	original, err := json.Marshal(item)
+	if err != nil {
+		return false
+	}
+
+	server.upstreamsMutex.Lock()
+	upstream := server.upstreams[data.Upstream]
+	server.upstreamsMutex.Unlock()
+	if upstream != nil && upstream.provides("completionProvider") {
+		if result, err := upstream.request(req.Method, original); err == nil && !isEmptyResult(result) {
+			server.sendResult(req.ID, result)
+			return true
+		}
+	}
+	server.sendResult(req.ID, json.RawMessage(original))
+	return true
+}
+
+// upstreamForLocked returns the upstream server for the document `uri`, starting
+// it if needed, or nil if its language has none or the workspace isn't trusted.
+// The caller holds `server.upstreamsMutex`.
+func (server *Server) upstreamForLocked(uri string) *upstreamServer {
+	languageID := server.documentLanguages[uriKey(uri)]
+	command, ok := server.upstreamCommands[languageID]
+	if !ok {
+		return nil
+	}
+	if upstream, ok := server.upstreams[languageID]; ok {
+		return upstream
+	}
+	upstream := server.startUpstream(languageID, command)
+	if upstream != nil {
+		server.upstreams[languageID] = upstream
+	}
+	return upstream
+}
+
+// startUpstream starts the upstream server `command` for `languageID`. The
+// handshake runs in the background; until it is done, messages are queued.
+// Like ctags, upstream servers only run in trusted workspaces; until the
+// workspace is trusted, it returns nil and the tag index answers alone.
+func (server *Server) startUpstream(languageID string, command []string) *upstreamServer {
+	if !server.mayRunCommands() {
+		return nil
+	}
+	upstream := &upstreamServer{
+		languageID: languageID,
+		queue:      make(chan []byte, upstreamQueueSize),
+		queueOpen:  true,
+		ready:      make(chan struct{}),
+		failed:     make(chan struct{}),
+		pending:    make(map[string]chan RPCRequest),
+	}
+	fail := func(err error) *upstreamServer {
+		slog.Warn("failed to start upstream server", "language", languageID, "command", strings.Join(command, " "), "error", err)
+		upstream.fail()
+		close(upstream.ready)
+		return upstream
+	}
+
+	upstream.cmd = exec.Command(command[0], command[1:]...)
+	upstream.cmd.Dir = fileURIToPath(server.rootURI)
+	upstream.cmd.Stderr = log.Writer()
+	stdin, err := upstream.cmd.StdinPipe()
+	if err != nil {
+		return fail(err)
+	}
+	stdout, err := upstream.cmd.StdoutPipe()
+	if err != nil {
+		return fail(err)
+	}
+	if err := upstream.cmd.Start(); err != nil {
+		return fail(err)
+	}
+	upstream.stdin = stdin
+	go upstream.readLoop(server, bufio.NewReader(stdout))
+	go upstream.handshake(server.upstreamInitialize)
+	return upstream
+}
+
+// handshake initializes the upstream server, then sends it the queued messages.
+func (upstream *upstreamServer) handshake(initialize json.RawMessage) {
+	result, err := upstream.call("initialize", initialize, clientRequestTimeout)
+	var initialized struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+	}
+	if err == nil {
+		err = json.Unmarshal(result, &initialized)
+	}
+	if err != nil {
+		slog.Warn("failed to initialize upstream server", "language", upstream.languageID, "error", err)
+		upstream.fail()
+		close(upstream.ready)
+		upstream.stop()
+		return
+	}
+	upstream.capabilities = initialized.Capabilities
+	upstream.write(RPCNotification{Jsonrpc: "2.0", Method: "initialized", Params: struct{}{}})
+	close(upstream.ready)
+
+	for body := range upstream.queue {
+		if upstream.hasFailed() {
+			return
+		}
+		upstream.writeFrame(body)
+	}
+}
+
+// fail gives up on the upstream server: requests are answered from tags from
+// now on, and the queue is closed, dropping the messages still in it.
+func (upstream *upstreamServer) fail() {
+	upstream.failOnce.Do(func() {
+		close(upstream.failed)
+		upstream.queueMutex.Lock()
+		upstream.queueOpen = false
+		close(upstream.queue)
+		upstream.queueMutex.Unlock()
+	})
+}
+
+// hasFailed reports whether `fail` ran.
+func (upstream *upstreamServer) hasFailed() bool {
+	select {
+	case <-upstream.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// provides reports whether the upstream server has `capability`, waiting for its
+// handshake if needed.
+func (upstream *upstreamServer) provides(capability string) bool {
+	select {
+	case <-upstream.ready:
+	case <-time.After(upstreamRequestTimeout):
+		return false
+	}
+	if upstream.hasFailed() {
+		return false
+	}
+	value := string(bytes.TrimSpace(upstream.capabilities[capability]))
+	return value != "" && value != "false" && value != "null"
+}
+
+// send queues `message` for the upstream server, behind the messages sent before.
+// It never blocks: if the queue is full, the upstream server has missed messages
+// it needs to keep its documents in sync, so it is given up on.
+func (upstream *upstreamServer) send(message any) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	upstream.queueMutex.Lock()
+	full := false
+	if upstream.queueOpen {
+		select {
+		case upstream.queue <- body:
+		default:
+			full = true
+		}
+	}
+	upstream.queueMutex.Unlock()
+	if full {
+		slog.Warn("upstream server is not keeping up; answering from tags", "language", upstream.languageID)
+		upstream.fail()
+		go upstream.stop()
+	}
+}
+
+// request sends a request through the queue and waits for its result.
+func (upstream *upstreamServer) request(method string, params json.RawMessage) (json.RawMessage, error) {
+	id, responses := upstream.expectResponse()
+	defer upstream.forgetResponse(id)
+	upstream.send(RPCOutgoingRequest{Jsonrpc: "2.0", ID: id, Method: method, Params: params})
+	return upstream.await(method, responses, upstreamRequestTimeout)
+}
+
+// call sends a request directly, skipping the queue; for the handshake.
+func (upstream *upstreamServer) call(method string, params any, timeout time.Duration) (json.RawMessage, error) {
+	id, responses := upstream.expectResponse()
+	defer upstream.forgetResponse(id)
+	upstream.write(RPCOutgoingRequest{Jsonrpc: "2.0", ID: id, Method: method, Params: params})
+	return upstream.await(method, responses, timeout)
+}
+
+func (upstream *upstreamServer) expectResponse() (int64, chan RPCRequest) {
+	responses := make(chan RPCRequest, 1)
+	upstream.pendingMutex.Lock()
+	upstream.nextID++
+	id := upstream.nextID
+	upstream.pending[strconv.FormatInt(id, 10)] = responses
+	upstream.pendingMutex.Unlock()
+	return id, responses
+}
+
+func (upstream *upstreamServer) forgetResponse(id int64) {
+	upstream.pendingMutex.Lock()
+	delete(upstream.pending, strconv.FormatInt(id, 10))
+	upstream.pendingMutex.Unlock()
+}
+
+func (upstream *upstreamServer) await(method string, responses chan RPCRequest, timeout time.Duration) (json.RawMessage, error) {
+	select {
+	case resp := <-responses:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s failed: %s (%d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		return resp.Result, nil
+	case <-upstream.failed:
+		return nil, fmt.Errorf("%s: upstream server failed", method)
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%s: no response after %s", method, timeout)
+	}
+}
+
+func (upstream *upstreamServer) write(message any) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	upstream.writeFrame(body)
+}
+
+func (upstream *upstreamServer) writeFrame(body []byte) {
+	upstream.writeMutex.Lock()
+	defer upstream.writeMutex.Unlock()
+	fmt.Fprintf(upstream.stdin, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// readLoop routes the messages of the upstream server: responses to the waiting
+// requests, a few notifications to the client, and requests to minimal answers,
+// since the client can't be asked on its behalf. When the upstream server's
+// output ends, it has exited or is about to, and is given up on.
+func (upstream *upstreamServer) readLoop(server *Server, reader *bufio.Reader) {
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			if !upstream.hasFailed() {
+				slog.Warn("upstream server exited; answering from tags", "language", upstream.languageID, "error", err)
+			}
+			upstream.fail()
+			go upstream.stop()
+			return
+		}
+		message, err := parseMessage(body)
+		if err != nil {
+			continue
+		}
+		switch {
+		case isResponse(message):
+			upstream.pendingMutex.Lock()
+			responses, ok := upstream.pending[string(*message.ID)]
+			upstream.pendingMutex.Unlock()
+			if !ok {
+				continue
+			}
+			// A response to a request that was answered already must not block
+			// the loop; nobody reads it.
+			select {
+			case responses <- message:
+			default:
+			}
+		case isNotification(message):
+			if upstreamNotifications[message.Method] {
+				server.sendResponse(json.RawMessage(body))
+			}
+		default:
+			upstream.write(RPCSuccessResponse{Jsonrpc: "2.0", ID: message.ID, Result: upstreamRequestResult(message)})
+		}
+	}
+}
+
+// upstreamRequestResult answers a request of an upstream server: one null per
+// item for `workspace/configuration`, null otherwise.
+func upstreamRequestResult(req RPCRequest) any {
+	if req.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(req.Params, &params)
+		return make([]any, len(params.Items))
+	}
+	return nil
+}
+
+// stop shuts the upstream server down, killing it if it doesn't exit in time.
+// Only the first call does anything; later ones wait for it.
+func (upstream *upstreamServer) stop() {
+	upstream.stopOnce.Do(func() {
+		select {
+		case <-upstream.ready:
+			if !upstream.hasFailed() {
+				upstream.call("shutdown", nil, upstreamShutdownTimeout)
+				upstream.write(RPCNotification{Jsonrpc: "2.0", Method: "exit"})
+			}
+		default:
+		}
+		upstream.fail()
+		upstream.stdin.Close()
+		exited := make(chan struct{})
+		go func() {
+			upstream.cmd.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(upstreamShutdownTimeout):
+			upstream.cmd.Process.Kill()
+		}
+	})
+}
+
+// stopUpstreams shuts down every upstream server.
+func (server *Server) stopUpstreams() {
+	server.upstreamsMutex.Lock()
+	upstreams := server.upstreams
+	server.upstreams = make(map[string]*upstreamServer)
+	server.upstreamsMutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, upstream := range upstreams {
+		if upstream.cmd == nil || upstream.cmd.Process == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			upstream.stop()
+		}()
+	}
+	wg.Wait()
+}
+
+// isEmptyResult reports whether an upstream result has nothing in it: null, an
+// empty array or object, a completion list without items or a hover without
+// contents.
+func isEmptyResult(result json.RawMessage) bool {
+	switch string(bytes.TrimSpace(result)) {
+	case "", "null", "[]", "{}":
+		return true
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(result, &object) != nil {
+		return false
+	}
+	if items, ok := object["items"]; ok {
+		return isEmptyResult(items)
+	}
+	if contents, ok := object["contents"]; ok {
+		var markup struct {
+			Value *string `json:"value"`
+		}
+		if json.Unmarshal(contents, &markup) == nil && markup.Value != nil {
+			return *markup.Value == ""
+		}
+		return isEmptyResult(contents) || string(bytes.TrimSpace(contents)) == `""`
+	}
+	return false
+}
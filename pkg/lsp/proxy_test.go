@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestUpstreamHelper is the upstream server of TestUpstreamProxy, run as a child
+// process. It can only go to definitions on the first line and has no hover.
+// For TestUpstreamFailures, it can also exit at once or never read.
+func TestUpstreamHelper(t *testing.T) {
+	switch os.Getenv("CTAGS_LSP_TEST_UPSTREAM") {
+	case "1":
+	case "exit":
+		os.Exit(0)
+	case "stall":
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	default:
+		t.Skip("run by TestUpstreamProxy")
+	}
+	reader := bufio.NewReader(os.Stdin)
+	reply := func(message any) {
+		body, _ := json.Marshal(message)
+		fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			os.Exit(0)
+		}
+		req, err := parseMessage(body)
+		if err != nil {
+			os.Exit(1)
+		}
+		switch req.Method {
+		case "initialize":
+			reply(RPCSuccessResponse{Jsonrpc: "2.0", ID: req.ID, Result: map[string]any{
+				"capabilities": map[string]any{"definitionProvider": true},
+			}})
+		case "textDocument/didOpen":
+			reply(RPCNotification{Jsonrpc: "2.0", Method: "textDocument/publishDiagnostics", Params: map[string]any{
+				"uri": "file:///upstream.go", "diagnostics": []any{},
+			}})
+		case "textDocument/definition":
+			var params TextDocumentPositionParams
+			json.Unmarshal(req.Params, &params)
+			var result any
+			if params.Position.Line == 0 {
+				result = Location{URI: "file:///upstream.go", Range: Range{Start: Position{Line: 41}, End: Position{Line: 41}}}
+			}
+			reply(RPCSuccessResponse{Jsonrpc: "2.0", ID: req.ID, Result: result})
+		case "exit":
+			os.Exit(0)
+		default:
+			if req.ID != nil {
+				reply(RPCSuccessResponse{Jsonrpc: "2.0", ID: req.ID, Result: map[string]any{"upstream": true}})
+			}
+		}
+	}
+}
+
+func TestUpstreamProxy(t *testing.T) {
+	t.Setenv("CTAGS_LSP_TEST_UPSTREAM", "1")
+	root := t.TempDir()
+	tags := "Shape\tshapes.go\t/^type Shape struct{}$/;\"\tkind:struct\tline:3\n"
+	if err := os.WriteFile(filepath.Join(root, "tags"), []byte(tags), 0o644); err != nil {
+		t.Fatalf("write tags: %v", err)
+	}
+	uri := writeTestFile(t, root, "shapes.go", "package shapes\n\ntype Shape struct{}\n\nvar s Shape\n")
+
+	if _, err := parseUpstreams("go"); err == nil {
+		t.Fatal("expected an upstream without a command to be rejected")
+	}
+	config, err := parseFlags([]string{"ctags-lsp", "--upstream", "go=" + os.Args[0] + " -test.run=^TestUpstreamHelper$"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	var output bytes.Buffer
+	server := newServer(config, &output)
+	server.sequential = true
+
+	var input bytes.Buffer
+	send := func(id int, method string, params string) {
+		body := fmt.Sprintf(`{"jsonrpc": "2.0", "method": %q, "params": %s}`, method, params)
+		if id > 0 {
+			body = fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "method": %q, "params": %s}`, id, method, params)
+		}
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	position := func(line, character int) string {
+		return fmt.Sprintf(`{"textDocument": {"uri": %q}, "position": {"line": %d, "character": %d}}`, uri, line, character)
+	}
+	send(1, "initialize", fmt.Sprintf(`{"rootUri": %q, "capabilities": {}}`, pathToFileURI(root)))
+	send(0, "initialized", `{}`)
+	send(0, "textDocument/didOpen", fmt.Sprintf(`{"textDocument": {"uri": %q, "languageId": "go", "version": 1, "text": "package shapes\n\ntype Shape struct{}\n\nvar s Shape\n"}}`, uri))
+	send(2, "textDocument/definition", position(0, 9))
+	send(3, "textDocument/definition", position(4, 7))
+	send(4, "textDocument/hover", position(4, 7))
+	send(5, "shutdown", `null`)
+	send(0, "exit", `null`)
+
+	if err := serve(&input, server); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	server.pending.Wait()
+
+	results := make(map[string]string)
+	diagnostics := false
+	for _, frame := range readFrames(t, &output) {
+		if frame.Method == "textDocument/publishDiagnostics" {
+			diagnostics = true
+		}
+		results[string(frame.ID)] = string(frame.Result)
+	}
+	if !diagnostics {
+		t.Error("expected the upstream diagnostics to reach the client")
+	}
+	if !strings.Contains(results["2"], "file:///upstream.go") {
+		t.Errorf("expected the upstream definition, got %s", results["2"])
+	}
+	if !strings.Contains(results["3"], uri) || !strings.Contains(results["3"], `"line":2`) {
+		t.Errorf("expected the tag definition when upstream has none, got %s", results["3"])
+	}
+	if results["4"] == "" || strings.Contains(results["4"], "upstream") {
+		t.Errorf("expected a tag hover when upstream has no hover provider, got %s", results["4"])
+	}
+}
+
+func TestUpstreamStoppedOnExit(t *testing.T) {
+	t.Setenv("CTAGS_LSP_TEST_UPSTREAM", "1")
+	root := t.TempDir()
+	uri := writeTestFile(t, root, "shapes.go", "package shapes\n")
+	config, err := parseFlags([]string{"ctags-lsp", "--upstream", "go=" + os.Args[0] + " -test.run=^TestUpstreamHelper$"}, io.Discard)
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	server := newServer(config, io.Discard)
+	server.sequential = true
+
+	reader, writer := io.Pipe()
+	done := make(chan error)
+	go func() { done <- serve(reader, server) }()
+	send := func(method string, params string) {
+		body := fmt.Sprintf(`{"jsonrpc": "2.0", "method": %q, "params": %s}`, method, params)
+		fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	send("textDocument/didOpen", fmt.Sprintf(`{"textDocument": {"uri": %q, "languageId": "go", "version": 1, "text": "package shapes\n"}}`, uri))
+
+	var upstream *upstreamServer
+	for deadline := time.Now().Add(5 * time.Second); upstream == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		server.upstreamsMutex.Lock()
+		upstream = server.upstreams["go"]
+		server.upstreamsMutex.Unlock()
+	}
+	if upstream == nil {
+		t.Fatal("expected an upstream server for the opened document")
+	}
+	// The client exits without asking to shut down first.
+	send("exit", `null`)
+	if err := <-done; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if upstream.cmd.ProcessState == nil {
+		t.Fatal("expected the upstream server to be stopped on exit")
+	}
+}
+
+func TestUpstreamFailures(t *testing.T) {
+	command := []string{os.Args[0], "-test.run=^TestUpstreamHelper$"}
+	server := newTestServer(t, nil)
+
+	server.untrusted.Store(true)
+	if upstream := server.startUpstream("go", command); upstream != nil {
+		t.Fatal("expected no upstream server in an untrusted workspace")
+	}
+	server.untrusted.Store(false)
+
+	t.Run("exited", func(t *testing.T) {
+		t.Setenv("CTAGS_LSP_TEST_UPSTREAM", "exit")
+		upstream := server.startUpstream("go", command)
+		defer upstream.stop()
+		select {
+		case <-upstream.failed:
+		case <-time.After(upstreamRequestTimeout):
+			t.Fatal("expected the upstream server to be given up on when it exits")
+		}
+		if upstream.provides("definitionProvider") {
+			t.Fatal("expected a failed upstream server to provide nothing")
+		}
+		upstream.send(RPCNotification{Jsonrpc: "2.0", Method: "textDocument/didClose"})
+	})
+
+	t.Run("stalled", func(t *testing.T) {
+		t.Setenv("CTAGS_LSP_TEST_UPSTREAM", "stall")
+		upstream := server.startUpstream("go", command)
+		defer upstream.stop()
+		sent := make(chan struct{})
+		go func() {
+			for range upstreamQueueSize + 1 {
+				upstream.send(RPCNotification{Jsonrpc: "2.0", Method: "textDocument/didChange"})
+			}
+			close(sent)
+		}()
+		select {
+		case <-sent:
+		case <-time.After(upstreamRequestTimeout):
+			t.Fatal("expected sending to a full queue not to block")
+		}
+		if !upstream.hasFailed() {
+			t.Fatal("expected an upstream server that fell behind to be given up on")
+		}
+	})
+
+	t.Run("repeated response", func(t *testing.T) {
+		upstream := &upstreamServer{languageID: "go", queue: make(chan []byte), failed: make(chan struct{}), pending: make(map[string]chan RPCRequest)}
+		upstream.stopOnce.Do(func() {}) // There is no process to stop.
+		_, first := upstream.expectResponse()
+		_, second := upstream.expectResponse()
+		var input strings.Builder
+		for _, id := range []int{1, 1, 2} {
+			body := fmt.Sprintf(`{"jsonrpc": "2.0", "id": %d, "result": null}`, id)
+			fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+		}
+		go upstream.readLoop(server, bufio.NewReader(strings.NewReader(input.String())))
+		select {
+		case <-second:
+		case <-time.After(upstreamRequestTimeout):
+			t.Fatal("expected a repeated response not to block the responses after it")
+		}
+		if len(first) != 1 {
+			t.Fatalf("expected the first response to be kept, got %d", len(first))
+		}
+	})
+}
+
+func TestIsEmptyResult(t *testing.T) {
+	for result, want := range map[string]bool{
+		`null`:                                 true,
+		`[]`:                                   true,
+		`{"isIncomplete": false, "items": []}`: true,
+		`{"contents": {"kind": "markdown", "value": ""}}`: true,
+		`{"contents": []}`:          true,
+		`[{"uri": "file:///a.go"}]`: false,
+		`{"contents": "doc"}`:       false,
+	} {
+		if got := isEmptyResult(json.RawMessage(result)); got != want {
+			t.Errorf("isEmptyResult(%s) = %v, want %v", result, got, want)
+		}
+	}
+}
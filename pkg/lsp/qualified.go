@@ -0,0 +1,241 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// qualifiedSeparators are the scope separators of every supported language,
+// longest first so "::" wins over ":".
+var qualifiedSeparators = []string{"::", ".", "\\", "#"}
+
+// containerSeparators is the separator each language writes between nested
+// scopes, for languages that don't use ".".
+var containerSeparators = map[string]string{
+	"C++":  "::",
+	"Rust": "::",
+	"Perl": "::",
+	"Ruby": "::",
+	"PHP":  "\\",
+}
+
+// containerSeparator returns the separator `language` writes between nested scopes.
+func containerSeparator(language string) string {
+	if separator, ok := containerSeparators[language]; ok {
+		return separator
+	}
+	return "."
+}
+
+// memberSeparator returns the separator between `entry` and its scope: Ruby writes
+// instance methods as "Class#method", PHP puts "::" between a class and its members.
+func memberSeparator(entry TagEntry) string {
+	switch {
+	case entry.Language == "Ruby" && entry.Kind == "method":
+		return "#"
+	case entry.Language == "Ruby" && entry.Kind == "singletonMethod":
+		return "."
+	case entry.Language == "PHP" && entry.ScopeKind != "" && entry.ScopeKind != "namespace":
+		return "::"
+	}
+	return containerSeparator(entry.Language)
+}
+
+// containerName rewrites the scope chain of `entry` with its language's separator,
+// e.g. the Ruby scope "Outer.Inner" as "Outer::Inner".
+func containerName(entry TagEntry) string {
+	if entry.Scope == "" {
+		return ""
+	}
+	return strings.Join(splitQualifiedName(entry.Scope), containerSeparator(entry.Language))
+}
+
+// qualifiedEntryName returns the fully qualified name of `entry` as its language writes it.
+func qualifiedEntryName(entry TagEntry) string {
+	if isQualifiedTag(entry) {
+		name := unqualifiedName(entry.Name)
+		scope := strings.TrimSuffix(entry.Name, name)
+		for _, separator := range qualifiedSeparators {
+			if trimmed, ok := strings.CutSuffix(scope, separator); ok {
+				entry.Scope = trimmed
+				break
+			}
+		}
+		entry.Name = name
+	}
+	if entry.Scope == "" {
+		return entry.Name
+	}
+	return containerName(entry) + memberSeparator(entry) + entry.Name
+}
+
+// maxScopeDepth bounds scope chain resolution, in case tags form a cycle.
+const maxScopeDepth = 32
+
+// scopeIndex finds the tags that open a scope by file and name, to resolve the
+// immediate scopes some parsers emit ("Inner") into full chains ("Outer.Inner").
+type scopeIndex map[[2]string][]TagEntry
+
+// newScopeIndex indexes the entries from the files in `paths`.
+func newScopeIndex(entries []TagEntry, paths map[string]bool) scopeIndex {
+	index := make(scopeIndex)
+	for _, entry := range entries {
+		if paths[entry.Path] && !isQualifiedTag(entry) {
+			key := [2]string{entry.Path, entry.Name}
+			index[key] = append(index[key], entry)
+		}
+	}
+	return index
+}
+
+// parent returns the tag defining the scope of `entry`, preferring one whose kind
+// is the entry's scope kind.
+func (index scopeIndex) parent(entry TagEntry) (TagEntry, bool) {
+	candidates := index[[2]string{entry.Path, entry.Scope}]
+	for _, candidate := range candidates {
+		if candidate.Kind == entry.ScopeKind {
+			return candidate, true
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], true
+	}
+	return TagEntry{}, false
+}
+
+// fullScope returns the scope chain of `entry`, joined with ".". Scopes that are
+// already qualified are taken as complete.
+func (index scopeIndex) fullScope(entry TagEntry) string {
+	scope := entry.Scope
+	current := entry
+	for range maxScopeDepth {
+		if current.Scope == "" || isQualifiedName(current.Scope) {
+			break
+		}
+		parent, ok := index.parent(current)
+		if !ok || parent.Scope == "" {
+			break
+		}
+		scope = parent.Scope + "." + scope
+		current = parent
+	}
+	return scope
+}
+
+// splitQualifiedName splits a name at every scope separator.
+func splitQualifiedName(name string) []string {
+	for _, separator := range qualifiedSeparators[1:] {
+		name = strings.ReplaceAll(name, separator, qualifiedSeparators[0])
+	}
+	return strings.Split(name, qualifiedSeparators[0])
+}
+
+// normalizeQualifiedName joins the segments of a qualified name with ".", so names
+// written with different separators compare equal.
+func normalizeQualifiedName(name string) string {
+	return strings.Join(splitQualifiedName(name), ".")
+}
+
+// isQualifiedTag reports whether `entry` is an extra tag emitted by `--extras=+q`,
+// whose name is prefixed with its scope (e.g. "Outer.Inner.method").
+func isQualifiedTag(entry TagEntry) bool {
+	if entry.Extras != "" {
+		for _, extra := range strings.Split(entry.Extras, ",") {
+			if extra == "qualified" {
+				return true
+			}
+		}
+		return false
+	}
+	// Without the extras field, recognize the scope prefix directly.
+	for _, separator := range qualifiedSeparators {
+		if entry.Scope != "" && strings.HasPrefix(entry.Name, entry.Scope+separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// unqualifiedName returns the last segment of a qualified name.
+func unqualifiedName(name string) string {
+	for _, separator := range qualifiedSeparators {
+		if i := strings.LastIndex(name, separator); i >= 0 {
+			name = name[i+len(separator):]
+		}
+	}
+	return name
+}
+
+// isQualifiedName reports whether `name` contains a scope separator.
+func isQualifiedName(name string) bool {
+	return unqualifiedName(name) != name
+}
+
+// matchesQualifiedName reports whether `entry` is the definition of `qualified`,
+// either as a qualified tag or as a plain tag whose scope ends the qualifier.
+// Separators don't need to match, so "Outer#method" finds ctags' "Outer.method".
+func matchesQualifiedName(entry TagEntry, qualified string) bool {
+	qualified = normalizeQualifiedName(qualified)
+	if isQualifiedTag(entry) {
+		return normalizeQualifiedName(entry.Name) == qualified
+	}
+	if entry.Scope == "" {
+		return false
+	}
+	key := normalizeQualifiedName(entry.Scope + "." + entry.Name)
+	return qualified == key || strings.HasSuffix(qualified, "."+key)
+}
+
+// getQualifiedWord extends the word at `pos` to the left across scope separators,
+// e.g. "pkg.Type.method" with the cursor on "method". If `prefixOnly` is set, the
+// word ends at the cursor instead of at the end of the identifier.
+func (server *Server) getQualifiedWord(filePath string, pos Position, prefixOnly bool) (string, error) {
+	lines, err := server.cache.GetOrLoadFileContent(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load file content: %v", err)
+	}
+	if pos.Line >= len(lines) {
+		return "", fmt.Errorf("line %d out of range", pos.Line)
+	}
+
+	runes := []rune(lines[pos.Line])
+	if pos.Character > len(runes) {
+		return "", fmt.Errorf("character %d out of range", pos.Character)
+	}
+
+	end := pos.Character
+	if !prefixOnly {
+		for end < len(runes) && isIdentifierChar(runes[end]) {
+			end++
+		}
+	}
+
+	// "\\" and "#" only separate scopes in PHP and Ruby; elsewhere they are
+	// escapes, comments or preprocessor directives.
+	separator := rune(0)
+	switch syntaxExtensions[strings.ToLower(filepath.Ext(fileURIToPath(filePath)))] {
+	case "PHP":
+		separator = '\\'
+	case "Ruby":
+		separator = '#'
+	}
+
+	start := pos.Character
+	for start > 0 {
+		if isIdentifierChar(runes[start-1]) {
+			start--
+		} else if (runes[start-1] == '.' || runes[start-1] == separator) && start > 1 && isIdentifierChar(runes[start-2]) {
+			start--
+		} else if runes[start-1] == ':' && start > 2 && runes[start-2] == ':' && isIdentifierChar(runes[start-3]) {
+			start -= 2
+		} else {
+			break
+		}
+	}
+
+	if start == end {
+		return "", fmt.Errorf("no word found at position")
+	}
+	return string(runes[start:end]), nil
+}
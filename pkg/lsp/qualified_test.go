@@ -0,0 +1,78 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDefinitionByQualifiedName(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shapes.py", "class Circle:\n    def draw(self): pass\nclass Square:\n    def draw(self): pass\nSquare.draw(s)\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "draw", Path: uri, Line: 2, Kind: "method", Scope: "Circle", ScopeKind: "class"},
+		{Name: "Circle.draw", Path: uri, Line: 2, Kind: "method", Scope: "Circle", ScopeKind: "class", Extras: "qualified"},
+		{Name: "draw", Path: uri, Line: 4, Kind: "method", Scope: "Square", ScopeKind: "class"},
+		{Name: "Square.draw", Path: uri, Line: 4, Kind: "method", Scope: "Square", ScopeKind: "class", Extras: "qualified"},
+	})
+
+	frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 4, Character: 8},
+	})
+	var location Location
+	if err := json.Unmarshal(frames[0].Result, &location); err != nil {
+		t.Fatalf("expected a single location, got %s", frames[0].Result)
+	}
+	want := Range{Start: Position{Line: 3, Character: 8}, End: Position{Line: 3, Character: 12}}
+	if location.Range != want {
+		t.Fatalf("expected %+v, got %+v", want, location.Range)
+	}
+
+	if symbols := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	}); strings.Contains(string(symbols[0].Result), "Circle.draw") {
+		t.Fatalf("expected qualified tags to be left out of the outline, got %s", symbols[0].Result)
+	}
+}
+
+func TestScopeSeparatorsPerLanguage(t *testing.T) {
+	for _, test := range []struct {
+		entry     TagEntry
+		container string
+		qualified string
+	}{
+		{TagEntry{Name: "run", Scope: "Outer.Inner", Kind: "method", Language: "Ruby"}, "Outer::Inner", "Outer::Inner#run"},
+		{TagEntry{Name: "build", Scope: "Outer", Kind: "singletonMethod", Language: "Ruby"}, "Outer", "Outer.build"},
+		{TagEntry{Name: "draw", Scope: "gfx::Canvas", Kind: "function", Language: "C++"}, "gfx::Canvas", "gfx::Canvas::draw"},
+		{TagEntry{Name: "User", Scope: "App\\Models", ScopeKind: "namespace", Kind: "class", Language: "PHP"}, "App\\Models", "App\\Models\\User"},
+		{TagEntry{Name: "save", Scope: "App\\Models\\User", ScopeKind: "class", Kind: "method", Language: "PHP"}, "App\\Models\\User", "App\\Models\\User::save"},
+		{TagEntry{Name: "method", Scope: "pkg.Type", Kind: "member", Language: "Python"}, "pkg.Type", "pkg.Type.method"},
+	} {
+		if got := containerName(test.entry); got != test.container {
+			t.Errorf("containerName(%+v) = %q, want %q", test.entry, got, test.container)
+		}
+		if got := qualifiedEntryName(test.entry); got != test.qualified {
+			t.Errorf("qualifiedEntryName(%+v) = %q, want %q", test.entry, got, test.qualified)
+		}
+		if !matchesQualifiedName(test.entry, test.qualified) {
+			t.Errorf("expected %q to find %+v", test.qualified, test.entry)
+		}
+	}
+
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.rb", "class Inner\n  def run; end\n  def walk; end\nend\nInner#run\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "run", Path: uri, Line: 2, Kind: "method", Scope: "Inner", ScopeKind: "class", Language: "Ruby"},
+		{Name: "run", Path: uri, Line: 3, Kind: "method", Scope: "Other", ScopeKind: "class", Language: "Ruby"},
+	})
+	frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 4, Character: 7},
+	})
+	var location Location
+	if err := json.Unmarshal(frames[0].Result, &location); err != nil || location.Range.Start.Line != 1 {
+		t.Fatalf("expected Inner#run to resolve to line 2 only, got %s (%v)", frames[0].Result, err)
+	}
+}
@@ -0,0 +1,62 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// startQueryServer serves the index as JSON on `addr`, for scripts and tools
+// that don't speak LSP. Like profiles, the index reveals the workspace, so only
+// loopback addresses are accepted; an empty host binds to localhost.
+func startQueryServer(addr string, server *Server) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid query address %q: %w", addr, err)
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("query address must be a loopback address, got %q", host)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("query listener: %w", err)
+	}
+
+	go http.Serve(listener, queryHandler(server))
+	return nil
+}
+
+// queryHandler answers `GET /symbols?q=<query>` like `workspace/symbol` and
+// `GET /definition?name=<name>` with the tags defining `name`. Both return a JSON
+// array of tags in the format of `ctags --output-format=json`, with file paths.
+func queryHandler(server *Server) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /symbols", func(w http.ResponseWriter, r *http.Request) {
+		writeQueryTags(w, server.Symbols(r.URL.Query().Get("q")))
+	})
+	mux.HandleFunc("GET /definition", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			http.Error(w, "missing name parameter", http.StatusBadRequest)
+			return
+		}
+		writeQueryTags(w, server.Definitions(name))
+	})
+	return mux
+}
+
+func writeQueryTags(w http.ResponseWriter, entries []TagEntry) {
+	tags := make([]TagEntry, len(entries))
+	for i, entry := range entries {
+		entry.Type = "tag"
+		entry.Path = fileURIToPath(entry.Path)
+		tags[i] = entry
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryAPI(t *testing.T) {
+	root := t.TempDir()
+	server := newTestServer(t, []TagEntry{
+		{Name: "Render", Path: pathToFileURI(filepath.Join(root, "view.go")), Line: 4, Kind: "func"},
+		{Name: "renderAll", Path: pathToFileURI(filepath.Join(root, "view.go")), Line: 9, Kind: "func"},
+	})
+	server.options.workspaceSymbolLimit = defaultWorkspaceSymbolLimit
+	endpoint := httptest.NewServer(queryHandler(server))
+	defer endpoint.Close()
+
+	get := func(path string) (int, []TagEntry) {
+		t.Helper()
+		resp, err := http.Get(endpoint.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var tags []TagEntry
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+				t.Fatalf("decode %s: %v", path, err)
+			}
+		}
+		return resp.StatusCode, tags
+	}
+
+	if _, tags := get("/symbols?q=render"); len(tags) != 2 || tags[0].Path != filepath.Join(root, "view.go") {
+		t.Fatalf("expected both symbols with file paths, got %+v", tags)
+	}
+	if _, tags := get("/definition?name=Render"); len(tags) != 1 || tags[0].Line != 4 || tags[0].Type != "tag" {
+		t.Fatalf("expected the definition of Render, got %+v", tags)
+	}
+	if status, _ := get("/definition"); status != http.StatusBadRequest {
+		t.Fatalf("expected a missing name to be rejected, got %d", status)
+	}
+	if err := startQueryServer("0.0.0.0:0", server); err == nil {
+		t.Fatal("expected a non-loopback address to be rejected")
+	}
+}
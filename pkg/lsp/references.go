@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+type ReferenceParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	Context      ReferenceContext       `json:"context"`
+}
+
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// handleReferences returns the places the symbol at the cursor is used, from the
+// reference tags of the index (see `--reference-tags`), in file and line order.
+// With `includeDeclaration`, the definitions go-to-definition finds come first.
+// Like completion, only references in the document's extension family count.
+// Like workspace symbols, the references found by the request deadline are sent.
+func handleReferences(server *Server, req RPCRequest) {
+	var params ReferenceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	name := unqualifiedName(symbol)
+
+	ctx, done := server.trackRequest(req, "")
+	defer done()
+
+	server.mutex.Lock()
+	var definitions []TagEntry
+	if params.Context.IncludeDeclaration {
+		definitions = server.findDefinitionEntries(normalizedURI, params.Position, symbol)
+	}
+	family := server.documentFamily(normalizedURI)
+	var references []TagEntry
+	for i, entry := range server.referenceEntries {
+		if deadlineExceeded(ctx, i) {
+			break
+		}
+		if entry.Name == name && family.includes(entry) {
+			references = append(references, entry)
+		}
+	}
+	server.mutex.Unlock()
+
+	slices.SortFunc(references, func(a, b TagEntry) int {
+		return cmp.Or(strings.Compare(a.Path, b.Path), cmp.Compare(a.Line, b.Line))
+	})
+	locations := server.entryLocationsUntil(ctx, slices.Concat(definitions, references))
+
+	if requestCancelled(ctx) {
+		server.sendRequestCancelled(req)
+		return
+	}
+	if ctx.Err() != nil && len(locations) == 0 {
+		server.sendRequestFailed(req)
+		return
+	}
+	server.sendResult(req.ID, locations)
+}
@@ -0,0 +1,53 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestReferences(t *testing.T) {
+	dir := t.TempDir()
+	helpers := writeTestFile(t, dir, "helpers.py", "def render():\n    pass\n")
+	app := writeTestFile(t, dir, "app.py", "from helpers import render\n\nrender()\n")
+	other := writeTestFile(t, dir, "render.c", "#include \"render\"\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "render", Path: helpers, Line: 1, Kind: "function", Language: "Python"},
+	})
+	server.referenceEntries = []TagEntry{
+		{Name: "render", Path: app, Line: 1, Kind: "unknown", Language: "Python", Roles: "imported"},
+		{Name: "render", Path: other, Line: 1, Kind: "header", Language: "C", Roles: "local"},
+	}
+
+	references := func(includeDeclaration bool) []Location {
+		frames := callHandler(t, server, "textDocument/references", ReferenceParams{
+			TextDocument: TextDocumentIdentifier{URI: app},
+			Position:     Position{Line: 2, Character: 2},
+			Context:      ReferenceContext{IncludeDeclaration: includeDeclaration},
+		})
+		var locations []Location
+		if err := json.Unmarshal(frames[0].Result, &locations); err != nil {
+			t.Fatalf("unmarshal references %s: %v", frames[0].Result, err)
+		}
+		return locations
+	}
+
+	locations := references(false)
+	if len(locations) != 1 || locations[0].URI != app || locations[0].Range.Start != (Position{Line: 0, Character: 20}) {
+		t.Fatalf("expected the import in app.py only, got %+v", locations)
+	}
+	locations = references(true)
+	if len(locations) != 2 || locations[0].URI != helpers || locations[1].URI != app {
+		t.Fatalf("expected the definition before the reference, got %+v", locations)
+	}
+
+	server.options.requestTimeout = time.Nanosecond
+	frames := callHandler(t, server, "textDocument/references", ReferenceParams{
+		TextDocument: TextDocumentIdentifier{URI: app},
+		Position:     Position{Line: 2, Character: 2},
+	})
+	var rpcErr RPCError
+	if len(frames) != 1 || frames[0].Error == nil || json.Unmarshal(*frames[0].Error, &rpcErr) != nil || rpcErr.Code != -32803 {
+		t.Fatalf("expected a RequestFailed error past the deadline, got %+v", frames)
+	}
+}
@@ -0,0 +1,174 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"unicode"
+)
+
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+// RenameOptions is announced instead of a plain `renameProvider` to clients that
+// send `textDocument/prepareRename`.
+type RenameOptions struct {
+	PrepareProvider bool `json:"prepareProvider"`
+}
+
+type RenameParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+	NewName      string                 `json:"newName"`
+}
+
+// WorkspaceEdit matches LSP 3.17 `WorkspaceEdit` with only `changes`, which all
+// clients support.
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+// handleRename renames the symbol at the cursor by text: every whole-word
+// occurrence of its name outside comments and string literals is replaced, in
+// the files that define it and in the open and cached files of the document's
+// extension family. It can't tell apart different symbols of the same name, so
+// it refuses names the index doesn't define.
+func handleRename(server *Server, req RPCRequest) {
+	var params RenameParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+	if !isIdentifierName(params.NewName) {
+		server.sendError(req.ID, -32602, fmt.Sprintf("%q is not a valid name", params.NewName), nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	name, _, definitions, err := server.renameTarget(normalizedURI, params.Position)
+	if err != nil {
+		server.sendError(req.ID, -32803, err.Error(), nil)
+		return
+	}
+
+	// Cache keys may be folded to lower case; edits name files as tags do.
+	uris := make(map[string]string)
+	for _, entry := range definitions {
+		if _, err := server.cache.GetOrLoadFileContent(entry.Path); err != nil {
+			slog.Warn("failed to get file content", "path", entry.Path, "error", err)
+		}
+		uris[uriKey(entry.Path)] = entry.Path
+	}
+	uris[uriKey(normalizedURI)] = normalizedURI
+
+	family := server.documentFamily(normalizedURI)
+	server.cache.mutex.RLock()
+	files := maps.Clone(server.cache.content)
+	server.cache.mutex.RUnlock()
+
+	edit := WorkspaceEdit{Changes: make(map[string][]TextEdit)}
+	for _, key := range slices.Sorted(maps.Keys(files)) {
+		uri, ok := uris[key]
+		if !ok {
+			uri = key
+			if !family.includes(TagEntry{Path: uri, Language: languageForFile(uri, "")}) {
+				continue
+			}
+		}
+		var edits []TextEdit
+		for _, rng := range findCodeOccurrences(files[key], name, syntaxForFile(uri, "")) {
+			edits = append(edits, TextEdit{Range: rng, NewText: params.NewName})
+		}
+		if len(edits) > 0 {
+			edit.Changes[uri] = edits
+		}
+	}
+
+	server.sendResult(req.ID, edit)
+}
+
+// handlePrepareRename returns the range of the identifier at the cursor, with
+// its name as the placeholder, if `textDocument/rename` would accept it there.
+func handlePrepareRename(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	server.mutex.Lock()
+	name, rng, _, err := server.renameTarget(normalizedURI, params.Position)
+	server.mutex.Unlock()
+	if err != nil {
+		server.sendError(req.ID, -32803, err.Error(), nil)
+		return
+	}
+	server.sendResult(req.ID, PrepareRenameResult{Range: rng, Placeholder: name})
+}
+
+// renameTarget returns the name of the identifier at `position` of the document
+// `uri`, its range and the definitions the index has of it. It fails, with a
+// message for the user, on positions outside identifiers, in comments or string
+// literals, and on names the index doesn't define.
+// The caller holds `server.mutex`.
+func (server *Server) renameTarget(uri string, position Position) (string, Range, []TagEntry, error) {
+	symbol, err := server.getCurrentWord(uri, position)
+	if err != nil {
+		return "", Range{}, nil, errors.New("no symbol at the cursor")
+	}
+	name := unqualifiedName(symbol)
+
+	lines, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil {
+		return "", Range{}, nil, err
+	}
+	var occurrence *Range
+	for _, rng := range findCodeOccurrences(lines, name, syntaxForFile(uri, "")) {
+		if rng.Start.Line == position.Line && rng.Start.Character <= position.Character && position.Character <= rng.End.Character {
+			occurrence = &rng
+			break
+		}
+	}
+	if occurrence == nil {
+		return "", Range{}, nil, fmt.Errorf("%s is in a comment or string", name)
+	}
+
+	definitions := server.findDefinitionEntries(uri, position, symbol)
+	if len(definitions) == 0 {
+		return "", Range{}, nil, fmt.Errorf("%s isn't defined in the index", name)
+	}
+	return name, *occurrence, definitions, nil
+}
+
+// isIdentifierName reports whether `name` can replace an identifier: made of
+// identifier characters, and not starting with a digit.
+func isIdentifierName(name string) bool {
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		return false
+	}
+	for _, c := range name {
+		if !isIdentifierChar(c) {
+			return false
+		}
+	}
+	return true
+}
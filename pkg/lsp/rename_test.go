@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	dir := t.TempDir()
+	util := writeTestFile(t, dir, "util.c", "int parse(void) { return 0; }\n")
+	main := writeTestFile(t, dir, "main.c", "int main(void) {\n  // parse the input\n  return parse() + parse();\n}\n")
+	script := writeTestFile(t, dir, "parse.py", "parse = 1\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "parse", Path: util, Line: 1, Kind: "function", Language: "C"},
+		{Name: "main", Path: main, Line: 1, Kind: "function", Language: "C"},
+	})
+	for _, uri := range []string{main, script} {
+		if _, err := server.cache.GetOrLoadFileContent(uri); err != nil {
+			t.Fatalf("load %s: %v", uri, err)
+		}
+	}
+
+	rename := func(line, character int, newName string) []rpcRawEnvelope {
+		return callHandler(t, server, "textDocument/rename", RenameParams{
+			TextDocument: TextDocumentIdentifier{URI: main},
+			Position:     Position{Line: line, Character: character},
+			NewName:      newName,
+		})
+	}
+
+	frames := rename(2, 10, "parseInput")
+	var edit WorkspaceEdit
+	if err := json.Unmarshal(frames[0].Result, &edit); err != nil {
+		t.Fatalf("unmarshal rename %s: %v", frames[0].Result, err)
+	}
+	if len(edit.Changes) != 2 || len(edit.Changes[util]) != 1 {
+		t.Fatalf("expected edits in util.c and main.c only, got %+v", edit.Changes)
+	}
+	want := []TextEdit{
+		{Range: Range{Start: Position{Line: 2, Character: 9}, End: Position{Line: 2, Character: 14}}, NewText: "parseInput"},
+		{Range: Range{Start: Position{Line: 2, Character: 19}, End: Position{Line: 2, Character: 24}}, NewText: "parseInput"},
+	}
+	if !slices.Equal(edit.Changes[main], want) {
+		t.Fatalf("expected the calls outside the comment to be renamed, got %+v", edit.Changes[main])
+	}
+
+	if frames := rename(2, 10, "2fast"); frames[0].Error == nil {
+		t.Fatalf("expected an invalid name to be rejected, got %s", frames[0].Result)
+	}
+	if frames := rename(2, 3, "answer"); frames[0].Error == nil {
+		t.Fatalf("expected a keyword the index doesn't define to be rejected, got %s", frames[0].Result)
+	}
+
+	prepare := func(line, character int) []rpcRawEnvelope {
+		return callHandler(t, server, "textDocument/prepareRename", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: main},
+			Position:     Position{Line: line, Character: character},
+		})
+	}
+	frames = prepare(2, 24)
+	var prepared PrepareRenameResult
+	if err := json.Unmarshal(frames[0].Result, &prepared); err != nil {
+		t.Fatalf("unmarshal prepareRename %s: %v", frames[0].Result, err)
+	}
+	if prepared != (PrepareRenameResult{Range: want[1].Range, Placeholder: "parse"}) {
+		t.Fatalf("expected the range of the second call, got %+v", prepared)
+	}
+	if frames := prepare(1, 7); frames[0].Error == nil {
+		t.Fatalf("expected a name in a comment to be rejected, got %s", frames[0].Result)
+	}
+	if frames := prepare(2, 15); frames[0].Error == nil {
+		t.Fatalf("expected a position outside identifiers to be rejected, got %s", frames[0].Result)
+	}
+
+	if provider := (ClientCapabilities{}).renameProvider(); provider != true {
+		t.Fatalf("expected a plain rename provider without prepareSupport, got %v", provider)
+	}
+}
@@ -0,0 +1,269 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rpcLogEntry is a single frame read back from an `--rpc-log` file.
+type rpcLogEntry struct {
+	time      time.Time
+	direction string
+	body      []byte
+}
+
+// readRPCLog parses the format written by `rpcLogger.record`.
+func readRPCLog(r io.Reader) ([]rpcLogEntry, error) {
+	reader := bufio.NewReader(r)
+	var entries []rpcLogEntry
+	for {
+		header, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) && header == "" {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read rpc log header: %w", err)
+		}
+
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed rpc log header %q", strings.TrimSpace(header))
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed rpc log timestamp: %w", err)
+		}
+		length, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("malformed rpc log length: %w", err)
+		}
+
+		body := make([]byte, length+1)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return nil, fmt.Errorf("read rpc log body: %w", err)
+		}
+		entries = append(entries, rpcLogEntry{time: timestamp, direction: fields[1], body: body[:length]})
+	}
+}
+
+// runReplayCommand implements `ctags-lsp replay [options] <rpc-log>`.
+// Server options are parsed like the top-level flags so a session can be replayed
+// with the same configuration the user ran with.
+func runReplayCommand(process Process, args []string, stdout, stderr io.Writer, checkCtags func(string) error) int {
+	config, err := parseFlags(args, stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	config.version = process.Version
+	if len(config.args) != 1 {
+		fmt.Fprintf(stderr, "Error: expected exactly one rpc log path\n")
+		return 2
+	}
+	if err := process.setLogger(config, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := checkCtags(config.ctagsBin); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	file, err := os.Open(config.args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer file.Close()
+
+	entries, err := readRPCLog(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	var output bytes.Buffer
+	server := newServer(config, &output)
+	mismatches, err := replaySession(server, entries, &output, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if mismatches > 0 {
+		return 1
+	}
+	return 0
+}
+
+// replaySession feeds the inbound frames of `entries` through `serve` one message at a
+// time, then compares each replayed response with the recorded one by request id.
+// It reports every response on `report` and returns the number of mismatches.
+// Requests the server sends the client are answered with the client's recorded
+// responses (see `replayClient`) rather than replayed as input.
+func replaySession(server *Server, entries []rpcLogEntry, output *bytes.Buffer, report io.Writer) (int, error) {
+	var input bytes.Buffer
+	methods := make(map[string]string)
+	recorded := make(map[string]json.RawMessage)
+	var order []string
+	serverMethods := make(map[string]string)
+	client := &replayClient{server: server, output: output, answers: make(map[string][]RPCRequest)}
+
+	for _, entry := range entries {
+		message, err := parseMessage(entry.body)
+		switch {
+		case entry.direction == rpcDirectionIn && err == nil && isResponse(message):
+			method := serverMethods[string(*message.ID)]
+			client.answers[method] = append(client.answers[method], message)
+		case entry.direction == rpcDirectionIn:
+			fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(entry.body), entry.body)
+			if err == nil && message.ID != nil {
+				methods[string(*message.ID)] = message.Method
+			}
+		case entry.direction == rpcDirectionOut:
+			id, ok := responseID(entry.body)
+			if !ok {
+				if err == nil && message.ID != nil {
+					serverMethods[string(*message.ID)] = message.Method
+				}
+				continue
+			}
+			if _, seen := recorded[id]; !seen {
+				order = append(order, id)
+			}
+			recorded[id] = entry.body
+		}
+	}
+
+	server.output = client
+	server.sequential = true
+	if err := serve(&input, server); err != nil {
+		return 0, err
+	}
+	server.pending.Wait()
+
+	replayed := make(map[string]json.RawMessage)
+	reader := bufio.NewReader(output)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+		if id, ok := responseID(body); ok {
+			replayed[id] = body
+		}
+	}
+
+	mismatches := 0
+	for _, id := range order {
+		want := canonicalJSON(recorded[id])
+		got, ok := replayed[id]
+		switch {
+		case !ok:
+			mismatches++
+			fmt.Fprintf(report, "MISSING id=%s (%s)\n", id, methods[id])
+		case canonicalJSON(got) != want:
+			mismatches++
+			fmt.Fprintf(report, "DIFF id=%s (%s)\n", id, methods[id])
+			fmt.Fprintf(report, "- %s\n+ %s\n", want, canonicalJSON(got))
+		default:
+			fmt.Fprintf(report, "OK id=%s (%s)\n", id, methods[id])
+		}
+	}
+	fmt.Fprintf(report, "%d responses compared, %d mismatched\n", len(order), mismatches)
+
+	return mismatches, nil
+}
+
+// replayClient stands in for the client while a session is replayed: it keeps
+// what the server writes in `output`, and answers each request the server sends
+// with the next recorded response to a request of the same method. Messages are
+// replayed one at a time, so a handler waiting for the client would otherwise
+// wait for input that is only read after it returns.
+type replayClient struct {
+	server  *Server
+	output  *bytes.Buffer
+	partial []byte                  // The start of a frame not completely written yet.
+	answers map[string][]RPCRequest // By the method of the request they answer.
+}
+
+func (client *replayClient) Write(p []byte) (int, error) {
+	client.output.Write(p)
+	client.partial = append(client.partial, p...)
+	for {
+		body, rest, ok := cutFrame(client.partial)
+		if !ok {
+			break
+		}
+		client.partial = rest
+		if req, err := parseMessage(body); err == nil && req.ID != nil && req.Method != "" {
+			client.answer(req)
+		}
+	}
+	return len(p), nil
+}
+
+func (client *replayClient) answer(req RPCRequest) {
+	resp := RPCRequest{Jsonrpc: "2.0", ID: req.ID}
+	if answers := client.answers[req.Method]; len(answers) > 0 {
+		resp.Result, resp.Error = answers[0].Result, answers[0].Error
+		client.answers[req.Method] = answers[1:]
+	} else {
+		resp.Error = &RPCError{Code: -32603, Message: "no recorded response"}
+	}
+	client.server.handleClientResponse(resp)
+}
+
+// cutFrame splits the first complete frame off `data`, returning its body.
+func cutFrame(data []byte) (body, rest []byte, ok bool) {
+	header, after, found := bytes.Cut(data, []byte("\r\n\r\n"))
+	if !found {
+		return nil, data, false
+	}
+	length := -1
+	for _, line := range strings.Split(string(header), "\r\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	if length < 0 || len(after) < length {
+		return nil, data, false
+	}
+	return after[:length], after[length:], true
+}
+
+// responseID returns the id of a response frame; requests and notifications are skipped.
+func responseID(body []byte) (string, bool) {
+	var message struct {
+		ID     *json.RawMessage `json:"id"`
+		Method string           `json:"method"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil || message.ID == nil || message.Method != "" {
+		return "", false
+	}
+	return string(*message.ID), true
+}
+
+// canonicalJSON re-encodes `body` with sorted object keys so responses compare structurally.
+func canonicalJSON(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	canonical, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(canonical)
+}
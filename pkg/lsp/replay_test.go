@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplaySession(t *testing.T) {
+	var trace bytes.Buffer
+	logger := &rpcLogger{w: &trace}
+	logger.record(rpcDirectionIn, []byte(`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`))
+	logger.record(rpcDirectionOut, []byte(`{"jsonrpc":"2.0","id":1,"result":null}`))
+	logger.record(rpcDirectionIn, []byte("{\"jsonrpc\":\"2.0\",\n\"id\":2,\"method\":\"unknown/method\"}"))
+	logger.record(rpcDirectionOut, []byte(`{"jsonrpc":"2.0","id":2,"result":[]}`))
+	logger.record(rpcDirectionIn, []byte(`{"jsonrpc":"2.0","method":"exit"}`))
+
+	entries, err := readRPCLog(&trace)
+	if err != nil {
+		t.Fatalf("read rpc log: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+
+	var output, report bytes.Buffer
+	server := newTestServer(t, nil)
+	server.output = &output
+	mismatches, err := replaySession(server, entries, &output, &report)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	if mismatches != 1 {
+		t.Fatalf("expected 1 mismatch, got %d:\n%s", mismatches, report.String())
+	}
+	if !strings.Contains(report.String(), "OK id=1 (shutdown)") {
+		t.Fatalf("expected shutdown to match, got:\n%s", report.String())
+	}
+	if !strings.Contains(report.String(), "DIFF id=2 (unknown/method)") {
+		t.Fatalf("expected unknown method to differ, got:\n%s", report.String())
+	}
+}
+
+func TestReplayAnswersServerRequests(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.requireTrust = true
+
+	// The client's initialize and the server's trust question both have id 1.
+	var trace bytes.Buffer
+	logger := &rpcLogger{w: &trace}
+	logger.record(rpcDirectionIn, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":%q,"capabilities":{}}}`, server.rootURI)))
+	logger.record(rpcDirectionOut, []byte(`{"jsonrpc":"2.0","id":1,"method":"window/showMessageRequest","params":{}}`))
+	logger.record(rpcDirectionIn, []byte(`{"jsonrpc":"2.0","id":1,"result":{"title":"Don't trust"}}`))
+	logger.record(rpcDirectionOut, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+	logger.record(rpcDirectionIn, []byte(`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`))
+	logger.record(rpcDirectionOut, []byte(`{"jsonrpc":"2.0","id":2,"result":null}`))
+	entries, err := readRPCLog(&trace)
+	if err != nil {
+		t.Fatalf("read rpc log: %v", err)
+	}
+
+	var output, report bytes.Buffer
+	start := time.Now()
+	if _, err := replaySession(server, entries, &output, &report); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > clientRequestTimeout/2 {
+		t.Fatalf("expected the trust question to be answered from the recording, took %s", elapsed)
+	}
+	if !server.untrusted.Load() {
+		t.Fatal("expected the recorded answer not to trust the workspace")
+	}
+	if !strings.Contains(report.String(), "id=1 (initialize)") || !strings.Contains(report.String(), "OK id=2 (shutdown)") {
+		t.Fatalf("unexpected report:\n%s", report.String())
+	}
+}
@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxScanRetries is how often a file that ctags fails on is retried on its own
+// before it is given up on. Most failures are transient: an exec format error
+// while the system is under load, or a file locked by another process on Windows.
+const maxScanRetries = 3
+
+// scanRetryBackoff is the wait before the first retry of a file; it doubles with
+// every retry.
+var scanRetryBackoff = 200 * time.Millisecond
+
+// maxScanBackoff caps the waits of one `scanChunk` call, however many of its
+// files fail.
+var maxScanBackoff = 2 * time.Second
+
+// scanChunk runs ctags with `args` over `files`, relative to `rootDir`. When ctags
+// fails, it runs it again over both halves of the chunk separately, down to single
+// files, so a file that breaks ctags takes no others down with it. A single file
+// is retried `maxScanRetries` times before it is recorded as failed (see
+// `failedFiles`).
+func (server *Server) scanChunk(rootDir string, args, files []string) {
+	backoff := maxScanBackoff
+	server.bisectChunk(rootDir, args, files, 0, &backoff)
+}
+
+// bisectChunk is `scanChunk` on try `retry` of `files`, with at most `backoff`
+// left to wait before retries.
+func (server *Server) bisectChunk(rootDir string, args, files []string, retry int, backoff *time.Duration) {
+	cmd := exec.Command(server.ctagsBin, server.parseCtagsArgs(args...)...)
+	cmd.Dir = rootDir
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\n"))
+	err := server.processTagsOutput(cmd)
+	if err == nil {
+		return
+	}
+	if len(files) > 1 {
+		slog.Debug("splitting failed ctags chunk", "files", len(files), "error", err)
+		half := len(files) / 2
+		server.bisectChunk(rootDir, args, files[:half], 0, backoff)
+		server.bisectChunk(rootDir, args, files[half:], 0, backoff)
+		return
+	}
+	if retry >= maxScanRetries {
+		slog.Warn("giving up on a file ctags keeps failing on", "file", files[0], "error", err)
+		server.recordFailedFiles(rootDir, files, err)
+		return
+	}
+
+	slog.Debug("retrying file ctags failed on", "file", files[0], "retry", retry+1, "error", err)
+	wait := min(scanRetryBackoff<<retry, *backoff)
+	*backoff -= wait
+	time.Sleep(wait)
+	server.bisectChunk(rootDir, args, files, retry+1, backoff)
+}
+
+// recordFailedFiles remembers `files`, relative to `rootDir`, as not indexed
+// because of `err`.
+func (server *Server) recordFailedFiles(rootDir string, files []string, err error) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if server.failedFiles == nil {
+		server.failedFiles = make(map[string]string)
+	}
+	for _, file := range files {
+		server.failedFiles[pathToFileURI(absoluteIn(rootDir, file))] = err.Error()
+	}
+}
+
+// clearFailedFile forgets that `uri` failed to index, once it has been indexed.
+func (server *Server) clearFailedFile(uri string) {
+	server.mutex.Lock()
+	delete(server.failedFiles, uri)
+	server.mutex.Unlock()
+}
+
+// failedFileURIs returns the files that failed to index, sorted.
+// The caller holds `server.mutex`.
+func (server *Server) failedFileURIs() []string {
+	uris := make([]string, 0, len(server.failedFiles))
+	for uri := range server.failedFiles {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+	return uris
+}
+
+// RetryFailedResult is the result of the custom `ctagsLsp/retryFailedFiles` request.
+type RetryFailedResult struct {
+	Indexed []string `json:"indexed"`
+	Failed  []string `json:"failed"`
+}
+
+// handleRetryFailedFiles indexes the files that failed during the workspace scan
+// again, one by one, and reports which of them now succeeded.
+func handleRetryFailedFiles(server *Server, req RPCRequest) {
+	server.mutex.Lock()
+	uris := server.failedFileURIs()
+	server.mutex.Unlock()
+
+	result := RetryFailedResult{Indexed: []string{}, Failed: []string{}}
+	for _, uri := range uris {
+		if err := server.scanSingleFileTag(uri); err != nil {
+			slog.Warn("failed to index file again", "uri", uri, "error", err)
+			result.Failed = append(result.Failed, uri)
+			continue
+		}
+		result.Indexed = append(result.Indexed, uri)
+	}
+	server.sendResult(req.ID, result)
+}
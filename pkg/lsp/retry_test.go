@@ -0,0 +1,68 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetryFailedFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+	// The waits would take hours if they weren't capped.
+	scanRetryBackoff, maxScanBackoff = time.Hour, 10*time.Millisecond
+	defer func() { scanRetryBackoff, maxScanBackoff = 200*time.Millisecond, 2*time.Second }()
+
+	server := newTestServer(t, nil)
+	dir := fileURIToPath(server.rootURI)
+	// The fake ctags fails whenever bad.go is among its files, unless ok exists.
+	ok := filepath.Join(t.TempDir(), "ok")
+	script := "#!/bin/sh\n" + `files="$(cat) $*"
+case "$files" in *bad.go*) [ -e "` + ok + `" ] || exit 1 ;; esac
+for f in $files; do
+	case "$f" in *.go) echo "{\"_type\": \"tag\", \"name\": \"$(basename $f .go)\", \"path\": \"$f\", \"line\": 1, \"kind\": \"func\"}" ;; esac
+done
+`
+	server.ctagsBin = filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(server.ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	var files []string
+	for i := range 16 {
+		files = append(files, fmt.Sprintf("f%d.go", i))
+	}
+	files[5] = "bad.go"
+	for _, name := range files {
+		writeTestFile(t, dir, name, "package a\n")
+	}
+
+	server.scanChunk(dir, nil, files)
+	if len(server.tagEntries) != 15 {
+		t.Fatalf("expected the good files to be indexed, got %+v", server.tagEntries)
+	}
+	badURI := pathToFileURI(filepath.Join(dir, "bad.go"))
+	if stats := server.indexStats(); !slices.Equal(stats.FailedFiles, []string{badURI}) {
+		t.Fatalf("expected only bad.go to fail, got %v", stats.FailedFiles)
+	}
+
+	if err := os.WriteFile(ok, nil, 0o644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+	frames := callHandler(t, server, "ctagsLsp/retryFailedFiles", nil)
+	var result RetryFailedResult
+	if len(frames) != 1 || json.Unmarshal(frames[0].Result, &result) != nil {
+		t.Fatalf("unexpected response %+v", frames)
+	}
+	if !slices.Equal(result.Indexed, []string{badURI}) || len(result.Failed) != 0 {
+		t.Fatalf("expected bad.go to be indexed on retry, got %+v", result)
+	}
+	if stats := server.indexStats(); len(stats.FailedFiles) != 0 || len(server.tagEntries) != 16 {
+		t.Fatalf("expected no failed files left, got %v and %d entries", stats.FailedFiles, len(server.tagEntries))
+	}
+}
@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	rpcDirectionIn  = "in"
+	rpcDirectionOut = "out"
+)
+
+// rpcLogger records every JSON-RPC frame body verbatim.
+// Each record is a header line `<RFC3339 timestamp> <in|out> <length>` followed by
+// exactly `length` body bytes and a newline, so bodies never need escaping.
+type rpcLogger struct {
+	mutex  sync.Mutex
+	w      io.Writer
+	redact bool // Replace document text with `redactedText`, see `redactRPCBody`.
+}
+
+func openRPCLog(path string, redact bool) (*rpcLogger, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open rpc log: %w", err)
+	}
+	return &rpcLogger{w: file, redact: redact}, nil
+}
+
+// record appends one frame; it is a no-op on a nil logger.
+func (logger *rpcLogger) record(direction string, body []byte) {
+	if logger == nil {
+		return
+	}
+	if logger.redact {
+		body = redactRPCBody(body)
+	}
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	fmt.Fprintf(logger.w, "%s %s %d\n%s\n", time.Now().UTC().Format(time.RFC3339Nano), direction, len(body), body)
+}
+
+// redactedText replaces document text in a redacted rpc log.
+const redactedText = "[redacted]"
+
+// redactedKeys are the fields that carry document text: the buffers sent with
+// didOpen, didChange and didSave, and the text of edits.
+var redactedKeys = map[string]bool{"text": true, "newText": true}
+
+// redactRPCBody returns `body` with every string in a `redactedKeys` field
+// replaced by `redactedText`, so a trace can be shared without the source code
+// it touched. Bodies that aren't JSON are recorded as they are.
+func redactRPCBody(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var message any
+	if err := decoder.Decode(&message); err != nil {
+		return body
+	}
+	redacted, err := json.Marshal(redactValue(message))
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+func redactValue(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if _, isString := field.(string); isString && redactedKeys[key] {
+				value[key] = redactedText
+			} else {
+				value[key] = redactValue(field)
+			}
+		}
+	case []any:
+		for i, element := range value {
+			value[i] = redactValue(element)
+		}
+	}
+	return value
+}
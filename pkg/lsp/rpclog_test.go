@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRPCLogRecordsBothDirections(t *testing.T) {
+	for _, redact := range []bool{false, true} {
+		t.Run(fmt.Sprintf("redact=%t", redact), func(t *testing.T) {
+			server := newTestServer(t, nil)
+			var output bytes.Buffer
+			server.output = &output
+			path := filepath.Join(t.TempDir(), "rpc.log")
+			logger, err := openRPCLog(path, redact)
+			if err != nil {
+				t.Fatalf("open rpc log: %v", err)
+			}
+			t.Cleanup(func() { logger.w.(*os.File).Close() })
+			server.rpcLog = logger
+
+			uri := server.rootURI + "/secret.go"
+			var input bytes.Buffer
+			for _, body := range []string{
+				fmt.Sprintf(`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": %q, "languageId": "go", "version": 1, "text": "package secret\n"}}}`, uri),
+				`{"jsonrpc": "2.0", "id": 1, "method": "shutdown"}`,
+			} {
+				fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+			}
+			if err := serve(&input, server); err != nil {
+				t.Fatalf("serve: %v", err)
+			}
+			server.pending.Wait()
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read rpc log: %v", err)
+			}
+			entries, err := readRPCLog(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("parse rpc log: %v", err)
+			}
+			directions := make(map[string]int)
+			for _, entry := range entries {
+				directions[entry.direction]++
+			}
+			if directions[rpcDirectionIn] != 2 || directions[rpcDirectionOut] == 0 {
+				t.Fatalf("expected both inbound frames and a response, got %v:\n%s", directions, data)
+			}
+
+			if redact {
+				if strings.Contains(string(data), "package secret") || !strings.Contains(string(data), `"text":"[redacted]"`) {
+					t.Fatalf("expected the document text to be redacted, got:\n%s", data)
+				}
+				if !strings.Contains(string(data), uri) {
+					t.Fatalf("expected everything but the document text to be kept, got:\n%s", data)
+				}
+			} else if !strings.Contains(string(data), `"text": "package secret\n"`) {
+				t.Fatalf("expected frames to be recorded verbatim, got:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestRedactRPCBody(t *testing.T) {
+	body := []byte(`{"jsonrpc":"2.0","id":3,"result":{"changes":{"file:///a.go":[{"range":{},"newText":"func main() {}"}]},"text":12}}`)
+	redacted := string(redactRPCBody(body))
+	if strings.Contains(redacted, "func main") || !strings.Contains(redacted, `"newText":"[redacted]"`) {
+		t.Fatalf("expected edit text to be redacted, got %s", redacted)
+	}
+	if !strings.Contains(redacted, `"text":12`) || !strings.Contains(redacted, `"id":3`) {
+		t.Fatalf("expected values that aren't text to be kept, got %s", redacted)
+	}
+	if got := string(redactRPCBody([]byte("not json"))); got != "not json" {
+		t.Fatalf("expected a body that isn't JSON to be kept, got %q", got)
+	}
+}
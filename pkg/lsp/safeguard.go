@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// defaultMaxWorkspaceFiles is the file count above which the user is asked before
+// the workspace is scanned, e.g. because the editor was started in $HOME.
+const defaultMaxWorkspaceFiles = 100000
+
+// Choices offered for workspaces with more than `maxWorkspaceFiles` files. The empty
+// choice is a workspace of normal size.
+const (
+	largeWorkspaceIndexAll  = "Index all files"
+	largeWorkspaceOpenFiles = "Index open files only"
+	largeWorkspaceSkip      = "Don't index"
+)
+
+type MessageActionItem struct {
+	Title string `json:"title"`
+}
+
+type ShowMessageRequestParams struct {
+	Type    int                 `json:"type"`
+	Message string              `json:"message"`
+	Actions []MessageActionItem `json:"actions"`
+}
+
+// confirmLargeWorkspace asks the user how to index a workspace of `count` files.
+// Without an answer only open files are indexed: that keeps the editor usable, and
+// pinning the CPU for minutes is what the question is meant to prevent. With
+// `--session`, the answer is remembered for the workspace and not asked again.
+func (server *Server) confirmLargeWorkspace(count int) string {
+	if choice := server.rememberedWorkspaceChoice(); choice != "" {
+		slog.Info("using remembered choice for large workspace", "files", count, "choice", choice)
+		return choice
+	}
+	message := fmt.Sprintf("ctags-lsp: the workspace %s has %d files, more than the limit of %d. Indexing all of them may take a long time.",
+		fileURIToPath(server.rootURI), count, server.getOptions().maxWorkspaceFiles)
+	result, err := server.sendRequest("window/showMessageRequest", ShowMessageRequestParams{
+		Type:    MessageTypeWarning,
+		Message: message,
+		Actions: []MessageActionItem{
+			{Title: largeWorkspaceIndexAll},
+			{Title: largeWorkspaceOpenFiles},
+			{Title: largeWorkspaceSkip},
+		},
+	})
+	if err != nil {
+		slog.Warn("no answer about indexing a large workspace; indexing open files only", "files", count, "error", err)
+		return largeWorkspaceOpenFiles
+	}
+
+	// The result is null when the user dismissed the message.
+	var action *MessageActionItem
+	if err := json.Unmarshal(result, &action); err != nil || action == nil {
+		return largeWorkspaceOpenFiles
+	}
+	switch action.Title {
+	case largeWorkspaceIndexAll, largeWorkspaceOpenFiles, largeWorkspaceSkip:
+		server.rememberWorkspaceChoice(action.Title)
+		return action.Title
+	default:
+		return largeWorkspaceOpenFiles
+	}
+}
+
+// setLargeWorkspaceChoice records how the current workspace is indexed.
+func (server *Server) setLargeWorkspaceChoice(choice string) {
+	server.mutex.Lock()
+	server.workspaceChoice = choice
+	server.mutex.Unlock()
+}
+
+// indexesOpenFilesOnly reports whether files are indexed as they are opened,
+// rather than by the workspace scan.
+func (server *Server) indexesOpenFilesOnly() bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.workspaceChoice == largeWorkspaceOpenFiles
+}
+
+// indexingSkipped reports whether the user chose not to index the workspace at all.
+func (server *Server) indexingSkipped() bool {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.workspaceChoice == largeWorkspaceSkip
+}
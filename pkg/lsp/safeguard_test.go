@@ -0,0 +1,59 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+)
+
+func TestLargeWorkspaceAsksBeforeScanning(t *testing.T) {
+	for _, tc := range []struct {
+		answer string
+		want   string
+	}{
+		{answer: `{"title": "Don't index"}`, want: largeWorkspaceSkip},
+		{answer: `{"title": "Index open files only"}`, want: largeWorkspaceOpenFiles},
+		{answer: `null`, want: largeWorkspaceOpenFiles},
+	} {
+		t.Run(tc.answer, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			dir := fileURIToPath(server.rootURI)
+			for _, name := range []string{"a.go", "b.go", "c.go"} {
+				writeTestFile(t, dir, name, "package a\n")
+			}
+			server.options.maxWorkspaceFiles = 2
+			reader, writer := io.Pipe()
+			server.output = writer
+
+			done := make(chan error)
+			go func() { done <- server.scanWorkspace() }()
+
+			body, err := readFrame(bufio.NewReader(reader))
+			if err != nil {
+				t.Fatalf("read message request: %v", err)
+			}
+			var request RPCOutgoingRequest
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Fatalf("unmarshal request: %v", err)
+			}
+			if request.Method != "window/showMessageRequest" {
+				t.Fatalf("expected window/showMessageRequest, got %q", request.Method)
+			}
+
+			id := json.RawMessage(strconv.FormatInt(request.ID, 10))
+			server.handleClientResponse(RPCRequest{ID: &id, Result: json.RawMessage(tc.answer)})
+			if err := <-done; err != nil {
+				t.Fatalf("scan workspace: %v", err)
+			}
+
+			if server.workspaceChoice != tc.want {
+				t.Fatalf("expected choice %q, got %q", tc.want, server.workspaceChoice)
+			}
+			if len(server.tagEntries) != 0 {
+				t.Fatalf("expected no workspace scan, got %d entries", len(server.tagEntries))
+			}
+		})
+	}
+}
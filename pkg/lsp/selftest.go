@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selftestFiles is the fixture workspace of `--selftest`. C, since every ctags
+// build has a C parser.
+var selftestFiles = map[string]string{
+	"util.h": "#ifndef UTIL_H\n#define UTIL_H\n\nstruct shape {\n\tint sides;\n};\n\nint shape_area(const struct shape *s);\n\n#endif\n",
+	"util.c": "#include \"util.h\"\n\nint shape_area(const struct shape *s)\n{\n\treturn s->sides * 10;\n}\n",
+	"main.c": "#include \"util.h\"\n\nint main(void)\n{\n\tstruct shape square = {4};\n\treturn shape_area(&square);\n}\n",
+}
+
+// selftestEdit is main.c after the edit of the scripted session, with a name
+// being typed on line 5.
+const selftestEdit = "#include \"util.h\"\n\nint main(void)\n{\n\tstruct shape square = {4};\n\tshape_\n\treturn shape_area(&square);\n}\n"
+
+// selftestStep is one message of the scripted session. Notifications have no
+// `check`; requests pass when `check` accepts their result.
+type selftestStep struct {
+	method string
+	params any
+	check  func(result json.RawMessage) error
+}
+
+// runSelftest indexes the fixture workspace with `server` and drives a scripted
+// LSP session through `serve`, reporting each request on `report`. It returns
+// the number of requests that failed.
+func runSelftest(server *Server, report io.Writer) (int, error) {
+	root, err := os.MkdirTemp("", "ctags-lsp-selftest-")
+	if err != nil {
+		return 0, fmt.Errorf("create selftest workspace: %w", err)
+	}
+	defer os.RemoveAll(root)
+	for name, content := range selftestFiles {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			return 0, fmt.Errorf("write selftest workspace: %w", err)
+		}
+	}
+	// The fixture is ours, so there's nobody to ask about trusting it.
+	server.requireTrust = false
+
+	steps := selftestSession(root)
+	var input bytes.Buffer
+	for i, step := range steps {
+		message := map[string]any{"jsonrpc": "2.0", "method": step.method, "params": step.params}
+		if step.check != nil {
+			message["id"] = i
+		}
+		body, err := json.Marshal(message)
+		if err != nil {
+			return 0, fmt.Errorf("marshal %s: %w", step.method, err)
+		}
+		fmt.Fprintf(&input, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	var output bytes.Buffer
+	server.output = &output
+	server.sequential = true
+	if err := serve(&input, server); err != nil {
+		return 0, err
+	}
+	server.pending.Wait()
+
+	responses := make(map[string]RPCRequest)
+	reader := bufio.NewReader(&output)
+	for {
+		body, err := readFrame(reader)
+		if err != nil {
+			break
+		}
+		if response, err := parseMessage(body); err == nil && isResponse(response) {
+			responses[string(*response.ID)] = response
+		}
+	}
+
+	failures, requests := 0, 0
+	for i, step := range steps {
+		if step.check == nil {
+			continue
+		}
+		requests++
+		response, ok := responses[strconv.Itoa(i)]
+		switch {
+		case !ok:
+			err = fmt.Errorf("no response")
+		case response.Error != nil:
+			err = fmt.Errorf("error %d: %s", response.Error.Code, response.Error.Message)
+		default:
+			err = step.check(response.Result)
+		}
+		if err != nil {
+			failures++
+			fmt.Fprintf(report, "FAIL %s: %v\n", step.method, err)
+			continue
+		}
+		fmt.Fprintf(report, "ok   %s\n", step.method)
+	}
+	fmt.Fprintf(report, "%d of %d requests passed\n", requests-failures, requests)
+	return failures, nil
+}
+
+// selftestSession is the scripted session run against the fixture in `root`:
+// initialize, open and edit main.c, complete, go to definition, list symbols and
+// shut down.
+func selftestSession(root string) []selftestStep {
+	uri := func(name string) string { return pathToFileURI(filepath.Join(root, name)) }
+	mainURI := uri("main.c")
+
+	return []selftestStep{
+		{method: "initialize", params: InitializeParams{RootURI: pathToFileURI(root)}, check: func(result json.RawMessage) error {
+			var initialize InitializeResult
+			if err := json.Unmarshal(result, &initialize); err != nil {
+				return err
+			}
+			if !initialize.Capabilities.DefinitionProvider || initialize.Capabilities.CompletionProvider == nil {
+				return fmt.Errorf("expected definition and completion support, got %s", result)
+			}
+			return nil
+		}},
+		{method: "initialized", params: struct{}{}},
+		{method: "textDocument/didOpen", params: DidOpenTextDocumentParams{
+			TextDocument: TextDocument{URI: mainURI, LanguageID: "c", Version: 1, Text: selftestFiles["main.c"]},
+		}},
+		{method: "textDocument/didChange", params: DidChangeTextDocumentParams{
+			TextDocument:   VersionedTextDocumentIdentifier{URI: mainURI, Version: 2},
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: selftestEdit}},
+		}},
+		{method: "textDocument/completion", params: CompletionParams{
+			TextDocument: PositionParams{URI: mainURI},
+			Position:     Position{Line: 5, Character: 7},
+		}, check: func(result json.RawMessage) error {
+			var list CompletionList
+			if err := json.Unmarshal(result, &list); err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(list.Items, func(item CompletionItem) bool { return item.Label == "shape_area" }) {
+				return fmt.Errorf("expected shape_area, got %s", result)
+			}
+			return nil
+		}},
+		{method: "textDocument/definition", params: TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: mainURI},
+			Position:     Position{Line: 6, Character: 10},
+		}, check: func(result json.RawMessage) error {
+			if !strings.Contains(string(result), uri("util.c")) {
+				return fmt.Errorf("expected the definition in util.c, got %s", result)
+			}
+			return nil
+		}},
+		{method: "textDocument/documentSymbol", params: DocumentSymbolParams{
+			TextDocument: TextDocumentIdentifier{URI: mainURI},
+		}, check: func(result json.RawMessage) error {
+			var symbols []SymbolInformation
+			if err := json.Unmarshal(result, &symbols); err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(symbols, func(symbol SymbolInformation) bool { return symbol.Name == "main" }) {
+				return fmt.Errorf("expected main, got %s", result)
+			}
+			return nil
+		}},
+		{method: "workspace/symbol", params: WorkspaceSymbolParams{Query: "shape"}, check: func(result json.RawMessage) error {
+			var symbols []SymbolInformation
+			if err := json.Unmarshal(result, &symbols); err != nil {
+				return err
+			}
+			if !slices.ContainsFunc(symbols, func(symbol SymbolInformation) bool { return symbol.Name == "shape_area" }) {
+				return fmt.Errorf("expected shape_area, got %s", result)
+			}
+			return nil
+		}},
+		{method: "shutdown", check: func(result json.RawMessage) error {
+			if len(result) > 0 && string(result) != "null" {
+				return fmt.Errorf("expected null, got %s", result)
+			}
+			return nil
+		}},
+		{method: "exit"},
+	}
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSelftest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+
+	// The fake ctags knows the tags of the fixture files.
+	script := `#!/bin/sh
+case "$*" in *--print-language*) exit 0 ;; esac
+files="$*"
+case "$*" in *"-L -"*) files="$(cat)" ;; esac
+for f in $files; do
+	case "$f" in
+	*util.h)
+		echo '{"_type": "tag", "name": "shape", "path": "util.h", "line": 4, "kind": "struct"}'
+		echo '{"_type": "tag", "name": "shape_area", "path": "util.h", "line": 8, "kind": "prototype"}' ;;
+	*util.c) echo '{"_type": "tag", "name": "shape_area", "path": "util.c", "line": 3, "kind": "function"}' ;;
+	*main.c) echo '{"_type": "tag", "name": "main", "path": "main.c", "line": 3, "kind": "function"}' ;;
+	esac
+done
+`
+	ctagsBin := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+
+	var report bytes.Buffer
+	server := newServer(&Config{ctagsBin: ctagsBin}, io.Discard)
+	failures, err := runSelftest(server, &report)
+	if err != nil {
+		t.Fatalf("selftest: %v", err)
+	}
+	if failures != 0 || !strings.Contains(report.String(), "6 of 6 requests passed") {
+		t.Fatalf("expected every request to pass, got:\n%s", report.String())
+	}
+
+	// Without tags, the requests that need them fail.
+	if err := os.WriteFile(ctagsBin, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	report.Reset()
+	server = newServer(&Config{ctagsBin: ctagsBin}, io.Discard)
+	failures, err = runSelftest(server, &report)
+	if err != nil {
+		t.Fatalf("selftest: %v", err)
+	}
+	if failures == 0 || !strings.Contains(report.String(), "FAIL textDocument/definition") {
+		t.Fatalf("expected failures without tags, got:\n%s", report.String())
+	}
+}
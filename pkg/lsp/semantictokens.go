@@ -0,0 +1,436 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// semanticTokenTypes is the token type legend: a token's type is its index here.
+var semanticTokenTypes = []string{
+	"namespace",
+	"type",
+	"class",
+	"enum",
+	"interface",
+	"struct",
+	"typeParameter",
+	"parameter",
+	"variable",
+	"property",
+	"enumMember",
+	"function",
+	"method",
+	"macro",
+}
+
+// semanticTokenModifiers is the token modifier legend: modifier i is bit 1<<i.
+var semanticTokenModifiers = []string{
+	"declaration",
+	"readonly",
+}
+
+const (
+	semanticModifierDeclaration = 1 << iota
+	semanticModifierReadonly
+)
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend      `json:"legend"`
+	Range  bool                      `json:"range"`
+	Full   SemanticTokensFullOptions `json:"full"`
+}
+
+type SemanticTokensFullOptions struct {
+	Delta bool `json:"delta"`
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SemanticTokensDeltaParams struct {
+	TextDocument     TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                 `json:"previousResultId"`
+}
+
+type SemanticTokensRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+// SemanticTokens matches LSP 3.17 `SemanticTokens`: five integers per token,
+// relative to the token before it.
+type SemanticTokens struct {
+	ResultID string `json:"resultId,omitempty"`
+	Data     []int  `json:"data"`
+}
+
+// SemanticTokensDelta matches LSP 3.17 `SemanticTokensDelta`: the edits that
+// turn the data of the previous result into the data of this one.
+type SemanticTokensDelta struct {
+	ResultID string               `json:"resultId,omitempty"`
+	Edits    []SemanticTokensEdit `json:"edits"`
+}
+
+type SemanticTokensEdit struct {
+	Start       int   `json:"start"`
+	DeleteCount int   `json:"deleteCount"`
+	Data        []int `json:"data,omitempty"`
+}
+
+// semanticTokensCache keeps the last full result sent for each document, with
+// the version of the document and the index it was computed from. A request for
+// an open document that changed neither reuses it, and a delta request is
+// answered with the edits from it.
+type semanticTokensCache struct {
+	mutex        sync.Mutex
+	versions     map[string]int // Of the open documents, by `uriKey`.
+	documents    map[string]*documentTokens
+	nextResultID int
+}
+
+type documentTokens struct {
+	resultID   string
+	version    int
+	generation int
+	size       int
+	data       []int
+}
+
+// semanticToken is a token at an absolute position, before encoding.
+type semanticToken struct {
+	line, character, length int
+	tokenType, modifiers    int
+}
+
+func semanticTokensOptions() *SemanticTokensOptions {
+	return &SemanticTokensOptions{
+		Legend: SemanticTokensLegend{TokenTypes: semanticTokenTypes, TokenModifiers: semanticTokenModifiers},
+		Range:  true,
+		Full:   SemanticTokensFullOptions{Delta: true},
+	}
+}
+
+// semanticTokenType returns the index in `semanticTokenTypes` of the type of the
+// tokens named like `entry` and their modifiers, or false for tags that shouldn't
+// color their name.
+func semanticTokenType(entry TagEntry) (int, int, bool) {
+	index := func(name string) int { return slices.Index(semanticTokenTypes, name) }
+
+	switch entry.Kind {
+	case "macro", "define":
+		return index("macro"), semanticModifierReadonly, true
+	case "parameter":
+		return index("parameter"), 0, true
+	}
+	kind := GetLSPSymbolKind(entry.Kind)
+	if isCallableEntry(entry) && kind != SymbolKindClass && kind != SymbolKindStruct {
+		if kind == SymbolKindMethod || kind == SymbolKindConstructor || entry.Scope != "" {
+			return index("method"), 0, true
+		}
+		return index("function"), 0, true
+	}
+	switch kind {
+	case SymbolKindFile:
+		return 0, 0, false
+	case SymbolKindModule, SymbolKindNamespace, SymbolKindPackage:
+		return index("namespace"), 0, true
+	case SymbolKindClass, SymbolKindObject:
+		return index("class"), 0, true
+	case SymbolKindEnum:
+		return index("enum"), 0, true
+	case SymbolKindInterface:
+		return index("interface"), 0, true
+	case SymbolKindStruct:
+		return index("struct"), 0, true
+	case SymbolKindTypeParameter:
+		return index("typeParameter"), 0, true
+	case SymbolKindProperty, SymbolKindField:
+		return index("property"), 0, true
+	case SymbolKindEnumMember:
+		return index("enumMember"), semanticModifierReadonly, true
+	case SymbolKindConstant:
+		return index("variable"), semanticModifierReadonly, true
+	}
+	if typeAliasKinds[entry.Kind] || entry.Kind == "type" {
+		return index("type"), 0, true
+	}
+	return index("variable"), 0, true
+}
+
+// handleSemanticTokensFull colors the identifiers of a document that are named
+// like tags of its extension family, by the kind of the tag.
+func handleSemanticTokensFull(server *Server, req RPCRequest) {
+	var params SemanticTokensParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	current, _, ok := server.documentSemanticTokens(normalizedURI)
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, SemanticTokens{ResultID: current.resultID, Data: current.data})
+}
+
+// handleSemanticTokensDelta answers with the edits from the result the client
+// has to the current tokens, or with the full tokens if that result is no longer
+// the last one sent.
+func handleSemanticTokensDelta(server *Server, req RPCRequest) {
+	var params SemanticTokensDeltaParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	current, previous, ok := server.documentSemanticTokens(normalizedURI)
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	if previous == nil || previous.resultID != params.PreviousResultID {
+		server.sendResult(req.ID, SemanticTokens{ResultID: current.resultID, Data: current.data})
+		return
+	}
+	server.sendResult(req.ID, SemanticTokensDelta{ResultID: current.resultID, Edits: semanticTokensEdits(previous.data, current.data)})
+}
+
+// handleSemanticTokensRange is `handleSemanticTokensFull` for the tokens in a
+// range, typically the lines on screen, so large files are colored as they are
+// scrolled through.
+func handleSemanticTokensRange(server *Server, req RPCRequest) {
+	var params SemanticTokensRangeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	tokens, ok := server.semanticTokens(normalizedURI, &params.Range)
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, SemanticTokens{Data: encodeSemanticTokens(tokens)})
+}
+
+// semanticTokens finds the tokens of the document `uri`, in order: every
+// identifier outside comments and string literals named like a tag of the
+// document's family. Tags of the document win over those of other files, and
+// locals and parameters only count in their own file. Identifiers on the line of
+// a tag of their name in the document are declarations.
+// With `rng`, only the tokens overlapping it are returned, and the lines after it
+// aren't scanned; the lines before it still are, to know the comments and string
+// literals open where it starts.
+func (server *Server) semanticTokens(uri string, rng *Range) ([]semanticToken, bool) {
+	lines, err := server.cache.GetOrLoadFileContent(uri)
+	if err != nil {
+		return nil, false
+	}
+	if rng != nil {
+		lines = lines[:min(max(rng.End.Line+1, 0), len(lines))]
+	}
+
+	type tokenKind struct {
+		tokenType, modifiers int
+		local                bool
+	}
+	type declaration struct {
+		line int
+		name string
+	}
+	kinds := make(map[string]tokenKind)
+	declarations := make(map[declaration]bool)
+
+	server.mutex.Lock()
+	family := server.documentFamily(uri)
+	language := ""
+	for _, entry := range server.tagEntries {
+		if isQualifiedTag(entry) {
+			continue
+		}
+		local := sameURI(entry.Path, uri)
+		if !local && (entry.Kind == "local" || entry.Kind == "parameter" || !family.includes(entry)) {
+			continue
+		}
+		if local {
+			declarations[declaration{line: entry.Line - 1, name: entry.Name}] = true
+			if language == "" {
+				language = entry.Language
+			}
+		}
+		if previous, ok := kinds[entry.Name]; ok && (previous.local || !local) {
+			continue
+		}
+		if tokenType, modifiers, ok := semanticTokenType(entry); ok {
+			kinds[entry.Name] = tokenKind{tokenType: tokenType, modifiers: modifiers, local: local}
+		}
+	}
+	server.mutex.Unlock()
+
+	var tokens []semanticToken
+	forEachCodeIdentifier(lines, syntaxForFile(uri, language), func(identifier []rune, identifierRange Range) {
+		if rng != nil && (!positionBefore(identifierRange.Start, rng.End) || !positionBefore(rng.Start, identifierRange.End)) {
+			return
+		}
+		name := string(identifier)
+		kind, ok := kinds[name]
+		if !ok {
+			return
+		}
+		modifiers := kind.modifiers
+		if declarations[declaration{line: identifierRange.Start.Line, name: name}] {
+			modifiers |= semanticModifierDeclaration
+		}
+		tokens = append(tokens, semanticToken{
+			line:      identifierRange.Start.Line,
+			character: identifierRange.Start.Character,
+			length:    len(identifier),
+			tokenType: kind.tokenType,
+			modifiers: modifiers,
+		})
+	})
+	return tokens, true
+}
+
+// encodeSemanticTokens encodes `tokens`, which are in order, relative to each other.
+func encodeSemanticTokens(tokens []semanticToken) []int {
+	data := make([]int, 0, 5*len(tokens))
+	line, character := 0, 0
+	for _, token := range tokens {
+		if token.line != line {
+			character = 0
+		}
+		data = append(data, token.line-line, token.character-character, token.length, token.tokenType, token.modifiers)
+		line, character = token.line, token.character
+	}
+	return data
+}
+
+// positionBefore reports whether `a` comes before `b`.
+func positionBefore(a, b Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+// documentSemanticTokens returns the full tokens of the document `uri`, from the
+// cache if neither the open document nor the index changed since they were last
+// sent, and the result sent before, if any.
+func (server *Server) documentSemanticTokens(uri string) (current, previous *documentTokens, ok bool) {
+	cache := &server.semantic
+	key := uriKey(uri)
+	// The version is read before the content: `handleDidChange` stores them the
+	// other way round, so tokens are never cached under a version newer than
+	// their content.
+	cache.mutex.Lock()
+	version, open := cache.versions[key]
+	previous = cache.documents[key]
+	cache.mutex.Unlock()
+
+	server.mutex.Lock()
+	generation, size := server.indexGeneration(), len(server.tagEntries)
+	server.mutex.Unlock()
+
+	if open && previous != nil && previous.version == version && previous.generation == generation && previous.size == size {
+		return previous, previous, true
+	}
+
+	tokens, ok := server.semanticTokens(uri, nil)
+	if !ok {
+		return nil, nil, false
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.nextResultID++
+	current = &documentTokens{
+		resultID:   strconv.Itoa(cache.nextResultID),
+		version:    version,
+		generation: generation,
+		size:       size,
+		data:       encodeSemanticTokens(tokens),
+	}
+	if cache.documents == nil {
+		cache.documents = make(map[string]*documentTokens)
+	}
+	cache.documents[key] = current
+	return current, previous, true
+}
+
+// noteVersion records the version of the open document `uri`.
+func (cache *semanticTokensCache) noteVersion(uri string, version int) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.versions == nil {
+		cache.versions = make(map[string]int)
+	}
+	cache.versions[uriKey(uri)] = version
+}
+
+// forget drops the version and tokens of the closed document `uri`.
+func (cache *semanticTokensCache) forget(uri string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.versions, uriKey(uri))
+	delete(cache.documents, uriKey(uri))
+}
+
+// forgetMatching drops the tokens of the documents whose `uriKey` `match`
+// accepts, such as those under a renamed folder.
+func (cache *semanticTokensCache) forgetMatching(match func(key string) bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key := range cache.documents {
+		if match(key) {
+			delete(cache.documents, key)
+		}
+	}
+}
+
+// semanticTokensEdits returns the edit that turns `previous` into `current`: the
+// integers between their common prefix and suffix, replaced. Typing changes the
+// tokens of one place, so one edit is enough. It returns no edits if they are
+// equal.
+func semanticTokensEdits(previous, current []int) []SemanticTokensEdit {
+	prefix := 0
+	for prefix < len(previous) && prefix < len(current) && previous[prefix] == current[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(previous)-prefix && suffix < len(current)-prefix &&
+		previous[len(previous)-1-suffix] == current[len(current)-1-suffix] {
+		suffix++
+	}
+	if prefix == len(previous) && prefix == len(current) {
+		return []SemanticTokensEdit{}
+	}
+	return []SemanticTokensEdit{{
+		Start:       prefix,
+		DeleteCount: len(previous) - prefix - suffix,
+		Data:        current[prefix : len(current)-suffix],
+	}}
+}
@@ -0,0 +1,118 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "area.py", "def area(r):\n    return MAX * r  # area\n\nMAX = 3\nprint(area(MAX), \"MAX\")\n")
+	other := writeTestFile(t, dir, "other.py", "def r():\n    pass\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "area", Path: uri, Line: 1, Kind: "function", Language: "Python", Signature: "(r)"},
+		{Name: "MAX", Path: uri, Line: 4, Kind: "constant", Language: "Python"},
+		{Name: "r", Path: uri, Line: 1, Kind: "parameter", Language: "Python"},
+		{Name: "r", Path: other, Line: 1, Kind: "function", Language: "Python", Signature: "()"},
+	})
+
+	frames := callHandler(t, server, "textDocument/semanticTokens/full", SemanticTokensParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+	})
+	var tokens SemanticTokens
+	if err := json.Unmarshal(frames[0].Result, &tokens); err != nil {
+		t.Fatalf("decode semantic tokens %s: %v", frames[0].Result, err)
+	}
+	function := slices.Index(semanticTokenTypes, "function")
+	variable := slices.Index(semanticTokenTypes, "variable")
+	parameter := slices.Index(semanticTokenTypes, "parameter")
+	expected := []int{
+		0, 4, 4, function, semanticModifierDeclaration,
+		0, 5, 1, parameter, semanticModifierDeclaration,
+		1, 11, 3, variable, semanticModifierReadonly,
+		0, 6, 1, parameter, 0,
+		2, 0, 3, variable, semanticModifierReadonly | semanticModifierDeclaration,
+		1, 6, 4, function, 0,
+		0, 5, 3, variable, semanticModifierReadonly,
+	}
+	if !slices.Equal(tokens.Data, expected) {
+		t.Fatalf("expected tokens %v, got %v", expected, tokens.Data)
+	}
+
+	frames = callHandler(t, server, "textDocument/semanticTokens/range", SemanticTokensRangeParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Range:        Range{Start: Position{Line: 1, Character: 12}, End: Position{Line: 3, Character: 7}},
+	})
+	if err := json.Unmarshal(frames[0].Result, &tokens); err != nil {
+		t.Fatalf("decode semantic tokens range %s: %v", frames[0].Result, err)
+	}
+	expected = []int{
+		1, 11, 3, variable, semanticModifierReadonly,
+		0, 6, 1, parameter, 0,
+		2, 0, 3, variable, semanticModifierReadonly | semanticModifierDeclaration,
+	}
+	if !slices.Equal(tokens.Data, expected) {
+		t.Fatalf("expected tokens in range %v, got %v", expected, tokens.Data)
+	}
+}
+
+func TestSemanticTokensDelta(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "area.py", "")
+	server := newTestServer(t, []TagEntry{
+		{Name: "area", Path: uri, Line: 1, Kind: "function", Language: "Python", Signature: "(r)"},
+		{Name: "MAX", Path: uri, Line: 4, Kind: "constant", Language: "Python"},
+	})
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: uri, LanguageID: "python", Version: 1, Text: "def area(r):\n    return MAX * r\n"},
+	})
+
+	full := func() SemanticTokens {
+		frames := callHandler(t, server, "textDocument/semanticTokens/full", SemanticTokensParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		var tokens SemanticTokens
+		if err := json.Unmarshal(frames[0].Result, &tokens); err != nil {
+			t.Fatalf("decode semantic tokens %s: %v", frames[0].Result, err)
+		}
+		return tokens
+	}
+	first := full()
+	if first.ResultID == "" {
+		t.Fatalf("expected a result ID, got %+v", first)
+	}
+	if again := full(); again.ResultID != first.ResultID {
+		t.Fatalf("expected the cached result %q for an unchanged document, got %q", first.ResultID, again.ResultID)
+	}
+
+	callHandler(t, server, "textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "def area(r):\n    return MAX * r\n\nMAX = 3\n"}},
+	})
+	frames := callHandler(t, server, "textDocument/semanticTokens/full/delta", SemanticTokensDeltaParams{
+		TextDocument:     TextDocumentIdentifier{URI: uri},
+		PreviousResultID: first.ResultID,
+	})
+	var delta SemanticTokensDelta
+	if err := json.Unmarshal(frames[0].Result, &delta); err != nil {
+		t.Fatalf("decode semantic tokens delta %s: %v", frames[0].Result, err)
+	}
+	if delta.ResultID == "" || delta.ResultID == first.ResultID || len(delta.Edits) != 1 {
+		t.Fatalf("expected one edit under a new result ID, got %+v", delta)
+	}
+	edit := delta.Edits[0]
+	data := slices.Concat(first.Data[:edit.Start], edit.Data, first.Data[edit.Start+edit.DeleteCount:])
+	if current := full(); current.ResultID != delta.ResultID || !slices.Equal(data, current.Data) {
+		t.Fatalf("expected the edit to give %v under %q, got %v under %q", current.Data, current.ResultID, data, delta.ResultID)
+	}
+
+	frames = callHandler(t, server, "textDocument/semanticTokens/full/delta", SemanticTokensDeltaParams{
+		TextDocument:     TextDocumentIdentifier{URI: uri},
+		PreviousResultID: first.ResultID,
+	})
+	var tokens SemanticTokens
+	if err := json.Unmarshal(frames[0].Result, &tokens); err != nil || tokens.Data == nil {
+		t.Fatalf("expected full tokens for an outdated result ID, got %s", frames[0].Result)
+	}
+}
@@ -0,0 +1,206 @@
+package lsp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// maxRecentSymbols is how many jumped-to names completion ranks first.
+const maxRecentSymbols = 50
+
+// workspaceSession is the state of a workspace that `--session` keeps across
+// editor restarts, stored as JSON in a file per workspace.
+type workspaceSession struct {
+	Root string `json:"root"`
+	// Tagfile is the tagfile the index came from, if any.
+	Tagfile string `json:"tagfile,omitempty"`
+	// Settings is the last "ctagsLsp" settings object the client sent.
+	Settings json.RawMessage `json:"settings,omitempty"`
+	// WorkspaceChoice is the answer to the large workspace question.
+	WorkspaceChoice string `json:"workspaceChoice,omitempty"`
+	// RecentSymbols are the names last jumped to, most recent first.
+	RecentSymbols []string `json:"recentSymbols,omitempty"`
+}
+
+// sessionState is what the server remembers for `workspaceSession`. Recent
+// symbols are kept, and rank completions, even without `--session`.
+type sessionState struct {
+	mutex sync.Mutex
+	// dir is where sessions are stored; empty without `--session`.
+	dir             string
+	settings        json.RawMessage
+	workspaceChoice string
+	recentSymbols   []string
+}
+
+// defaultSessionDir is where `--session` stores sessions.
+func defaultSessionDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ctags-lsp", "sessions")
+}
+
+// sessionPath returns the file of the session of the workspace at `rootDir`.
+func sessionPath(dir, rootDir string) string {
+	sum := sha256.Sum256([]byte(rootDir))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// restoreSession loads the session of the workspace, if there is one: settings
+// apply before the first scan, so it indexes like the last one did, and a tagfile
+// that still exists is used again unless `--tagfile` names another.
+func (server *Server) restoreSession() {
+	server.session.mutex.Lock()
+	dir := server.session.dir
+	server.session.mutex.Unlock()
+	if dir == "" {
+		return
+	}
+	rootDir := fileURIToPath(server.rootURI)
+	data, err := os.ReadFile(sessionPath(dir, rootDir))
+	if err != nil {
+		return
+	}
+	var session workspaceSession
+	if err := json.Unmarshal(data, &session); err != nil || session.Root != rootDir {
+		slog.Warn("ignoring invalid session", "root", rootDir, "error", err)
+		return
+	}
+
+	if len(session.Settings) > 0 {
+		var settings Settings
+		if err := json.Unmarshal(session.Settings, &settings); err == nil {
+			server.applySettings(settings)
+		}
+	}
+	if server.tagfilePath == "" && session.Tagfile != "" {
+		if _, err := os.Stat(session.Tagfile); err == nil {
+			server.tagfilePath = session.Tagfile
+		}
+	}
+
+	server.session.mutex.Lock()
+	server.session.settings = session.Settings
+	server.session.workspaceChoice = session.WorkspaceChoice
+	server.session.recentSymbols = session.RecentSymbols
+	server.session.mutex.Unlock()
+	slog.Info("restored session", "root", rootDir)
+}
+
+// saveSession stores the session of the workspace, with `--session`.
+func (server *Server) saveSession() {
+	server.session.mutex.Lock()
+	dir := server.session.dir
+	session := workspaceSession{
+		Root:            fileURIToPath(server.rootURI),
+		Settings:        server.session.settings,
+		WorkspaceChoice: server.session.workspaceChoice,
+		RecentSymbols:   slices.Clone(server.session.recentSymbols),
+	}
+	server.session.mutex.Unlock()
+	if dir == "" || server.rootURI == "" {
+		return
+	}
+	server.mutex.Lock()
+	session.Tagfile = server.tagfileInUse
+	server.mutex.Unlock()
+
+	if err := writeSession(sessionPath(dir, session.Root), session); err != nil {
+		slog.Warn("failed to save session", "root", session.Root, "error", err)
+	}
+}
+
+func writeSession(path string, session workspaceSession) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".session-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace session: %w", err)
+	}
+	return nil
+}
+
+// rememberSettings keeps the settings object the client sent for the session.
+func (server *Server) rememberSettings(raw json.RawMessage) {
+	server.session.mutex.Lock()
+	server.session.settings = slices.Clone(raw)
+	server.session.mutex.Unlock()
+}
+
+// rememberedWorkspaceChoice returns the answer to the large workspace question
+// given in an earlier session, or "".
+func (server *Server) rememberedWorkspaceChoice() string {
+	server.session.mutex.Lock()
+	defer server.session.mutex.Unlock()
+	return server.session.workspaceChoice
+}
+
+// rememberWorkspaceChoice keeps the user's answer to the large workspace question
+// and saves the session, so the question isn't asked again.
+func (server *Server) rememberWorkspaceChoice(choice string) {
+	server.session.mutex.Lock()
+	server.session.workspaceChoice = choice
+	server.session.mutex.Unlock()
+	server.saveSession()
+}
+
+// rememberJump moves `name` to the front of the recently jumped-to names.
+func (server *Server) rememberJump(name string) {
+	server.session.mutex.Lock()
+	defer server.session.mutex.Unlock()
+	recent := slices.DeleteFunc(server.session.recentSymbols, func(recent string) bool { return recent == name })
+	recent = slices.Insert(recent, 0, name)
+	server.session.recentSymbols = recent[:min(len(recent), maxRecentSymbols)]
+}
+
+// recentSymbolRanks maps the recently jumped-to names to their rank, 0 for the
+// most recent.
+func (server *Server) recentSymbolRanks() map[string]int {
+	server.session.mutex.Lock()
+	defer server.session.mutex.Unlock()
+	ranks := make(map[string]int, len(server.session.recentSymbols))
+	for i, name := range server.session.recentSymbols {
+		ranks[name] = i
+	}
+	return ranks
+}
+
+// rankRecentCompletions sorts the items of recently jumped-to names first, most
+// recent first, and the rest by label.
+func rankRecentCompletions(items []CompletionItem, ranks map[string]int) {
+	if len(ranks) == 0 {
+		return
+	}
+	for i := range items {
+		if rank, ok := ranks[items[i].Label]; ok {
+			items[i].SortText = fmt.Sprintf("0%02d%s", rank, items[i].Label)
+		} else {
+			items[i].SortText = "1" + items[i].Label
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package lsp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	source := writeTestFile(t, dir, "app.go", "package app\n\nfunc parseArgs() {}\nfunc parseConfig() {}\n\nfunc main() { parseConfig() }\nparse\n")
+
+	entries := []TagEntry{
+		{Name: "parseArgs", Path: source, Line: 3, Kind: "func", Language: "Go"},
+		{Name: "parseConfig", Path: source, Line: 4, Kind: "func", Language: "Go"},
+	}
+	server := newTestServer(t, entries)
+	server.rootURI = pathToFileURI(dir)
+	server.session.dir = filepath.Join(t.TempDir(), "sessions")
+	callHandler(t, server, "textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocument{URI: source, LanguageID: "go", Text: "package app\n\nfunc parseArgs() {}\nfunc parseConfig() {}\n\nfunc main() { parseConfig() }\nparse\n"},
+	})
+	callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: source},
+		Position:     Position{Line: 5, Character: 16},
+	})
+	server.updateSettings(json.RawMessage(`{"workspaceSymbolLimit": 7}`))
+	server.rememberWorkspaceChoice(largeWorkspaceOpenFiles)
+
+	completionLabels := func(server *Server) []string {
+		frames := callHandler(t, server, "textDocument/completion", CompletionParams{
+			TextDocument: PositionParams{URI: source},
+			Position:     Position{Line: 6, Character: 5},
+		})
+		var list CompletionList
+		if err := json.Unmarshal(frames[0].Result, &list); err != nil {
+			t.Fatalf("unmarshal completion: %v", err)
+		}
+		slices.SortFunc(list.Items, func(a, b CompletionItem) int { return strings.Compare(a.SortText, b.SortText) })
+		var labels []string
+		for _, item := range list.Items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+	if labels := completionLabels(server); !slices.Equal(labels, []string{"parseConfig", "parseArgs"}) {
+		t.Fatalf("expected the jumped-to name first, got %v", labels)
+	}
+
+	restored := newTestServer(t, entries)
+	restored.rootURI = server.rootURI
+	restored.session.dir = server.session.dir
+	restored.cache.content = server.cache.content
+	restored.restoreSession()
+	if limit := restored.getOptions().workspaceSymbolLimit; limit != 7 {
+		t.Fatalf("expected restored workspaceSymbolLimit 7, got %d", limit)
+	}
+	if choice := restored.confirmLargeWorkspace(200000); choice != largeWorkspaceOpenFiles {
+		t.Fatalf("expected the remembered large workspace choice, got %q", choice)
+	}
+	if labels := completionLabels(restored); !slices.Equal(labels, []string{"parseConfig", "parseArgs"}) {
+		t.Fatalf("expected restored recent jumps to rank completion, got %v", labels)
+	}
+
+	other := newTestServer(t, entries)
+	other.session.dir = server.session.dir
+	other.restoreSession()
+	if choice := other.rememberedWorkspaceChoice(); choice != "" {
+		t.Fatalf("expected no session for another workspace, got choice %q", choice)
+	}
+}
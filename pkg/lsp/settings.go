@@ -0,0 +1,364 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// settingsSection is the configuration section requested via `workspace/configuration`.
+const settingsSection = "ctagsLsp"
+
+// serverOptions holds the tunables that can change at runtime through client settings.
+// Read them with `server.getOptions()`; never access `server.options` directly outside this file.
+type serverOptions struct {
+	languages              string
+	ctagArgs               []string
+	workspaceSymbolLimit   int
+	maxResponseSize        int
+	requestTimeout         time.Duration
+	trimTrailingWhitespace bool
+	referenceTags          bool
+	qualifiedTags          bool
+	embeddedLanguages      bool
+	encoding               string
+	includePaths           []string
+	compileCommands        string
+	goModuleDeps           bool
+	sitePackages           bool
+	venv                   string
+	rubyGems               bool
+	jvmSources             bool
+	extensionFamilies      [][]string
+	maxFileSize            int64
+	maxWorkspaceFiles      int
+	exclude                []string
+	workers                int
+	lowPriority            bool
+	typingPause            time.Duration
+	fuzzySymbolSearch      bool
+	fileSymbols            bool
+	followTypedefs         bool
+	hoverBlame             bool
+	unusedSymbols          bool
+	unknownSymbols         bool
+	inlayHints             bool
+	codeLens               bool
+
+	documentSymbolExcludeKinds []string
+	documentSymbolOrder        string
+}
+
+// Settings mirrors the "ctagsLsp" configuration section.
+// Fields left unset keep the value from the command line.
+type Settings struct {
+	Languages              *string                 `json:"languages,omitempty"`
+	CtagsArgs              *string                 `json:"ctagsArgs,omitempty"`
+	WorkspaceSymbolLimit   *int                    `json:"workspaceSymbolLimit,omitempty"`
+	MaxResponseSize        *int                    `json:"maxResponseSize,omitempty"`
+	RequestTimeout         *settingsDuration       `json:"requestTimeout,omitempty"`
+	TrimTrailingWhitespace *bool                   `json:"trimTrailingWhitespace,omitempty"`
+	DocumentSymbol         *DocumentSymbolSettings `json:"documentSymbol,omitempty"`
+	ReferenceTags          *bool                   `json:"referenceTags,omitempty"`
+	QualifiedTags          *bool                   `json:"qualifiedTags,omitempty"`
+	EmbeddedLanguages      *bool                   `json:"embeddedLanguages,omitempty"`
+	Encoding               *string                 `json:"encoding,omitempty"`
+	IncludePaths           *[]string               `json:"includePaths,omitempty"`
+	CompileCommands        *string                 `json:"compileCommands,omitempty"`
+	GoModuleDeps           *bool                   `json:"goModuleDeps,omitempty"`
+	SitePackages           *bool                   `json:"sitePackages,omitempty"`
+	Venv                   *string                 `json:"venv,omitempty"`
+	RubyGems               *bool                   `json:"rubyGems,omitempty"`
+	JVMSources             *bool                   `json:"jvmSources,omitempty"`
+	ExtensionFamilies      *[][]string             `json:"extensionFamilies,omitempty"`
+	MaxFileSize            *int64                  `json:"maxFileSize,omitempty"`
+	MaxWorkspaceFiles      *int                    `json:"maxWorkspaceFiles,omitempty"`
+	Exclude                *[]string               `json:"exclude,omitempty"`
+	Workers                *int                    `json:"workers,omitempty"`
+	LowPriority            *bool                   `json:"lowPriority,omitempty"`
+	TypingPause            *settingsDuration       `json:"typingPause,omitempty"`
+	FuzzySymbolSearch      *bool                   `json:"fuzzySymbolSearch,omitempty"`
+	FileSymbols            *bool                   `json:"fileSymbols,omitempty"`
+	FollowTypedefs         *bool                   `json:"followTypedefs,omitempty"`
+	HoverBlame             *bool                   `json:"hoverBlame,omitempty"`
+	UnusedSymbols          *bool                   `json:"unusedSymbols,omitempty"`
+	UnknownSymbols         *bool                   `json:"unknownSymbols,omitempty"`
+	InlayHints             *bool                   `json:"inlayHints,omitempty"`
+	CodeLens               *bool                   `json:"codeLens,omitempty"`
+}
+
+type DocumentSymbolSettings struct {
+	// ExcludeKinds lists ctags kind names (e.g. "local", "variable") to hide from outlines.
+	ExcludeKinds *[]string `json:"excludeKinds,omitempty"`
+	// Order is "position" or "kind".
+	Order *string `json:"order,omitempty"`
+}
+
+// settingsDuration accepts either a Go duration string ("500ms") or a number of milliseconds.
+type settingsDuration time.Duration
+
+func (d *settingsDuration) UnmarshalJSON(data []byte) error {
+	var millis float64
+	if err := json.Unmarshal(data, &millis); err == nil {
+		*d = settingsDuration(time.Duration(millis * float64(time.Millisecond)))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("duration must be a string or a number of milliseconds")
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = settingsDuration(parsed)
+	return nil
+}
+
+type ConfigurationItem struct {
+	Section string `json:"section,omitempty"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type DidChangeConfigurationParams struct {
+	Settings json.RawMessage `json:"settings"`
+}
+
+func (server *Server) getOptions() serverOptions {
+	server.optionsMutex.RLock()
+	defer server.optionsMutex.RUnlock()
+	return server.options
+}
+
+// applySettings merges `settings` into the current options and reports whether
+// options affecting the tag index changed.
+func (server *Server) applySettings(settings Settings) (reindex bool) {
+	server.optionsMutex.Lock()
+	defer server.optionsMutex.Unlock()
+
+	// Settings may come from the workspace itself, so in an untrusted one they
+	// can't pass arguments to ctags, make the scan run `go list` (which may
+	// download a toolchain named in go.mod) or `bundle list` (which evaluates the
+	// Gemfile), point at a compilation database to read include paths from, add
+	// directories outside the workspace to the scan, or raise the number of files
+	// that are scanned.
+	trusted := func(setting string) bool {
+		if !server.mayRunCommands() {
+			slog.Warn("ignoring setting in an untrusted workspace", "setting", setting)
+			return false
+		}
+		return true
+	}
+
+	previous := server.options
+	if settings.Languages != nil {
+		server.options.languages = *settings.Languages
+	}
+	if settings.CtagsArgs != nil && trusted("ctagsArgs") {
+		server.options.ctagArgs = strings.Fields(*settings.CtagsArgs)
+	}
+	if settings.WorkspaceSymbolLimit != nil {
+		server.options.workspaceSymbolLimit = *settings.WorkspaceSymbolLimit
+	}
+	if settings.MaxResponseSize != nil {
+		server.options.maxResponseSize = *settings.MaxResponseSize
+	}
+	if settings.RequestTimeout != nil {
+		server.options.requestTimeout = time.Duration(*settings.RequestTimeout)
+	}
+	if settings.TrimTrailingWhitespace != nil {
+		server.options.trimTrailingWhitespace = *settings.TrimTrailingWhitespace
+	}
+	if settings.ReferenceTags != nil {
+		server.options.referenceTags = *settings.ReferenceTags
+	}
+	if settings.QualifiedTags != nil {
+		server.options.qualifiedTags = *settings.QualifiedTags
+	}
+	if settings.Encoding != nil {
+		if enc, err := lookupEncoding(*settings.Encoding); err != nil {
+			slog.Warn("ignoring encoding setting", "error", err)
+		} else {
+			server.options.encoding = *settings.Encoding
+			server.cache.setEncoding(enc)
+		}
+	}
+	if settings.IncludePaths != nil && trusted("includePaths") {
+		server.options.includePaths = *settings.IncludePaths
+	}
+	if settings.CompileCommands != nil && trusted("compileCommands") {
+		server.options.compileCommands = *settings.CompileCommands
+	}
+	if settings.GoModuleDeps != nil && trusted("goModuleDeps") {
+		server.options.goModuleDeps = *settings.GoModuleDeps
+	}
+	if settings.SitePackages != nil && trusted("sitePackages") {
+		server.options.sitePackages = *settings.SitePackages
+	}
+	if settings.Venv != nil && trusted("venv") {
+		server.options.venv = *settings.Venv
+	}
+	if settings.RubyGems != nil && trusted("rubyGems") {
+		server.options.rubyGems = *settings.RubyGems
+	}
+	if settings.JVMSources != nil && trusted("jvmSources") {
+		server.options.jvmSources = *settings.JVMSources
+	}
+	if settings.ExtensionFamilies != nil {
+		server.options.extensionFamilies = nil
+		for _, family := range *settings.ExtensionFamilies {
+			server.options.extensionFamilies = append(server.options.extensionFamilies, normalizeExtensions(family))
+		}
+	}
+	if settings.MaxFileSize != nil {
+		server.options.maxFileSize = *settings.MaxFileSize
+	}
+	if settings.MaxWorkspaceFiles != nil && trusted("maxWorkspaceFiles") {
+		server.options.maxWorkspaceFiles = *settings.MaxWorkspaceFiles
+	}
+	if settings.Exclude != nil {
+		server.options.exclude = *settings.Exclude
+	}
+	if settings.Workers != nil {
+		server.options.workers = *settings.Workers
+	}
+	if settings.LowPriority != nil {
+		server.options.lowPriority = *settings.LowPriority
+	}
+	if settings.TypingPause != nil {
+		server.options.typingPause = time.Duration(*settings.TypingPause)
+	}
+	if settings.FuzzySymbolSearch != nil {
+		server.options.fuzzySymbolSearch = *settings.FuzzySymbolSearch
+	}
+	if settings.FileSymbols != nil {
+		server.options.fileSymbols = *settings.FileSymbols
+	}
+	if settings.FollowTypedefs != nil {
+		server.options.followTypedefs = *settings.FollowTypedefs
+	}
+	if settings.HoverBlame != nil {
+		server.options.hoverBlame = *settings.HoverBlame
+	}
+	if settings.UnusedSymbols != nil {
+		server.options.unusedSymbols = *settings.UnusedSymbols
+	}
+	if settings.UnknownSymbols != nil {
+		server.options.unknownSymbols = *settings.UnknownSymbols
+	}
+	if settings.InlayHints != nil {
+		server.options.inlayHints = *settings.InlayHints
+	}
+	if settings.CodeLens != nil {
+		server.options.codeLens = *settings.CodeLens
+	}
+	if settings.EmbeddedLanguages != nil {
+		server.options.embeddedLanguages = *settings.EmbeddedLanguages
+	}
+	if documentSymbol := settings.DocumentSymbol; documentSymbol != nil {
+		if documentSymbol.ExcludeKinds != nil {
+			server.options.documentSymbolExcludeKinds = *documentSymbol.ExcludeKinds
+		}
+		if documentSymbol.Order != nil {
+			server.options.documentSymbolOrder = *documentSymbol.Order
+		}
+	}
+
+	return previous.languages != server.options.languages ||
+		previous.referenceTags != server.options.referenceTags ||
+		previous.qualifiedTags != server.options.qualifiedTags ||
+		previous.embeddedLanguages != server.options.embeddedLanguages ||
+		previous.maxFileSize != server.options.maxFileSize ||
+		previous.compileCommands != server.options.compileCommands ||
+		previous.goModuleDeps != server.options.goModuleDeps ||
+		previous.sitePackages != server.options.sitePackages ||
+		previous.venv != server.options.venv ||
+		previous.rubyGems != server.options.rubyGems ||
+		previous.jvmSources != server.options.jvmSources ||
+		!slices.Equal(previous.ctagArgs, server.options.ctagArgs) ||
+		!slices.Equal(previous.exclude, server.options.exclude)
+}
+
+// fetchSettings pulls the "ctagsLsp" section from the client and applies it.
+// It is a no-op for clients that don't advertise `workspace.configuration`.
+func (server *Server) fetchSettings() {
+	if !server.clientCapabilities.Workspace.Configuration {
+		return
+	}
+
+	result, err := server.sendRequest("workspace/configuration", ConfigurationParams{
+		Items: []ConfigurationItem{{Section: settingsSection}},
+	})
+	if err != nil {
+		slog.Warn("failed to fetch settings", "error", err)
+		return
+	}
+
+	var sections []json.RawMessage
+	if err := json.Unmarshal(result, &sections); err != nil || len(sections) == 0 {
+		slog.Warn("invalid workspace/configuration response", "error", err)
+		return
+	}
+	server.updateSettings(sections[0])
+}
+
+// updateSettings decodes a settings object and rescans the workspace if needed.
+func (server *Server) updateSettings(raw json.RawMessage) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return
+	}
+
+	var settings Settings
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		slog.Warn("invalid ctagsLsp settings", "error", err)
+		return
+	}
+	server.rememberSettings(raw)
+
+	if server.applySettings(settings) {
+		if err := server.rescanWorkspace(); err != nil {
+			slog.Error("failed to rescan workspace after settings change", "error", err)
+		}
+	}
+}
+
+func handleInitialized(server *Server, _ RPCRequest) {
+	// Settings are pulled asynchronously: the response arrives through the same
+	// read loop that dispatched this notification.
+	go server.fetchSettings()
+	go server.reportStaleTagfile()
+}
+
+func handleDidChangeConfiguration(server *Server, req RPCRequest) {
+	var params DidChangeConfigurationParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return
+	}
+
+	// Push-style clients send the settings inline; pull-style clients send null.
+	var sections map[string]json.RawMessage
+	if json.Unmarshal(params.Settings, &sections) == nil {
+		if section, ok := sections[settingsSection]; ok {
+			server.updateSettings(section)
+			return
+		}
+	}
+	go server.fetchSettings()
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFetchSettingsFromClient(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.clientCapabilities.Workspace.Configuration = true
+	reader, writer := io.Pipe()
+	server.output = writer
+
+	done := make(chan struct{})
+	go func() {
+		server.fetchSettings()
+		close(done)
+	}()
+
+	body, err := readFrame(bufio.NewReader(reader))
+	if err != nil {
+		t.Fatalf("read configuration request: %v", err)
+	}
+	var request RPCOutgoingRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	if request.Method != "workspace/configuration" {
+		t.Fatalf("expected workspace/configuration, got %q", request.Method)
+	}
+
+	id := json.RawMessage(strconv.FormatInt(request.ID, 10))
+	server.handleClientResponse(RPCRequest{
+		ID:     &id,
+		Result: json.RawMessage(`[{"workspaceSymbolLimit": 7, "requestTimeout": "250ms"}]`),
+	})
+	<-done
+
+	options := server.getOptions()
+	if options.workspaceSymbolLimit != 7 {
+		t.Fatalf("expected workspace symbol limit 7, got %d", options.workspaceSymbolLimit)
+	}
+	if options.requestTimeout != 250*time.Millisecond {
+		t.Fatalf("expected request timeout 250ms, got %s", options.requestTimeout)
+	}
+}
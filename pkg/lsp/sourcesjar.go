@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jvmBuildFiles mark a workspace as a JVM project whose dependencies are worth
+// looking for.
+var jvmBuildFiles = []string{"pom.xml", "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"}
+
+// jvmSourceExtensions are the files extracted from sources jars.
+var jvmSourceExtensions = map[string]bool{".java": true, ".kt": true}
+
+// extractedMarker is written into an extraction directory once a jar has been
+// extracted completely, recording which version of the jar it came from.
+const extractedMarker = ".ctags-lsp-extracted"
+
+// jvmRepositoryDirs returns the local Maven repository and the Gradle module cache,
+// where build tools download sources jars to.
+func jvmRepositoryDirs(getenv func(string) string) []string {
+	home := getenv("HOME")
+	if home == "" {
+		home = getenv("USERPROFILE")
+	}
+	gradleHome := getenv("GRADLE_USER_HOME")
+	if gradleHome == "" && home != "" {
+		gradleHome = filepath.Join(home, ".gradle")
+	}
+
+	var dirs []string
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".m2", "repository"))
+	}
+	if gradleHome != "" {
+		dirs = append(dirs, filepath.Join(gradleHome, "caches", "modules-2", "files-2.1"))
+	}
+	return dirs
+}
+
+// findSourcesJars returns every *-sources.jar below `dirs`.
+func findSourcesJars(dirs []string) []string {
+	var jars []string
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.HasSuffix(d.Name(), "-sources.jar") {
+				jars = append(jars, path)
+			}
+			return nil
+		})
+	}
+	return jars
+}
+
+// extractSourcesJar extracts the Java and Kotlin sources of `jar` into a directory
+// of its own below `cacheDir` and returns that directory. A jar that was extracted
+// before and hasn't changed since isn't extracted again.
+func extractSourcesJar(jar, cacheDir string) (string, error) {
+	info, err := os.Stat(jar)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(jar))
+	dir := filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+"-"+strings.TrimSuffix(filepath.Base(jar), ".jar"))
+	version := fmt.Sprintf("%d %d", info.Size(), info.ModTime().UnixNano())
+	if marker, err := os.ReadFile(filepath.Join(dir, extractedMarker)); err == nil && string(marker) == version {
+		return dir, nil
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	archive, err := zip.OpenReader(jar)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !jvmSourceExtensions[filepath.Ext(file.Name)] {
+			continue
+		}
+		// Entries must not escape the extraction directory ("zip slip").
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) {
+			continue
+		}
+		if err := extractZipFile(file, filepath.Join(dir, name)); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, extractedMarker), []byte(version), 0o644)
+}
+
+func extractZipFile(file *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, reader); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// jvmSourceFiles returns the library sources of a JVM workspace: the sources jars
+// in the Maven and Gradle caches, extracted below `cacheDir`. Files larger than
+// `maxFileSize` (unless it is 0) are left out. Workspaces without a Maven or
+// Gradle build file get none.
+func jvmSourceFiles(rootDir, cacheDir string, maxFileSize int64, getenv func(string) string) []string {
+	isJVM := false
+	for _, name := range jvmBuildFiles {
+		if _, err := os.Stat(filepath.Join(rootDir, name)); err == nil {
+			isJVM = true
+			break
+		}
+	}
+	if !isJVM {
+		return nil
+	}
+
+	jars := findSourcesJars(jvmRepositoryDirs(getenv))
+	var files []string
+	for _, jar := range jars {
+		dir, err := extractSourcesJar(jar, cacheDir)
+		if err != nil {
+			slog.Warn("failed to extract sources jar", "jar", jar, "error", err)
+			continue
+		}
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !jvmSourceExtensions[filepath.Ext(path)] {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && (maxFileSize == 0 || info.Size() <= maxFileSize) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	slog.Info("indexing sources jars", "jars", len(jars), "files", len(files))
+	return files
+}
+
+// defaultSourcesCacheDir is where sources jars are extracted unless configured.
+func defaultSourcesCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "ctags-lsp", "sources")
+}
@@ -0,0 +1,68 @@
+package lsp
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestJVMSourceFiles(t *testing.T) {
+	home := t.TempDir()
+	repo := filepath.Join(home, ".m2", "repository", "com", "example", "lib", "1.0")
+	if err := os.MkdirAll(repo, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	jar := filepath.Join(repo, "lib-1.0-sources.jar")
+	file, err := os.Create(jar)
+	if err != nil {
+		t.Fatalf("create jar: %v", err)
+	}
+	archive := zip.NewWriter(file)
+	for _, name := range []string{"com/example/Lib.java", "com/example/Ext.kt", "META-INF/MANIFEST.MF", "../escape.java"} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("add %s: %v", name, err)
+		}
+		fmt.Fprintf(w, "class %s {}\n", filepath.Base(name))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("close jar: %v", err)
+	}
+	file.Close()
+
+	root := t.TempDir()
+	cache := filepath.Join(t.TempDir(), "sources")
+	getenv := func(name string) string {
+		if name == "HOME" {
+			return home
+		}
+		return ""
+	}
+	if files := jvmSourceFiles(root, cache, 0, getenv); files != nil {
+		t.Fatalf("expected no files outside a JVM project, got %v", files)
+	}
+
+	writeTestFile(t, root, "pom.xml", "<project/>\n")
+	files := jvmSourceFiles(root, cache, 0, getenv)
+	if len(files) != 2 || !strings.HasSuffix(files[0], filepath.Join("com", "example", "Ext.kt")) || !strings.HasSuffix(files[1], filepath.Join("com", "example", "Lib.java")) {
+		t.Fatalf("unexpected extracted files %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(cache), "escape.java")); err == nil {
+		t.Fatalf("expected entries outside the extraction directory to be skipped")
+	}
+
+	// A second scan reuses the extraction.
+	if err := os.WriteFile(files[0], []byte("// edited\n"), 0o644); err != nil {
+		t.Fatalf("edit extracted file: %v", err)
+	}
+	if again := jvmSourceFiles(root, cache, 0, getenv); !slices.Equal(again, files) {
+		t.Fatalf("expected the same files, got %v", again)
+	}
+	if content, _ := os.ReadFile(files[0]); string(content) != "// edited\n" {
+		t.Fatalf("expected an unchanged jar not to be extracted again")
+	}
+}
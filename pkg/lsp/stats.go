@@ -0,0 +1,90 @@
+package lsp
+
+import (
+	"time"
+	"unsafe"
+
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+// IndexStats is the result of the custom `ctagsLsp/stats` request.
+type IndexStats struct {
+	Entries          int                    `json:"entries"`
+	ReferenceEntries int                    `json:"referenceEntries"`
+	Files            int                    `json:"files"`
+	ByLanguage       map[string]int         `json:"byLanguage"`
+	ByKind           map[string]int         `json:"byKind"`
+	MemoryBytes      int64                  `json:"memoryBytes"`
+	CachedFiles      int                    `json:"cachedFiles"`
+	LastScanMillis   float64                `json:"lastScanMillis"`
+	LastScanAt       *time.Time             `json:"lastScanAt,omitempty"`
+	SkippedFiles     workspace.SkippedFiles `json:"skippedFiles"`
+	FailedFiles      []string               `json:"failedFiles,omitempty"`
+	Source           string                 `json:"source"`
+	Tagfile          string                 `json:"tagfile,omitempty"`
+}
+
+// handleStats reports index health for editor plugins.
+func handleStats(server *Server, req RPCRequest) {
+	server.sendResult(req.ID, server.indexStats())
+}
+
+func (server *Server) indexStats() IndexStats {
+	stats := IndexStats{
+		ByLanguage: make(map[string]int),
+		ByKind:     make(map[string]int),
+		Source:     "ctags",
+	}
+
+	server.mutex.Lock()
+	files := make(map[string]bool)
+	for _, entry := range server.tagEntries {
+		files[entry.Path] = true
+		language := entry.Language
+		if language == "" {
+			language = "unknown"
+		}
+		stats.ByLanguage[language]++
+		stats.ByKind[entry.Kind]++
+		stats.MemoryBytes += tagEntrySize(entry)
+	}
+	for _, entry := range server.referenceEntries {
+		stats.MemoryBytes += tagEntrySize(entry)
+	}
+	stats.Entries = len(server.tagEntries)
+	stats.ReferenceEntries = len(server.referenceEntries)
+	stats.Files = len(files)
+	if server.tagfileInUse != "" {
+		stats.Source = "tagfile"
+		stats.Tagfile = server.tagfileInUse
+	}
+	stats.LastScanMillis = float64(server.lastScanDuration) / float64(time.Millisecond)
+	if !server.lastScanAt.IsZero() {
+		lastScanAt := server.lastScanAt
+		stats.LastScanAt = &lastScanAt
+	}
+	stats.SkippedFiles = server.lastScanSkipped
+	if len(server.failedFiles) > 0 {
+		stats.FailedFiles = server.failedFileURIs()
+	}
+	server.mutex.Unlock()
+
+	server.cache.mutex.RLock()
+	stats.CachedFiles = len(server.cache.content)
+	for _, lines := range server.cache.content {
+		for _, line := range lines {
+			stats.MemoryBytes += int64(unsafe.Sizeof(line)) + int64(len(line))
+		}
+	}
+	server.cache.mutex.RUnlock()
+
+	return stats
+}
+
+// tagEntrySize estimates the memory held by an entry: the struct plus its string data.
+// Strings shared between entries are counted once per entry, so this is an upper bound.
+func tagEntrySize(entry TagEntry) int64 {
+	return int64(unsafe.Sizeof(entry)) + int64(len(entry.Type)+len(entry.Name)+len(entry.Path)+
+		len(entry.Pattern)+len(entry.Kind)+len(entry.Scope)+len(entry.ScopeKind)+
+		len(entry.TypeRef)+len(entry.Language)+len(entry.Roles)+len(entry.Extras))
+}
@@ -0,0 +1,30 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIndexStats(t *testing.T) {
+	server := newTestServer(t, []TagEntry{
+		{Name: "Render", Path: "file:///a.go", Line: 3, Kind: "func", Language: "Go"},
+		{Name: "View", Path: "file:///a.go", Line: 5, Kind: "type", Language: "Go"},
+		{Name: "view", Path: "file:///b.py", Line: 1, Kind: "function", Language: "Python"},
+	})
+	server.tagfileInUse = "/work/tags"
+
+	frames := callHandler(t, server, "ctagsLsp/stats", nil)
+	var stats IndexStats
+	if err := json.Unmarshal(frames[0].Result, &stats); err != nil {
+		t.Fatalf("unmarshal stats: %v", err)
+	}
+	if stats.Entries != 3 || stats.Files != 2 {
+		t.Fatalf("expected 3 entries in 2 files, got %+v", stats)
+	}
+	if stats.ByLanguage["Go"] != 2 || stats.ByKind["function"] != 1 {
+		t.Fatalf("unexpected breakdown: %+v", stats)
+	}
+	if stats.Source != "tagfile" || stats.Tagfile != "/work/tags" || stats.MemoryBytes == 0 {
+		t.Fatalf("unexpected source or memory estimate: %+v", stats)
+	}
+}
@@ -0,0 +1,36 @@
+package lsp
+
+// States reported by the `$/ctagsLsp/indexingStatus` notification.
+const (
+	indexingStateScanning = "scanning"
+	indexingStateReady    = "ready"
+	indexingStateError    = "error"
+)
+
+// IndexingStatusParams is sent whenever the workspace index starts or finishes building,
+// so status-line plugins can show index state without implementing work-done progress.
+type IndexingStatusParams struct {
+	State   string `json:"state"`
+	Entries int    `json:"entries"`
+	Files   int    `json:"files"`
+	Message string `json:"message,omitempty"`
+}
+
+// sendIndexingStatus notifies the client of the index state, with the current counts.
+func (server *Server) sendIndexingStatus(state string, err error) {
+	params := IndexingStatusParams{State: state}
+
+	server.mutex.Lock()
+	files := make(map[string]bool)
+	for _, entry := range server.tagEntries {
+		files[entry.Path] = true
+	}
+	params.Entries = len(server.tagEntries)
+	params.Files = len(files)
+	server.mutex.Unlock()
+
+	if err != nil {
+		params.Message = err.Error()
+	}
+	server.sendNotification("$/ctagsLsp/indexingStatus", params)
+}
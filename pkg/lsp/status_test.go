@@ -0,0 +1,37 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestIndexingStatusAfterRescan(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.tagfilePath = "missing-tags"
+
+	var output bytes.Buffer
+	server.output = &output
+	if err := server.rescanWorkspace(); err == nil {
+		t.Fatal("expected rescan to fail for a missing tagfile")
+	}
+
+	var states []string
+	for _, frame := range strings.Split(output.String(), "Content-Length:")[1:] {
+		_, body, _ := strings.Cut(frame, "\r\n\r\n")
+		var notification struct {
+			Method string               `json:"method"`
+			Params IndexingStatusParams `json:"params"`
+		}
+		if err := json.Unmarshal([]byte(body), &notification); err != nil {
+			t.Fatalf("unmarshal notification: %v", err)
+		}
+		if notification.Method == "$/ctagsLsp/indexingStatus" {
+			states = append(states, notification.Params.State)
+		}
+	}
+	if strings.Join(states, ",") != "scanning,error" {
+		t.Fatalf("expected scanning then error, got %v", states)
+	}
+}
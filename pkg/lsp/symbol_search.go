@@ -0,0 +1,222 @@
+package lsp
+
+import (
+	"cmp"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Orderings for document symbol results.
+const (
+	documentSymbolOrderPosition = "position"
+	documentSymbolOrderKind     = "kind"
+)
+
+const (
+	defaultWorkspaceSymbolLimit = 500
+	workspaceSymbolPageSize     = 100
+)
+
+// Match tiers for workspace symbol queries, best first.
+const (
+	symbolMatchExact = iota
+	symbolMatchFold
+	symbolMatchPrefix
+	symbolMatchSubstring
+	symbolMatchTypo
+	symbolMatchNone
+)
+
+// symbolCandidate is a tag entry that matched a workspace symbol query.
+type symbolCandidate struct {
+	entry TagEntry
+	kind  int
+	tier  int
+	// score orders typo matches, lower is better; it is 0 for other tiers.
+	score int
+	// file is set for the candidates of `fileSymbolCandidates`, whose entry only
+	// has a name and a path.
+	file bool
+}
+
+// indexedFiles caches the files of the index for `fileSymbolCandidates`; like
+// `symbolQueryCache`, it is valid for the index at `generation` with `size` entries.
+type indexedFiles struct {
+	generation int
+	size       int
+	paths      []string
+}
+
+// fileSymbolCandidates returns the indexed files whose name matches `query`, or
+// whose path under the root contains it, as candidates of kind `SymbolKindFile`.
+// The caller holds `server.mutex`.
+func (server *Server) fileSymbolCandidates(query string, generation int) []symbolCandidate {
+	files := server.indexedFiles
+	if files == nil || files.generation != generation || files.size != len(server.tagEntries) {
+		files = &indexedFiles{generation: generation, size: len(server.tagEntries)}
+		seen := make(map[string]bool)
+		for _, entry := range server.tagEntries {
+			if !seen[entry.Path] {
+				seen[entry.Path] = true
+				files.paths = append(files.paths, entry.Path)
+			}
+		}
+		server.indexedFiles = files
+	}
+
+	lowerQuery := strings.ToLower(query)
+	var candidates []symbolCandidate
+	for _, uri := range files.paths {
+		name := filepath.Base(fileURIToPath(uri))
+		tier := matchSymbolQuery(name, query)
+		if tier == symbolMatchNone {
+			relative, ok := cutURIPrefix(uri, server.rootURI+"/")
+			if !ok || !strings.Contains(strings.ToLower(relative), lowerQuery) {
+				continue
+			}
+			tier = symbolMatchSubstring
+		}
+		candidates = append(candidates, symbolCandidate{
+			entry: TagEntry{Name: name, Path: uri},
+			kind:  SymbolKindFile,
+			tier:  tier,
+			file:  true,
+		})
+	}
+	return candidates
+}
+
+// relativeDirectory returns the directory of `uri` relative to the workspace
+// root, empty for the root itself, or the full directory for files outside it.
+func (server *Server) relativeDirectory(uri string) string {
+	dir := filepath.Dir(fileURIToPath(uri))
+	relative, err := filepath.Rel(fileURIToPath(server.rootURI), dir)
+	switch {
+	case err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)):
+		return dir
+	case relative == ".":
+		return ""
+	}
+	return filepath.ToSlash(relative)
+}
+
+// symbolQueryCache remembers which tag entries matched the last workspace symbol
+// query. Pickers send a request per keystroke, and a query that contains the last
+// one can only match entries among those, since every match tier but typos
+// requires the name to contain the query.
+type symbolQueryCache struct {
+	// query is lowercased; matches holds indices into `server.tagEntries`, which
+	// had `size` entries at index generation `generation`.
+	query      string
+	generation int
+	size       int
+	matches    []int
+}
+
+// narrow returns the entries worth matching against `query`: those matched by the
+// cached query, if `query` contains it and the index hasn't changed since.
+func (cache *symbolQueryCache) narrow(query string, generation, size int) ([]int, bool) {
+	if cache == nil || cache.generation != generation || cache.size != size ||
+		!strings.Contains(strings.ToLower(query), cache.query) {
+		return nil, false
+	}
+	return cache.matches, true
+}
+
+// matchSymbolQuery returns the match tier of `name` for `query`.
+// An empty query matches every name.
+func matchSymbolQuery(name, query string) int {
+	if query == "" || name == query {
+		return symbolMatchExact
+	}
+	lowerName := strings.ToLower(name)
+	lowerQuery := strings.ToLower(query)
+	switch {
+	case lowerName == lowerQuery:
+		return symbolMatchFold
+	case strings.HasPrefix(lowerName, lowerQuery):
+		return symbolMatchPrefix
+	case strings.Contains(lowerName, lowerQuery):
+		return symbolMatchSubstring
+	default:
+		return symbolMatchNone
+	}
+}
+
+// matchSymbolTypo scores `name` as a misspelling of `query`, or of its start:
+// fewer edits first, then longer shared prefixes. It reports false when the
+// query is too short to tell typos from different names.
+func matchSymbolTypo(name, query string) (int, bool) {
+	limit := typoLimit(len([]rune(query)))
+	if limit == 0 {
+		return 0, false
+	}
+	lowerName := strings.ToLower(name)
+	lowerQuery := strings.ToLower(query)
+	distance := prefixEditDistance(lowerQuery, lowerName, limit)
+	if distance > limit {
+		return 0, false
+	}
+	return distance*64 - min(commonPrefixLength(lowerQuery, lowerName), 63), true
+}
+
+// symbolKindRank orders symbol kinds so that type and callable definitions
+// surface before variables and other noise when results are truncated.
+func symbolKindRank(kind int) int {
+	switch kind {
+	case SymbolKindClass, SymbolKindInterface, SymbolKindStruct, SymbolKindEnum:
+		return 0
+	case SymbolKindFunction, SymbolKindMethod, SymbolKindConstructor:
+		return 1
+	case SymbolKindModule, SymbolKindNamespace, SymbolKindPackage:
+		return 2
+	case SymbolKindConstant, SymbolKindEnumMember, SymbolKindField, SymbolKindProperty:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// rankSymbolCandidates sorts candidates best first and truncates them to `limit`.
+// A non-positive limit disables truncation.
+func rankSymbolCandidates(candidates []symbolCandidate, limit int) []symbolCandidate {
+	slices.SortStableFunc(candidates, func(a, b symbolCandidate) int {
+		return cmp.Or(
+			cmp.Compare(a.tier, b.tier),
+			cmp.Compare(a.score, b.score),
+			cmp.Compare(symbolKindRank(a.kind), symbolKindRank(b.kind)),
+			cmp.Compare(len(a.entry.Name), len(b.entry.Name)),
+			strings.Compare(a.entry.Name, b.entry.Name),
+			strings.Compare(a.entry.Path, b.entry.Path),
+			cmp.Compare(a.entry.Line, b.entry.Line),
+		)
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// sortDocumentSymbols orders an outline by position, or groups it by kind
+// (types, then callables, then the rest) with position order inside each group.
+func sortDocumentSymbols(symbols []SymbolInformation, order string) {
+	byPosition := func(a, b SymbolInformation) int {
+		return cmp.Or(
+			cmp.Compare(a.Location.Range.Start.Line, b.Location.Range.Start.Line),
+			cmp.Compare(a.Location.Range.Start.Character, b.Location.Range.Start.Character),
+		)
+	}
+
+	if order == documentSymbolOrderKind {
+		slices.SortStableFunc(symbols, func(a, b SymbolInformation) int {
+			return cmp.Or(
+				cmp.Compare(symbolKindRank(a.Kind), symbolKindRank(b.Kind)),
+				cmp.Compare(a.Kind, b.Kind),
+				byPosition(a, b),
+			)
+		})
+		return
+	}
+	slices.SortStableFunc(symbols, byPosition)
+}
@@ -0,0 +1,236 @@
+package lsp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceSymbolRankingAndLimit(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n\nvar handlerCount int\n\nfunc Handler() {}\n\ntype handler struct{}\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "handlerCount", Path: uri, Line: 3, Kind: "variable"},
+		{Name: "Handler", Path: uri, Line: 5, Kind: "func"},
+		{Name: "handler", Path: uri, Line: 7, Kind: "type"},
+	})
+
+	t.Run("exact match first", func(t *testing.T) {
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: "handler"})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		got := make([]string, 0, len(symbols))
+		for _, symbol := range symbols {
+			got = append(got, symbol.Name)
+		}
+		want := []string{"handler", "Handler", "handlerCount"}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		server.options.workspaceSymbolLimit = 1
+		defer func() { server.options.workspaceSymbolLimit = 0 }()
+
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: ""})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		if len(symbols) != 1 || symbols[0].Name != "handler" {
+			t.Fatalf("expected only the struct symbol, got %+v", symbols)
+		}
+	})
+
+	t.Run("partial results", func(t *testing.T) {
+		token := json.RawMessage(`"picker"`)
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{PartialResultToken: &token})
+		if len(frames) != 2 {
+			t.Fatalf("expected one progress page and a response, got %d frames", len(frames))
+		}
+		if frames[0].Method != "$/progress" {
+			t.Fatalf("expected $/progress, got %q", frames[0].Method)
+		}
+		if string(frames[1].Result) != "[]" {
+			t.Fatalf("expected empty final result, got %s", frames[1].Result)
+		}
+	})
+}
+
+func TestDocumentSymbolFilterAndOrder(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "int total;\nstruct point {};\nvoid draw(void) {\n  int i;\n}\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "draw", Path: uri, Line: 3, Kind: "function"},
+		{Name: "i", Path: uri, Line: 4, Kind: "local"},
+		{Name: "point", Path: uri, Line: 2, Kind: "structure"},
+		{Name: "total", Path: uri, Line: 1, Kind: "variable"},
+	})
+
+	names := func() string {
+		frames := callHandler(t, server, "textDocument/documentSymbol", DocumentSymbolParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		got := make([]string, 0, len(symbols))
+		for _, symbol := range symbols {
+			got = append(got, symbol.Name)
+		}
+		return strings.Join(got, ",")
+	}
+
+	if got := names(); got != "total,point,draw,i" {
+		t.Fatalf("expected position order, got %s", got)
+	}
+
+	server.options.documentSymbolExcludeKinds = []string{"local"}
+	server.options.documentSymbolOrder = documentSymbolOrderKind
+	if got := names(); got != "point,draw,total" {
+		t.Fatalf("expected kind order without locals, got %s", got)
+	}
+}
+
+func TestWorkspaceSymbolFullContainerNames(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.py", "class Outer:\n    class Inner:\n        def run(self): pass\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Outer", Path: uri, Line: 1, Kind: "class", Language: "Python"},
+		{Name: "Inner", Path: uri, Line: 2, Kind: "class", Scope: "Outer", ScopeKind: "class", Language: "Python"},
+		{Name: "run", Path: uri, Line: 3, Kind: "method", Scope: "Inner", ScopeKind: "class", Language: "Python"},
+	})
+
+	frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: "run"})
+	var symbols []SymbolInformation
+	if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+		t.Fatalf("unmarshal symbols: %v", err)
+	}
+	if len(symbols) != 1 || symbols[0].ContainerName != "Outer.Inner" {
+		t.Fatalf("expected container Outer.Inner, got %+v", symbols)
+	}
+}
+
+func TestWorkspaceSymbolTypoTolerance(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n\nvar handlerCount int\n\nfunc Handle() {}\n\nfunc render() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "handlerCount", Path: uri, Line: 3, Kind: "variable"},
+		{Name: "Handle", Path: uri, Line: 5, Kind: "func"},
+		{Name: "render", Path: uri, Line: 7, Kind: "func"},
+	})
+	query := func(query string) []string {
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+
+	if names := query("hnadler"); len(names) != 0 {
+		t.Fatalf("expected no typo matches by default, got %v", names)
+	}
+	server.options.fuzzySymbolSearch = true
+	if names := query("hnadler"); !slices.Equal(names, []string{"handlerCount"}) {
+		t.Fatalf("expected a typo match on the start of a name, got %v", names)
+	}
+	if names := query("handel"); !slices.Equal(names, []string{"Handle", "handlerCount"}) {
+		t.Fatalf("expected typo matches ranked by distance, got %v", names)
+	}
+}
+
+func TestWorkspaceSymbolNarrowing(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.go", "package a\n\nfunc render() {}\n\nfunc Renderer() {}\n\nfunc reload() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "render", Path: uri, Line: 3, Kind: "func"},
+		{Name: "Renderer", Path: uri, Line: 5, Kind: "func"},
+		{Name: "reload", Path: uri, Line: 7, Kind: "func"},
+	})
+	query := func(query string) []string {
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		return names
+	}
+
+	if names := query("ren"); !slices.Equal(names, []string{"render", "Renderer"}) {
+		t.Fatalf("unexpected matches %v", names)
+	}
+	// Renaming an entry behind the cache's back shows that the next keystroke
+	// only looks at the entries "ren" matched.
+	server.tagEntries[2].Name = "renderAll"
+	if names := query("rend"); !slices.Equal(names, []string{"render", "Renderer"}) {
+		t.Fatalf("expected the query to be narrowed to the last matches, got %v", names)
+	}
+	server.invalidateUsage()
+	if names := query("rend"); !slices.Equal(names, []string{"render", "Renderer", "renderAll"}) {
+		t.Fatalf("expected a changed index to be searched in full, got %v", names)
+	}
+	server.tagEntries[2].Name = "reload"
+	if names := query("load"); !slices.Equal(names, []string{"reload"}) {
+		t.Fatalf("expected a query that doesn't extend the last one to be searched in full, got %v", names)
+	}
+}
+
+func TestWorkspaceFileSymbols(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	renderer := writeTestFile(t, dir, "src/renderer.go", "package src\n\nfunc Draw() {}\n")
+	main := writeTestFile(t, dir, "main.go", "package main\n\nfunc renderAll() {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Draw", Path: renderer, Line: 3, Kind: "func"},
+		{Name: "renderAll", Path: main, Line: 3, Kind: "func"},
+	})
+	server.rootURI = pathToFileURI(dir)
+	query := func(query string) []SymbolInformation {
+		frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+		var symbols []SymbolInformation
+		if err := json.Unmarshal(frames[0].Result, &symbols); err != nil {
+			t.Fatalf("unmarshal symbols: %v", err)
+		}
+		return symbols
+	}
+
+	if symbols := query("render"); len(symbols) != 1 || symbols[0].Name != "renderAll" {
+		t.Fatalf("expected no file symbols by default, got %+v", symbols)
+	}
+
+	server.options.fileSymbols = true
+	symbols := query("render")
+	if len(symbols) != 2 || symbols[0].Name != "renderAll" || symbols[1].Name != "renderer.go" {
+		t.Fatalf("expected renderAll and renderer.go, got %+v", symbols)
+	}
+	file := symbols[1]
+	if file.Kind != SymbolKindFile || file.Location.URI != renderer || file.ContainerName != "src" || file.Location.Range != (Range{}) {
+		t.Fatalf("unexpected file symbol %+v", file)
+	}
+	if symbols := query("src/rend"); len(symbols) != 1 || symbols[0].Location.URI != renderer {
+		t.Fatalf("expected a path query to find renderer.go, got %+v", symbols)
+	}
+	if symbols := query(""); len(symbols) != 2 {
+		t.Fatalf("expected an empty query to list only tags, got %+v", symbols)
+	}
+}
@@ -0,0 +1,141 @@
+package lsp
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+	"golang.org/x/text/encoding"
+)
+
+// loadTagfile parses `tagsPath` into the index and remembers it as the index source.
+func (server *Server) loadTagfile(tagsPath string) error {
+	entries, err := server.parseTagfile(tagsPath)
+	if err != nil {
+		return err
+	}
+	entries = server.filterTags(entries)
+	for i := range entries {
+		// Tags a filter added or rewrote still come from the tagfile.
+		entries[i].FromTagfile = true
+	}
+
+	definitions, references := ctags.SplitReferences(entries)
+
+	server.mutex.Lock()
+	server.tagEntries = append(server.tagEntries, definitions...)
+	server.referenceEntries = append(server.referenceEntries, references...)
+	server.tagfileInUse = tagsPath
+	server.mutex.Unlock()
+	server.invalidateUsage()
+	return nil
+}
+
+// tagfileStaleness summarizes how far a tagfile has drifted from the files it indexes.
+type tagfileStaleness struct {
+	missingFiles   int
+	staleEntries   int
+	checkedFiles   int
+	checkedEntries int
+}
+
+func (staleness tagfileStaleness) isStale() bool {
+	return staleness.missingFiles > 0 || staleness.staleEntries > 0
+}
+
+// checkTagfileStaleness verifies that every indexed file still exists and that each
+// entry's name still appears on its recorded line. Files are read without populating
+// the cache so that checking a large tagfile doesn't pin every file in memory.
+func checkTagfileStaleness(entries []TagEntry, fallback encoding.Encoding) tagfileStaleness {
+	byURI := make(map[string][]TagEntry)
+	for _, entry := range entries {
+		byURI[entry.Path] = append(byURI[entry.Path], entry)
+	}
+
+	var staleness tagfileStaleness
+	for uri, fileEntries := range byURI {
+		staleness.checkedFiles++
+		staleness.checkedEntries += len(fileEntries)
+
+		lines, err := readFileLines(uri, fallback)
+		if err != nil {
+			staleness.missingFiles++
+			continue
+		}
+		for _, entry := range fileEntries {
+			if entry.Line <= 0 || isQualifiedTag(entry) {
+				continue
+			}
+			if entry.Line > len(lines) || !strings.Contains(lines[entry.Line-1], entry.Name) {
+				staleness.staleEntries++
+			}
+		}
+	}
+	return staleness
+}
+
+// reportStaleTagfile warns the user via `window/showMessage` when the tagfile in use
+// points at missing files or moved symbols.
+func (server *Server) reportStaleTagfile() {
+	server.mutex.Lock()
+	tagsPath := server.tagfileInUse
+	entries := slices.Clone(server.tagEntries)
+	server.mutex.Unlock()
+	if tagsPath == "" {
+		return
+	}
+
+	server.cache.mutex.RLock()
+	fallback := server.cache.encoding
+	server.cache.mutex.RUnlock()
+
+	staleness := checkTagfileStaleness(entries, fallback)
+	if !staleness.isStale() {
+		return
+	}
+
+	message := fmt.Sprintf(
+		"Tagfile %s looks out of date: %d of %d files are missing and %d of %d tags no longer match their line. Regenerate it with ctags to fix navigation.",
+		tagsPath, staleness.missingFiles, staleness.checkedFiles, staleness.staleEntries, staleness.checkedEntries,
+	)
+	slog.Warn("stale tagfile", "path", tagsPath, "missingFiles", staleness.missingFiles, "staleEntries", staleness.staleEntries)
+	server.showMessage(MessageTypeWarning, message)
+}
+
+// parseTagfile reads a tags file and returns entries in the same shape as `processTagsOutput`.
+// It skips entries whose paths can't be normalized to file URIs.
+func (server *Server) parseTagfile(tagsPath string) ([]TagEntry, error) {
+	entries, err := ctags.ParseTagfile(tagsPath)
+	if err != nil {
+		return nil, err
+	}
+	normalized := entries[:0]
+	for _, entry := range entries {
+		uri, err := tagfilePathToFileURI(tagsPath, server.localPath(entry.Path))
+		if err != nil {
+			log.Printf("Failed to normalize path for %s: %v", entry.Path, err)
+			continue
+		}
+		entry.Path = uri
+		normalized = append(normalized, entry)
+	}
+	return normalized, nil
+}
+
+// tagfilePathToFileURI normalizes a tags-file path to an absolute file URI.
+// Relative paths are interpreted relative to the tagfile's directory.
+func tagfilePathToFileURI(tagsPath, raw string) (string, error) {
+	if raw == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	baseDir := filepath.Dir(tagsPath)
+	normalized, err := normalizePath(baseDir, raw)
+	if err != nil {
+		return "", err
+	}
+	return pathToFileURI(normalized), nil
+}
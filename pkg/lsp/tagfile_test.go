@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckTagfileStaleness(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.py", "def kept():\n    pass\n")
+	missing := pathToFileURI(filepath.Join(dir, "deleted.py"))
+
+	staleness := checkTagfileStaleness([]TagEntry{
+		{Name: "kept", Path: uri, Line: 1},
+		{Name: "moved", Path: uri, Line: 2},
+		{Name: "gone", Path: missing, Line: 1},
+	}, nil)
+	if staleness.missingFiles != 1 || staleness.staleEntries != 1 || staleness.checkedFiles != 2 {
+		t.Fatalf("unexpected staleness summary: %+v", staleness)
+	}
+}
+
+func TestTagfileReferenceTagsAreNotDefinitions(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "main.c", "#include \"util.h\"\nint util;\n")
+	tagsPath := filepath.Join(dir, "tags")
+	tagfile := "util.h\tmain.c\t1;\"\th\tline:1\troles:local\n" +
+		"util\tmain.c\t2;\"\tv\tline:2\troles:def\n"
+	if err := os.WriteFile(tagsPath, []byte(tagfile), 0o644); err != nil {
+		t.Fatalf("write tagfile: %v", err)
+	}
+
+	server := newTestServer(t, nil)
+	if err := server.loadTagfile(tagsPath); err != nil {
+		t.Fatalf("loadTagfile: %v", err)
+	}
+	if len(server.tagEntries) != 1 || server.tagEntries[0].Name != "util" {
+		t.Fatalf("expected only the definition in tagEntries, got %+v", server.tagEntries)
+	}
+	if len(server.referenceEntries) != 1 || server.referenceEntries[0].Name != "util.h" {
+		t.Fatalf("expected the include in referenceEntries, got %+v", server.referenceEntries)
+	}
+
+	server.removeIndexedPath(uri)
+	if len(server.tagEntries) != 0 || len(server.referenceEntries) != 0 {
+		t.Fatalf("expected both indexes to drop %s", uri)
+	}
+}
@@ -0,0 +1,85 @@
+package lsp
+
+import "encoding/json"
+
+// TagsParams selects tag entries either by `name` or by the symbol at a position.
+type TagsParams struct {
+	Name         string                  `json:"name,omitempty"`
+	TextDocument *TextDocumentIdentifier `json:"textDocument,omitempty"`
+	Position     *Position               `json:"position,omitempty"`
+}
+
+// TagRecord is a raw tag entry as returned by `ctagsLsp/tags`.
+type TagRecord struct {
+	TagEntry
+	// Source is "tagfile" or "ctags".
+	Source    string `json:"source"`
+	Reference bool   `json:"reference,omitempty"`
+}
+
+// handleTags returns the raw tag entries for a symbol, to debug why navigation picks
+// a target. With a document position, the definition entries are the ones
+// go-to-definition would use; reference tags of the same name are listed after them.
+func handleTags(server *Server, req RPCRequest) {
+	var params TagsParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	name := params.Name
+	var uri string
+	if name == "" {
+		if params.TextDocument == nil || params.Position == nil {
+			server.sendError(req.ID, -32602, "Invalid params", "expected name or textDocument and position")
+			return
+		}
+		normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+		if err != nil {
+			server.sendError(req.ID, -32602, "Invalid params", err.Error())
+			return
+		}
+		uri = normalizedURI
+		name, err = server.getCurrentWord(uri, *params.Position)
+		if err != nil {
+			server.sendResult(req.ID, []TagRecord{})
+			return
+		}
+	}
+
+	server.mutex.Lock()
+	var definitions []TagEntry
+	if uri != "" {
+		definitions = server.findDefinitionEntries(uri, *params.Position, name)
+	} else {
+		for _, entry := range server.tagEntries {
+			if entry.Name == name || (isQualifiedName(name) && matchesQualifiedName(entry, name)) {
+				definitions = append(definitions, entry)
+			}
+		}
+	}
+	var references []TagEntry
+	for _, entry := range server.referenceEntries {
+		if entry.Name == name {
+			references = append(references, entry)
+		}
+	}
+	server.mutex.Unlock()
+
+	records := make([]TagRecord, 0, len(definitions)+len(references))
+	for _, entry := range definitions {
+		records = append(records, newTagRecord(entry, false))
+	}
+	for _, entry := range references {
+		records = append(records, newTagRecord(entry, true))
+	}
+	server.sendResult(req.ID, records)
+}
+
+func newTagRecord(entry TagEntry, reference bool) TagRecord {
+	source := "ctags"
+	if entry.FromTagfile {
+		source = "tagfile"
+	}
+	return TagRecord{TagEntry: entry, Source: source, Reference: reference}
+}
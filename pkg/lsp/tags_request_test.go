@@ -0,0 +1,32 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTagsRequest(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "#include \"render.h\"\nvoid render(void) {}\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "render", Path: uri, Line: 2, Kind: "function", Pattern: "/^void render(void) {}$/"},
+		{Name: "render", Path: uri, Line: 9, Kind: "prototype", FromTagfile: true},
+		{Name: "other", Path: uri, Line: 3, Kind: "function"},
+	})
+	server.referenceEntries = []TagEntry{{Name: "render", Path: uri, Line: 1, Kind: "header", Roles: "local"}}
+
+	frames := callHandler(t, server, "ctagsLsp/tags", TagsParams{Name: "render"})
+	var records []TagRecord
+	if err := json.Unmarshal(frames[0].Result, &records); err != nil {
+		t.Fatalf("unmarshal records: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	if records[0].Source != "ctags" || records[1].Source != "tagfile" || !records[2].Reference {
+		t.Fatalf("unexpected sources: %+v", records)
+	}
+	if records[0].Pattern != "/^void render(void) {}$/" || records[0].Kind != "function" {
+		t.Fatalf("expected raw entry fields, got %+v", records[0])
+	}
+}
@@ -0,0 +1,119 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// telemetryTimeout bounds the upload of a telemetry report on shutdown.
+const telemetryTimeout = 5 * time.Second
+
+// telemetryReport holds aggregate, anonymous usage data: no paths, symbol names or
+// file contents are included.
+type telemetryReport struct {
+	Version        string                      `json:"version"`
+	OS             string                      `json:"os"`
+	Arch           string                      `json:"arch"`
+	CtagsVersion   string                      `json:"ctagsVersion"`
+	IndexEntries   int                         `json:"indexEntries"`
+	IndexFiles     int                         `json:"indexFiles"`
+	IndexSource    string                      `json:"indexSource"`
+	LastScanMillis float64                     `json:"lastScanMillis"`
+	Requests       map[string]requestTelemetry `json:"requests"`
+}
+
+type requestTelemetry struct {
+	Count     uint64  `json:"count"`
+	P50Millis float64 `json:"p50Millis"`
+	P90Millis float64 `json:"p90Millis"`
+	P99Millis float64 `json:"p99Millis"`
+}
+
+// collectTelemetry summarizes the session from the process-wide metrics.
+func (server *Server) collectTelemetry() telemetryReport {
+	stats := server.indexStats()
+	report := telemetryReport{
+		Version:        server.version,
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		CtagsVersion:   ctagsVersion(server.ctagsBin),
+		IndexEntries:   stats.Entries,
+		IndexFiles:     stats.Files,
+		IndexSource:    stats.Source,
+		LastScanMillis: stats.LastScanMillis,
+		Requests:       make(map[string]requestTelemetry),
+	}
+
+	for _, method := range metricRequestLatency.labels() {
+		h := metricRequestLatency.get(method)
+		h.mutex.Lock()
+		count := h.count
+		h.mutex.Unlock()
+		report.Requests[method] = requestTelemetry{
+			Count:     count,
+			P50Millis: h.quantile(0.5) * 1000,
+			P90Millis: h.quantile(0.9) * 1000,
+			P99Millis: h.quantile(0.99) * 1000,
+		}
+	}
+	return report
+}
+
+// ctagsVersion returns the first line of `ctags --version`, or "" if it can't be run.
+func ctagsVersion(ctagsBin string) string {
+	output, err := exec.Command(ctagsBin, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	firstLine, _, _ := strings.Cut(string(output), "\n")
+	return strings.TrimSpace(firstLine)
+}
+
+// sendTelemetry delivers `report` to `target`: "stderr" writes it to `stderr`,
+// an http(s) URL receives it as a JSON POST.
+func sendTelemetry(target string, report telemetryReport, stderr io.Writer) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal telemetry: %w", err)
+	}
+
+	if target == "stderr" {
+		_, err := fmt.Fprintf(stderr, "%s\n", body)
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send telemetry: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// validateTelemetryTarget rejects targets other than "stderr" and http(s) URLs at startup.
+func validateTelemetryTarget(target string) error {
+	if target == "" || target == "stderr" ||
+		strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		return nil
+	}
+	return fmt.Errorf("invalid --telemetry target %q: expected \"stderr\" or an http(s) URL", target)
+}
@@ -0,0 +1,52 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelemetryReport(t *testing.T) {
+	h := newHistogram()
+	for range 9 {
+		h.observe(2 * time.Millisecond)
+	}
+	h.observe(time.Second)
+	if got := h.quantile(0.5); got != 0.005 {
+		t.Fatalf("expected p50 in the 5ms bucket, got %v", got)
+	}
+	if got := h.quantile(0.99); got != 1 {
+		t.Fatalf("expected p99 in the 1s bucket, got %v", got)
+	}
+
+	var received telemetryReport
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode telemetry: %v", err)
+		}
+	}))
+	defer endpoint.Close()
+
+	server := newTestServer(t, []TagEntry{{Name: "Render", Path: "file:///secret/a.go", Line: 1, Kind: "func"}})
+	server.ctagsBin = "ctags-lsp-missing-binary"
+	report := server.collectTelemetry()
+	if err := sendTelemetry(endpoint.URL, report, io.Discard); err != nil {
+		t.Fatalf("sendTelemetry: %v", err)
+	}
+	if received.IndexEntries != 1 || received.OS == "" {
+		t.Fatalf("unexpected report: %+v", received)
+	}
+
+	body, _ := json.Marshal(report)
+	if strings.Contains(string(body), "secret") || strings.Contains(string(body), "Render") {
+		t.Fatalf("telemetry must not contain paths or names: %s", body)
+	}
+
+	if err := validateTelemetryTarget("ftp://example.com"); err == nil {
+		t.Fatal("expected an invalid target to be rejected")
+	}
+}
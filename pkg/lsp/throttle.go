@@ -0,0 +1,28 @@
+package lsp
+
+import "time"
+
+// typingPauseChunkSize caps how many files one ctags process scans when the scan
+// pauses while typing, so that it notices edits soon enough.
+const typingPauseChunkSize = 200
+
+// noteEdit records that the user just changed a document.
+func (server *Server) noteEdit() {
+	server.lastEdit.Store(time.Now().UnixNano())
+}
+
+// waitWhileTyping blocks until no document has changed for `pause`, so that a scan
+// running in the background doesn't compete with the editor for the CPU. A zero
+// `pause` never blocks.
+func (server *Server) waitWhileTyping(pause time.Duration) {
+	if pause <= 0 {
+		return
+	}
+	for {
+		idle := time.Since(time.Unix(0, server.lastEdit.Load()))
+		if idle >= pause {
+			return
+		}
+		time.Sleep(pause - idle)
+	}
+}
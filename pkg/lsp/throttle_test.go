@@ -0,0 +1,44 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestScanThrottling(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+	server := newTestServer(t, nil)
+	dir := fileURIToPath(server.rootURI)
+	for i := range 5 {
+		writeTestFile(t, dir, fmt.Sprintf("f%d.go", i), "package a\n")
+	}
+	script := "#!/bin/sh\n" +
+		"case \" $* \" in *\" --print-language \"*) while read f || [ -n \"$f\" ]; do echo \"$f: Go\"; done; exit 0;; esac\n" +
+		"while read f || [ -n \"$f\" ]; do echo \"{\\\"_type\\\":\\\"tag\\\",\\\"name\\\":\\\"$(basename $f .go)\\\",\\\"path\\\":\\\"$f\\\",\\\"line\\\":1,\\\"kind\\\":\\\"function\\\"}\"; done\n"
+	ctags := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(ctags, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	server.ctagsBin = ctags
+	server.options.workers = 2
+	server.options.lowPriority = true
+	server.options.typingPause = 50 * time.Millisecond
+
+	server.noteEdit()
+	start := time.Now()
+	if err := server.scanWorkspace(); err != nil {
+		t.Fatalf("scan workspace: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < server.options.typingPause {
+		t.Fatalf("expected the scan to wait for typing to pause, took %s", elapsed)
+	}
+	if len(server.tagEntries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(server.tagEntries))
+	}
+}
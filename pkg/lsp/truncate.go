@@ -0,0 +1,84 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// defaultMaxResponseSize is the size above which results are truncated. Some
+// clients take seconds to parse multi-megabyte responses.
+const defaultMaxResponseSize = 4 << 20
+
+// truncateResult shortens the result of `response`, whose message `body` is
+// larger than `limit` bytes, by dropping trailing results until the message fits.
+// Results are either an array or a completion list, whose items are dropped and
+// which is then marked incomplete so the client asks again as the user types.
+// It returns the shortened result and the number of results dropped, or false if
+// the result is neither.
+func truncateResult(response RPCSuccessResponse, body []byte, limit int) (json.RawMessage, int, bool) {
+	raw, err := json.Marshal(response.Result)
+	if err != nil {
+		return nil, 0, false
+	}
+	var list map[string]json.RawMessage
+	itemsJSON := raw
+	if bytes.HasPrefix(raw, []byte("{")) {
+		if json.Unmarshal(raw, &list) != nil || list["items"] == nil {
+			return nil, 0, false
+		}
+		itemsJSON = list["items"]
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(itemsJSON, &items) != nil {
+		return nil, 0, false
+	}
+
+	// Everything but the items themselves, plus a little room for `isIncomplete`.
+	size := len(body) - len(itemsJSON) + len(`[],"isIncomplete":false`)
+	kept := 0
+	for kept < len(items) && size+len(items[kept])+1 <= limit {
+		size += len(items[kept]) + 1
+		kept++
+	}
+
+	truncated, err := json.Marshal(items[:kept])
+	if err != nil {
+		return nil, 0, false
+	}
+	if list != nil {
+		list["items"] = truncated
+		list["isIncomplete"] = json.RawMessage("true")
+		if truncated, err = json.Marshal(list); err != nil {
+			return nil, 0, false
+		}
+	}
+	return truncated, len(items) - kept, true
+}
+
+// limitResponseSize returns `body`, the encoded `resp`, or a truncated version
+// if it is a result larger than the `maxResponseSize` option. The client is told
+// about truncated results with a log message.
+func (server *Server) limitResponseSize(resp any, body []byte) []byte {
+	limit := server.getOptions().maxResponseSize
+	response, ok := resp.(RPCSuccessResponse)
+	if limit <= 0 || len(body) <= limit || !ok {
+		return body
+	}
+	result, dropped, ok := truncateResult(response, body, limit)
+	if !ok {
+		slog.Warn("response exceeds size limit but can't be truncated", requestIDAttr(RPCRequest{ID: response.ID}), "bytes", len(body), "limit", limit)
+		return body
+	}
+	response.Result = result
+	truncated, err := json.Marshal(response)
+	if err != nil {
+		return body
+	}
+
+	message := fmt.Sprintf("Dropped %d results from a %d byte response to stay under maxResponseSize (%d bytes)", dropped, len(body), limit)
+	slog.Warn("response truncated", requestIDAttr(RPCRequest{ID: response.ID}), "dropped", dropped, "bytes", len(body), "limit", limit)
+	server.logMessage(MessageTypeWarning, message)
+	return truncated
+}
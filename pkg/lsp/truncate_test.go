@@ -0,0 +1,68 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestResponseSizeLimit(t *testing.T) {
+	var entries []TagEntry
+	var content strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&content, "func handler%03d() {}\n", i)
+	}
+	path := writeTestFile(t, t.TempDir(), "handlers.go", content.String())
+	for i := range 200 {
+		entries = append(entries, TagEntry{Name: fmt.Sprintf("handler%03d", i), Path: path, Kind: "function", Line: i + 1})
+	}
+	server := newTestServer(t, entries)
+	server.options.maxResponseSize = 4096
+
+	frames := callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: "handler"})
+	if len(frames) != 2 || frames[0].Method != "window/logMessage" {
+		t.Fatalf("expected a log message and the response, got %d frames", len(frames))
+	}
+	if !strings.Contains(string(frames[0].Params), "Dropped") {
+		t.Fatalf("expected the log message to report dropped results, got %s", frames[0].Params)
+	}
+	var symbols []SymbolInformation
+	if err := json.Unmarshal(frames[1].Result, &symbols); err != nil {
+		t.Fatalf("decode symbols: %v", err)
+	}
+	if len(symbols) == 0 || len(symbols) >= len(entries) {
+		t.Fatalf("expected some but not all %d symbols, got %d", len(entries), len(symbols))
+	}
+	if symbols[0].Name != "handler000" {
+		t.Fatalf("expected the first results to be kept, got %q", symbols[0].Name)
+	}
+	body, _ := json.Marshal(frames[1])
+	if len(body) > 4096 {
+		t.Fatalf("expected the response to fit in 4096 bytes, got %d", len(body))
+	}
+
+	var output bytes.Buffer
+	server.output = &output
+	id := json.RawMessage("2")
+	items := make([]CompletionItem, 200)
+	for i := range items {
+		items[i] = CompletionItem{Label: entries[i].Name}
+	}
+	server.sendResult(&id, CompletionList{Items: items})
+	frames = readFrames(t, &output)
+	var list CompletionList
+	if err := json.Unmarshal(frames[len(frames)-1].Result, &list); err != nil {
+		t.Fatalf("decode completion list: %v", err)
+	}
+	if !list.IsIncomplete || len(list.Items) == 0 || len(list.Items) >= len(items) {
+		t.Fatalf("expected a shorter incomplete list, got %d items, incomplete %v", len(list.Items), list.IsIncomplete)
+	}
+
+	server.options.maxResponseSize = 0
+	frames = callHandler(t, server, "workspace/symbol", WorkspaceSymbolParams{Query: "handler"})
+	if len(frames) != 1 {
+		t.Fatalf("expected only the response without a limit, got %d frames", len(frames))
+	}
+}
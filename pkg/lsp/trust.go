@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// Answers offered when asking whether to trust a workspace.
+const (
+	trustWorkspace     = "Trust"
+	distrustWorkspace  = "Don't trust"
+	untrustedWorkspace = "ctags-lsp: the workspace isn't trusted, so no commands are run in it and only an existing tagfile is used."
+)
+
+// isTrustedDir reports whether `dir` is one of `trustedDirs` or below one of them.
+func isTrustedDir(dir string, trustedDirs []string) bool {
+	for _, trusted := range trustedDirs {
+		if trusted, err := filepath.Abs(trusted); err == nil && isWithinDir(trusted, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWorkspaceTrust decides whether commands may run in the workspace. Without
+// `--require-trust` every workspace is trusted. Otherwise it must be in one of the
+// `--trusted-dirs`, or the user is asked; the answer holds for this session.
+// Trust can't be given through client settings, since those may come from the
+// workspace itself.
+func (server *Server) checkWorkspaceTrust() {
+	rootDir := fileURIToPath(server.rootURI)
+	trusted := !server.requireTrust || isTrustedDir(rootDir, server.trustedDirs) || server.confirmWorkspaceTrust(rootDir)
+	server.untrusted.Store(!trusted)
+	if !trusted {
+		slog.Warn("workspace is not trusted; not running commands in it", "root", rootDir)
+	}
+}
+
+// confirmWorkspaceTrust asks the user whether to trust the workspace at `rootDir`.
+// Without an answer it isn't trusted.
+func (server *Server) confirmWorkspaceTrust(rootDir string) bool {
+	message := fmt.Sprintf("ctags-lsp: do you trust the workspace %s? Indexing it runs git, jj and ctags in it, and ctags reads option files from it.", rootDir)
+	result, err := server.sendRequest("window/showMessageRequest", ShowMessageRequestParams{
+		Type:    MessageTypeWarning,
+		Message: message,
+		Actions: []MessageActionItem{
+			{Title: trustWorkspace},
+			{Title: distrustWorkspace},
+		},
+	})
+	if err != nil {
+		slog.Warn("no answer about trusting the workspace", "root", rootDir, "error", err)
+		return false
+	}
+
+	// The result is null when the user dismissed the message.
+	var action *MessageActionItem
+	if err := json.Unmarshal(result, &action); err != nil || action == nil {
+		return false
+	}
+	return action.Title == trustWorkspace
+}
+
+// mayRunCommands reports whether external commands may run on workspace files.
+func (server *Server) mayRunCommands() bool {
+	return !server.untrusted.Load()
+}
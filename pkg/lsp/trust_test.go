@@ -0,0 +1,110 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWorkspaceTrust(t *testing.T) {
+	server := newTestServer(t, nil)
+	dir := fileURIToPath(server.rootURI)
+	server.requireTrust = true
+	server.trustedDirs = []string{filepath.Dir(dir)}
+	server.checkWorkspaceTrust()
+	if !server.mayRunCommands() {
+		t.Fatalf("expected a workspace below a trusted dir to be trusted")
+	}
+
+	for _, tc := range []struct {
+		answer string
+		want   bool
+	}{
+		{answer: `{"title": "Trust"}`, want: true},
+		{answer: `{"title": "Don't trust"}`, want: false},
+		{answer: `null`, want: false},
+	} {
+		t.Run(tc.answer, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			server.requireTrust = true
+			reader, writer := io.Pipe()
+			server.output = writer
+
+			done := make(chan struct{})
+			go func() {
+				server.checkWorkspaceTrust()
+				close(done)
+			}()
+
+			body, err := readFrame(bufio.NewReader(reader))
+			if err != nil {
+				t.Fatalf("read message request: %v", err)
+			}
+			var request RPCOutgoingRequest
+			if err := json.Unmarshal(body, &request); err != nil {
+				t.Fatalf("unmarshal request: %v", err)
+			}
+			if request.Method != "window/showMessageRequest" {
+				t.Fatalf("expected window/showMessageRequest, got %q", request.Method)
+			}
+			id := json.RawMessage(strconv.FormatInt(request.ID, 10))
+			server.handleClientResponse(RPCRequest{ID: &id, Result: json.RawMessage(tc.answer)})
+			<-done
+
+			if server.mayRunCommands() != tc.want {
+				t.Fatalf("expected trust %v", tc.want)
+			}
+			if !tc.want && runtime.GOOS != "windows" {
+				writeTestFile(t, fileURIToPath(server.rootURI), "a.go", "package a\n")
+				bin := t.TempDir()
+				marker := filepath.Join(bin, "ran")
+				server.ctagsBin = filepath.Join(bin, "ctags")
+				if err := os.WriteFile(server.ctagsBin, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0o755); err != nil {
+					t.Fatalf("write fake ctags: %v", err)
+				}
+				if err := server.scanWorkspace(); err != nil {
+					t.Fatalf("scan workspace: %v", err)
+				}
+				if _, err := os.Stat(marker); err == nil {
+					t.Fatalf("expected ctags not to run in an untrusted workspace")
+				}
+			}
+		})
+	}
+}
+
+func TestUntrustedWorkspaceSettings(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.options.ctagArgs = strings.Fields("--fields=+n")
+	server.options.maxWorkspaceFiles = 100
+	var settings Settings
+	if err := json.Unmarshal([]byte(`{"ctagsArgs": "--options=evil.ctags  --kinds-c=+p", "compileCommands": "build", "maxWorkspaceFiles": 1000000, "goModuleDeps": true, "rubyGems": true, "includePaths": ["/usr/include"], "sitePackages": true, "venv": "/opt/venv", "jvmSources": true, "workspaceSymbolLimit": 7}`), &settings); err != nil {
+		t.Fatalf("unmarshal settings: %v", err)
+	}
+
+	server.untrusted.Store(true)
+	server.applySettings(settings)
+	options := server.getOptions()
+	if len(options.ctagArgs) != 1 || options.compileCommands != "" || options.maxWorkspaceFiles != 100 ||
+		options.goModuleDeps || options.rubyGems || options.includePaths != nil || options.sitePackages || options.venv != "" || options.jvmSources {
+		t.Fatalf("expected an untrusted workspace's settings not to change commands or scanned paths, got %+v", options)
+	}
+	if options.workspaceSymbolLimit != 7 {
+		t.Fatalf("expected other settings to apply, got limit %d", options.workspaceSymbolLimit)
+	}
+
+	server.untrusted.Store(false)
+	server.applySettings(settings)
+	options = server.getOptions()
+	if !slices.Equal(options.ctagArgs, []string{"--options=evil.ctags", "--kinds-c=+p"}) || options.compileCommands != "build" || options.maxWorkspaceFiles != 1000000 ||
+		!options.goModuleDeps || !options.rubyGems || len(options.includePaths) != 1 || !options.sitePackages || options.venv != "/opt/venv" || !options.jvmSources {
+		t.Fatalf("expected a trusted workspace's settings to apply, got %+v", options)
+	}
+}
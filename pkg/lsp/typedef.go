@@ -0,0 +1,129 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// typeAliasKinds are the kinds of tags that name another type, given by their
+// `typeref` field.
+var typeAliasKinds = map[string]bool{
+	"typedef":   true,
+	"alias":     true,
+	"typealias": true,
+	"using":     true,
+}
+
+// maxTypeAliasChain limits how many aliases of aliases are followed.
+const maxTypeAliasChain = 8
+
+// typeRefTarget returns the name and tag kind of the type a `typeref` field
+// refers to: "struct:point" names the struct `point`, "typename:const Node *" the
+// type `Node` of any kind, which is returned as "".
+func typeRefTarget(typeRef string) (name, kind string) {
+	kind, name, ok := strings.Cut(typeRef, ":")
+	if !ok {
+		return "", ""
+	}
+	if kind == "typename" {
+		kind = ""
+	}
+	name, _, _ = strings.Cut(name, "<")
+	name = strings.TrimRight(name, " *&[]")
+	if i := strings.LastIndexAny(name, " \t"); i >= 0 {
+		name = name[i+1:]
+	}
+	return unqualifiedName(name), kind
+}
+
+// followTypeAliases appends to the definitions `matches` the types that the
+// aliases among them stand for, each after its alias, following aliases of
+// aliases. Types are looked up by name like definitions, preferring the family of
+// the document `uri`.
+// The caller holds `server.mutex`.
+func (server *Server) followTypeAliases(matches []TagEntry, uri string) []TagEntry {
+	var family *documentFamily
+	result := make([]TagEntry, 0, len(matches))
+	seen := make(map[TagEntry]bool)
+	var follow func(entry TagEntry, depth int)
+	follow = func(entry TagEntry, depth int) {
+		if seen[entry] {
+			return
+		}
+		seen[entry] = true
+		result = append(result, entry)
+		if depth >= maxTypeAliasChain || !typeAliasKinds[entry.Kind] {
+			return
+		}
+		targets := server.typeRefEntries(entry)
+		if len(targets) == 0 {
+			return
+		}
+		if family == nil {
+			documentFamily := server.documentFamily(uri)
+			family = &documentFamily
+		}
+		for _, target := range preferDocumentFamily(targets, *family) {
+			follow(target, depth+1)
+		}
+	}
+	for _, entry := range matches {
+		follow(entry, 0)
+	}
+	return result
+}
+
+// typeRefEntries returns the tags of the type the `typeref` field of `entry`
+// names, or nil if it has none or names `entry` itself.
+// The caller holds `server.mutex`.
+func (server *Server) typeRefEntries(entry TagEntry) []TagEntry {
+	name, kind := typeRefTarget(entry.TypeRef)
+	if name == "" || name == unqualifiedName(entry.Name) && kind == "" {
+		return nil
+	}
+	var targets []TagEntry
+	for _, candidate := range server.tagEntries {
+		if candidate.Name == name && (kind == "" || candidate.Kind == kind) && !isQualifiedTag(candidate) {
+			targets = append(targets, candidate)
+		}
+	}
+	return targets
+}
+
+// handleTypeDefinition jumps from the symbol at the cursor to the type its
+// definitions have according to their `typeref` field, e.g. from a variable to
+// its struct. Aliases among the types are followed like with `--follow-typedefs`.
+func handleTypeDefinition(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	var types []TagEntry
+	family := server.documentFamily(normalizedURI)
+	for _, entry := range server.findDefinitionEntries(normalizedURI, params.Position, symbol) {
+		types = append(types, preferDocumentFamily(server.typeRefEntries(entry), family)...)
+	}
+	locations := server.entryLocations(server.followTypeAliases(types, normalizedURI))
+	if len(locations) == 0 {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, locations)
+}
@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestFollowTypedefs(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "shapes.h", "struct point { int x; };\ntypedef struct point point_t;\ntypedef const point_t *vec_t;\ntypedef int size;\n")
+	uri := writeTestFile(t, dir, "main.c", "#include \"shapes.h\"\nvec_t origin;\nsize count;\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "point", Path: header, Line: 1, Kind: "struct", Language: "C"},
+		{Name: "point_t", Path: header, Line: 2, Kind: "typedef", TypeRef: "struct:point", Language: "C"},
+		{Name: "vec_t", Path: header, Line: 3, Kind: "typedef", TypeRef: "typename:const point_t *", Language: "C"},
+		{Name: "size", Path: header, Line: 4, Kind: "typedef", TypeRef: "typename:int", Language: "C"},
+		{Name: "origin", Path: uri, Line: 2, Kind: "variable", TypeRef: "typename:vec_t", Language: "C"},
+	})
+	definitionLines := func(line int) []int {
+		frames := callHandler(t, server, "textDocument/definition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: 1},
+		})
+		var locations []Location
+		if err := json.Unmarshal(frames[0].Result, &locations); err != nil {
+			var location Location
+			if err := json.Unmarshal(frames[0].Result, &location); err != nil {
+				t.Fatalf("decode definition %s: %v", frames[0].Result, err)
+			}
+			locations = []Location{location}
+		}
+		var lines []int
+		for _, location := range locations {
+			lines = append(lines, location.Range.Start.Line)
+		}
+		return lines
+	}
+
+	if lines := definitionLines(1); !slices.Equal(lines, []int{2}) {
+		t.Fatalf("expected only the typedef without --follow-typedefs, got lines %v", lines)
+	}
+
+	server.options.followTypedefs = true
+	if lines := definitionLines(1); !slices.Equal(lines, []int{2, 1, 0}) {
+		t.Fatalf("expected vec_t, point_t and struct point, got lines %v", lines)
+	}
+	if lines := definitionLines(2); !slices.Equal(lines, []int{3}) {
+		t.Fatalf("expected a typedef of a builtin type to stay alone, got lines %v", lines)
+	}
+}
+
+func TestTypeDefinition(t *testing.T) {
+	dir := t.TempDir()
+	header := writeTestFile(t, dir, "shapes.h", "struct point { int x; };\ntypedef struct point point_t;\n")
+	uri := writeTestFile(t, dir, "main.c", "#include \"shapes.h\"\npoint_t origin;\nint count;\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "point", Path: header, Line: 1, Kind: "struct", Language: "C"},
+		{Name: "point_t", Path: header, Line: 2, Kind: "typedef", TypeRef: "struct:point", Language: "C"},
+		{Name: "origin", Path: uri, Line: 2, Kind: "variable", TypeRef: "typename:point_t", Language: "C"},
+		{Name: "count", Path: uri, Line: 3, Kind: "variable", TypeRef: "typename:int", Language: "C"},
+	})
+	typeDefinition := func(line, character int) json.RawMessage {
+		frames := callHandler(t, server, "textDocument/typeDefinition", TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: character},
+		})
+		return frames[0].Result
+	}
+
+	var locations []Location
+	if err := json.Unmarshal(typeDefinition(1, 10), &locations); err != nil {
+		t.Fatalf("decode type definition: %v", err)
+	}
+	if len(locations) != 2 || locations[0].URI != header || locations[0].Range.Start.Line != 1 || locations[1].Range.Start.Line != 0 {
+		t.Fatalf("expected point_t and then struct point, got %+v", locations)
+	}
+	if result := typeDefinition(2, 5); string(result) != "null" {
+		t.Fatalf("expected no type definition for a builtin type, got %s", result)
+	}
+}
+
+func TestTypeRefTarget(t *testing.T) {
+	cases := []struct {
+		typeRef, name, kind string
+	}{
+		{"struct:point", "point", "struct"},
+		{"typename:const Node *", "Node", ""},
+		{"typename:std::vector<int>", "vector", ""},
+		{"typename:unsigned long", "long", ""},
+		{"", "", ""},
+	}
+	for _, tc := range cases {
+		if name, kind := typeRefTarget(tc.typeRef); name != tc.name || kind != tc.kind {
+			t.Errorf("typeRefTarget(%q) = %q, %q, expected %q, %q", tc.typeRef, name, kind, tc.name, tc.kind)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package lsp
+
+import "encoding/json"
+
+// TypeHierarchyItem matches LSP 3.17 `TypeHierarchyItem`, which has the shape of
+// `CallHierarchyItem`.
+type TypeHierarchyItem CallHierarchyItem
+
+type TypeHierarchyParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+// typeGraph is the inheritance graph of the index, from the `inherits` fields of
+// its tags, as indices into `server.tagEntries`. It is built when first needed
+// and rebuilt once the index changes.
+type typeGraph struct {
+	generation int
+	size       int
+	supertypes map[int][]int
+	subtypes   map[int][]int
+}
+
+// typeHierarchyKinds are the symbol kinds of the tags a type hierarchy starts at,
+// besides those with bases or subtypes.
+var typeHierarchyKinds = map[int]bool{
+	SymbolKindClass:     true,
+	SymbolKindStruct:    true,
+	SymbolKindInterface: true,
+	SymbolKindEnum:      true,
+}
+
+// typeGraph returns the inheritance graph of the current index. Bases are looked
+// up by their unqualified name among the tags that aren't callables, preferring
+// one in the same file, like `ctags-lsp graph` does.
+// The caller holds `server.mutex`.
+func (server *Server) typeGraph() *typeGraph {
+	generation := server.indexGeneration()
+	if graph := server.types; graph != nil && graph.generation == generation && graph.size == len(server.tagEntries) {
+		return graph
+	}
+
+	byName := make(map[string][]int)
+	for i, entry := range server.tagEntries {
+		if !isCallableEntry(entry) && !isQualifiedTag(entry) {
+			byName[entry.Name] = append(byName[entry.Name], i)
+		}
+	}
+	graph := &typeGraph{
+		generation: generation,
+		size:       len(server.tagEntries),
+		supertypes: make(map[int][]int),
+		subtypes:   make(map[int][]int),
+	}
+	for i, entry := range server.tagEntries {
+		for _, base := range entry.Inherits.Names() {
+			parent := -1
+			for _, candidate := range byName[unqualifiedName(base)] {
+				if candidate == i {
+					continue
+				}
+				if server.tagEntries[candidate].Path == entry.Path {
+					parent = candidate
+					break
+				}
+				if parent < 0 {
+					parent = candidate
+				}
+			}
+			if parent >= 0 {
+				graph.supertypes[i] = append(graph.supertypes[i], parent)
+				graph.subtypes[parent] = append(graph.subtypes[parent], i)
+			}
+		}
+	}
+	server.types = graph
+	return graph
+}
+
+// handlePrepareTypeHierarchy returns an item for each definition of the type at
+// the cursor.
+func handlePrepareTypeHierarchy(server *Server, req RPCRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	symbol, err := server.getCurrentWord(normalizedURI, params.Position)
+	if err != nil {
+		server.sendResult(req.ID, nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	graph := server.typeGraph()
+	var items []TypeHierarchyItem
+	for _, entry := range server.findDefinitionEntries(normalizedURI, params.Position, symbol) {
+		if isCallableEntry(entry) || isQualifiedTag(entry) {
+			continue
+		}
+		if !typeHierarchyKinds[GetLSPSymbolKind(entry.Kind)] && entry.Inherits == "" {
+			if i, ok := server.findTagIndex(tagDataOf(entry)); !ok || len(graph.subtypes[i]) == 0 {
+				continue
+			}
+		}
+		if item, ok := server.hierarchyItem(entry); ok {
+			items = append(items, TypeHierarchyItem(item))
+		}
+	}
+	if len(items) == 0 {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	server.sendResult(req.ID, items)
+}
+
+// handleSupertypes returns the indexed bases of the item's type.
+func handleSupertypes(server *Server, req RPCRequest) {
+	handleTypeHierarchyStep(server, req, func(graph *typeGraph, i int) []int { return graph.supertypes[i] })
+}
+
+// handleSubtypes returns the indexed types that inherit from the item's type.
+func handleSubtypes(server *Server, req RPCRequest) {
+	handleTypeHierarchyStep(server, req, func(graph *typeGraph, i int) []int { return graph.subtypes[i] })
+}
+
+func handleTypeHierarchyStep(server *Server, req RPCRequest, step func(graph *typeGraph, i int) []int) {
+	var params TypeHierarchyParams
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Item.Data == nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	i, ok := server.findTagIndex(*params.Item.Data)
+	if !ok {
+		server.sendResult(req.ID, nil)
+		return
+	}
+	items := []TypeHierarchyItem{}
+	for _, related := range step(server.typeGraph(), i) {
+		if item, ok := server.hierarchyItem(server.tagEntries[related]); ok {
+			items = append(items, TypeHierarchyItem(item))
+		}
+	}
+	server.sendResult(req.ID, items)
+}
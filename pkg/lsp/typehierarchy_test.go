@@ -0,0 +1,55 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestTypeHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "shapes.py", "class Shape:\n    pass\n\nclass Square(Shape):\n    pass\n\nclass Cube(Square, Solid):\n    pass\n")
+	server := newTestServer(t, []TagEntry{
+		{Name: "Shape", Path: uri, Line: 1, Kind: "class", Language: "Python"},
+		{Name: "Square", Path: uri, Line: 4, Kind: "class", Language: "Python", Inherits: "Shape"},
+		{Name: "Cube", Path: uri, Line: 7, Kind: "class", Language: "Python", Inherits: "Square,Solid"},
+	})
+
+	frames := callHandler(t, server, "textDocument/prepareTypeHierarchy", TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: 6, Character: 12},
+	})
+	var items []TypeHierarchyItem
+	if err := json.Unmarshal(frames[0].Result, &items); err != nil {
+		t.Fatalf("decode prepare %s: %v", frames[0].Result, err)
+	}
+	if len(items) != 1 || items[0].Name != "Square" {
+		t.Fatalf("expected an item for Square, got %+v", items)
+	}
+
+	names := func(method string, item TypeHierarchyItem) []string {
+		frames := callHandler(t, server, method, TypeHierarchyParams{Item: item})
+		var items []TypeHierarchyItem
+		if err := json.Unmarshal(frames[0].Result, &items); err != nil {
+			t.Fatalf("decode %s %s: %v", method, frames[0].Result, err)
+		}
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	if supertypes := names("typeHierarchy/supertypes", items[0]); !slices.Equal(supertypes, []string{"Shape"}) {
+		t.Fatalf("expected Shape as the supertype of Square, got %v", supertypes)
+	}
+	if subtypes := names("typeHierarchy/subtypes", items[0]); !slices.Equal(subtypes, []string{"Cube"}) {
+		t.Fatalf("expected Cube as the subtype of Square, got %v", subtypes)
+	}
+
+	server.tagEntries = append(server.tagEntries, TagEntry{Name: "Circle", Path: uri, Line: 9, Kind: "class", Language: "Python", Inherits: "Shape"})
+	server.invalidateUsage()
+	shape := TypeHierarchyItem{Name: "Shape", Data: &tagData{Name: "Shape", Path: uri, Line: 1}}
+	if subtypes := names("typeHierarchy/subtypes", shape); !slices.Equal(subtypes, []string{"Square", "Circle"}) {
+		t.Fatalf("expected the graph to follow the index, got %v", subtypes)
+	}
+}
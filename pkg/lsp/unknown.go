@@ -0,0 +1,183 @@
+package lsp
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// cKeywords are shared by C and C++: keywords, preprocessor directives and the
+// standard library names most C files use without defining.
+const cKeywords = `auto break case char const continue default do double else enum extern float
+for goto if inline int long register restrict return short signed sizeof static struct
+switch typedef union unsigned void volatile while _Bool bool true false NULL
+size_t ssize_t ptrdiff_t intptr_t uintptr_t int8_t int16_t int32_t int64_t
+uint8_t uint16_t uint32_t uint64_t FILE stdin stdout stderr errno EOF
+include define undef ifdef ifndef elif endif pragma defined error
+printf fprintf sprintf snprintf puts putchar getchar scanf sscanf
+malloc calloc realloc free memcpy memmove memset memcmp
+strlen strcpy strncpy strcat strcmp strncmp strchr strrchr strstr strdup
+fopen fclose fread fwrite fgets fputs fflush exit abort assert`
+
+const javaScriptKeywords = `await break case catch class const continue debugger default delete do
+else export extends finally for from function if import in instanceof let new of
+return static super switch this throw try typeof var void while with yield async
+get set true false null undefined NaN Infinity arguments globalThis console window
+document Object Array String Number Boolean Symbol BigInt Map Set WeakMap WeakSet
+Promise JSON Math Date Error TypeError RangeError RegExp parseInt parseFloat isNaN
+setTimeout clearTimeout setInterval clearInterval require module exports process`
+
+// languageKeywords lists, by ctags language, the keywords and builtins that are
+// never tagged but are not unknown either. Languages missing here aren't checked
+// for unknown symbols, since everything they don't define would be reported.
+var languageKeywords = map[string]map[string]bool{
+	"C": wordSet(cKeywords),
+	"C++": wordSet(cKeywords + `
+class namespace template typename public private protected virtual override final
+friend operator new delete this using try catch throw noexcept constexpr consteval
+nullptr decltype explicit mutable static_cast dynamic_cast const_cast reinterpret_cast
+std string vector map set unordered_map unordered_set unique_ptr shared_ptr
+make_unique make_shared cout cerr endl move forward`),
+	"Go": wordSet(`break case chan const continue default defer else fallthrough for func go
+goto if import interface map package range return select struct switch type var
+bool byte complex64 complex128 error float32 float64 int int8 int16 int32 int64
+rune string uint uint8 uint16 uint32 uint64 uintptr any comparable true false iota nil
+append cap clear close complex copy delete imag len make max min new panic print
+println real recover`),
+	"Java": wordSet(`abstract assert boolean break byte case catch char class const continue
+default do double else enum extends final finally float for goto if implements import
+instanceof int interface long native new package private protected public return short
+static strictfp super switch synchronized this throw throws transient try void volatile
+while var record yield true false null String Object Integer Long Double Boolean
+Character Math System List Map Set ArrayList HashMap HashSet Override Exception
+RuntimeException`),
+	"JavaScript": wordSet(javaScriptKeywords),
+	"TypeScript": wordSet(javaScriptKeywords + `
+type interface enum namespace declare abstract implements private protected public
+readonly keyof infer is as satisfies any unknown never string number boolean bigint
+object Record Partial Required Readonly Pick Omit ReturnType`),
+	"Python": wordSet(`False None True and as assert async await break class continue def del
+elif else except finally for from global if import in is lambda nonlocal not or pass
+raise return try while with yield match case self cls print len range str int float
+bool bytes list dict set frozenset tuple object type isinstance issubclass super open
+enumerate zip map filter sorted reversed sum min max abs any all iter next repr hash
+id input format getattr setattr hasattr property staticmethod classmethod Exception
+ValueError TypeError KeyError IndexError RuntimeError AttributeError OSError
+NotImplementedError StopIteration __name__ __file__ __init__`),
+	"Ruby": wordSet(`BEGIN END alias and begin break case class def defined do else elsif end
+ensure false for if in module next nil not or redo rescue retry return self super then
+true undef unless until when while yield require require_relative include extend
+attr_reader attr_writer attr_accessor puts print p raise lambda proc new private
+protected public loop each map Integer String Array Hash Kernel StandardError`),
+	"Lua": wordSet(`and break do else elseif end false for function goto if in local nil not
+or repeat return then true until while print pairs ipairs require type tostring
+tonumber table string math os io error assert pcall xpcall select setmetatable
+getmetatable rawget rawset next unpack self`),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// unknownSymbolDiagnostics reports identifiers in `lines` that no tag defines and
+// that the file doesn't seem to define itself. Without a parser, an identifier
+// counts as defined locally when it occurs more than once, is followed by `=` or
+// `:` as in assignments, parameters with defaults and keyword arguments, or is on
+// a line where the file defines a tag. Members accessed with `.`, `->` or `::`
+// aren't checked. `entries` are the tags of `uri`.
+func (server *Server) unknownSymbolDiagnostics(uri string, lines []string, entries []TagEntry) []Diagnostic {
+	var language string
+	definitionLines := make(map[int]bool)
+	for _, entry := range entries {
+		language = cmp.Or(language, entry.Language)
+		definitionLines[entry.Line-1] = true
+	}
+	language = languageForFile(uri, language)
+	keywords, ok := languageKeywords[language]
+	if !ok {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	candidates := make(map[string]Range)
+	var order []string
+	forEachCodeIdentifier(lines, syntaxForFile(uri, language), func(identifier []rune, rng Range) {
+		name := string(identifier)
+		counts[name]++
+		if unicode.IsDigit(identifier[0]) || keywords[name] || definitionLines[rng.Start.Line] {
+			return
+		}
+		line := []rune(lines[rng.Start.Line])
+		if isMemberAccess(line[:rng.Start.Character]) || isBinding(line[rng.End.Character:]) {
+			return
+		}
+		if _, seen := candidates[name]; !seen {
+			order = append(order, name)
+		}
+		candidates[name] = rng
+	})
+	for name := range candidates {
+		if counts[name] > 1 {
+			delete(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	if len(server.tagEntries) == 0 {
+		// Before the workspace is indexed, every name would be unknown.
+		return nil
+	}
+	for _, tags := range [][]TagEntry{server.tagEntries, server.referenceEntries} {
+		for _, entry := range tags {
+			delete(candidates, entry.Name)
+		}
+	}
+
+	diagnostics := []Diagnostic{}
+	for _, name := range order {
+		rng, ok := candidates[name]
+		if !ok {
+			continue
+		}
+		message := fmt.Sprintf("Unknown symbol %q", name)
+		if suggestions := suggestNames(server.tagEntries, name); len(suggestions) > 0 {
+			message += fmt.Sprintf("; did you mean %q?", suggestions[0])
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    rng,
+			Severity: DiagnosticSeverityInformation,
+			Code:     "unknown-symbol",
+			Source:   diagnosticSource,
+			Message:  message,
+		})
+	}
+	return diagnostics
+}
+
+// isMemberAccess reports whether `before`, the text in front of an identifier,
+// ends in a member access operator.
+func isMemberAccess(before []rune) bool {
+	text := strings.TrimRightFunc(string(before), unicode.IsSpace)
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "->") || strings.HasSuffix(text, "::")
+}
+
+// isBinding reports whether `after`, the text behind an identifier, starts with an
+// assignment or a colon, but not a comparison or a scope separator.
+func isBinding(after []rune) bool {
+	text := strings.TrimLeftFunc(string(after), unicode.IsSpace)
+	switch {
+	case strings.HasPrefix(text, "=="), strings.HasPrefix(text, "::"):
+		return false
+	default:
+		return strings.HasPrefix(text, "=") || strings.HasPrefix(text, ":")
+	}
+}
@@ -0,0 +1,43 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnknownSymbolDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.py", "def handler(event):\n    total = len(event)\n    # hnadler in a comment\n    return hnadler(total, os.path, key=1)\n")
+	other := writeTestFile(t, dir, "b.txt", "hnadler\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "handler", Path: uri, Line: 1, Kind: "function", Language: "Python"},
+	})
+	server.options.unknownSymbols = true
+
+	diagnose := func(uri string) []Diagnostic {
+		frames := callHandler(t, server, "textDocument/diagnostic", DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		var report FullDocumentDiagnosticReport
+		if err := json.Unmarshal(frames[0].Result, &report); err != nil {
+			t.Fatalf("unmarshal report: %v", err)
+		}
+		return report.Items
+	}
+
+	items := diagnose(uri)
+	if len(items) != 2 || items[0].Code != "unknown-symbol" || items[1].Code != "unknown-symbol" {
+		t.Fatalf("expected two unknown-symbol diagnostics, got %+v", items)
+	}
+	if items[0].Message != `Unknown symbol "hnadler"; did you mean "handler"?` || items[0].Range.Start != (Position{Line: 3, Character: 11}) {
+		t.Fatalf("unexpected diagnostic: %+v", items[0])
+	}
+	if items[1].Message != `Unknown symbol "os"` || items[1].Severity != DiagnosticSeverityInformation {
+		t.Fatalf("unexpected diagnostic: %+v", items[1])
+	}
+
+	if items := diagnose(other); len(items) != 0 {
+		t.Fatalf("expected no diagnostics for a language without keywords, got %+v", items)
+	}
+}
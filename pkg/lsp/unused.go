@@ -0,0 +1,214 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+)
+
+// unusedSymbolKinds are the symbol kinds checked for uses. Others, like packages
+// or struct fields, are too often used in ways a text search can't see.
+var unusedSymbolKinds = map[int]bool{
+	SymbolKindFunction:  true,
+	SymbolKindMethod:    true,
+	SymbolKindClass:     true,
+	SymbolKindStruct:    true,
+	SymbolKindInterface: true,
+	SymbolKindEnum:      true,
+	SymbolKindConstant:  true,
+	SymbolKindVariable:  true,
+}
+
+// entryPointNames are called from outside the code, so they never look used.
+var entryPointNames = map[string]bool{
+	"main":     true,
+	"init":     true,
+	"__init__": true,
+}
+
+// usageIndex counts how often each name appears in the code of indexed files,
+// outside comments, string literals and the lines that define the name.
+type usageIndex struct {
+	uses map[string]int
+}
+
+// usageCounts keeps the name counts of each indexed file and their sum, so that
+// after an edit or a rescan of one file, only that file is counted again.
+type usageCounts struct {
+	files map[string]map[string]int // by `uriKey`
+	total map[string]int
+	// invalidated maps files to the generation their counts were last dropped at;
+	// counts begun before then are out of date. reset is that generation for all files.
+	invalidated map[string]int
+	reset       int
+}
+
+// invalidateUsage drops the usage counts of every file after the tag index was
+// rebuilt.
+func (server *Server) invalidateUsage() {
+	server.usageMutex.Lock()
+	server.usageGeneration++
+	server.usage = nil
+	server.usageCounts = usageCounts{reset: server.usageGeneration}
+	server.usageMutex.Unlock()
+}
+
+// invalidateUsageOf drops the usage counts of `uris` after their content or their
+// tags changed. Files no longer in the tag index are dropped by `usageIndex`.
+func (server *Server) invalidateUsageOf(uris ...string) {
+	server.usageMutex.Lock()
+	defer server.usageMutex.Unlock()
+	server.usageGeneration++
+	server.usage = nil
+	counts := &server.usageCounts
+	for _, uri := range uris {
+		key := uriKey(uri)
+		counts.drop(key)
+		if counts.invalidated == nil {
+			counts.invalidated = make(map[string]int)
+		}
+		counts.invalidated[key] = server.usageGeneration
+	}
+}
+
+// add records the counts of file `key` begun at `generation`, unless the file
+// was invalidated since.
+func (counts *usageCounts) add(key string, uses map[string]int, generation int) {
+	if counts.reset > generation || counts.invalidated[key] > generation {
+		return
+	}
+	counts.drop(key)
+	if counts.files == nil {
+		counts.files = make(map[string]map[string]int)
+		counts.total = make(map[string]int)
+	}
+	counts.files[key] = uses
+	for name, n := range uses {
+		counts.total[name] += n
+	}
+}
+
+func (counts *usageCounts) drop(key string) {
+	uses, ok := counts.files[key]
+	if !ok {
+		return
+	}
+	for name, n := range uses {
+		if counts.total[name] -= n; counts.total[name] <= 0 {
+			delete(counts.total, name)
+		}
+	}
+	delete(counts.files, key)
+}
+
+// indexGeneration returns a number that changes whenever the usage counts are
+// invalidated, for other caches derived from the tag index to check they are current.
+func (server *Server) indexGeneration() int {
+	server.usageMutex.Lock()
+	defer server.usageMutex.Unlock()
+	return server.usageGeneration
+}
+
+// usageIndex returns the usage index, counting the files whose counts are missing
+// or out of date. It returns nil if `ctx` ends first, since a partial count would
+// report used symbols as unused, but keeps the files it counted for the next call.
+// The caller must not hold `server.mutex`.
+func (server *Server) usageIndex(ctx context.Context) *usageIndex {
+	type definitionLine struct {
+		line int
+		name string
+	}
+	for {
+		server.usageMutex.Lock()
+		usage, generation := server.usage, server.usageGeneration
+		counted := make(map[string]bool, len(server.usageCounts.files))
+		for key := range server.usageCounts.files {
+			counted[key] = true
+		}
+		server.usageMutex.Unlock()
+		if usage != nil {
+			return usage
+		}
+
+		server.mutex.Lock()
+		indexed := make(map[string]bool)
+		definitions := make(map[string]map[definitionLine]bool)
+		languages := make(map[string]string)
+		var paths []string
+		for _, entry := range server.tagEntries {
+			key := uriKey(entry.Path)
+			indexed[key] = true
+			if counted[key] {
+				continue
+			}
+			if _, ok := definitions[entry.Path]; !ok {
+				definitions[entry.Path] = make(map[definitionLine]bool)
+				languages[entry.Path] = entry.Language
+				paths = append(paths, entry.Path)
+			}
+			definitions[entry.Path][definitionLine{entry.Line, entry.Name}] = true
+		}
+		server.mutex.Unlock()
+
+		for i, path := range paths {
+			if ctx.Err() != nil {
+				slog.Debug("usage index incomplete at deadline", "counted", i, "files", len(paths))
+				return nil
+			}
+			uses := make(map[string]int)
+			if lines, err := server.cache.peekFileContent(path); err == nil {
+				forEachCodeIdentifier(lines, syntaxForFile(path, languages[path]), func(identifier []rune, rng Range) {
+					name := string(identifier)
+					if !definitions[path][definitionLine{rng.Start.Line + 1, name}] {
+						uses[name]++
+					}
+				})
+			}
+			server.usageMutex.Lock()
+			server.usageCounts.add(uriKey(path), uses, generation)
+			server.usageMutex.Unlock()
+		}
+
+		server.usageMutex.Lock()
+		if server.usageGeneration == generation {
+			for key := range server.usageCounts.files {
+				if !indexed[key] {
+					server.usageCounts.drop(key)
+				}
+			}
+			server.usage = &usageIndex{uses: maps.Clone(server.usageCounts.total)}
+			if server.usage.uses == nil {
+				server.usage.uses = make(map[string]int)
+			}
+			usage = server.usage
+		}
+		server.usageMutex.Unlock()
+		if usage != nil || ctx.Err() != nil {
+			return usage
+		}
+		// A file changed while others were counted; count it too.
+	}
+}
+
+// unusedSymbolDiagnostic returns a hint for `entry` if its name is used nowhere
+// but on the lines defining it. Names are compared without regard to scope, so a
+// use of any symbol of the same name counts.
+func unusedSymbolDiagnostic(entry TagEntry, content []string, usage *usageIndex) (Diagnostic, bool) {
+	if usage == nil || entryPointNames[entry.Name] || usage.uses[entry.Name] > 0 {
+		return Diagnostic{}, false
+	}
+	// Kinds without a mapping are left out rather than checked as variables.
+	kind, ok := symbolKindByTagKind[entry.Kind]
+	if !ok || !unusedSymbolKinds[kind] {
+		return Diagnostic{}, false
+	}
+	return Diagnostic{
+		Range:    findSymbolRangeInFile(content, entry.Name, entry.Line),
+		Severity: DiagnosticSeverityHint,
+		Code:     "unused-symbol",
+		Source:   diagnosticSource,
+		Message:  fmt.Sprintf("%s %q is not used anywhere in the workspace", entry.Kind, entry.Name),
+		Tags:     []int{DiagnosticTagUnnecessary},
+	}, true
+}
@@ -0,0 +1,44 @@
+package lsp
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestUnusedSymbolDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	uri := writeTestFile(t, dir, "a.c", "int used(void) { return 1; }\nint unused(void) { return 2; }\nint main(void) { return used(); }\n")
+	other := writeTestFile(t, dir, "b.c", "/* unused */\nint helper(void) { return used(); }\n")
+
+	server := newTestServer(t, []TagEntry{
+		{Name: "used", Path: uri, Line: 1, Kind: "function", Language: "C"},
+		{Name: "unused", Path: uri, Line: 2, Kind: "function", Language: "C"},
+		{Name: "main", Path: uri, Line: 3, Kind: "function", Language: "C"},
+		{Name: "helper", Path: other, Line: 2, Kind: "function", Language: "C"},
+	})
+
+	diagnose := func() []Diagnostic {
+		frames := callHandler(t, server, "textDocument/diagnostic", DocumentDiagnosticParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+		})
+		var report FullDocumentDiagnosticReport
+		if err := json.Unmarshal(frames[0].Result, &report); err != nil {
+			t.Fatalf("unmarshal report: %v", err)
+		}
+		return report.Items
+	}
+
+	if items := diagnose(); len(items) != 0 {
+		t.Fatalf("expected no diagnostics by default, got %+v", items)
+	}
+
+	server.options.unusedSymbols = true
+	items := diagnose()
+	if len(items) != 1 || items[0].Code != "unused-symbol" || items[0].Severity != DiagnosticSeverityHint {
+		t.Fatalf("expected one unused-symbol hint, got %+v", items)
+	}
+	if items[0].Range.Start.Line != 1 || !slices.Equal(items[0].Tags, []int{DiagnosticTagUnnecessary}) {
+		t.Fatalf("unexpected unused-symbol diagnostic: %+v", items[0])
+	}
+}
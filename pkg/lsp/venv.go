@@ -0,0 +1,98 @@
+package lsp
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSitePackagesSize caps how many bytes of installed Python sources are indexed.
+// Environments with large packages such as numpy or torch would otherwise dwarf
+// the workspace itself.
+const maxSitePackagesSize = 200 << 20
+
+// venvDirs are looked for in the workspace root, in order, when no virtualenv is
+// configured or active.
+var venvDirs = []string{".venv", "venv"}
+
+// findVirtualenv returns the virtualenv to index: `configured` if set, then the
+// active one named by VIRTUAL_ENV, then one of `venvDirs` in the workspace root.
+// Only directories with a pyvenv.cfg count. It returns "" if there is none.
+func findVirtualenv(rootDir, configured string, getenv func(string) string) string {
+	candidates := []string{configured, getenv("VIRTUAL_ENV")}
+	for _, dir := range venvDirs {
+		candidates = append(candidates, filepath.Join(rootDir, dir))
+	}
+	for _, dir := range candidates {
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(rootDir, dir)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "pyvenv.cfg")); err == nil {
+			return filepath.Clean(dir)
+		}
+	}
+	return ""
+}
+
+// sitePackagesDirs returns the site-packages directories of the virtualenv at
+// `venv`: lib/pythonX.Y/site-packages on Unix, Lib/site-packages on Windows.
+func sitePackagesDirs(venv string) []string {
+	dirs, _ := filepath.Glob(filepath.Join(venv, "lib", "python*", "site-packages"))
+	if windows := filepath.Join(venv, "Lib", "site-packages"); len(dirs) == 0 {
+		if info, err := os.Stat(windows); err == nil && info.IsDir() {
+			dirs = append(dirs, windows)
+		}
+	}
+	return dirs
+}
+
+// sitePackagesFiles returns the Python sources installed in the virtualenv at
+// `venv`, leaving out files larger than `maxFileSize` (unless it is 0) and
+// stopping once `maxSitePackagesSize` bytes are collected.
+func sitePackagesFiles(venv string, maxFileSize int64) []string {
+	var files []string
+	var total int64
+	truncated := false
+	for _, dir := range sitePackagesDirs(venv) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if name == "__pycache__" || strings.HasSuffix(name, ".dist-info") || strings.HasSuffix(name, ".egg-info") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(name); ext != ".py" && ext != ".pyi" {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || (maxFileSize > 0 && info.Size() > maxFileSize) {
+				return nil
+			}
+			if total+info.Size() > maxSitePackagesSize {
+				truncated = true
+				return filepath.SkipAll
+			}
+			total += info.Size()
+			files = append(files, path)
+			return nil
+		})
+		if truncated {
+			break
+		}
+	}
+	if truncated {
+		slog.Warn("site-packages exceed the size cap, indexing only part of them", "venv", venv, "files", len(files), "cap", maxSitePackagesSize)
+	} else {
+		slog.Info("indexing site-packages", "venv", venv, "files", len(files))
+	}
+	return files
+}
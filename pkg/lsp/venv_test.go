@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSitePackagesFiles(t *testing.T) {
+	root := t.TempDir()
+	venv := filepath.Join(root, ".venv")
+	site := filepath.Join(venv, "lib", "python3.12", "site-packages")
+	for _, dir := range []string{filepath.Join(site, "requests", "__pycache__"), filepath.Join(site, "requests-2.0.dist-info")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+	}
+	writeTestFile(t, venv, "pyvenv.cfg", "home = /usr/bin\n")
+	for _, name := range []string{"requests/__init__.py", "requests/api.pyi", "requests/__pycache__/api.py", "requests/_speedups.so", "requests-2.0.dist-info/top.py"} {
+		writeTestFile(t, site, name, "def get(): pass\n")
+	}
+
+	noEnv := func(string) string { return "" }
+	if got := findVirtualenv(root, "", noEnv); got != venv {
+		t.Fatalf("expected virtualenv %q, got %q", venv, got)
+	}
+	active := func(name string) string {
+		if name == "VIRTUAL_ENV" {
+			return filepath.Join(root, "missing")
+		}
+		return ""
+	}
+	if got := findVirtualenv(root, "", active); got != venv {
+		t.Fatalf("expected a VIRTUAL_ENV without pyvenv.cfg to be skipped, got %q", got)
+	}
+	if got := findVirtualenv(t.TempDir(), "", noEnv); got != "" {
+		t.Fatalf("expected no virtualenv, got %q", got)
+	}
+
+	files := sitePackagesFiles(venv, 0)
+	want := []string{filepath.Join(site, "requests", "__init__.py"), filepath.Join(site, "requests", "api.pyi")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("expected %v, got %v", want, files)
+	}
+}
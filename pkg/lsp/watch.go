@@ -0,0 +1,305 @@
+package lsp
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netmute/ctags-lsp/internal/ctags"
+	"github.com/netmute/ctags-lsp/internal/workspace"
+)
+
+// watchPollInterval is how often `ctags-lsp watch` looks for changed files.
+const watchPollInterval = 2 * time.Second
+
+// watchRescanThreshold is how many files may change at once, e.g. on a branch
+// switch, before `ctags-lsp watch` rescans the workspace instead of each file.
+const watchRescanThreshold = 50
+
+// fileStamp is what `ctags-lsp watch` compares to tell that a file changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// runWatchCommand implements `ctags-lsp watch [options]`: it indexes `--root` like
+// the server would, writes the index to `--tagfile` ("tags" under the root by
+// default, or an Emacs TAGS file if it is named so) and keeps it up to date as
+// files change, until interrupted.
+func runWatchCommand(process Process, args []string, stdout, stderr io.Writer, checkCtags func(string) error) int {
+	config, err := parseFlags(args, stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	config.version = process.Version
+	if len(config.args) > 0 {
+		fmt.Fprintf(stderr, "Error: unexpected argument %q; use --root\n", config.args[0])
+		return 2
+	}
+	if err := process.setLogger(config, stderr); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if err := checkCtags(config.ctagsBin); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := watchWorkspace(ctx, newServer(config, io.Discard), config.root, watchPollInterval); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// watchWorkspace indexes `root`, writes the tagfile and then rescans the files
+// that changed every `interval`, rewriting the tagfile after each change, until
+// `ctx` ends. Existing tagfiles are ignored: the one being written would
+// otherwise be read back as the index.
+func watchWorkspace(ctx context.Context, server *Server, root string, interval time.Duration) error {
+	server.ignoreTagfiles = true
+	if err := server.Index(root); err != nil {
+		return err
+	}
+	rootDir := fileURIToPath(server.rootURI)
+	tagsPath := cmp.Or(server.localPath(server.tagfilePath), "tags")
+	if !filepath.IsAbs(tagsPath) {
+		tagsPath = filepath.Join(rootDir, tagsPath)
+	}
+	tagsPath = filepath.Clean(tagsPath)
+
+	stamps := server.stampWorkspaceFiles(rootDir, tagsPath)
+	if err := server.writeWatchedTagfile(tagsPath); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := server.stampWorkspaceFiles(rootDir, tagsPath)
+		var changed, removed []string
+		for path, stamp := range current {
+			if previous, ok := stamps[path]; !ok || previous != stamp {
+				changed = append(changed, path)
+			}
+		}
+		for path := range stamps {
+			if _, ok := current[path]; !ok {
+				removed = append(removed, path)
+			}
+		}
+		stamps = current
+		if len(changed) == 0 && len(removed) == 0 {
+			continue
+		}
+
+		slog.Info("files changed", "changed", len(changed), "removed", len(removed))
+		if len(changed)+len(removed) > watchRescanThreshold {
+			if err := server.rescanWorkspace(); err != nil {
+				slog.Warn("failed to rescan workspace", "error", err)
+			}
+		} else {
+			for _, path := range removed {
+				server.removeIndexedPath(pathToFileURI(path))
+			}
+			for _, path := range changed {
+				if err := server.scanSingleFileTag(pathToFileURI(path)); err != nil {
+					slog.Warn("failed to rescan file", "path", path, "error", err)
+				}
+			}
+		}
+		if err := server.writeWatchedTagfile(tagsPath); err != nil {
+			slog.Warn("failed to write tagfile", "path", tagsPath, "error", err)
+		}
+	}
+}
+
+// stampWorkspaceFiles lists the files of the workspace like a scan does, except
+// for the tagfile, with their modification time and size.
+func (server *Server) stampWorkspaceFiles(rootDir, tagsPath string) map[string]fileStamp {
+	options := server.getOptions()
+	files, _, err := workspace.ListFiles(rootDir, options.maxFileSize, options.exclude)
+	if err != nil {
+		slog.Warn("failed to list workspace files", "error", err)
+	}
+	stamps := make(map[string]fileStamp, len(files))
+	for _, path := range files {
+		if path == "" {
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootDir, path)
+		}
+		if path == tagsPath {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// writeWatchedTagfile writes the index to `tagsPath`, replacing the file at once
+// so that editors never read half of it.
+func (server *Server) writeWatchedTagfile(tagsPath string) error {
+	server.mutex.Lock()
+	entries := slices.Concat(server.tagEntries, server.referenceEntries)
+	server.mutex.Unlock()
+	entries = slices.DeleteFunc(entries, isQualifiedTag)
+
+	var buffer bytes.Buffer
+	if filepath.Base(tagsPath) == "TAGS" {
+		writeEtags(&buffer, entries, filepath.Dir(tagsPath))
+	} else {
+		writeCtags(&buffer, entries, filepath.Dir(tagsPath), server.version)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(tagsPath), ".tags-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buffer.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), tagsPath); err != nil {
+		return err
+	}
+	slog.Info("wrote tagfile", "path", tagsPath, "tags", len(entries))
+	return nil
+}
+
+// writeCtags writes `entries` in the extended format of Universal Ctags, sorted,
+// with paths relative to `dir`, where the tagfile is, and `version` as the
+// version of the program that wrote them.
+func writeCtags(w io.Writer, entries []TagEntry, dir, version string) {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		var line strings.Builder
+		pattern := entry.Pattern
+		if pattern == "" {
+			pattern = strconv.Itoa(entry.Line)
+		}
+		fmt.Fprintf(&line, "%s\t%s\t%s;\"", entry.Name, tagfileRelativePath(dir, entry.Path), pattern)
+		fields := [][2]string{
+			{"kind", entry.Kind},
+			{"line", strconv.Itoa(entry.Line)},
+			{"end", strconv.Itoa(entry.End)},
+			{"language", entry.Language},
+			{"signature", entry.Signature},
+			{"typeref", entry.TypeRef},
+			{"inherits", string(entry.Inherits)},
+			{"roles", entry.Roles},
+		}
+		if entry.Scope != "" {
+			scope := entry.Scope
+			if entry.ScopeKind != "" {
+				scope = entry.ScopeKind + ":" + scope
+			}
+			fields = append(fields, [2]string{"scope", scope})
+		}
+		for _, field := range fields {
+			if field[1] != "" && field[1] != "0" {
+				fmt.Fprintf(&line, "\t%s:%s", field[0], field[1])
+			}
+		}
+		lines = append(lines, line.String())
+	}
+	slices.Sort(lines)
+
+	fmt.Fprintln(w, "!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/")
+	fmt.Fprintln(w, "!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/")
+	fmt.Fprintln(w, "!_TAG_PROGRAM_NAME\tctags-lsp\t//")
+	fmt.Fprintf(w, "!_TAG_PROGRAM_VERSION\t%s\t//\n", version)
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// writeEtags writes `entries` in the format of Emacs TAGS files: a section per
+// file, with the text of each definition's line up to the name, the name, and
+// the line's number and byte offset.
+func writeEtags(w io.Writer, entries []TagEntry, dir string) {
+	byPath := make(map[string][]TagEntry)
+	for _, entry := range entries {
+		if entry.Line > 0 && !ctags.IsReference(entry) {
+			byPath[entry.Path] = append(byPath[entry.Path], entry)
+		}
+	}
+	for _, uri := range slices.Sorted(maps.Keys(byPath)) {
+		content, err := os.ReadFile(fileURIToPath(uri))
+		if err != nil {
+			continue
+		}
+		var offsets []int
+		for offset := 0; offset <= len(content); {
+			offsets = append(offsets, offset)
+			next := bytes.IndexByte(content[offset:], '\n')
+			if next < 0 {
+				break
+			}
+			offset += next + 1
+		}
+
+		fileEntries := byPath[uri]
+		slices.SortFunc(fileEntries, func(a, b TagEntry) int { return cmp.Compare(a.Line, b.Line) })
+		var section bytes.Buffer
+		for _, entry := range fileEntries {
+			if entry.Line > len(offsets) {
+				continue
+			}
+			start := offsets[entry.Line-1]
+			end := len(content)
+			if entry.Line < len(offsets) {
+				end = offsets[entry.Line] - 1
+			}
+			text := strings.TrimRight(string(content[start:end]), "\r")
+			if i := strings.Index(text, entry.Name); i >= 0 {
+				text = text[:i+len(entry.Name)]
+			}
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", text, entry.Name, entry.Line, start)
+		}
+		fmt.Fprintf(w, "\x0c\n%s,%d\n", tagfileRelativePath(dir, uri), section.Len())
+		w.Write(section.Bytes())
+	}
+}
+
+// tagfileRelativePath returns the path of `uri` relative to `dir`, with forward
+// slashes, or its absolute path if it's outside.
+func tagfileRelativePath(dir, uri string) string {
+	path := fileURIToPath(uri)
+	if relative, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(relative) {
+		return filepath.ToSlash(relative)
+	}
+	return path
+}
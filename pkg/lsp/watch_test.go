@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchWorkspace(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ctags is a shell script")
+	}
+
+	script := `#!/bin/sh
+files="$*"
+case "$*" in *"-L -"*) files="$(cat)" ;; esac
+for f in $files; do
+	case "$f" in
+	*.go) awk -v f="$f" '/^func /{ name=$2; sub(/\(.*/, "", name); printf "{\"_type\": \"tag\", \"name\": \"%s\", \"path\": \"%s\", \"line\": %d, \"kind\": \"func\", \"pattern\": \"/^%s$/\"}\n", name, f, NR, $0 }' "$f" ;;
+	esac
+done
+`
+	ctagsBin := filepath.Join(t.TempDir(), "ctags")
+	if err := os.WriteFile(ctagsBin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake ctags: %v", err)
+	}
+	root := t.TempDir()
+	writeTestFile(t, root, "view.go", "package view\n\nfunc Render() {}\n")
+	writeTestFile(t, root, "old.go", "package view\n\nfunc Legacy() {}\n")
+	// A stale tagfile must not be read back as the index.
+	writeTestFile(t, root, "tags", "Stale\tview.go\t1;\"\tkind:func\n")
+
+	config, err := parseFlags([]string{"ctags-lsp", "--ctags-bin", ctagsBin}, io.Discard)
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	server := newServer(config, io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watchWorkspace(ctx, server, root, 10*time.Millisecond) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("watch: %v", err)
+		}
+	}()
+
+	tagsPath := filepath.Join(root, "tags")
+	waitForTags := func(want func(names map[string]int) bool) map[string]int {
+		t.Helper()
+		var names map[string]int
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			entries, err := server.parseTagfile(tagsPath)
+			if err != nil {
+				continue
+			}
+			names = make(map[string]int)
+			for _, entry := range entries {
+				names[entry.Name] = entry.Line
+			}
+			if want(names) {
+				return names
+			}
+		}
+		t.Fatalf("tagfile never matched, last read %v", names)
+		return nil
+	}
+
+	waitForTags(func(names map[string]int) bool {
+		return names["Render"] == 3 && names["Legacy"] == 3 && names["Stale"] == 0
+	})
+
+	writeTestFile(t, root, "view.go", "package view\n\nfunc Render() {}\n\nfunc Paint() {}\n")
+	if err := os.Remove(filepath.Join(root, "old.go")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	names := waitForTags(func(names map[string]int) bool { _, legacy := names["Legacy"]; return names["Paint"] == 5 && !legacy })
+	if names["Render"] != 3 {
+		t.Fatalf("expected Render to stay indexed, got %v", names)
+	}
+
+	content, err := os.ReadFile(tagsPath)
+	if err != nil {
+		t.Fatalf("read tagfile: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "!_TAG_FILE_FORMAT\t2\t") || !strings.Contains(string(content), "Paint\tview.go\t/^func Paint() {}$/;\"\tkind:func\tline:5\n") {
+		t.Fatalf("unexpected tagfile:\n%s", content)
+	}
+}
+
+func TestWriteEtags(t *testing.T) {
+	root := t.TempDir()
+	uri := writeTestFile(t, root, "view.go", "package view\n\nfunc Render() {}\n")
+
+	var output bytes.Buffer
+	writeEtags(&output, []TagEntry{{Name: "Render", Path: uri, Line: 3, Kind: "func"}}, root)
+	section := "func Render\x7fRender\x013,14\n"
+	if want := fmt.Sprintf("\x0c\nview.go,%d\n%s", len(section), section); output.String() != want {
+		t.Fatalf("expected %q, got %q", want, output.String())
+	}
+}
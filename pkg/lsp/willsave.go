@@ -0,0 +1,81 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf16"
+)
+
+type WillSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Reason       int                    `json:"reason"`
+}
+
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+func handleWillSave(_ *Server, _ RPCRequest) {
+	// Nothing to prepare; supported so clients can complete their save handshake.
+}
+
+// handleWillSaveWaitUntil returns the edits to apply before saving.
+// By default there are none; with trailing-whitespace trimming enabled,
+// every line's trailing spaces and tabs are removed.
+func handleWillSaveWaitUntil(server *Server, req RPCRequest) {
+	var params WillSaveTextDocumentParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", nil)
+		return
+	}
+
+	edits := []TextEdit{}
+	if !server.getOptions().trimTrailingWhitespace {
+		server.sendResult(req.ID, edits)
+		return
+	}
+
+	normalizedURI, err := normalizeDocumentURI(server.localURI(params.TextDocument.URI))
+	if err != nil {
+		server.sendError(req.ID, -32602, "Invalid params", err.Error())
+		return
+	}
+
+	server.cache.mutex.RLock()
+	lines, ok := server.cache.content[uriKey(normalizedURI)]
+	server.cache.mutex.RUnlock()
+	if !ok {
+		server.sendResult(req.ID, edits)
+		return
+	}
+
+	server.sendResult(req.ID, trailingWhitespaceEdits(lines))
+}
+
+func trailingWhitespaceEdits(lines []string) []TextEdit {
+	edits := []TextEdit{}
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t")
+		if len(trimmed) == len(line) {
+			continue
+		}
+		edits = append(edits, TextEdit{
+			Range: Range{
+				Start: Position{Line: i, Character: utf16Len(trimmed)},
+				End:   Position{Line: i, Character: utf16Len(line)},
+			},
+			NewText: "",
+		})
+	}
+	return edits
+}
+
+// utf16Len returns the length of `s` in UTF-16 code units, the unit LSP positions use.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWillSaveWaitUntilTrimsTrailingWhitespace(t *testing.T) {
+	server := newTestServer(t, nil)
+	uri := pathToFileURI(t.TempDir()) + "/a.go"
+	server.cache.content[uriKey(uri)] = []string{"func a() {  ", "\treturn\t \t", "// héllo wörld 🙂 ", "}"}
+	params := WillSaveTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}, Reason: 1}
+
+	frames := callHandler(t, server, "textDocument/willSaveWaitUntil", params)
+	if string(frames[0].Result) != "[]" {
+		t.Fatalf("expected no edits without trimming enabled, got %s", frames[0].Result)
+	}
+
+	server.options.trimTrailingWhitespace = true
+	frames = callHandler(t, server, "textDocument/willSaveWaitUntil", params)
+	var edits []TextEdit
+	if err := json.Unmarshal(frames[0].Result, &edits); err != nil {
+		t.Fatalf("decode edits %s: %v", frames[0].Result, err)
+	}
+	want := []Range{
+		{Start: Position{Line: 0, Character: 10}, End: Position{Line: 0, Character: 12}},
+		{Start: Position{Line: 1, Character: 7}, End: Position{Line: 1, Character: 10}},
+		// Clients count characters in UTF-16 code units unless told otherwise,
+		// so the emoji counts twice.
+		{Start: Position{Line: 2, Character: 17}, End: Position{Line: 2, Character: 18}},
+	}
+	if len(edits) != len(want) {
+		t.Fatalf("expected %d edits, got %+v", len(want), edits)
+	}
+	for i, edit := range edits {
+		if edit.Range != want[i] || edit.NewText != "" {
+			t.Errorf("edit %d: expected deletion of %+v, got %+v", i, want[i], edit)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// Values of `--path-mapping`.
+const (
+	pathMappingNone = "none"
+	pathMappingWSL  = "wsl"
+)
+
+var (
+	wslDrivePath     = regexp.MustCompile(`^/mnt/([a-zA-Z])(/.*)?$`)
+	windowsDrivePath = regexp.MustCompile(`^/?([a-zA-Z]):([/\\].*)?$`)
+	wslFileURI       = regexp.MustCompile(`"file:///mnt/[a-zA-Z]/`)
+	windowsFileURI   = regexp.MustCompile(`"file:///[a-zA-Z]:/`)
+)
+
+// validatePathMapping checks a `--path-mapping` value.
+func validatePathMapping(value string) error {
+	switch value {
+	case pathMappingNone, pathMappingWSL:
+		return nil
+	}
+	return fmt.Errorf("invalid path mapping %q: expected %q or %q", value, pathMappingNone, pathMappingWSL)
+}
+
+// windowsToWSLPath turns C:\dir, C:/dir or /C:/dir (the path of a Windows file
+// URI) into /mnt/c/dir.
+func windowsToWSLPath(path string) (string, bool) {
+	match := windowsDrivePath.FindStringSubmatch(path)
+	if match == nil {
+		return "", false
+	}
+	return "/mnt/" + strings.ToLower(match[1]) + strings.ReplaceAll(match[2], `\`, "/"), true
+}
+
+// wslToWindowsPath turns /mnt/c/dir into C:\dir.
+func wslToWindowsPath(path string) (string, bool) {
+	match := wslDrivePath.FindStringSubmatch(path)
+	if match == nil {
+		return "", false
+	}
+	rest := strings.ReplaceAll(match[2], "/", `\`)
+	if rest == "" {
+		rest = `\`
+	}
+	return strings.ToUpper(match[1]) + ":" + rest, true
+}
+
+// fromForeignPath translates `path` from the other form to the form of the OS the
+// server runs on.
+func fromForeignPath(path string) (string, bool) {
+	if runtime.GOOS == "windows" {
+		return wslToWindowsPath(path)
+	}
+	return windowsToWSLPath(path)
+}
+
+// localPath returns `path` in the form of the OS the server runs on. Without
+// `server.wslPaths` it is returned unchanged.
+func (server *Server) localPath(path string) string {
+	if !server.wslPaths {
+		return path
+	}
+	if translated, ok := fromForeignPath(path); ok {
+		return translated
+	}
+	return path
+}
+
+// localURI returns the file URI `uri` from the client with its path in the form
+// of the OS the server runs on, like `localPath`.
+func (server *Server) localURI(uri string) string {
+	if !server.wslPaths {
+		return uri
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	if path, ok := fromForeignPath(parsed.Path); ok {
+		return pathToFileURI(filepath.Clean(path))
+	}
+	return uri
+}
+
+// clientUsesForeignPaths reports whether the client named the workspace root in
+// the other form, so URIs sent to it must be translated back.
+func (server *Server) clientUsesForeignPaths(params InitializeParams) bool {
+	if !server.wslPaths {
+		return false
+	}
+	root := params.RootPath
+	if len(params.WorkspaceFolders) > 0 {
+		root = params.WorkspaceFolders[0].URI
+	} else if params.RootURI != "" {
+		root = params.RootURI
+	}
+	if parsed, err := url.Parse(root); err == nil && parsed.Scheme == "file" {
+		root = parsed.Path
+	}
+	_, ok := fromForeignPath(root)
+	return ok
+}
+
+// toForeignURIs translates the file URIs in the JSON message `body` to the form
+// of the other OS.
+func toForeignURIs(body []byte) []byte {
+	if runtime.GOOS == "windows" {
+		// "file:///C:/ becomes "file:///mnt/c/.
+		return windowsFileURI.ReplaceAllFunc(body, func(match []byte) []byte {
+			return []byte(`"file:///mnt/` + strings.ToLower(string(match[9])) + "/")
+		})
+	}
+	// "file:///mnt/c/ becomes "file:///C:/.
+	return wslFileURI.ReplaceAllFunc(body, func(match []byte) []byte {
+		return []byte(`"file:///` + strings.ToUpper(string(match[13])) + ":/")
+	})
+}
@@ -0,0 +1,49 @@
+package lsp
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWSLPathMapping(t *testing.T) {
+	if path, ok := windowsToWSLPath(`C:\src\app\main.go`); !ok || path != "/mnt/c/src/app/main.go" {
+		t.Fatalf("unexpected WSL path %q", path)
+	}
+	if path, ok := wslToWindowsPath("/mnt/d/src"); !ok || path != `D:\src` {
+		t.Fatalf("unexpected Windows path %q", path)
+	}
+	if _, ok := wslToWindowsPath("/mnt/data/src"); ok {
+		t.Fatal("expected a directory below /mnt that isn't a drive to stay untranslated")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("the rest runs the server on the WSL side")
+	}
+
+	server := newTestServer(t, nil)
+	if uri := server.localURI("file:///c%3A/src/app"); uri != "file:///c%3A/src/app" {
+		t.Fatalf("expected no translation without --path-mapping wsl, got %q", uri)
+	}
+	server.wslPaths = true
+	uri, err := normalizeFileURI(server.localURI("file:///c%3A/src/app"))
+	if err != nil || uri != "file:///mnt/c/src/app" {
+		t.Fatalf("expected the client URI in WSL form, got %q (%v)", uri, err)
+	}
+	path, err := normalizePath("/mnt/c/src/app", server.localPath(`C:\src\app\main.go`))
+	if err != nil || path != "/mnt/c/src/app/main.go" {
+		t.Fatalf("expected the ctags path in WSL form, got %q (%v)", path, err)
+	}
+	if !server.clientUsesForeignPaths(InitializeParams{RootURI: "file:///c%3A/src/app"}) || server.clientUsesForeignPaths(InitializeParams{RootURI: "file:///mnt/c/src/app"}) {
+		t.Fatal("expected only the Windows root to count as foreign")
+	}
+
+	var output bytes.Buffer
+	server.output = &output
+	server.foreignClientPaths.Store(true)
+	server.sendResult(nil, Location{URI: "file:///mnt/c/src/app/main.go"})
+	frames := readFrames(t, &output)
+	if len(frames) != 1 || !strings.Contains(string(frames[0].Result), `"file:///C:/src/app/main.go"`) {
+		t.Fatalf("expected the response URI in Windows form, got %+v", frames)
+	}
+}